@@ -25,10 +25,12 @@ import (
 )
 
 // Usage: builder <shared volume path>
-func Run(logger *zap.Logger, shareVolume string) error {
-	builder := builder.MakeBuilder(logger, shareVolume)
+func Run(logger *zap.Logger, shareVolume string, cacheDir string) error {
+	builder := builder.MakeBuilder(logger, shareVolume, cacheDir)
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", builder.Handler)
+	mux.HandleFunc("/logs/", builder.LogsHandler)
+	mux.HandleFunc("/cancel/", builder.CancelHandler)
 	mux.HandleFunc("/version", builder.VersionHandler)
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -49,6 +49,17 @@ func main() {
 		}
 	}
 
-	err = app.Run(logger, shareVolume)
+	// dependencies are cached between builds if the builder manager
+	// mounted a cache volume
+	cacheDir := os.Getenv("BUILDER_CACHE_DIR")
+	if len(cacheDir) > 0 {
+		err = os.MkdirAll(cacheDir, 0755)
+		if err != nil {
+			logger.Error("error creating build cache directory, disabling build cache", zap.Error(err), zap.String("directory", cacheDir))
+			cacheDir = ""
+		}
+	}
+
+	err = app.Run(logger, shareVolume, cacheDir)
 	logger.Error("error running builder", zap.Error(err))
 }
@@ -27,9 +27,11 @@ import (
 	"sync/atomic"
 
 	"contrib.go.opencensus.io/exporter/jaeger"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opencensus.io/plugin/ochttp"
 	"go.opencensus.io/trace"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/resource"
 
 	"github.com/fission/fission/pkg/fetcher"
 )
@@ -68,6 +70,8 @@ func Run(logger *zap.Logger) {
 	specializePayload := flag.String("specialize-request", "", "JSON payload for specialize request")
 	secretDir := flag.String("secret-dir", "", "Path to shared secrets directory")
 	configDir := flag.String("cfgmap-dir", "", "Path to shared configmap directory")
+	archiveCacheDir := flag.String("archive-cache-dir", "", "Path to the archive cache directory shared by the fetchers of the node")
+	archiveCacheSize := flag.String("archive-cache-size", "1Gi", "Maximum size of the archive cache")
 
 	flag.Parse()
 	if flag.NArg() == 0 {
@@ -91,7 +95,12 @@ func Run(logger *zap.Logger) {
 		}
 	}()
 
-	f, err := fetcher.MakeFetcher(logger, dir, *secretDir, *configDir)
+	cacheSize, err := resource.ParseQuantity(*archiveCacheSize)
+	if err != nil {
+		logger.Fatal("error parsing archive cache size", zap.Error(err), zap.String("size", *archiveCacheSize))
+	}
+
+	f, err := fetcher.MakeFetcher(logger, dir, *secretDir, *configDir, *archiveCacheDir, cacheSize.Value())
 	if err != nil {
 		logger.Fatal("error making fetcher", zap.Error(err))
 	}
@@ -119,9 +128,11 @@ func Run(logger *zap.Logger) {
 	mux.HandleFunc("/fetch", f.FetchHandler)
 	mux.HandleFunc("/specialize", f.SpecializeHandler)
 	mux.HandleFunc("/upload", f.UploadHandler)
+	mux.HandleFunc("/refresh", f.RefreshHandler)
 	mux.HandleFunc("/version", f.VersionHandler)
 	mux.HandleFunc("/wsevent/start", f.WsStartHandler)
 	mux.HandleFunc("/wsevent/end", f.WsEndHandler)
+	mux.Handle("/metrics", promhttp.Handler())
 
 	readinessHandler := func(w http.ResponseWriter, r *http.Request) {
 		if atomic.LoadUint32(&readyToServe) == 1 {
@@ -146,5 +157,5 @@ func Run(logger *zap.Logger) {
 }
 
 func fetcherUsage() {
-	fmt.Println("Usage: fetcher [-specialize-on-startup] [-specialize-request <json>] [-secret-dir <string>] [-cfgmap-dir <string>] <shared volume path>")
+	fmt.Println("Usage: fetcher [-specialize-on-startup] [-specialize-request <json>] [-secret-dir <string>] [-cfgmap-dir <string>] [-archive-cache-dir <string>] [-archive-cache-size <quantity>] <shared volume path>")
 }
@@ -43,8 +43,8 @@ import (
 	"github.com/fission/fission/pkg/utils/profile"
 )
 
-func runController(logger *zap.Logger, port int) {
-	controller.Start(logger, port, false)
+func runController(logger *zap.Logger, port int, routerUrl string) {
+	controller.Start(logger, port, routerUrl, false)
 	logger.Fatal("controller exited")
 }
 
@@ -209,7 +209,7 @@ Use it to start one or more of the fission servers:
  backends.
 
 Usage:
-  fission-bundle --controllerPort=<port>
+  fission-bundle --controllerPort=<port> [--routerUrl=<url>]
   fission-bundle --routerPort=<port> [--executorUrl=<url>]
   fission-bundle --executorPort=<port> [--namespace=<namespace>] [--fission-namespace=<namespace>]
   fission-bundle --kubewatcher [--routerUrl=<url>]
@@ -278,7 +278,7 @@ Options:
 
 	if arguments["--controllerPort"] != nil {
 		port := getPort(logger, arguments["--controllerPort"])
-		runController(logger, port)
+		runController(logger, port, routerUrl)
 	}
 
 	if arguments["--routerPort"] != nil {
@@ -322,6 +322,10 @@ Options:
 
 		if arguments["--storageType"] != nil && arguments["--storageType"] == string(storagesvc.StorageTypeS3) {
 			storage = storagesvc.NewS3Storage()
+		} else if arguments["--storageType"] == string(storagesvc.StorageTypeGCS) {
+			storage = storagesvc.NewGCSStorage()
+		} else if arguments["--storageType"] == string(storagesvc.StorageTypeAzure) {
+			storage = storagesvc.NewAzureStorage()
 		} else if arguments["--storageType"] == string(storagesvc.StorageTypeLocal) {
 			storage = storagesvc.NewLocalStorage("/fission")
 		}
@@ -37,6 +37,10 @@ const (
 
 	// ArchiveTypeUrl means the package contents are at the specified URL.
 	ArchiveTypeUrl ArchiveType = "url"
+
+	// ArchiveTypeOCI means the package contents are an OCI artifact in a registry,
+	// referenced by digest in the URL field.
+	ArchiveTypeOCI ArchiveType = "oci"
 )
 
 const (
@@ -71,6 +75,32 @@ const (
 	PodInfoMount           = "/etc/podinfo"
 )
 
+const (
+	// AllowConcurrent allows runs of a time trigger to overlap.
+	AllowConcurrent ConcurrencyPolicy = "Allow"
+
+	// ForbidConcurrent skips a run if the previous one is still running.
+	ForbidConcurrent ConcurrencyPolicy = "Forbid"
+
+	// ReplaceConcurrent cancels the running run and starts the new one.
+	ReplaceConcurrent ConcurrencyPolicy = "Replace"
+)
+
+const (
+	// CatchUpOnce fires the latest run missed within the starting deadline.
+	CatchUpOnce CatchUpPolicy = "Once"
+
+	// CatchUpAll fires every run missed within the starting deadline.
+	CatchUpAll CatchUpPolicy = "All"
+)
+
+const (
+	WatchPredicateOpIn           WatchPredicateOperator = "In"
+	WatchPredicateOpNotIn        WatchPredicateOperator = "NotIn"
+	WatchPredicateOpExists       WatchPredicateOperator = "Exists"
+	WatchPredicateOpDoesNotExist WatchPredicateOperator = "DoesNotExist"
+)
+
 const (
 	MessageQueueTypeNats  = "nats-streaming"
 	MessageQueueTypeASQ   = "azure-storage-queue"
@@ -91,9 +121,24 @@ const (
 )
 
 const (
-	// failure type currently supported is http status code. This could be extended
-	// in the future.
-	FailureTypeStatusCode FailureType = "status-code"
+	// failure types of canary configs: the percentage of requests failed with an http
+	// status code, the latency percentile of the new version of the function, or its
+	// latency percentile relative to the old version
+	FailureTypeStatusCode      FailureType = "status-code"
+	FailureTypeLatency         FailureType = "latency"
+	FailureTypeRelativeLatency FailureType = "relative-latency"
+
+	// DefaultCanaryLatencyPercentile is the latency percentile compared by default
+	DefaultCanaryLatencyPercentile = 99
+
+	// metrics providers of canary configs
+	CanaryMetricsProviderPrometheus CanaryMetricsProviderType = "prometheus"
+	CanaryMetricsProviderWebhook    CanaryMetricsProviderType = "webhook"
+
+	// strategies of canary configs: the canary strategy shifts the traffic to the new
+	// version of the function gradually, the blue/green strategy switches all of it at once
+	CanaryStrategyCanary    CanaryStrategy = "canary"
+	CanaryStrategyBlueGreen CanaryStrategy = "bluegreen"
 
 	// Status of canary config can be one of the following
 	CanaryConfigStatusPending   = "pending"
@@ -101,6 +146,26 @@ const (
 	CanaryConfigStatusFailed    = "failed"
 	CanaryConfigStatusAborted   = "aborted"
 
+	// manual actions on canary configs
+	CanaryActionPromote CanaryAction = "promote"
+	CanaryActionAbort   CanaryAction = "abort"
+
+	// transitions of canary configs
+	CanaryTransitionPaused          CanaryTransitionType = "paused"
+	CanaryTransitionResumed         CanaryTransitionType = "resumed"
+	CanaryTransitionPromoted        CanaryTransitionType = "promoted"
+	CanaryTransitionAborted         CanaryTransitionType = "aborted"
+	CanaryTransitionRolledBack      CanaryTransitionType = "rolled-back"
+	CanaryTransitionSucceeded       CanaryTransitionType = "succeeded"
+	CanaryTransitionSwitched        CanaryTransitionType = "switched"
+	CanaryTransitionSmokeTestFailed CanaryTransitionType = "smoke-test-failed"
+
+	// MaxCanaryTransitions is the number of transitions kept in the status of canary configs
+	MaxCanaryTransitions = 20
+
+	// MaxCanaryHistory is the number of weight increment intervals kept in the status of canary configs
+	MaxCanaryHistory = 20
+
 	// set a max number for iterations to prevent infinite processing of canary config
 	MaxIterationsForCanaryConfig = 10
 )
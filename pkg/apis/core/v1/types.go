@@ -20,6 +20,7 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 //
@@ -132,7 +133,8 @@ type (
 	KubernetesWatchTrigger struct {
 		metav1.TypeMeta   `json:",inline"`
 		metav1.ObjectMeta `json:"metadata"`
-		Spec              KubernetesWatchTriggerSpec `json:"spec"`
+		Spec              KubernetesWatchTriggerSpec   `json:"spec"`
+		Status            KubernetesWatchTriggerStatus `json:"status,omitempty"`
 	}
 
 	// KubernetesWatchTriggerList is a list of KubernetesWatchTriggers
@@ -153,7 +155,8 @@ type (
 		metav1.TypeMeta   `json:",inline"`
 		metav1.ObjectMeta `json:"metadata"`
 
-		Spec TimeTriggerSpec `json:"spec"`
+		Spec   TimeTriggerSpec   `json:"spec"`
+		Status TimeTriggerStatus `json:"status,omitempty"`
 	}
 
 	// TimeTriggerList is a list of TimeTriggers.
@@ -224,7 +227,7 @@ type (
 		Sum  string       `json:"sum,omitempty"`
 	}
 
-	// ArchiveType is either literal or URL, indicating whether
+	// ArchiveType is either literal, URL or OCI, indicating whether
 	// the package is specified in the Archive struct or
 	// externally.
 	ArchiveType string
@@ -232,10 +235,11 @@ type (
 	// Archive contains or references a collection of source or
 	// binary files.
 	Archive struct {
-		// Type defines how the package is specified: literal or URL.
+		// Type defines how the package is specified: literal, URL or OCI.
 		// Available value:
 		//  - literal
 		//  - url
+		//  - oci
 		// +optional
 		Type ArchiveType `json:"type,omitempty"`
 
@@ -244,7 +248,9 @@ type (
 		// +optional
 		Literal []byte `json:"literal,omitempty"`
 
-		// URL references a package.
+		// URL references a package. For OCI archives, it is the
+		// reference of the artifact by digest, such as
+		// registry.example.com/repo@sha256:<digest>.
 		// +optional
 		URL string `json:"url,omitempty"`
 
@@ -252,6 +258,28 @@ type (
 		// referenced by URL. Ignored for literals.
 		// +optional
 		Checksum Checksum `json:"checksum,omitempty"`
+
+		// ImagePullSecret is the name of the docker registry secret,
+		// in the namespace of the package, holding the credentials
+		// to pull OCI archives.
+		// +optional
+		ImagePullSecret string `json:"imagepullsecret,omitempty"`
+
+		// Signature is the detached signature of the archive, checked
+		// by the fetcher if the environment has a signature policy.
+		// +optional
+		Signature *ArchiveSignature `json:"signature,omitempty"`
+	}
+
+	// ArchiveSignature is an ed25519 signature of the sha256 checksum
+	// of an archive, of the message "sha256:<hex checksum>".
+	ArchiveSignature struct {
+		// KeyID is the fingerprint of the public key of the signature.
+		// +optional
+		KeyID string `json:"keyid,omitempty"`
+
+		// Signature is the base64 encoded signature.
+		Signature string `json:"signature"`
 	}
 
 	// EnvironmentReference is a reference to a environment.
@@ -294,9 +322,42 @@ type (
 		// +optional
 		BuildCommand string `json:"buildcmd,omitempty"`
 
+		// BuildTimeout is the maximum duration in seconds of the build command,
+		// after which the build is stopped and fails. Defaults to the build
+		// timeout of the environment builder.
+		// +optional
+		BuildTimeout *int `json:"buildTimeout,omitempty"`
+
+		// BuildRetries is the number of times a build failing on a transient
+		// error, like failing to download the source archive, is retried.
+		// Defaults to the build retries of the environment builder.
+		// +optional
+		BuildRetries *int `json:"buildRetries,omitempty"`
+
+		// OCIRepository is the registry repository the deployment archive built
+		// from the source archive is pushed to, as an OCI artifact, instead of
+		// being uploaded to the storage service. Defaults to the OCI repository
+		// of the environment builder.
+		// +optional
+		OCIRepository *OCIRepository `json:"ociRepository,omitempty"`
+
 		// In the future, we can have a debug build here too
 	}
 
+	// OCIRepository is a registry repository archives are pushed to.
+	OCIRepository struct {
+		// Repository is the registry repository, such as
+		// registry.example.com/team/repo[:tag]. Archives are tagged with
+		// their checksum unless the repository has a tag.
+		Repository string `json:"repository"`
+
+		// ImagePullSecret is the name of the docker registry secret, in the
+		// namespace of the package, holding the credentials to push and pull
+		// the archives.
+		// +optional
+		ImagePullSecret string `json:"imagepullsecret,omitempty"`
+	}
+
 	// PackageStatus contains the build status of a package also the build log for examination.
 	PackageStatus struct {
 		// TODO: Add another status field to indicate whether a package
@@ -316,6 +377,51 @@ type (
 		// +optional
 		// +nullable
 		LastUpdateTimestamp metav1.Time `json:"lastUpdateTimestamp,omitempty"`
+
+		// Provenance records what the deployment archive was built from,
+		// for auditing and tracing a deployment back to its source.
+		// +optional
+		Provenance *BuildProvenance `json:"provenance,omitempty"`
+	}
+
+	// BuildProvenance records the inputs and output of a package build.
+	BuildProvenance struct {
+		// SourceChecksum is the checksum of the source archive.
+		// +optional
+		SourceChecksum Checksum `json:"sourceChecksum,omitempty"`
+
+		// BuilderImage is the image of the environment builder.
+		// +optional
+		BuilderImage string `json:"builderImage,omitempty"`
+
+		// BuilderImageDigest is the ID, including the digest, of the image
+		// the builder container ran.
+		// +optional
+		BuilderImageDigest string `json:"builderImageDigest,omitempty"`
+
+		// BuildCommand is the command the builder ran.
+		// +optional
+		BuildCommand string `json:"buildCommand,omitempty"`
+
+		// EnvironmentResourceVersion is the resource version of the
+		// environment the package was built with.
+		// +optional
+		EnvironmentResourceVersion string `json:"environmentResourceVersion,omitempty"`
+
+		// StartTimestamp is the time the build started.
+		// +optional
+		// +nullable
+		StartTimestamp metav1.Time `json:"startTimestamp,omitempty"`
+
+		// EndTimestamp is the time the build completed.
+		// +optional
+		// +nullable
+		EndTimestamp metav1.Time `json:"endTimestamp,omitempty"`
+
+		// DeploymentChecksum is the checksum of the deployment archive the
+		// build produced.
+		// +optional
+		DeploymentChecksum Checksum `json:"deploymentChecksum,omitempty"`
 	}
 
 	// PackageRef is a reference to the package.
@@ -549,6 +655,12 @@ type (
 		//
 		// You can set either PodSpec or Container, but not both.
 		PodSpec *apiv1.PodSpec `json:"podspec,omitempty"`
+
+		// (Optional) Reload tells that the runtime reloads the secrets and
+		// configmaps of the function through its /reload endpoint, so that
+		// the function pods are refreshed in place instead of being recycled
+		// when they change.
+		Reload bool `json:"reload,omitempty"`
 	}
 
 	// Builder is the setting for environment builder.
@@ -572,6 +684,19 @@ type (
 
 		// PodSpec will store the spec of the pod that will be applied to the pod created for the builder
 		PodSpec *apiv1.PodSpec `json:"podspec,omitempty"`
+
+		// (Optional) BuildTimeout is the default maximum duration in seconds of
+		// the build command of packages. Builds don't time out if zero.
+		BuildTimeout int `json:"buildTimeout,omitempty"`
+
+		// (Optional) BuildRetries is the default number of times a package build
+		// failing on a transient error is retried.
+		BuildRetries int `json:"buildRetries,omitempty"`
+
+		// (Optional) OCIRepository is the default registry repository the
+		// deployment archives of packages are pushed to. They are uploaded to
+		// the storage service if not set.
+		OCIRepository *OCIRepository `json:"ociRepository,omitempty"`
 	}
 
 	// EnvironmentSpec contains with builder, runtime and some other related environment settings.
@@ -637,7 +762,29 @@ type (
 		// private registry.
 		// +optional
 		ImagePullSecret string `json:"imagepullsecret"`
+
+		// SignaturePolicy makes the fetcher refuse to load archives
+		// not signed by one of the trusted keys of the environment.
+		// +optional
+		SignaturePolicy *SignaturePolicy `json:"signaturePolicy,omitempty"`
+	}
+
+	// SignaturePolicy is the signature policy of an environment.
+	SignaturePolicy struct {
+		// KeySecrets are the names of the secrets, in the namespace of
+		// the environment, holding the trusted public keys under the
+		// "ed25519.pub" key.
+		KeySecrets []string `json:"keySecrets"`
+
+		// BuilderKeySecret is the name of the secret, in the namespace
+		// of the environment, holding the private key under the
+		// "ed25519.key" key with which the builder manager signs the
+		// deployment archives it builds. Its public key should be one
+		// of the trusted keys.
+		// +optional
+		BuilderKeySecret string `json:"builderKeySecret,omitempty"`
 	}
+
 	// AllowedFunctionsPerContainer defaults to 'single'. Related to Fission Workflows
 	AllowedFunctionsPerContainer string
 
@@ -713,20 +860,119 @@ type (
 
 	// KubernetesWatchTriggerSpec defines spec of KuberenetesWatchTrigger
 	KubernetesWatchTriggerSpec struct {
+		// Namespace of the resources to watch. Ignored for cluster scoped resources.
 		Namespace string `json:"namespace"`
 
-		// Type of resource to watch (Pod, Service, etc.)
+		// Type of resource to watch (Pod, Service, Deployment, etc.). Any
+		// kind or resource name served by the API server, including custom
+		// resources, is accepted. Ignored if Resource is set.
+		// +optional
 		Type string `json:"type"`
 
+		// Group of the resource to watch, empty for the core group. It
+		// disambiguates Type and Resource names served by several groups.
+		// +optional
+		Group string `json:"group,omitempty"`
+
+		// Version of the resource to watch. Defaults to the preferred version
+		// of the group.
+		// +optional
+		Version string `json:"version,omitempty"`
+
+		// Resource is the plural resource name to watch, e.g. deployments.
+		// +optional
+		Resource string `json:"resource,omitempty"`
+
 		// Resource labels
 		// +optional
 		LabelSelector map[string]string `json:"labelselector"`
 
+		// FieldSelector restricts the watched resources by field, e.g.
+		// status.phase=Running. The supported fields depend on the resource.
+		// +optional
+		FieldSelector string `json:"fieldselector,omitempty"`
+
+		// EventTypes are the watch event types the function is invoked for.
+		// Available value:
+		// - ADDED
+		// - MODIFIED
+		// - DELETED
+		// All event types if empty.
+		// +optional
+		EventTypes []string `json:"eventtypes,omitempty"`
+
+		// Predicates are evaluated on the watched object before the function
+		// is invoked, all of them have to match.
+		// +optional
+		Predicates []WatchPredicate `json:"predicates,omitempty"`
+
 		// The reference to a function for kubewatcher to invoke with
 		// when receiving events.
 		FunctionReference FunctionReference `json:"functionref"`
 	}
 
+	// WatchPredicate matches watched objects on the value of a field.
+	WatchPredicate struct {
+		// Path is a JSONPath template selecting the field, e.g. {.status.phase}
+		Path string `json:"path"`
+
+		// Operator applied to the values of the field.
+		// Available value:
+		// - In: a value is in Values
+		// - NotIn: no value is in Values
+		// - Exists: the field exists
+		// - DoesNotExist: the field does not exist
+		Operator WatchPredicateOperator `json:"operator"`
+
+		// Values compared to the values of the field, for the In and NotIn operators.
+		// +optional
+		Values []string `json:"values,omitempty"`
+	}
+
+	// WatchPredicateOperator is the operator of a watch predicate.
+	WatchPredicateOperator string
+
+	// KubernetesWatchTriggerStatus records where the kubewatcher left off
+	// processing the events of the watched objects, so that it resumes from
+	// there after a restart. The watched objects themselves are kept in a
+	// ConfigMap owned by the trigger, to find those deleted in the meantime.
+	KubernetesWatchTriggerStatus struct {
+		// LastResourceVersion is the resource version of the last processed event.
+		// Objects with a later resource version changed while the kubewatcher
+		// was down.
+		// +optional
+		LastResourceVersion string `json:"lastResourceVersion,omitempty"`
+
+		// LastUpdateTime is when the status was last updated. Objects created
+		// since were added while the kubewatcher was down.
+		// +optional
+		LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+
+		// LastErrorTime is the last time listing or watching the watched
+		// objects failed.
+		// +optional
+		LastErrorTime *metav1.Time `json:"lastErrorTime,omitempty"`
+
+		// LastError is the error of the last failed attempt to list or watch
+		// the watched objects, e.g. when the kubewatcher isn't allowed to.
+		// It is cleared once they are listed.
+		// +optional
+		LastError string `json:"lastError,omitempty"`
+	}
+
+	// WatchedObject identifies a version of a watched object, as recorded
+	// by the kubewatcher.
+	WatchedObject struct {
+		// +optional
+		Namespace string `json:"namespace,omitempty"`
+
+		Name string `json:"name"`
+
+		UID types.UID `json:"uid"`
+
+		ResourceVersion string `json:"resourceVersion"`
+	}
+
 	// MessageQueueType refers to Type of message queue
 	MessageQueueType string
 
@@ -808,7 +1054,79 @@ type (
 
 		// The reference to function
 		FunctionReference `json:"functionref"`
+
+		// Timezone is the IANA name of the time zone the cron schedule is
+		// evaluated in, e.g. "Europe/Berlin". Defaults to the time zone of
+		// the timer, which is UTC in the default deployment.
+		// +optional
+		Timezone string `json:"timezone,omitempty"`
+
+		// Body is sent as the request body to the function on every run.
+		// +optional
+		Body string `json:"body,omitempty"`
+
+		// ContentType is the content type of the request body.
+		// +optional
+		ContentType string `json:"contentType,omitempty"`
+
+		// JitterSeconds delays each run by a random duration of up to the
+		// given number of seconds, to spread the load of triggers sharing
+		// the same schedule.
+		// +optional
+		JitterSeconds int32 `json:"jitterSeconds,omitempty"`
+
+		// ConcurrencyPolicy specifies how to treat a run that is due while
+		// the previous one is still running. A run is running until the
+		// function responded or the request was given up on.
+		// Available value:
+		// - Allow (default): runs may overlap
+		// - Forbid: the new run is skipped
+		// - Replace: the running run is cancelled in favor of the new one
+		// +optional
+		ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+
+		// StartingDeadlineSeconds enables catching up on runs missed while the
+		// timer was down. When the timer starts, runs that were scheduled within
+		// the given number of seconds since the last schedule time are fired.
+		// Missed runs are skipped if not set.
+		// +optional
+		StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+
+		// CatchUpPolicy specifies how many of the missed runs within the
+		// starting deadline are fired.
+		// Available value:
+		// - Once (default): the latest missed run only
+		// - All: every missed run, oldest first
+		// +optional
+		CatchUpPolicy CatchUpPolicy `json:"catchUpPolicy,omitempty"`
 	}
+
+	// ConcurrencyPolicy describes how overlapping runs of a time trigger are handled.
+	ConcurrencyPolicy string
+
+	// CatchUpPolicy describes how runs of a time trigger missed while the
+	// timer was down are handled.
+	CatchUpPolicy string
+
+	// TimeTriggerStatus is the observed state of the runs of a time trigger.
+	TimeTriggerStatus struct {
+		// LastScheduleTime is the last time a run was scheduled.
+		// +optional
+		LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+		// LastSuccessfulTime is the last time a run completed successfully.
+		// +optional
+		LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+
+		// LastErrorTime is the last time a run failed.
+		// +optional
+		LastErrorTime *metav1.Time `json:"lastErrorTime,omitempty"`
+
+		// LastError is the error of the last failed run.
+		// +optional
+		LastError string `json:"lastError,omitempty"`
+	}
+
 	// FailureType refers to the type of failure
 	FailureType string
 
@@ -836,11 +1154,204 @@ type (
 		FailureThreshold int `json:"failurethreshold"`
 		// +optional
 		FailureType FailureType `json:"failureType"`
+
+		// Latency percentile compared by the latency failure types, ex: 99 for the p99 latency (default: 99)
+		// +optional
+		LatencyPercentile int `json:"latencyPercentile,omitempty"`
+
+		// Latency beyond which the new version of the function is considered unstable for the latency failure type,
+		// string representation of time.Duration, ex: 500ms
+		// +optional
+		LatencyThreshold string `json:"latencyThreshold,omitempty"`
+
+		// Minimum number of requests to the functions in a weight increment interval before the new version
+		// of the function is analysed (default: 1)
+		// +optional
+		MinRequests int `json:"minRequests,omitempty"`
+
+		// Metrics provider analysing the new version of the function (default: the prometheus server of the
+		// canary deployment feature)
+		// +optional
+		MetricsProvider *CanaryMetricsProvider `json:"metricsProvider,omitempty"`
+
+		// Custom queries the new version of the function is analysed with, in addition to the failure type
+		// +optional
+		Queries []CanaryMetricQuery `json:"queries,omitempty"`
+
+		// Paused canary configs keep the current weights of the functions until resumed
+		// +optional
+		Paused bool `json:"paused,omitempty"`
+
+		// Action taken on the canary config: promote sends all the traffic to the new version of the function,
+		// abort rolls back to the old version
+		// +optional
+		Action CanaryAction `json:"action,omitempty"`
+
+		// Strategy of the canary config: canary shifts the traffic to the new version of the function gradually,
+		// bluegreen switches all the traffic at once and rolls back if the analysis after the switch fails
+		// (default: canary)
+		// +optional
+		Strategy CanaryStrategy `json:"strategy,omitempty"`
+
+		// Weights of the new version of the function and how long each is kept before the next one, replacing
+		// the weight increment for the canary strategy, ex: 1% for 1m, 5% for 5m, 25% for 10m, then 100%
+		// +optional
+		Steps []CanaryWeightStep `json:"steps,omitempty"`
+
+		// Function called before the traffic is switched to the new version of the function for the bluegreen
+		// strategy. The switch is cancelled unless it responds with a 2xx status code
+		// +optional
+		SmokeTestFunction string `json:"smokeTestFunction,omitempty"`
+	}
+
+	// CanaryAction refers to a manual action on a canary config
+	CanaryAction string
+
+	// CanaryStrategy refers to the way the traffic is shifted to the new version of the function
+	CanaryStrategy string
+
+	// CanaryWeightStep is a weight of the new version of a function in the step schedule of a canary config
+	CanaryWeightStep struct {
+		// Weight of the new version of the function
+		Weight int `json:"weight"`
+
+		// How long the weight is kept before the next step, string representation of time.Duration
+		// (default: the weight increment interval)
+		// +optional
+		Duration string `json:"duration,omitempty"`
+	}
+
+	// CanaryMetricsProviderType refers to the type of metrics provider
+	CanaryMetricsProviderType string
+
+	// CanaryMetricsProvider is the metrics provider analysing the new version of a function
+	CanaryMetricsProvider struct {
+		// Type of the metrics provider: prometheus or webhook
+		Type CanaryMetricsProviderType `json:"type"`
+
+		// URL of the prometheus server, or of the webhook called with the canary config analysed and
+		// returning whether the new version of the function passes
+		// +optional
+		URL string `json:"url,omitempty"`
+	}
+
+	// CanaryMetricQuery is a custom query the new version of a function is analysed with
+	CanaryMetricQuery struct {
+		// Name of the query
+		Name string `json:"name"`
+
+		// Query template, executed with the function ({{ .Function }}) and namespace ({{ .Namespace }}) of the
+		// new version of the function, the old version of the function ({{ .OldFunction }}), the http trigger
+		// url ({{ .Path }}) and methods regular expression ({{ .Methods }}), and the weight increment interval
+		// ({{ .Window }})
+		Query string `json:"query"`
+
+		// Minimum value of the query result, below which the new version of the function is considered unstable
+		// +optional
+		Min string `json:"min,omitempty"`
+
+		// Maximum value of the query result, beyond which the new version of the function is considered unstable
+		// +optional
+		Max string `json:"max,omitempty"`
 	}
 
 	// CanaryConfigStatus represents canary config status
 	CanaryConfigStatus struct {
 		Status string `json:"status"`
+
+		// Whether the canary config is paused
+		// +optional
+		Paused bool `json:"paused,omitempty"`
+
+		// Weight of the new version of the function
+		// +optional
+		Weight int `json:"weight,omitempty"`
+
+		// Time of the last transition
+		// +optional
+		LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+
+		// Last transitions of the canary config, the most recent last
+		// +optional
+		Transitions []CanaryTransition `json:"transitions,omitempty"`
+
+		// Current weights of the functions
+		// +optional
+		FunctionWeights map[string]int `json:"functionWeights,omitempty"`
+
+		// Number of weight increments of the new version of the function
+		// +optional
+		Step int `json:"step,omitempty"`
+
+		// Last weight increment intervals of the canary config, the most recent last
+		// +optional
+		History []CanaryStep `json:"history,omitempty"`
+	}
+
+	// CanaryStep is a weight increment interval of a canary config
+	CanaryStep struct {
+		// Number of weight increments at the end of the interval
+		Step int `json:"step"`
+
+		// End time of the interval
+		Time metav1.Time `json:"time"`
+
+		// Weights of the functions at the end of the interval
+		// +optional
+		FunctionWeights map[string]int `json:"functionWeights,omitempty"`
+
+		// Analysis of the new version of the function in the interval
+		// +optional
+		Analysis *CanaryAnalysis `json:"analysis,omitempty"`
+	}
+
+	// CanaryAnalysis is the analysis of the new version of a function in a weight increment interval
+	CanaryAnalysis struct {
+		// Verdict of the analysis: pass, fail or inconclusive
+		Verdict string `json:"verdict"`
+
+		// Reason of the verdict
+		// +optional
+		Reason string `json:"reason,omitempty"`
+
+		// Number of requests to the new version of the function
+		// +optional
+		Requests int `json:"requests,omitempty"`
+
+		// Percentage of failed requests to the new version of the function
+		// +optional
+		FailurePercentage string `json:"failurePercentage,omitempty"`
+
+		// Latency percentile of the new version of the function, string representation of time.Duration
+		// +optional
+		Latency string `json:"latency,omitempty"`
+
+		// Latency percentile of the old version of the function, string representation of time.Duration
+		// +optional
+		OldLatency string `json:"oldLatency,omitempty"`
+
+		// Results of the custom queries
+		// +optional
+		Queries map[string]string `json:"queries,omitempty"`
+	}
+
+	// CanaryTransitionType refers to the type of transition of a canary config
+	CanaryTransitionType string
+
+	// CanaryTransition is a transition of a canary config
+	CanaryTransition struct {
+		// Type of the transition
+		Type CanaryTransitionType `json:"type"`
+
+		// Time of the transition
+		Time metav1.Time `json:"time"`
+
+		// Weight of the new version of the function after the transition
+		Weight int `json:"weight"`
+
+		// Message describing the transition
+		// +optional
+		Message string `json:"message,omitempty"`
 	}
 
 	// MetadataAccessor lets you work with object metadata and type metadata
@@ -28,29 +28,75 @@ package v1
 
 // AUTO-GENERATED FUNCTIONS START HERE. DO NOT EDIT.
 var map_Archive = map[string]string{
-	"":         "Archive contains or references a collection of source or binary files.",
-	"type":     "Type defines how the package is specified: literal or URL. Available value:\n - literal\n - url",
-	"literal":  "Literal contents of the package. Can be used for encoding packages below TODO (256KB?) size.",
-	"url":      "URL references a package.",
-	"checksum": "Checksum ensures the integrity of packages referenced by URL. Ignored for literals.",
+	"":                "Archive contains or references a collection of source or binary files.",
+	"type":            "Type defines how the package is specified: literal, URL or OCI. Available value:\n - literal\n - url\n - oci",
+	"literal":         "Literal contents of the package. Can be used for encoding packages below TODO (256KB?) size.",
+	"url":             "URL references a package. For OCI archives, it is the reference of the artifact by digest, such as registry.example.com/repo@sha256:<digest>.",
+	"checksum":        "Checksum ensures the integrity of packages referenced by URL. Ignored for literals.",
+	"imagepullsecret": "ImagePullSecret is the name of the docker registry secret, in the namespace of the package, holding the credentials to pull OCI archives.",
+	"signature":       "Signature is the detached signature of the archive, checked by the fetcher if the environment has a signature policy.",
 }
 
 func (Archive) SwaggerDoc() map[string]string {
 	return map_Archive
 }
 
+var map_ArchiveSignature = map[string]string{
+	"":          "ArchiveSignature is an ed25519 signature of the sha256 checksum of an archive, of the message \"sha256:<hex checksum>\".",
+	"keyid":     "KeyID is the fingerprint of the public key of the signature.",
+	"signature": "Signature is the base64 encoded signature.",
+}
+
+func (ArchiveSignature) SwaggerDoc() map[string]string {
+	return map_ArchiveSignature
+}
+
 var map_Builder = map[string]string{
-	"":          "Builder is the setting for environment builder.",
-	"image":     "Image for containing the language compilation environment.",
-	"command":   "(Optional) Default build command to run for this build environment.",
-	"container": "(Optional) Container allows the modification of the deployed builder container using the Kubernetes Container spec. Fission overrides the following fields: - Name - Image; set to the Builder.Image - Command; set to the Builder.Command - TerminationMessagePath - ImagePullPolicy - ReadinessProbe",
-	"podspec":   "PodSpec will store the spec of the pod that will be applied to the pod created for the builder",
+	"":              "Builder is the setting for environment builder.",
+	"image":         "Image for containing the language compilation environment.",
+	"command":       "(Optional) Default build command to run for this build environment.",
+	"container":     "(Optional) Container allows the modification of the deployed builder container using the Kubernetes Container spec. Fission overrides the following fields: - Name - Image; set to the Builder.Image - Command; set to the Builder.Command - TerminationMessagePath - ImagePullPolicy - ReadinessProbe",
+	"podspec":       "PodSpec will store the spec of the pod that will be applied to the pod created for the builder",
+	"buildTimeout":  "(Optional) BuildTimeout is the default maximum duration in seconds of the build command of packages. Builds don't time out if zero.",
+	"buildRetries":  "(Optional) BuildRetries is the default number of times a package build failing on a transient error is retried.",
+	"ociRepository": "(Optional) OCIRepository is the default registry repository the deployment archives of packages are pushed to. They are uploaded to the storage service if not set.",
 }
 
 func (Builder) SwaggerDoc() map[string]string {
 	return map_Builder
 }
 
+var map_BuildProvenance = map[string]string{
+	"":                           "BuildProvenance records the inputs and output of a package build.",
+	"sourceChecksum":             "SourceChecksum is the checksum of the source archive.",
+	"builderImage":               "BuilderImage is the image of the environment builder.",
+	"builderImageDigest":         "BuilderImageDigest is the ID, including the digest, of the image the builder container ran.",
+	"buildCommand":               "BuildCommand is the command the builder ran.",
+	"environmentResourceVersion": "EnvironmentResourceVersion is the resource version of the environment the package was built with.",
+	"startTimestamp":             "StartTimestamp is the time the build started.",
+	"endTimestamp":               "EndTimestamp is the time the build completed.",
+	"deploymentChecksum":         "DeploymentChecksum is the checksum of the deployment archive the build produced.",
+}
+
+func (BuildProvenance) SwaggerDoc() map[string]string {
+	return map_BuildProvenance
+}
+
+var map_CanaryAnalysis = map[string]string{
+	"":                  "CanaryAnalysis is the analysis of the new version of a function in a weight increment interval",
+	"verdict":           "Verdict of the analysis: pass, fail or inconclusive",
+	"reason":            "Reason of the verdict",
+	"requests":          "Number of requests to the new version of the function",
+	"failurePercentage": "Percentage of failed requests to the new version of the function",
+	"latency":           "Latency percentile of the new version of the function, string representation of time.Duration",
+	"oldLatency":        "Latency percentile of the old version of the function, string representation of time.Duration",
+	"queries":           "Results of the custom queries",
+}
+
+func (CanaryAnalysis) SwaggerDoc() map[string]string {
+	return map_CanaryAnalysis
+}
+
 var map_CanaryConfig = map[string]string{
 	"": "CanaryConfig is for canary deployment of two functions.",
 }
@@ -68,13 +114,23 @@ func (CanaryConfigList) SwaggerDoc() map[string]string {
 }
 
 var map_CanaryConfigSpec = map[string]string{
-	"":                 "CanaryConfigSpec defines the canary configuration spec",
-	"trigger":          "HTTP trigger that this config references",
-	"newfunction":      "New version of the function",
-	"oldfunction":      "Old stable version of the function",
-	"weightincrement":  "Weight increment step for function",
-	"duration":         "Weight increment interval, string representation of time.Duration, ex : 1m, 2h, 2d (default: \"2m\")",
-	"failurethreshold": "Threshold in percentage beyond which the new version of the function is considered unstable",
+	"":                  "CanaryConfigSpec defines the canary configuration spec",
+	"trigger":           "HTTP trigger that this config references",
+	"newfunction":       "New version of the function",
+	"oldfunction":       "Old stable version of the function",
+	"weightincrement":   "Weight increment step for function",
+	"duration":          "Weight increment interval, string representation of time.Duration, ex : 1m, 2h, 2d (default: \"2m\")",
+	"failurethreshold":  "Threshold in percentage beyond which the new version of the function is considered unstable",
+	"latencyPercentile": "Latency percentile compared by the latency failure types, ex: 99 for the p99 latency (default: 99)",
+	"latencyThreshold":  "Latency beyond which the new version of the function is considered unstable for the latency failure type, string representation of time.Duration, ex: 500ms",
+	"minRequests":       "Minimum number of requests to the functions in a weight increment interval before the new version of the function is analysed (default: 1)",
+	"metricsProvider":   "Metrics provider analysing the new version of the function (default: the prometheus server of the canary deployment feature)",
+	"queries":           "Custom queries the new version of the function is analysed with, in addition to the failure type",
+	"paused":            "Paused canary configs keep the current weights of the functions until resumed",
+	"action":            "Action taken on the canary config: promote sends all the traffic to the new version of the function, abort rolls back to the old version",
+	"strategy":          "Strategy of the canary config: canary shifts the traffic to the new version of the function gradually, bluegreen switches all the traffic at once and rolls back if the analysis after the switch fails (default: canary)",
+	"steps":             "Weights of the new version of the function and how long each is kept before the next one, replacing the weight increment for the canary strategy, ex: 1% for 1m, 5% for 5m, 25% for 10m, then 100%",
+	"smokeTestFunction": "Function called before the traffic is switched to the new version of the function for the bluegreen strategy. The switch is cancelled unless it responds with a 2xx status code",
 }
 
 func (CanaryConfigSpec) SwaggerDoc() map[string]string {
@@ -82,13 +138,76 @@ func (CanaryConfigSpec) SwaggerDoc() map[string]string {
 }
 
 var map_CanaryConfigStatus = map[string]string{
-	"": "CanaryConfigStatus represents canary config status",
+	"":                   "CanaryConfigStatus represents canary config status",
+	"paused":             "Whether the canary config is paused",
+	"weight":             "Weight of the new version of the function",
+	"lastTransitionTime": "Time of the last transition",
+	"transitions":        "Last transitions of the canary config, the most recent last",
+	"functionWeights":    "Current weights of the functions",
+	"step":               "Number of weight increments of the new version of the function",
+	"history":            "Last weight increment intervals of the canary config, the most recent last",
 }
 
 func (CanaryConfigStatus) SwaggerDoc() map[string]string {
 	return map_CanaryConfigStatus
 }
 
+var map_CanaryMetricQuery = map[string]string{
+	"":      "CanaryMetricQuery is a custom query the new version of a function is analysed with",
+	"name":  "Name of the query",
+	"query": "Query template, executed with the function ({{ .Function }}) and namespace ({{ .Namespace }}) of the new version of the function, the old version of the function ({{ .OldFunction }}), the http trigger url ({{ .Path }}) and methods regular expression ({{ .Methods }}), and the weight increment interval ({{ .Window }})",
+	"min":   "Minimum value of the query result, below which the new version of the function is considered unstable",
+	"max":   "Maximum value of the query result, beyond which the new version of the function is considered unstable",
+}
+
+func (CanaryMetricQuery) SwaggerDoc() map[string]string {
+	return map_CanaryMetricQuery
+}
+
+var map_CanaryMetricsProvider = map[string]string{
+	"":     "CanaryMetricsProvider is the metrics provider analysing the new version of a function",
+	"type": "Type of the metrics provider: prometheus or webhook",
+	"url":  "URL of the prometheus server, or of the webhook called with the canary config analysed and returning whether the new version of the function passes",
+}
+
+func (CanaryMetricsProvider) SwaggerDoc() map[string]string {
+	return map_CanaryMetricsProvider
+}
+
+var map_CanaryStep = map[string]string{
+	"":                "CanaryStep is a weight increment interval of a canary config",
+	"step":            "Number of weight increments at the end of the interval",
+	"time":            "End time of the interval",
+	"functionWeights": "Weights of the functions at the end of the interval",
+	"analysis":        "Analysis of the new version of the function in the interval",
+}
+
+func (CanaryStep) SwaggerDoc() map[string]string {
+	return map_CanaryStep
+}
+
+var map_CanaryTransition = map[string]string{
+	"":        "CanaryTransition is a transition of a canary config",
+	"type":    "Type of the transition",
+	"time":    "Time of the transition",
+	"weight":  "Weight of the new version of the function after the transition",
+	"message": "Message describing the transition",
+}
+
+func (CanaryTransition) SwaggerDoc() map[string]string {
+	return map_CanaryTransition
+}
+
+var map_CanaryWeightStep = map[string]string{
+	"":         "CanaryWeightStep is a weight of the new version of a function in the step schedule of a canary config",
+	"weight":   "Weight of the new version of the function",
+	"duration": "How long the weight is kept before the next step, string representation of time.Duration (default: the weight increment interval)",
+}
+
+func (CanaryWeightStep) SwaggerDoc() map[string]string {
+	return map_CanaryWeightStep
+}
+
 var map_Checksum = map[string]string{
 	"": "Checksum of package contents when the contents are stored outside the Package struct. Type is the checksum algorithm; \"sha256\" is the only currently supported one. Sum is hex encoded.",
 }
@@ -141,6 +260,7 @@ var map_EnvironmentSpec = map[string]string{
 	"terminationGracePeriod":       "The grace time for pod to perform connection draining before termination. The unit is in seconds. (Optional) defaults to 360 seconds",
 	"keeparchive":                  "KeepArchive is used by fetcher to determine if the extracted archive or unarchived file should be placed, which is then used by specialize handler. (This is mainly for the JVM environment because .jar is one kind of zip archive.)",
 	"imagepullsecret":              "ImagePullSecret is the secret for Kubernetes to pull an image from a private registry.",
+	"signaturePolicy":              "SignaturePolicy makes the fetcher refuse to load archives not signed by one of the trusted keys of the environment.",
 }
 
 func (EnvironmentSpec) SwaggerDoc() map[string]string {
@@ -289,8 +409,15 @@ func (KubernetesWatchTriggerList) SwaggerDoc() map[string]string {
 
 var map_KubernetesWatchTriggerSpec = map[string]string{
 	"":              "KubernetesWatchTriggerSpec defines spec of KuberenetesWatchTrigger",
-	"type":          "Type of resource to watch (Pod, Service, etc.)",
+	"namespace":     "Namespace of the resources to watch. Ignored for cluster scoped resources.",
+	"type":          "Type of resource to watch (Pod, Service, Deployment, etc.). Any kind or resource name served by the API server, including custom resources, is accepted. Ignored if Resource is set.",
+	"group":         "Group of the resource to watch, empty for the core group. It disambiguates Type and Resource names served by several groups.",
+	"version":       "Version of the resource to watch. Defaults to the preferred version of the group.",
+	"resource":      "Resource is the plural resource name to watch, e.g. deployments.",
 	"labelselector": "Resource labels",
+	"fieldselector": "FieldSelector restricts the watched resources by field, e.g. status.phase=Running. The supported fields depend on the resource.",
+	"eventtypes":    "EventTypes are the watch event types the function is invoked for. Available value: - ADDED - MODIFIED - DELETED All event types if empty.",
+	"predicates":    "Predicates are evaluated on the watched object before the function is invoked, all of them have to match.",
 	"functionref":   "The reference to a function for kubewatcher to invoke with when receiving events.",
 }
 
@@ -298,6 +425,18 @@ func (KubernetesWatchTriggerSpec) SwaggerDoc() map[string]string {
 	return map_KubernetesWatchTriggerSpec
 }
 
+var map_KubernetesWatchTriggerStatus = map[string]string{
+	"":                    "KubernetesWatchTriggerStatus records where the kubewatcher left off processing the events of the watched objects, so that it resumes from there after a restart. The watched objects themselves are kept in a ConfigMap owned by the trigger, to find those deleted in the meantime.",
+	"lastResourceVersion": "LastResourceVersion is the resource version of the last processed event. Objects with a later resource version changed while the kubewatcher was down.",
+	"lastUpdateTime":      "LastUpdateTime is when the status was last updated. Objects created since were added while the kubewatcher was down.",
+	"lastErrorTime":       "LastErrorTime is the last time listing or watching the watched objects failed.",
+	"lastError":           "LastError is the error of the last failed attempt to list or watch the watched objects, e.g. when the kubewatcher isn't allowed to. It is cleared once they are listed.",
+}
+
+func (KubernetesWatchTriggerStatus) SwaggerDoc() map[string]string {
+	return map_KubernetesWatchTriggerStatus
+}
+
 var map_MessageQueueTrigger = map[string]string{
 	"": "MessageQueueTrigger invokes functions when messages arrive to certain topic that trigger subscribes to.",
 }
@@ -337,6 +476,16 @@ func (MessageQueueTriggerSpec) SwaggerDoc() map[string]string {
 	return map_MessageQueueTriggerSpec
 }
 
+var map_OCIRepository = map[string]string{
+	"":                "OCIRepository is a registry repository archives are pushed to.",
+	"repository":      "Repository is the registry repository, such as registry.example.com/team/repo[:tag]. Archives are tagged with their checksum unless the repository has a tag.",
+	"imagepullsecret": "ImagePullSecret is the name of the docker registry secret, in the namespace of the package, holding the credentials to push and pull the archives.",
+}
+
+func (OCIRepository) SwaggerDoc() map[string]string {
+	return map_OCIRepository
+}
+
 var map_Package = map[string]string{
 	"":       "Package Think of these as function-level images.",
 	"status": "Status indicates the build status of package.",
@@ -364,11 +513,14 @@ func (PackageRef) SwaggerDoc() map[string]string {
 }
 
 var map_PackageSpec = map[string]string{
-	"":            "PackageSpec includes source/deploy archives and the reference of environment to build the package.",
-	"environment": "Environment is a reference to the environment for building source archive.",
-	"source":      "Source is the archive contains source code and dependencies file. If the package status is in PENDING state, builder manager will then notify builder to compile source and save the result as deployable archive.",
-	"deployment":  "Deployment is the deployable archive that environment runtime used to run user function.",
-	"buildcmd":    "BuildCommand is a custom build command that builder used to build the source archive.",
+	"":              "PackageSpec includes source/deploy archives and the reference of environment to build the package.",
+	"environment":   "Environment is a reference to the environment for building source archive.",
+	"source":        "Source is the archive contains source code and dependencies file. If the package status is in PENDING state, builder manager will then notify builder to compile source and save the result as deployable archive.",
+	"deployment":    "Deployment is the deployable archive that environment runtime used to run user function.",
+	"buildcmd":      "BuildCommand is a custom build command that builder used to build the source archive.",
+	"buildTimeout":  "BuildTimeout is the maximum duration in seconds of the build command, after which the build is stopped and fails. Defaults to the build timeout of the environment builder.",
+	"buildRetries":  "BuildRetries is the number of times a build failing on a transient error, like failing to download the source archive, is retried. Defaults to the build retries of the environment builder.",
+	"ociRepository": "OCIRepository is the registry repository the deployment archive built from the source archive is pushed to, as an OCI artifact, instead of being uploaded to the storage service. Defaults to the OCI repository of the environment builder.",
 }
 
 func (PackageSpec) SwaggerDoc() map[string]string {
@@ -380,6 +532,7 @@ var map_PackageStatus = map[string]string{
 	"buildstatus":         "BuildStatus is the package build status.",
 	"buildlog":            "BuildLog stores build log during the compilation.",
 	"lastUpdateTimestamp": "LastUpdateTimestamp will store the timestamp the package was last updated metav1.Time is a wrapper around time.Time which supports correct marshaling to YAML and JSON. https://github.com/kubernetes/apimachinery/blob/44bd77c24ef93cd3a5eb6fef64e514025d10d44e/pkg/apis/meta/v1/time.go#L26-L35",
+	"provenance":          "Provenance records what the deployment archive was built from, for auditing and tracing a deployment back to its source.",
 }
 
 func (PackageStatus) SwaggerDoc() map[string]string {
@@ -391,6 +544,7 @@ var map_Runtime = map[string]string{
 	"image":     "Image for containing the language runtime.",
 	"container": "(Optional) Container allows the modification of the deployed runtime container using the Kubernetes Container spec. Fission overrides the following fields: - Name - Image; set to the Runtime.Image - TerminationMessagePath - ImagePullPolicy\n\nYou can set either PodSpec or Container, but not both. kubebuilder:validation:XPreserveUnknownFields=true",
 	"podspec":   "(Optional) Podspec allows modification of deployed runtime pod with Kubernetes PodSpec The merging logic is briefly described below and detailed MergePodSpec function - Volumes mounts and env variables for function and fetcher container are appended - All additional containers and init containers are appended - Volume definitions are appended - Lists such as tolerations, ImagePullSecrets, HostAliases are appended - Structs are merged and variables from pod spec take precedence\n\nYou can set either PodSpec or Container, but not both.",
+	"reload":    "(Optional) Reload tells that the runtime reloads the secrets and configmaps of the function through its /reload endpoint, so that the function pods are refreshed in place instead of being recycled when they change.",
 }
 
 func (Runtime) SwaggerDoc() map[string]string {
@@ -405,6 +559,16 @@ func (SecretReference) SwaggerDoc() map[string]string {
 	return map_SecretReference
 }
 
+var map_SignaturePolicy = map[string]string{
+	"":                 "SignaturePolicy is the signature policy of an environment.",
+	"keySecrets":       "KeySecrets are the names of the secrets, in the namespace of the environment, holding the trusted public keys under the \"ed25519.pub\" key.",
+	"builderKeySecret": "BuilderKeySecret is the name of the secret, in the namespace of the environment, holding the private key under the \"ed25519.key\" key with which the builder manager signs the deployment archives it builds. Its public key should be one of the trusted keys.",
+}
+
+func (SignaturePolicy) SwaggerDoc() map[string]string {
+	return map_SignaturePolicy
+}
+
 var map_TimeTrigger = map[string]string{
 	"": "TimeTrigger invokes functions based on given cron schedule.",
 }
@@ -422,13 +586,51 @@ func (TimeTriggerList) SwaggerDoc() map[string]string {
 }
 
 var map_TimeTriggerSpec = map[string]string{
-	"":            "TimeTriggerSpec invokes the specific function at a time or times specified by a cron string.",
-	"cron":        "Cron schedule",
-	"functionref": "The reference to function",
+	"":                        "TimeTriggerSpec invokes the specific function at a time or times specified by a cron string.",
+	"cron":                    "Cron schedule",
+	"functionref":             "The reference to function",
+	"timezone":                "Timezone is the IANA name of the time zone the cron schedule is evaluated in, e.g. \"Europe/Berlin\". Defaults to the time zone of the timer, which is UTC in the default deployment.",
+	"body":                    "Body is sent as the request body to the function on every run.",
+	"contentType":             "ContentType is the content type of the request body.",
+	"jitterSeconds":           "JitterSeconds delays each run by a random duration of up to the given number of seconds, to spread the load of triggers sharing the same schedule.",
+	"concurrencyPolicy":       "ConcurrencyPolicy specifies how to treat a run that is due while the previous one is still running. A run is running until the function responded or the request was given up on. Available value: - Allow (default): runs may overlap - Forbid: the new run is skipped - Replace: the running run is cancelled in favor of the new one",
+	"startingDeadlineSeconds": "StartingDeadlineSeconds enables catching up on runs missed while the timer was down. When the timer starts, runs that were scheduled within the given number of seconds since the last schedule time are fired. Missed runs are skipped if not set.",
+	"catchUpPolicy":           "CatchUpPolicy specifies how many of the missed runs within the starting deadline are fired. Available value: - Once (default): the latest missed run only - All: every missed run, oldest first",
 }
 
 func (TimeTriggerSpec) SwaggerDoc() map[string]string {
 	return map_TimeTriggerSpec
 }
 
+var map_TimeTriggerStatus = map[string]string{
+	"":                   "TimeTriggerStatus is the observed state of the runs of a time trigger.",
+	"lastScheduleTime":   "LastScheduleTime is the last time a run was scheduled.",
+	"lastSuccessfulTime": "LastSuccessfulTime is the last time a run completed successfully.",
+	"lastErrorTime":      "LastErrorTime is the last time a run failed.",
+	"lastError":          "LastError is the error of the last failed run.",
+}
+
+func (TimeTriggerStatus) SwaggerDoc() map[string]string {
+	return map_TimeTriggerStatus
+}
+
+var map_WatchPredicate = map[string]string{
+	"":         "WatchPredicate matches watched objects on the value of a field.",
+	"path":     "Path is a JSONPath template selecting the field, e.g. {.status.phase}",
+	"operator": "Operator applied to the values of the field. Available value: - In: a value is in Values - NotIn: no value is in Values - Exists: the field exists - DoesNotExist: the field does not exist",
+	"values":   "Values compared to the values of the field, for the In and NotIn operators.",
+}
+
+func (WatchPredicate) SwaggerDoc() map[string]string {
+	return map_WatchPredicate
+}
+
+var map_WatchedObject = map[string]string{
+	"": "WatchedObject identifies a version of a watched object, as recorded by the kubewatcher.",
+}
+
+func (WatchedObject) SwaggerDoc() map[string]string {
+	return map_WatchedObject
+}
+
 // AUTO-GENERATED FUNCTIONS END HERE
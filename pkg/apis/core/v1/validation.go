@@ -17,18 +17,27 @@ limitations under the License.
 package v1
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/robfig/cron"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/util/jsonpath"
 
 	"github.com/fission/fission/pkg/mqtrigger/validator"
+	"github.com/fission/fission/pkg/oci"
 )
 
 const (
@@ -178,21 +187,84 @@ func (checksum Checksum) Validate() error {
 	return result.ErrorOrNil()
 }
 
+func (repo OCIRepository) Validate() error {
+	result := &multierror.Error{}
+
+	ref, err := oci.ParseReference(repo.Repository)
+	if err != nil {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "OCIRepository.Repository", repo.Repository, err.Error()))
+	} else if len(ref.Digest) > 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "OCIRepository.Repository", repo.Repository, "repository to push archives to must not have a digest"))
+	}
+
+	if len(repo.ImagePullSecret) > 0 {
+		e := validation.IsDNS1123Subdomain(repo.ImagePullSecret)
+		if len(e) > 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "OCIRepository.ImagePullSecret", repo.ImagePullSecret, e...))
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
 func (archive Archive) Validate() error {
 	result := &multierror.Error{}
 
 	if len(archive.Type) > 0 {
 		switch archive.Type {
 		case ArchiveTypeLiteral, ArchiveTypeUrl: // no op
+		case ArchiveTypeOCI:
+			ref, err := oci.ParseReference(archive.URL)
+			if err != nil {
+				result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "Archive.URL", archive.URL, err.Error()))
+			} else if len(ref.Digest) == 0 {
+				result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "Archive.URL", archive.URL, "OCI archives must be referenced by digest"))
+			}
 		default:
 			result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "Archive.Type", archive.Type, "not a valid archive type"))
 		}
 	}
 
+	if len(archive.ImagePullSecret) > 0 {
+		e := validation.IsDNS1123Subdomain(archive.ImagePullSecret)
+		if len(e) > 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "Archive.ImagePullSecret", archive.ImagePullSecret, e...))
+		}
+	}
+
 	if archive.Checksum != (Checksum{}) {
 		result = multierror.Append(result, archive.Checksum.Validate())
 	}
 
+	if archive.Signature != nil {
+		sig, err := base64.StdEncoding.DecodeString(archive.Signature.Signature)
+		if err != nil || len(sig) != ed25519.SignatureSize {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "Archive.Signature", archive.Signature.Signature, "not a base64 encoded ed25519 signature"))
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (policy SignaturePolicy) Validate() error {
+	result := &multierror.Error{}
+
+	if len(policy.KeySecrets) == 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "SignaturePolicy.KeySecrets", policy.KeySecrets, "at least one trusted key is required"))
+	}
+	for _, name := range policy.KeySecrets {
+		e := validation.IsDNS1123Subdomain(name)
+		if len(e) > 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "SignaturePolicy.KeySecrets", name, e...))
+		}
+	}
+	if len(policy.BuilderKeySecret) > 0 {
+		e := validation.IsDNS1123Subdomain(policy.BuilderKeySecret)
+		if len(e) > 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "SignaturePolicy.BuilderKeySecret", policy.BuilderKeySecret, e...))
+		}
+	}
+
 	return result.ErrorOrNil()
 }
 
@@ -225,6 +297,18 @@ func (spec PackageSpec) Validate() error {
 		}
 	}
 
+	if spec.BuildTimeout != nil && *spec.BuildTimeout < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "PackageSpec.BuildTimeout", *spec.BuildTimeout, "must be greater than or equal to 0"))
+	}
+
+	if spec.BuildRetries != nil && *spec.BuildRetries < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "PackageSpec.BuildRetries", *spec.BuildRetries, "must be greater than or equal to 0"))
+	}
+
+	if spec.OCIRepository != nil {
+		result = multierror.Append(result, spec.OCIRepository.Validate())
+	}
+
 	return result.ErrorOrNil()
 }
 
@@ -367,8 +451,21 @@ func (runtime Runtime) Validate() error {
 }
 
 func (builder Builder) Validate() error {
-	// do nothing for now
-	return nil
+	result := &multierror.Error{}
+
+	if builder.BuildTimeout < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "Builder.BuildTimeout", builder.BuildTimeout, "must be greater than or equal to 0"))
+	}
+
+	if builder.BuildRetries < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "Builder.BuildRetries", builder.BuildRetries, "must be greater than or equal to 0"))
+	}
+
+	if builder.OCIRepository != nil {
+		result = multierror.Append(result, builder.OCIRepository.Validate())
+	}
+
+	return result.ErrorOrNil()
 }
 
 func (spec EnvironmentSpec) Validate() error {
@@ -400,6 +497,10 @@ func (spec EnvironmentSpec) Validate() error {
 		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "EnvironmentSpec.TerminationGracePeriod", spec.TerminationGracePeriod, "must be greater than or equal to 0"))
 	}
 
+	if spec.SignaturePolicy != nil {
+		result = multierror.Append(result, spec.SignaturePolicy.Validate())
+	}
+
 	return result.ErrorOrNil()
 }
 
@@ -491,10 +592,27 @@ func (config IngressConfig) Validate() error {
 func (spec KubernetesWatchTriggerSpec) Validate() error {
 	result := &multierror.Error{}
 
-	switch strings.ToUpper(spec.Type) {
-	case "POD", "SERVICE", "REPLICATIONCONTROLLER", "JOB":
-	default:
-		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "KubernetesWatchTriggerSpec.Type", spec.Type, "not a valid supported type"))
+	if len(spec.Type) == 0 && len(spec.Resource) == 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "KubernetesWatchTriggerSpec.Type", spec.Type, "either type or resource is required"))
+	}
+
+	if len(spec.FieldSelector) > 0 {
+		_, err := fields.ParseSelector(spec.FieldSelector)
+		if err != nil {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "KubernetesWatchTriggerSpec.FieldSelector", spec.FieldSelector, err.Error()))
+		}
+	}
+
+	for _, t := range spec.EventTypes {
+		switch strings.ToUpper(t) {
+		case "ADDED", "MODIFIED", "DELETED":
+		default:
+			result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "KubernetesWatchTriggerSpec.EventTypes", t, "not a valid event type"))
+		}
+	}
+
+	for _, p := range spec.Predicates {
+		result = multierror.Append(result, p.Validate())
 	}
 
 	result = multierror.Append(result,
@@ -505,6 +623,30 @@ func (spec KubernetesWatchTriggerSpec) Validate() error {
 	return result.ErrorOrNil()
 }
 
+func (p WatchPredicate) Validate() error {
+	result := &multierror.Error{}
+
+	err := jsonpath.New("predicate").Parse(p.Path)
+	if err != nil {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "WatchPredicate.Path", p.Path, err.Error()))
+	}
+
+	switch p.Operator {
+	case WatchPredicateOpIn, WatchPredicateOpNotIn:
+		if len(p.Values) == 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "WatchPredicate.Values", p.Values, "values are required for the In and NotIn operators"))
+		}
+	case WatchPredicateOpExists, WatchPredicateOpDoesNotExist:
+		if len(p.Values) > 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "WatchPredicate.Values", p.Values, "values are not allowed for the Exists and DoesNotExist operators"))
+		}
+	default:
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "WatchPredicate.Operator", p.Operator, "not a valid operator"))
+	}
+
+	return result.ErrorOrNil()
+}
+
 func (spec MessageQueueTriggerSpec) Validate() error {
 	result := &multierror.Error{}
 
@@ -520,6 +662,13 @@ func (spec MessageQueueTriggerSpec) Validate() error {
 		if len(spec.ResponseTopic) > 0 && !validator.IsValidTopic((string)(spec.MessageQueueType), spec.ResponseTopic, spec.MqtKind) {
 			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageQueueTriggerSpec.ResponseTopic", spec.ResponseTopic, "not a valid topic"))
 		}
+
+		// message queue types not publishing function errors, such as Azure
+		// Queue Storage, ignore error topics, which are accepted as before
+		if len(spec.ErrorTopic) > 0 && validator.SupportsErrorTopic((string)(spec.MessageQueueType), spec.MqtKind) &&
+			!validator.IsValidTopic((string)(spec.MessageQueueType), spec.ErrorTopic, spec.MqtKind) {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "MessageQueueTriggerSpec.ErrorTopic", spec.ErrorTopic, "not a valid topic"))
+		}
 	}
 
 	return result.ErrorOrNil()
@@ -535,6 +684,163 @@ func (spec TimeTriggerSpec) Validate() error {
 
 	result = multierror.Append(result, spec.FunctionReference.Validate())
 
+	if len(spec.Timezone) > 0 {
+		_, err = time.LoadLocation(spec.Timezone)
+		if err != nil {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.Timezone", spec.Timezone, "not a valid time zone"))
+		}
+	}
+
+	if spec.JitterSeconds < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.JitterSeconds", spec.JitterSeconds, "must be greater than or equal to 0"))
+	}
+
+	switch spec.ConcurrencyPolicy {
+	case "", AllowConcurrent, ForbidConcurrent, ReplaceConcurrent:
+	default:
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "TimeTriggerSpec.ConcurrencyPolicy", spec.ConcurrencyPolicy, "not a valid concurrency policy"))
+	}
+
+	if spec.StartingDeadlineSeconds != nil && *spec.StartingDeadlineSeconds < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "TimeTriggerSpec.StartingDeadlineSeconds", *spec.StartingDeadlineSeconds, "must be greater than or equal to 0"))
+	}
+
+	switch spec.CatchUpPolicy {
+	case "", CatchUpOnce, CatchUpAll:
+	default:
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "TimeTriggerSpec.CatchUpPolicy", spec.CatchUpPolicy, "not a valid catch-up policy"))
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (spec CanaryConfigSpec) Validate() error {
+	result := &multierror.Error{}
+
+	if spec.FailureThreshold < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryConfigSpec.FailureThreshold", spec.FailureThreshold, "must be greater than or equal to 0"))
+	}
+
+	switch spec.FailureType {
+	case "", FailureTypeStatusCode, FailureTypeRelativeLatency:
+	case FailureTypeLatency:
+		threshold, err := time.ParseDuration(spec.LatencyThreshold)
+		if err != nil || threshold <= 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryConfigSpec.LatencyThreshold", spec.LatencyThreshold, "must be a positive duration for the latency failure type"))
+		}
+	default:
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "CanaryConfigSpec.FailureType", spec.FailureType, "not a valid failure type"))
+	}
+
+	if spec.LatencyPercentile < 0 || spec.LatencyPercentile >= 100 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryConfigSpec.LatencyPercentile", spec.LatencyPercentile, "must be between 1 and 99"))
+	}
+
+	if spec.MinRequests < 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryConfigSpec.MinRequests", spec.MinRequests, "must be greater than or equal to 0"))
+	}
+
+	if spec.MetricsProvider != nil {
+		result = multierror.Append(result, spec.MetricsProvider.Validate())
+	}
+
+	switch spec.Action {
+	case "", CanaryActionPromote, CanaryActionAbort:
+	default:
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "CanaryConfigSpec.Action", spec.Action, "not a valid action"))
+	}
+
+	switch spec.Strategy {
+	case "", CanaryStrategyCanary:
+		if len(spec.SmokeTestFunction) > 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryConfigSpec.SmokeTestFunction", spec.SmokeTestFunction, "only supported by the bluegreen strategy"))
+		}
+	case CanaryStrategyBlueGreen:
+		if len(spec.Steps) > 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryConfigSpec.Steps", len(spec.Steps), "not supported by the bluegreen strategy"))
+		}
+	default:
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "CanaryConfigSpec.Strategy", spec.Strategy, "not a valid strategy"))
+	}
+
+	for i, step := range spec.Steps {
+		if step.Weight < 1 || step.Weight > 100 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryWeightStep.Weight", step.Weight, "must be between 1 and 100"))
+		} else if i > 0 && step.Weight <= spec.Steps[i-1].Weight {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryWeightStep.Weight", step.Weight, "must be greater than the weight of the previous step"))
+		}
+		if len(step.Duration) > 0 {
+			duration, err := time.ParseDuration(step.Duration)
+			if err != nil || duration <= 0 {
+				result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryWeightStep.Duration", step.Duration, "must be a positive duration"))
+			}
+		}
+	}
+	if len(spec.Steps) > 0 && spec.Steps[len(spec.Steps)-1].Weight != 100 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryConfigSpec.Steps", spec.Steps[len(spec.Steps)-1].Weight, "the weight of the last step must be 100"))
+	}
+
+	names := make(map[string]bool)
+	for _, query := range spec.Queries {
+		if names[query.Name] {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryMetricQuery.Name", query.Name, "duplicate query name"))
+		}
+		names[query.Name] = true
+		result = multierror.Append(result, query.Validate())
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (provider CanaryMetricsProvider) Validate() error {
+	result := &multierror.Error{}
+
+	switch provider.Type {
+	case CanaryMetricsProviderPrometheus:
+	case CanaryMetricsProviderWebhook:
+		if len(provider.URL) == 0 {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryMetricsProvider.URL", provider.URL, "required for the webhook metrics provider"))
+		}
+	default:
+		result = multierror.Append(result, MakeValidationErr(ErrorUnsupportedType, "CanaryMetricsProvider.Type", provider.Type, "not a valid metrics provider"))
+	}
+
+	if len(provider.URL) > 0 {
+		u, err := url.Parse(provider.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryMetricsProvider.URL", provider.URL, "not a valid http url"))
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (query CanaryMetricQuery) Validate() error {
+	result := &multierror.Error{}
+
+	if len(query.Name) == 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryMetricQuery.Name", query.Name, "name is required"))
+	}
+
+	_, err := template.New(query.Name).Parse(query.Query)
+	if len(query.Query) == 0 || err != nil {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryMetricQuery.Query", query.Query, "not a valid query template"))
+	}
+
+	if len(query.Min) == 0 && len(query.Max) == 0 {
+		result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryMetricQuery", query.Name, "min or max is required"))
+	}
+	if len(query.Min) > 0 {
+		if _, err = strconv.ParseFloat(query.Min, 64); err != nil {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryMetricQuery.Min", query.Min, "not a valid number"))
+		}
+	}
+	if len(query.Max) > 0 {
+		if _, err = strconv.ParseFloat(query.Max, 64); err != nil {
+			result = multierror.Append(result, MakeValidationErr(ErrorInvalidValue, "CanaryMetricQuery.Max", query.Max, "not a valid number"))
+		}
+	}
+
 	return result.ErrorOrNil()
 }
 
@@ -676,3 +982,13 @@ func (ml *MessageQueueTriggerList) Validate() error {
 	}
 	return result.ErrorOrNil()
 }
+
+func (c *CanaryConfig) Validate() error {
+	result := &multierror.Error{}
+
+	result = multierror.Append(result,
+		validateMetadata("CanaryConfig", c.ObjectMeta),
+		c.Spec.Validate())
+
+	return result.ErrorOrNil()
+}
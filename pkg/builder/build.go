@@ -0,0 +1,203 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	ferror "github.com/fission/fission/pkg/error"
+)
+
+const (
+	// cancelledBuildRetention is how long the IDs of builds cancelled
+	// before they started are remembered.
+	cancelledBuildRetention = 10 * time.Minute
+)
+
+var (
+	// ErrBuildCancelled is returned for builds cancelled through the cancel API.
+	ErrBuildCancelled = errors.New("build cancelled")
+
+	// ErrBuildTimeout is returned for builds running longer than their timeout.
+	ErrBuildTimeout = errors.New("build timed out")
+)
+
+// build is a running build.
+type build struct {
+	log *buildLog
+
+	lock      sync.Mutex
+	cmd       *exec.Cmd
+	cancelled bool
+}
+
+// start starts the build command in its own process group, so that
+// cancelling the build kills the processes spawned by the command too.
+func (b *build) start(cmd *exec.Cmd) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.cancelled {
+		return ErrBuildCancelled
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+	b.cmd = cmd
+	return nil
+}
+
+// cancel kills the process group of the build command.
+func (b *build) cancel() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.cancelled = true
+	return b.kill()
+}
+
+// kill kills the process group of the build command, if started. The
+// caller must hold the lock.
+func (b *build) kill() error {
+	if b.cmd == nil || b.cmd.Process == nil {
+		return nil
+	}
+	err := syscall.Kill(-b.cmd.Process.Pid, syscall.SIGKILL)
+	if err != nil && err != syscall.ESRCH {
+		return errors.Wrap(err, "error killing build process group")
+	}
+	return nil
+}
+
+// killOnDone kills the process group of the build command once ctx is
+// done, until stop is closed.
+func (b *build) killOnDone(ctx context.Context, stop <-chan struct{}) {
+	select {
+	case <-ctx.Done():
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		b.kill() //nolint: errCheck
+	case <-stop:
+	}
+}
+
+func (b *build) isCancelled() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.cancelled
+}
+
+// startBuild registers a build, unless it was already cancelled.
+func (builder *Builder) startBuild(buildID string) (*build, error) {
+	builder.lock.Lock()
+	defer builder.lock.Unlock()
+	if _, ok := builder.cancelled[buildID]; ok {
+		delete(builder.cancelled, buildID)
+		return nil, ErrBuildCancelled
+	}
+	if _, ok := builder.builds[buildID]; ok {
+		return nil, errors.Errorf("build %q is already running", buildID)
+	}
+	b := &build{log: newBuildLog()}
+	builder.builds[buildID] = b
+	return b, nil
+}
+
+func (builder *Builder) finishBuild(buildID string, b *build) {
+	b.log.close()
+	builder.lock.Lock()
+	defer builder.lock.Unlock()
+	delete(builder.builds, buildID)
+}
+
+// cancelBuild cancels a running build. Builds not started yet are refused
+// when they start.
+func (builder *Builder) cancelBuild(buildID string) error {
+	builder.lock.Lock()
+	b, ok := builder.builds[buildID]
+	if !ok {
+		now := time.Now()
+		for id, t := range builder.cancelled {
+			if now.Sub(t) > cancelledBuildRetention {
+				delete(builder.cancelled, id)
+			}
+		}
+		builder.cancelled[buildID] = now
+	}
+	builder.lock.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return b.cancel()
+}
+
+// LogsHandler writes the log of a running build. With the "follow" query
+// parameter set, it streams the log until the build completes.
+func (builder *Builder) LogsHandler(w http.ResponseWriter, r *http.Request) {
+	buildID := strings.TrimPrefix(r.URL.Path, "/logs/")
+	follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+
+	builder.lock.Lock()
+	b, ok := builder.builds[buildID]
+	builder.lock.Unlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("build %q is not running", buildID), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	err := b.log.copyTo(r.Context(), w, follow, flush)
+	if err != nil && r.Context().Err() == nil {
+		builder.logger.Error("error writing build log", zap.Error(err), zap.String("build_id", buildID))
+	}
+}
+
+// CancelHandler cancels a build, killing the processes of the build command.
+func (builder *Builder) CancelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	buildID := strings.TrimPrefix(r.URL.Path, "/cancel/")
+	builder.logger.Info("cancelling build", zap.String("build_id", buildID))
+	err := builder.cancelBuild(buildID)
+	if err != nil {
+		builder.logger.Error("error cancelling build", zap.Error(err), zap.String("build_id", buildID))
+		code, msg := ferror.GetHTTPError(err)
+		http.Error(w, msg, code)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBuildTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "builder")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// the build command spawns a process outliving it, which is killed too
+	command := filepath.Join(dir, "build")
+	writeFile(t, command, "#!/bin/sh\nsleep 60 &\nsleep 60\n")
+	require.NoError(t, os.Chmod(command, 0755))
+	src := filepath.Join(dir, "src")
+	require.NoError(t, os.MkdirAll(src, 0755))
+
+	builder := MakeBuilder(zap.NewNop(), dir, "")
+	run, err := builder.startBuild("timeout")
+	require.NoError(t, err)
+	defer builder.finishBuild("timeout", run)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = builder.build(ctx, run, command, src, filepath.Join(dir, "deploy"), nil)
+	require.Equal(t, ErrBuildTimeout, err)
+	require.Less(t, int64(time.Since(start)), int64(10*time.Second))
+}
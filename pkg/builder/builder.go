@@ -17,7 +17,7 @@ limitations under the License.
 package builder
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -28,6 +28,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dchest/uniuri"
@@ -52,24 +53,54 @@ type (
 		// 1. SRC_PKG: path to source package directory
 		// 2. DEPLOY_PKG: path to deployment package directory
 		BuildCommand string `json:"command"`
+		// CacheKey identifies the environment of the build, whose builds
+		// share a dependency cache. Builds aren't cached if empty.
+		CacheKey string `json:"cacheKey,omitempty"`
+		// BuildID identifies the build to follow its logs or cancel it.
+		// Defaults to the source package filename.
+		BuildID string `json:"buildID,omitempty"`
+		// BuildTimeout is the maximum duration in seconds of the build
+		// command. Builds don't time out if zero.
+		BuildTimeout int `json:"buildTimeout,omitempty"`
 	}
 
 	PackageBuildResponse struct {
 		ArtifactFilename string `json:"artifactFilename"`
 		BuildLogs        string `json:"buildLogs"`
+		// Cancelled is set if the build failed because it was cancelled
+		Cancelled bool `json:"cancelled,omitempty"`
+		// TimedOut is set if the build failed because it ran longer than
+		// its timeout
+		TimedOut bool `json:"timedOut,omitempty"`
 	}
 
 	Builder struct {
 		logger           *zap.Logger
 		sharedVolumePath string
+		cache            *buildCache
+
+		lock sync.Mutex
+		// builds are the running builds by build ID
+		builds map[string]*build
+		// cancelled are the build IDs cancelled before the build started,
+		// with the cancellation time
+		cancelled map[string]time.Time
 	}
 )
 
-func MakeBuilder(logger *zap.Logger, sharedVolumePath string) *Builder {
-	return &Builder{
+// MakeBuilder returns a Builder building the source packages of the shared
+// volume. Dependencies are cached between builds in cacheDir, if not empty.
+func MakeBuilder(logger *zap.Logger, sharedVolumePath string, cacheDir string) *Builder {
+	builder := &Builder{
 		logger:           logger.Named("builder"),
 		sharedVolumePath: sharedVolumePath,
+		builds:           make(map[string]*build),
+		cancelled:        make(map[string]time.Time),
 	}
+	if len(cacheDir) > 0 {
+		builder.cache = makeBuildCache(cacheDir)
+	}
+	return builder
 }
 
 func (builder *Builder) VersionHandler(w http.ResponseWriter, r *http.Request) {
@@ -115,6 +146,21 @@ func (builder *Builder) Handler(w http.ResponseWriter, r *http.Request) {
 	}
 	builder.logger.Info("builder received request", zap.Any("request", req))
 
+	buildID := req.BuildID
+	if len(buildID) == 0 {
+		buildID = req.SrcPkgFilename
+	}
+	run, err := builder.startBuild(buildID)
+	if err != nil {
+		builder.logger.Error("error starting build", zap.Error(err), zap.String("build_id", buildID))
+		builder.replyWith(w, PackageBuildResponse{
+			BuildLogs: fmt.Sprintf("%v\n", err),
+			Cancelled: err == ErrBuildCancelled,
+		}, http.StatusInternalServerError)
+		return
+	}
+	defer builder.finishBuild(buildID, run)
+
 	builder.logger.Info("starting build")
 	srcPkgPath := filepath.Join(builder.sharedVolumePath, req.SrcPkgFilename)
 	deployPkgFilename := fmt.Sprintf("%v-%v", req.SrcPkgFilename, strings.ToLower(uniuri.NewLen(6)))
@@ -124,26 +170,98 @@ func (builder *Builder) Handler(w http.ResponseWriter, r *http.Request) {
 		// use default build command
 		buildCmd = "/build"
 	}
-	buildLogs, err := builder.build(buildCmd, srcPkgPath, deployPkgPath)
+
+	var cache *cacheEntry
+	if builder.cache != nil && len(req.CacheKey) > 0 {
+		var cacheLogs string
+		cache, cacheLogs = builder.restoreCache(req.CacheKey, srcPkgPath)
+		run.log.WriteString(cacheLogs) //nolint: errCheck
+	}
+
+	// the build command is killed once it times out, or if the build
+	// request is aborted
+	ctx := r.Context()
+	timeout := time.Duration(req.BuildTimeout) * time.Second
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err = builder.build(ctx, run, buildCmd, srcPkgPath, deployPkgPath, cache)
 	if err != nil {
 		e := "error building source package"
 		builder.logger.Error(e, zap.Error(err))
 
 		// append error at the end of build logs
-		buildLogs += fmt.Sprintf("%s: %s\n", e, err.Error())
-		builder.reply(w, deployPkgFilename, buildLogs, http.StatusInternalServerError)
+		if err == ErrBuildTimeout {
+			run.log.WriteString(fmt.Sprintf("%s: %s after %v\n", e, err.Error(), timeout)) //nolint: errCheck
+		} else {
+			run.log.WriteString(fmt.Sprintf("%s: %s\n", e, err.Error())) //nolint: errCheck
+		}
+		builder.replyWith(w, PackageBuildResponse{
+			ArtifactFilename: deployPkgFilename,
+			BuildLogs:        run.log.String(),
+			Cancelled:        err == ErrBuildCancelled,
+			TimedOut:         err == ErrBuildTimeout,
+		}, http.StatusInternalServerError)
 		return
 	}
 
-	builder.reply(w, deployPkgFilename, buildLogs, http.StatusOK)
+	if cache != nil {
+		run.log.WriteString(builder.saveCache(cache, srcPkgPath)) //nolint: errCheck
+	}
+
+	builder.reply(w, deployPkgFilename, run.log.String(), http.StatusOK)
+}
+
+// restoreCache restores the cached dependencies of the source package, and
+// returns the cache entry of the build along with build logs. Cache errors
+// don't fail the build, which then starts from scratch.
+func (builder *Builder) restoreCache(cacheKey string, srcPkgPath string) (*cacheEntry, string) {
+	fi, err := os.Stat(srcPkgPath)
+	if err != nil || !fi.IsDir() {
+		return nil, ""
+	}
+
+	entry, err := builder.cache.entry(cacheKey, srcPkgPath)
+	if err != nil {
+		builder.logger.Error("error looking up build cache", zap.Error(err))
+		return nil, fmt.Sprintf("build cache unavailable: %v\n", err)
+	}
+	restored, err := entry.restore(srcPkgPath)
+	if err != nil {
+		builder.logger.Error("error restoring build cache", zap.Error(err))
+		return entry, fmt.Sprintf("error restoring build cache: %v\n", err)
+	}
+	if len(restored) == 0 {
+		return entry, "build cache: no cached dependencies\n"
+	}
+	return entry, fmt.Sprintf("build cache: restored %v\n", strings.Join(restored, ", "))
+}
+
+// saveCache saves the dependencies installed by a successful build, and
+// returns build logs.
+func (builder *Builder) saveCache(entry *cacheEntry, srcPkgPath string) string {
+	err := entry.save(srcPkgPath)
+	if err == nil {
+		err = entry.prune()
+	}
+	if err != nil {
+		builder.logger.Error("error saving build cache", zap.Error(err))
+		return fmt.Sprintf("error saving build cache: %v\n", err)
+	}
+	return ""
 }
 
 func (builder *Builder) reply(w http.ResponseWriter, pkgFilename string, buildLogs string, statusCode int) {
-	resp := PackageBuildResponse{
+	builder.replyWith(w, PackageBuildResponse{
 		ArtifactFilename: pkgFilename,
 		BuildLogs:        buildLogs,
-	}
+	}, statusCode)
+}
 
+func (builder *Builder) replyWith(w http.ResponseWriter, resp PackageBuildResponse, statusCode int) {
 	rBody, err := json.Marshal(resp)
 	if err != nil {
 		e := errors.Wrap(err, "error encoding response body")
@@ -164,12 +282,12 @@ func (builder *Builder) reply(w http.ResponseWriter, pkgFilename string, buildLo
 	}
 }
 
-func (builder *Builder) build(command string, srcPkgPath string, deployPkgPath string) (string, error) {
-	cmd := exec.Command(command)
+func (builder *Builder) build(ctx context.Context, run *build, command string, srcPkgPath string, deployPkgPath string, cache *cacheEntry) error {
+	cmd := exec.CommandContext(ctx, command)
 
 	fi, err := os.Stat(srcPkgPath)
 	if err != nil {
-		return "", fmt.Errorf("could not find srcPkgPath: '%s'", srcPkgPath)
+		return fmt.Errorf("could not find srcPkgPath: '%s'", srcPkgPath)
 	}
 	if fi.IsDir() {
 		cmd.Dir = srcPkgPath
@@ -182,52 +300,47 @@ func (builder *Builder) build(command string, srcPkgPath string, deployPkgPath s
 		fmt.Sprintf("%v=%v", envSrcPkg, srcPkgPath),
 		fmt.Sprintf("%v=%v", envDeployPkg, deployPkgPath),
 	)
-
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return "", errors.Wrap(err, "error creating stdout pipe for cmd")
-	}
-
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return "", errors.Wrap(err, "error creating stderr pipe for cmd")
+	if cache != nil {
+		cmd.Env = append(cmd.Env, cache.env()...)
 	}
 
-	var buildLogs string
+	// Runtime logs go to the container output and to the build log
+	out := io.MultiWriter(os.Stdout, run.log)
+	cmd.Stdout = out
+	cmd.Stderr = out
 
 	fmt.Printf("\n=== Build Logs ===")
 	// Init logs
 	fmt.Printf("command=%v\n", command)
 	fmt.Printf("env=%v\n", cmd.Env)
 
-	out := io.MultiReader(stdout, stderr)
-	scanner := bufio.NewScanner(out)
-
-	err = cmd.Start()
+	err = run.start(cmd)
 	if err != nil {
-		return "", errors.Wrap(err, "error starting cmd")
+		if err == ErrBuildCancelled {
+			return err
+		}
+		return errors.Wrap(err, "error starting cmd")
 	}
 
-	// Runtime logs
-	for scanner.Scan() {
-		output := scanner.Text()
-		fmt.Println(output)
-		buildLogs += fmt.Sprintf("%v\n", output)
+	// exec only kills the build command itself once ctx is done
+	stop := make(chan struct{})
+	go run.killOnDone(ctx, stop)
+	err = cmd.Wait()
+	close(stop)
+	if run.isCancelled() {
+		fmt.Println(ErrBuildCancelled)
+		return ErrBuildCancelled
 	}
-
-	if err := scanner.Err(); err != nil {
-		scanErr := errors.Wrap(err, "error reading cmd output")
-		fmt.Println(scanErr)
-		return buildLogs, scanErr
+	if ctx.Err() == context.DeadlineExceeded {
+		fmt.Println(ErrBuildTimeout)
+		return ErrBuildTimeout
 	}
-
-	err = cmd.Wait()
 	if err != nil {
 		cmdErr := errors.Wrapf(err, "error waiting for cmd %q", command)
 		fmt.Println(cmdErr)
-		return buildLogs, cmdErr
+		return cmdErr
 	}
 	fmt.Printf("==================\n")
 
-	return buildLogs, nil
+	return nil
 }
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// buildLog is the output of a build command, which can be followed while
+// the build runs.
+type buildLog struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+	done bool
+	// changed is closed and replaced on every write
+	changed chan struct{}
+}
+
+func newBuildLog() *buildLog {
+	return &buildLog{changed: make(chan struct{})}
+}
+
+func (l *buildLog) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	n, err := l.buf.Write(p)
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return n, err
+}
+
+func (l *buildLog) WriteString(s string) (int, error) {
+	return l.Write([]byte(s))
+}
+
+// close marks the end of the log.
+func (l *buildLog) close() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.done = true
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func (l *buildLog) String() string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.buf.String()
+}
+
+// copyTo writes the log from its start to w. If follow is set, it keeps
+// writing the new output until the log is closed or ctx is done, calling
+// flush after each write.
+func (l *buildLog) copyTo(ctx context.Context, w io.Writer, follow bool, flush func()) error {
+	offset := 0
+	for {
+		l.lock.Lock()
+		data := append([]byte(nil), l.buf.Bytes()[offset:]...)
+		done := l.done
+		changed := l.changed
+		l.lock.Unlock()
+
+		if len(data) > 0 {
+			_, err := w.Write(data)
+			if err != nil {
+				return err
+			}
+			offset += len(data)
+			if flush != nil {
+				flush()
+			}
+		}
+		if done || !follow {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
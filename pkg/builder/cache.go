@@ -0,0 +1,323 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// environment variable pointing the build command to a cache directory
+	// shared by the builds of the environment
+	envBuildCache = "BUILD_CACHE"
+
+	// maxCacheEntries is the number of dependency sets, i.e. lock file
+	// hashes, cached per environment.
+	maxCacheEntries = 3
+)
+
+var (
+	// lockFiles pin the dependencies of a source package; their content keys
+	// the cached dependency directories.
+	lockFiles = []string{
+		"package-lock.json",
+		"yarn.lock",
+		"requirements.txt",
+		"Pipfile.lock",
+		"poetry.lock",
+		"go.sum",
+		"Gemfile.lock",
+		"composer.lock",
+	}
+
+	// dependencyDirs are directories of dependencies installed into the
+	// source directory by a build, which are restored before the next build
+	// with the same lock files.
+	dependencyDirs = []string{
+		"node_modules",
+		"vendor",
+		".venv",
+	}
+
+	// toolCacheEnvs point package managers to download caches shared by the
+	// builds of an environment, whatever their lock files.
+	toolCacheEnvs = map[string]string{
+		"npm_config_cache":   "npm",
+		"YARN_CACHE_FOLDER":  "yarn",
+		"PIP_CACHE_DIR":      "pip",
+		"GOMODCACHE":         "gomod",
+		"GOCACHE":            "gobuild",
+		"BUNDLE_USER_CACHE":  "bundler",
+		"COMPOSER_CACHE_DIR": "composer",
+	}
+)
+
+type (
+	// buildCache persists the dependencies installed by builds, keyed by the
+	// environment and the hash of the lock files of the source package.
+	buildCache struct {
+		dir string
+	}
+
+	// cacheEntry is the cache of a build.
+	cacheEntry struct {
+		// envDir holds the caches of an environment
+		envDir string
+		// depsDir holds the dependency directories of the lock files of the
+		// source package; empty if the source package has no lock files.
+		depsDir string
+		// restored is set once dependency directories are restored, which
+		// needn't be saved again after the build.
+		restored bool
+	}
+)
+
+func makeBuildCache(dir string) *buildCache {
+	return &buildCache{dir: dir}
+}
+
+// entry returns the cache entry of a build of the source package.
+func (c *buildCache) entry(cacheKey string, srcDir string) (*cacheEntry, error) {
+	envDir := filepath.Join(c.dir, hashString(cacheKey))
+	err := os.MkdirAll(envDir, 0755)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating build cache directory")
+	}
+
+	entry := &cacheEntry{envDir: envDir}
+	lockHash, err := lockFileHash(srcDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(lockHash) > 0 {
+		entry.depsDir = filepath.Join(envDir, "deps", lockHash)
+	}
+	return entry, nil
+}
+
+// env returns the environment variables pointing the build command and
+// package managers to the cache.
+func (entry *cacheEntry) env() []string {
+	env := []string{fmt.Sprintf("%v=%v", envBuildCache, filepath.Join(entry.envDir, "shared"))}
+	for name, dir := range toolCacheEnvs {
+		env = append(env, fmt.Sprintf("%v=%v", name, filepath.Join(entry.envDir, "tools", dir)))
+	}
+	sort.Strings(env)
+	return env
+}
+
+// restore copies the cached dependency directories into the source
+// directory, unless the source package ships them. It returns the
+// restored directories.
+func (entry *cacheEntry) restore(srcDir string) ([]string, error) {
+	if len(entry.depsDir) == 0 {
+		return nil, nil
+	}
+	var restored []string
+	for _, dir := range dependencyDirs {
+		cached := filepath.Join(entry.depsDir, dir)
+		if _, err := os.Lstat(cached); err != nil {
+			continue
+		}
+		dst := filepath.Join(srcDir, dir)
+		if _, err := os.Lstat(dst); err == nil {
+			continue
+		}
+		err := copyDir(cached, dst)
+		if err != nil {
+			os.RemoveAll(dst)
+			return restored, errors.Wrapf(err, "error restoring %q from build cache", dir)
+		}
+		restored = append(restored, dir)
+	}
+	if len(restored) > 0 {
+		entry.restored = true
+		now := time.Now()
+		os.Chtimes(entry.depsDir, now, now) //nolint: errCheck
+	}
+	return restored, nil
+}
+
+// save replaces the cached dependency directories with the ones of the
+// source directory after a successful build, unless they were restored
+// from the cache.
+func (entry *cacheEntry) save(srcDir string) error {
+	if len(entry.depsDir) == 0 || entry.restored {
+		return nil
+	}
+	var dirs []string
+	for _, dir := range dependencyDirs {
+		if fi, err := os.Lstat(filepath.Join(srcDir, dir)); err == nil && fi.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+
+	parent := filepath.Dir(entry.depsDir)
+	err := os.MkdirAll(parent, 0755)
+	if err != nil {
+		return errors.Wrap(err, "error creating build cache directory")
+	}
+	tmpDir, err := ioutil.TempDir(parent, ".tmp-")
+	if err != nil {
+		return errors.Wrap(err, "error creating build cache directory")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, dir := range dirs {
+		err = copyDir(filepath.Join(srcDir, dir), filepath.Join(tmpDir, dir))
+		if err != nil {
+			return errors.Wrapf(err, "error saving %q to build cache", dir)
+		}
+	}
+
+	// swap in the new entry; a concurrent build of the same lock files may
+	// have saved it first, which is as good.
+	err = os.RemoveAll(entry.depsDir)
+	if err != nil {
+		return errors.Wrap(err, "error removing stale build cache")
+	}
+	err = os.Rename(tmpDir, entry.depsDir)
+	if err != nil && !os.IsExist(err) {
+		return errors.Wrap(err, "error saving build cache")
+	}
+	return nil
+}
+
+// prune removes the least recently used dependency sets of the environment
+// beyond maxCacheEntries.
+func (entry *cacheEntry) prune() error {
+	depsRoot := filepath.Join(entry.envDir, "deps")
+	fis, err := ioutil.ReadDir(depsRoot)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "error reading build cache directory")
+	}
+
+	var entries []os.FileInfo
+	for _, fi := range fis {
+		if fi.IsDir() && fi.Name()[0] != '.' {
+			entries = append(entries, fi)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().After(entries[j].ModTime())
+	})
+	for i := maxCacheEntries; i < len(entries); i++ {
+		err = os.RemoveAll(filepath.Join(depsRoot, entries[i].Name()))
+		if err != nil {
+			return errors.Wrap(err, "error removing build cache entry")
+		}
+	}
+	return nil
+}
+
+// lockFileHash returns the hash of the lock files of the source directory,
+// or an empty string if it has none.
+func lockFileHash(srcDir string) (string, error) {
+	h := sha256.New()
+	found := false
+	for _, name := range lockFiles {
+		f, err := os.Open(filepath.Join(srcDir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", errors.Wrapf(err, "error reading %q", name)
+		}
+		found = true
+		fmt.Fprintf(h, "%s\x00", name)
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", errors.Wrapf(err, "error reading %q", name)
+		}
+	}
+	if !found {
+		return "", nil
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:16])
+}
+
+// copyDir recursively copies a directory, preserving file modes and
+// symbolic links.
+func copyDir(src string, dst string) error {
+	return filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case fi.IsDir():
+			return os.MkdirAll(target, fi.Mode().Perm()|0700)
+		case fi.Mode().IsRegular():
+			return copyFile(path, target, fi.Mode().Perm())
+		default:
+			// skip sockets, devices and the like
+			return nil
+		}
+	})
+}
+
+func copyFile(src string, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
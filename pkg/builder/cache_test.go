@@ -0,0 +1,103 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestBuildCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "build-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cache := makeBuildCache(filepath.Join(dir, "cache"))
+	newSrc := func(name string, lock string) string {
+		src := filepath.Join(dir, name)
+		writeFile(t, filepath.Join(src, "package-lock.json"), lock)
+		return src
+	}
+
+	// first build installs and saves the dependencies
+	src := newSrc("first", "v1")
+	entry, err := cache.entry("default/node/node-builder", src)
+	require.NoError(t, err)
+	restored, err := entry.restore(src)
+	require.NoError(t, err)
+	require.Empty(t, restored)
+	writeFile(t, filepath.Join(src, "node_modules", "left-pad", "index.js"), "module.exports = 1")
+	require.NoError(t, os.Symlink("../left-pad/index.js", filepath.Join(src, "node_modules", ".bin")))
+	require.NoError(t, entry.save(src))
+
+	// a build with the same lock files restores them
+	src = newSrc("second", "v1")
+	entry, err = cache.entry("default/node/node-builder", src)
+	require.NoError(t, err)
+	restored, err = entry.restore(src)
+	require.NoError(t, err)
+	require.Equal(t, []string{"node_modules"}, restored)
+	content, err := ioutil.ReadFile(filepath.Join(src, "node_modules", "left-pad", "index.js"))
+	require.NoError(t, err)
+	require.Equal(t, "module.exports = 1", string(content))
+	link, err := os.Readlink(filepath.Join(src, "node_modules", ".bin"))
+	require.NoError(t, err)
+	require.Equal(t, "../left-pad/index.js", link)
+
+	// changed lock files or another environment start from scratch
+	for _, test := range []struct{ key, lock string }{
+		{"default/node/node-builder", "v2"},
+		{"default/node/node-builder:2", "v1"},
+	} {
+		src = newSrc("other", test.lock)
+		entry, err = cache.entry(test.key, src)
+		require.NoError(t, err)
+		restored, err = entry.restore(src)
+		require.NoError(t, err)
+		require.Empty(t, restored)
+		require.NoError(t, os.RemoveAll(src))
+	}
+}
+
+func TestLockFileHash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "build-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	hash, err := lockFileHash(dir)
+	require.NoError(t, err)
+	require.Empty(t, hash)
+
+	writeFile(t, filepath.Join(dir, "requirements.txt"), "requests==2.25.1")
+	hash, err = lockFileHash(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, hash)
+
+	writeFile(t, filepath.Join(dir, "requirements.txt"), "requests==2.26.0")
+	changed, err := lockFileHash(dir)
+	require.NoError(t, err)
+	require.NotEqual(t, hash, changed)
+}
@@ -18,9 +18,13 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -45,7 +49,10 @@ func MakeClient(logger *zap.Logger, builderUrl string) *Client {
 	}
 }
 
-func (c *Client) Build(req *builder.PackageBuildRequest) (*builder.PackageBuildResponse, error) {
+// Build sends a build request to the builder and waits for the build to
+// complete. Requests the builder didn't reply to are retried, unless ctx
+// is done; failed builds are up to the caller to retry.
+func (c *Client) Build(ctx context.Context, req *builder.PackageBuildRequest) (*builder.PackageBuildResponse, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "error marshaling json")
@@ -55,15 +62,34 @@ func (c *Client) Build(req *builder.PackageBuildRequest) (*builder.PackageBuildR
 	var resp *http.Response
 
 	for i := 0; i < maxRetries; i++ {
-		resp, err = http.Post(c.url, "application/json", bytes.NewReader(body))
+		var httpReq *http.Request
+		httpReq, err = http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating build request")
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		resp, err = http.DefaultClient.Do(httpReq)
 
 		if err == nil {
 			if resp.StatusCode == 200 {
 				break
 			}
+			if failed := c.failedBuild(resp); failed != nil {
+				switch {
+				case failed.Cancelled:
+					return failed, builder.ErrBuildCancelled
+				case failed.TimedOut:
+					return failed, builder.ErrBuildTimeout
+				}
+				return failed, ferror.MakeErrorFromHTTP(resp)
+			}
 			err = ferror.MakeErrorFromHTTP(resp)
 		}
 
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
 		if i < maxRetries-1 {
 			time.Sleep(50 * time.Duration(2*i) * time.Millisecond)
 			c.logger.Error("error building package, retrying", zap.Error(err))
@@ -90,3 +116,53 @@ func (c *Client) Build(req *builder.PackageBuildRequest) (*builder.PackageBuildR
 
 	return &pkgBuildResp, ferror.MakeErrorFromHTTP(resp)
 }
+
+// failedBuild returns the builder response to a failed build, or nil if
+// the request didn't reach the builder. The response body is left readable.
+func (c *Client) failedBuild(resp *http.Response) *builder.PackageBuildResponse {
+	rBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(rBody))
+	if err != nil {
+		return nil
+	}
+	pkgBuildResp := builder.PackageBuildResponse{}
+	err = json.Unmarshal(rBody, &pkgBuildResp)
+	if err != nil || len(pkgBuildResp.BuildLogs) == 0 {
+		return nil
+	}
+	return &pkgBuildResp
+}
+
+// Logs returns the log of a running build. With follow set, the log is
+// streamed until the build completes.
+func (c *Client) Logs(ctx context.Context, buildID string, follow bool) (io.ReadCloser, error) {
+	u := fmt.Sprintf("%v/logs/%v?follow=%v", c.url, url.PathEscape(buildID), follow)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating build log request")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting build log")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, ferror.MakeErrorFromHTTP(resp)
+	}
+	return resp.Body, nil
+}
+
+// Cancel cancels a build, killing the processes of the build command.
+func (c *Client) Cancel(ctx context.Context, buildID string) error {
+	u := fmt.Sprintf("%v/cancel/%v", c.url, url.PathEscape(buildID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, nil)
+	if err != nil {
+		return errors.Wrap(err, "error creating build cancel request")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "error cancelling build")
+	}
+	defer resp.Body.Close()
+	return ferror.MakeErrorFromHTTP(resp)
+}
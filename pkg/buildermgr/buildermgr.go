@@ -54,8 +54,16 @@ func Start(logger *zap.Logger, storageSvcUrl string, envBuilderNamespace string)
 	informerFactory := genInformer.NewSharedInformerFactory(fissionClient, 60*time.Minute)
 	podInformer := k8sInformerFactory.Core().V1().Pods().Informer()
 	pkgInformer := informerFactory.Core().V1().Packages().Informer()
+	builds := makeBuildRegistry()
+	api := &buildAPI{
+		logger:        bmLogger.Named("api"),
+		fissionClient: fissionClient,
+		builds:        builds,
+	}
+	go api.serve(apiPort)
+
 	pkgWatcher := makePackageWatcher(bmLogger, fissionClient,
-		kubernetesClient, envBuilderNamespace, storageSvcUrl, &podInformer, &pkgInformer)
+		kubernetesClient, envBuilderNamespace, storageSvcUrl, &podInformer, &pkgInformer, builds)
 	pkgWatcher.Run()
 	return nil
 }
@@ -0,0 +1,341 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildermgr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opencensus.io/plugin/ochttp"
+	"go.uber.org/zap"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	builderClient "github.com/fission/fission/pkg/builder/client"
+	"github.com/fission/fission/pkg/crd"
+	ferror "github.com/fission/fission/pkg/error"
+)
+
+const (
+	// apiPort is the port of the builder manager API, serving the logs of
+	// running builds and cancelling them.
+	apiPort = 8000
+
+	// buildStartWait is how long a followed package pending a build is
+	// waited on for its build to start.
+	buildStartWait = 30 * time.Second
+)
+
+type (
+	// activeBuild is a package build in progress.
+	activeBuild struct {
+		logger *zap.Logger
+		cancel context.CancelFunc
+		// started is closed once the builder runs the build command
+		started chan struct{}
+		// done is closed once the build completes
+		done chan struct{}
+
+		lock      sync.Mutex
+		builderC  *builderClient.Client
+		buildID   string
+		cancelled bool
+		buildLogs string
+	}
+
+	// buildRegistry tracks the active builds by package.
+	buildRegistry struct {
+		lock   sync.Mutex
+		builds map[string]*activeBuild
+	}
+
+	buildAPI struct {
+		logger        *zap.Logger
+		fissionClient *crd.FissionClient
+		builds        *buildRegistry
+	}
+)
+
+func makeBuildRegistry() *buildRegistry {
+	return &buildRegistry{
+		builds: make(map[string]*activeBuild),
+	}
+}
+
+func buildKey(namespace string, name string) string {
+	return fmt.Sprintf("%v/%v", namespace, name)
+}
+
+// add registers the build of a package, cancelled with cancel.
+func (r *buildRegistry) add(logger *zap.Logger, pkg *fv1.Package, cancel context.CancelFunc) *activeBuild {
+	b := &activeBuild{
+		logger:  logger,
+		cancel:  cancel,
+		started: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.builds[buildKey(pkg.ObjectMeta.Namespace, pkg.ObjectMeta.Name)] = b
+	return b
+}
+
+func (r *buildRegistry) get(namespace string, name string) *activeBuild {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.builds[buildKey(namespace, name)]
+}
+
+// remove unregisters the build of a package once its status is updated.
+func (r *buildRegistry) remove(pkg *fv1.Package, b *activeBuild) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	key := buildKey(pkg.ObjectMeta.Namespace, pkg.ObjectMeta.Name)
+	if r.builds[key] == b {
+		delete(r.builds, key)
+	}
+}
+
+// startBuilder records the builder running the build command, which
+// changes when a build is retried. It returns false if the build was
+// cancelled in the meantime.
+func (b *activeBuild) startBuilder(builderC *builderClient.Client, buildID string) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.cancelled {
+		return false
+	}
+	b.builderC = builderC
+	b.buildID = buildID
+	select {
+	case <-b.started:
+	default:
+		close(b.started)
+	}
+	return true
+}
+
+// finish records the logs of the completed build.
+func (b *activeBuild) finish(buildLogs string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.buildLogs = buildLogs
+	close(b.done)
+}
+
+// logs returns the logs of the completed build.
+func (b *activeBuild) logs() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buildLogs
+}
+
+func (b *activeBuild) isCancelled() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.cancelled
+}
+
+// cancelBuild kills the build command if the builder runs it, or aborts
+// fetching the source package or uploading the deployment package.
+func (b *activeBuild) cancelBuild(ctx context.Context) {
+	b.lock.Lock()
+	b.cancelled = true
+	builderC, buildID := b.builderC, b.buildID
+	b.lock.Unlock()
+
+	if builderC != nil {
+		err := builderC.Cancel(ctx, buildID)
+		if err == nil {
+			// the builder replies to the build request with the logs
+			return
+		}
+		b.logger.Error("error cancelling build on builder, aborting build request", zap.Error(err), zap.String("build_id", buildID))
+	}
+	b.cancel()
+}
+
+// serve runs the builder manager API.
+func (api *buildAPI) serve(port int) {
+	r := mux.NewRouter()
+	r.HandleFunc("/v1/builds/{namespace}/{package}/logs", api.logsHandler).Methods("GET")
+	r.HandleFunc("/v1/builds/{namespace}/{package}/cancel", api.cancelHandler).Methods("POST")
+	r.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}).Methods("GET")
+
+	address := fmt.Sprintf(":%v", port)
+	err := http.ListenAndServe(address, &ochttp.Handler{
+		Handler: r,
+	})
+	api.logger.Fatal("done listening", zap.Error(err))
+}
+
+// logsHandler writes the build log of a package. With the "follow" query
+// parameter set, the log of a running or pending build is streamed until
+// the build completes.
+func (api *buildAPI) logsHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace, name := vars["namespace"], vars["package"]
+	follow, _ := strconv.ParseBool(r.URL.Query().Get("follow"))
+	ctx := r.Context()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	b := api.builds.get(namespace, name)
+	if b == nil {
+		pkg, err := api.fissionClient.CoreV1().Packages(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			code := http.StatusInternalServerError
+			if k8serrors.IsNotFound(err) {
+				code = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+		if follow && isBuildPending(pkg) {
+			b, pkg = api.waitForBuild(ctx, pkg)
+		}
+		if b == nil {
+			io.WriteString(w, pkg.Status.BuildLog) //nolint: errCheck
+			return
+		}
+	}
+
+	if !follow {
+		select {
+		case <-b.started:
+		default:
+			// the source package is being fetched
+			return
+		}
+	} else {
+		select {
+		case <-b.started:
+		case <-b.done:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	select {
+	case <-b.done:
+		io.WriteString(w, b.logs()) //nolint: errCheck
+		return
+	default:
+	}
+
+	b.lock.Lock()
+	builderC, buildID := b.builderC, b.buildID
+	b.lock.Unlock()
+	logs, err := builderC.Logs(ctx, buildID, follow)
+	if err != nil {
+		if ferror.IsNotFound(err) && follow {
+			// the build command just completed
+			select {
+			case <-b.done:
+				io.WriteString(w, b.logs()) //nolint: errCheck
+			case <-ctx.Done():
+			}
+			return
+		}
+		api.logger.Error("error getting build log from builder", zap.Error(err), zap.String("build_id", buildID))
+		code, msg := ferror.GetHTTPError(err)
+		http.Error(w, msg, code)
+		return
+	}
+	defer logs.Close()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := logs.Read(buf)
+		if n > 0 {
+			_, werr := w.Write(buf[:n])
+			if werr != nil {
+				return
+			}
+			flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// waitForBuild waits for the build of a pending package to start, and
+// returns it along with the latest package. The returned build is nil if
+// the package didn't start building.
+func (api *buildAPI) waitForBuild(ctx context.Context, pkg *fv1.Package) (*activeBuild, *fv1.Package) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	timeout := time.After(buildStartWait)
+	for {
+		select {
+		case <-ticker.C:
+		case <-timeout:
+			return nil, pkg
+		case <-ctx.Done():
+			return nil, pkg
+		}
+		if b := api.builds.get(pkg.ObjectMeta.Namespace, pkg.ObjectMeta.Name); b != nil {
+			return b, pkg
+		}
+		latest, err := api.fissionClient.CoreV1().Packages(pkg.ObjectMeta.Namespace).Get(ctx, pkg.ObjectMeta.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, pkg
+		}
+		pkg = latest
+		if !isBuildPending(pkg) {
+			return nil, pkg
+		}
+	}
+}
+
+// cancelHandler cancels the running build of a package. The package is
+// marked failed once the build is stopped.
+func (api *buildAPI) cancelHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	namespace, name := vars["namespace"], vars["package"]
+
+	b := api.builds.get(namespace, name)
+	if b == nil {
+		code, msg := ferror.GetHTTPError(ferror.MakeError(ferror.ErrorNotFound,
+			fmt.Sprintf("package %v/%v is not building", namespace, name)))
+		http.Error(w, msg, code)
+		return
+	}
+	api.logger.Info("cancelling build", zap.String("package", buildKey(namespace, name)))
+	b.cancelBuild(r.Context())
+	w.WriteHeader(http.StatusOK)
+}
+
+func isBuildPending(pkg *fv1.Package) bool {
+	return pkg.Status.BuildStatus == fv1.BuildStatusPending || pkg.Status.BuildStatus == fv1.BuildStatusRunning
+}
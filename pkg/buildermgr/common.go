@@ -17,6 +17,7 @@ limitations under the License.
 package buildermgr
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/http"
@@ -26,6 +27,7 @@ import (
 	"github.com/dchest/uniuri"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
@@ -35,17 +37,42 @@ import (
 	ferror "github.com/fission/fission/pkg/error"
 	"github.com/fission/fission/pkg/fetcher"
 	fetcherClient "github.com/fission/fission/pkg/fetcher/client"
+	"github.com/fission/fission/pkg/signing"
+	"github.com/fission/fission/pkg/utils"
 )
 
+const (
+	// buildRetryDelay is the delay before retrying a build failing on a
+	// transient error, doubled on every retry up to maxBuildRetryDelay.
+	buildRetryDelay    = 5 * time.Second
+	maxBuildRetryDelay = 2 * time.Minute
+)
+
+// transientError is a build failure that may not happen again on retry,
+// like failing to download the source archive from the storage service.
+type transientError struct {
+	error
+}
+
+func isTransientError(err error) bool {
+	_, ok := err.(transientError)
+	return ok
+}
+
 // buildPackage helps to build source package into deployment package.
 // Following is the steps buildPackage function takes to complete the whole process.
 // 1. Send fetch request to fetcher to fetch source package.
 // 2. Send build request to builder to start a build.
-// 3. Send upload request to fetcher to upload deployment package.
-// 4. Return upload response and build logs.
+// 3. Send upload request to fetcher to upload deployment package, or push it
+//    to the OCI repository of the package or environment.
+// 4. Return the deployment archive and build logs.
 // *. Return build logs and error if any one of steps above failed.
+// If the environment has a signature policy, the source archive must be
+// signed, and the deployment archive is signed with the builder key.
+// The checksum of the fetched source archive is recorded in the provenance
+// if the package doesn't have it.
 func buildPackage(ctx context.Context, logger *zap.Logger, fissionClient *crd.FissionClient, envBuilderNamespace string,
-	storageSvcUrl string, pkg *fv1.Package) (uploadResp *fetcher.ArchiveUploadResponse, buildLogs string, err error) {
+	storageSvcUrl string, pkg *fv1.Package, policy *signing.Policy, build *activeBuild, provenance *fv1.BuildProvenance) (deployment *fv1.Archive, buildLogs string, err error) {
 
 	env, err := fissionClient.CoreV1().Environments(pkg.Spec.Environment.Namespace).Get(context.TODO(), pkg.Spec.Environment.Name, metav1.GetOptions{})
 	if err != nil {
@@ -66,14 +93,25 @@ func buildPackage(ctx context.Context, logger *zap.Logger, fissionClient *crd.Fi
 		Filename:    srcPkgFilename,
 		KeepArchive: false,
 	}
+	if policy != nil {
+		fetchReq.RequireSignature = true
+		fetchReq.SignatureKeys = policy.PublicKeys
+	}
 
 	// send fetch request to fetcher
-	err = fetcherC.Fetch(ctx, fetchReq)
+	fetchResp, err := fetcherC.Fetch(ctx, fetchReq)
 	if err != nil {
 		e := "error fetching source package"
 		logger.Error(e, zap.Error(err))
 		e = fmt.Sprintf("%s: %v", e, err)
-		return nil, e, ferror.MakeError(http.StatusInternalServerError, e)
+		if fe, ok := err.(ferror.Error); ok && fe.Code == ferror.ErrorNotAuthorized {
+			// refused by the signature policy, retrying won't help
+			return nil, fmt.Sprintf("%v\n", e), ferror.MakeError(ferror.ErrorNotAuthorized, e)
+		}
+		return nil, fmt.Sprintf("%v\n", e), transientError{ferror.MakeError(http.StatusInternalServerError, e)}
+	}
+	if fetchResp.Checksum != nil && len(provenance.SourceChecksum.Sum) == 0 {
+		provenance.SourceChecksum = *fetchResp.Checksum
 	}
 
 	buildCmd := pkg.Spec.BuildCommand
@@ -84,11 +122,24 @@ func buildPackage(ctx context.Context, logger *zap.Logger, fissionClient *crd.Fi
 	pkgBuildReq := &builder.PackageBuildRequest{
 		SrcPkgFilename: srcPkgFilename,
 		BuildCommand:   buildCmd,
+		// dependencies installed by the builder image of the environment
+		// are reused by the following builds
+		CacheKey:     fmt.Sprintf("%v/%v/%v", env.ObjectMeta.Namespace, env.ObjectMeta.Name, env.Spec.Builder.Image),
+		BuildID:      srcPkgFilename,
+		BuildTimeout: env.Spec.Builder.BuildTimeout,
+	}
+	if pkg.Spec.BuildTimeout != nil {
+		pkgBuildReq.BuildTimeout = *pkg.Spec.BuildTimeout
+	}
+
+	if !build.startBuilder(builderC, pkgBuildReq.BuildID) {
+		e := builder.ErrBuildCancelled.Error()
+		return nil, fmt.Sprintf("%v\n", e), ferror.MakeError(http.StatusInternalServerError, e)
 	}
 
 	logger.Info("started building with source package", zap.String("source_package", srcPkgFilename))
 	// send build request to builder
-	buildResp, err := builderC.Build(pkgBuildReq)
+	buildResp, err := builderC.Build(ctx, pkgBuildReq)
 	if err != nil {
 		e := fmt.Sprintf("Error building deployment package: %v", err)
 		var buildLogs string
@@ -101,42 +152,161 @@ func buildPackage(ctx context.Context, logger *zap.Logger, fissionClient *crd.Fi
 
 	logger.Info("build succeed", zap.String("source_package", srcPkgFilename), zap.String("deployment_package", buildResp.ArtifactFilename))
 
+	if build.isCancelled() {
+		e := builder.ErrBuildCancelled.Error()
+		buildResp.BuildLogs += fmt.Sprintf("%v\n", e)
+		return nil, buildResp.BuildLogs, ferror.MakeError(http.StatusInternalServerError, e)
+	}
+
 	archivePackage := !env.Spec.KeepArchive
 
 	uploadReq := &fetcher.ArchiveUploadRequest{
 		Filename:       buildResp.ArtifactFilename,
 		StorageSvcUrl:  storageSvcUrl,
 		ArchivePackage: archivePackage,
+		OCIRepository:  ociRepository(pkg, env),
+		Namespace:      pkg.ObjectMeta.Namespace,
 	}
 
 	logger.Info("started uploading deployment package", zap.String("deployment_package", buildResp.ArtifactFilename))
 	// ask fetcher to upload the deployment package
-	uploadResp, err = fetcherC.Upload(ctx, uploadReq)
+	uploadResp, err := fetcherC.Upload(ctx, uploadReq)
 	if err != nil {
 		e := fmt.Sprintf("Error uploading deployment package: %v", err)
 		buildResp.BuildLogs += fmt.Sprintf("%v\n", e)
-		return nil, buildResp.BuildLogs, ferror.MakeError(http.StatusInternalServerError, e)
+		return nil, buildResp.BuildLogs, transientError{ferror.MakeError(http.StatusInternalServerError, e)}
+	}
+
+	deployment = &fv1.Archive{
+		Type:     fv1.ArchiveTypeUrl,
+		URL:      uploadResp.ArchiveDownloadUrl,
+		Checksum: uploadResp.Checksum,
+	}
+	if uploadResp.ArchiveType == fv1.ArchiveTypeOCI {
+		deployment.Type = fv1.ArchiveTypeOCI
+		deployment.ImagePullSecret = uploadReq.OCIRepository.ImagePullSecret
+		buildResp.BuildLogs += fmt.Sprintf("Pushed deployment package to oci://%v\n", deployment.URL)
+	}
+	if policy != nil && policy.BuilderKey != nil {
+		deployment.Signature, err = signing.Sign(policy.BuilderKey, uploadResp.Checksum)
+		if err != nil {
+			e := fmt.Sprintf("Error signing deployment package: %v", err)
+			buildResp.BuildLogs += fmt.Sprintf("%v\n", e)
+			return nil, buildResp.BuildLogs, ferror.MakeError(http.StatusInternalServerError, e)
+		}
+		buildResp.BuildLogs += fmt.Sprintf("Signed deployment package with key %v\n", deployment.Signature.KeyID)
+	}
+
+	return deployment, buildResp.BuildLogs, nil
+}
+
+// ociRepository returns the OCI repository the deployment archive of a
+// package is pushed to, nil if it is uploaded to the storage service.
+func ociRepository(pkg *fv1.Package, env *fv1.Environment) *fv1.OCIRepository {
+	if pkg.Spec.OCIRepository != nil {
+		return pkg.Spec.OCIRepository
 	}
+	return env.Spec.Builder.OCIRepository
+}
+
+// buildPackageWithRetries builds a package with buildPackage, retrying
+// builds failing on transient errors with exponential backoff. The build
+// logs of all attempts are returned.
+func buildPackageWithRetries(ctx context.Context, logger *zap.Logger, fissionClient *crd.FissionClient, envBuilderNamespace string,
+	storageSvcUrl string, pkg *fv1.Package, env *fv1.Environment, policy *signing.Policy, build *activeBuild, provenance *fv1.BuildProvenance) (*fv1.Archive, string, error) {
+
+	retries := env.Spec.Builder.BuildRetries
+	if pkg.Spec.BuildRetries != nil {
+		retries = *pkg.Spec.BuildRetries
+	}
+
+	var allLogs string
+	for attempt := 0; ; attempt++ {
+		deployment, buildLogs, err := buildPackage(ctx, logger, fissionClient, envBuilderNamespace, storageSvcUrl, pkg, policy, build, provenance)
+		allLogs += buildLogs
+		if err == nil || !isTransientError(err) || attempt >= retries || build.isCancelled() {
+			return deployment, allLogs, err
+		}
+
+		delay := retryDelay(attempt + 1)
+		logger.Info("retrying package build after transient error",
+			zap.Error(err),
+			zap.String("package_name", pkg.ObjectMeta.Name),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("delay", delay))
+		allLogs += fmt.Sprintf("Retrying build in %v (retry %v of %v)\n", delay, attempt+1, retries)
 
-	return uploadResp, buildResp.BuildLogs, nil
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, allLogs, err
+		}
+	}
 }
 
+// retryDelay returns the exponential backoff delay before the given retry.
+func retryDelay(retry int) time.Duration {
+	delay := buildRetryDelay
+	for i := 1; i < retry && delay < maxBuildRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxBuildRetryDelay {
+		delay = maxBuildRetryDelay
+	}
+	return delay
+}
+
+// newBuildProvenance returns the provenance of a package build starting on
+// the builder pod of the environment.
+func newBuildProvenance(pkg *fv1.Package, env *fv1.Environment, pod *apiv1.Pod) *fv1.BuildProvenance {
+	provenance := &fv1.BuildProvenance{
+		SourceChecksum:             pkg.Spec.Source.Checksum,
+		BuilderImage:               env.Spec.Builder.Image,
+		BuildCommand:               pkg.Spec.BuildCommand,
+		EnvironmentResourceVersion: env.ObjectMeta.ResourceVersion,
+		StartTimestamp:             metav1.Time{Time: time.Now().UTC()},
+	}
+
+	if len(provenance.SourceChecksum.Sum) == 0 && pkg.Spec.Source.Type == fv1.ArchiveTypeLiteral {
+		sum, err := utils.GetChecksum(bytes.NewReader(pkg.Spec.Source.Literal))
+		if err == nil {
+			provenance.SourceChecksum = *sum
+		}
+	}
+
+	if len(provenance.BuildCommand) == 0 {
+		provenance.BuildCommand = env.Spec.Builder.Command
+	}
+	if len(provenance.BuildCommand) == 0 {
+		// the default build command of the builder
+		provenance.BuildCommand = "/build"
+	}
+
+	for _, cStatus := range pod.Status.ContainerStatuses {
+		if cStatus.Name == "builder" {
+			provenance.BuilderImageDigest = cStatus.ImageID
+		}
+	}
+
+	return provenance
+}
+
+// updatePackage updates the build status of a package, and records the
+// deployment archive of a successful build. The provenance is recorded
+// for builds that ran on a builder, successful or not, and nil otherwise.
 func updatePackage(logger *zap.Logger, fissionClient *crd.FissionClient,
 	pkg *fv1.Package, status fv1.BuildStatus, buildLogs string,
-	uploadResp *fetcher.ArchiveUploadResponse) (*fv1.Package, error) {
+	deployment *fv1.Archive, provenance *fv1.BuildProvenance) (*fv1.Package, error) {
 
 	pkg.Status = fv1.PackageStatus{
 		BuildStatus:         status,
 		BuildLog:            buildLogs,
 		LastUpdateTimestamp: metav1.Time{Time: time.Now().UTC()},
+		Provenance:          provenance,
 	}
 
-	if uploadResp != nil {
-		pkg.Spec.Deployment = fv1.Archive{
-			Type:     fv1.ArchiveTypeUrl,
-			URL:      uploadResp.ArchiveDownloadUrl,
-			Checksum: uploadResp.Checksum,
-		}
+	if deployment != nil {
+		pkg.Spec.Deployment = *deployment
 	}
 
 	// update package spec
@@ -18,8 +18,11 @@ package buildermgr
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"path"
 	"strconv"
 	"time"
 
@@ -27,6 +30,8 @@ import (
 	"go.uber.org/zap"
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -51,6 +56,9 @@ const (
 	LABEL_ENV_RESOURCEVERSION = "envResourceVersion"
 	LABEL_DEPLOYMENT_OWNER    = "owner"
 	BUILDER_MGR               = "buildermgr"
+
+	buildCacheVolume    = "build-cache"
+	buildCacheMountPath = "/build-cache"
 )
 
 var (
@@ -77,6 +85,16 @@ type (
 		err         error
 	}
 
+	// buildCacheConfig is the volume in which builders cache dependencies
+	// between builds: an emptyDir living as long as the builder pod, or a
+	// persistent volume claim existing in the builder namespace. The claim
+	// is shared by the builders of all environments, which may run on any
+	// node, so it must have the ReadWriteMany access mode.
+	buildCacheConfig struct {
+		claimName string
+		sizeLimit *resource.Quantity
+	}
+
 	environmentWatcher struct {
 		logger                 *zap.Logger
 		cache                  map[string]*builderInfo
@@ -86,7 +104,9 @@ type (
 		kubernetesClient       *kubernetes.Clientset
 		fetcherConfig          *fetcherConfig.Config
 		builderImagePullPolicy apiv1.PullPolicy
+		builderResources       apiv1.ResourceRequirements
 		useIstio               bool
+		buildCache             *buildCacheConfig
 	}
 )
 
@@ -108,6 +128,19 @@ func makeEnvironmentWatcher(
 	}
 
 	builderImagePullPolicy := utils.GetImagePullPolicy(os.Getenv("BUILDER_IMAGE_PULL_POLICY"))
+	builderResources := getBuilderResources(logger)
+
+	var buildCache *buildCacheConfig
+	enableBuildCache := os.Getenv("BUILDER_CACHE_ENABLED")
+	if len(enableBuildCache) > 0 {
+		enabled, err := strconv.ParseBool(enableBuildCache)
+		if err != nil {
+			logger.Error("Failed to parse BUILDER_CACHE_ENABLED, defaults to false")
+		}
+		if enabled {
+			buildCache = makeBuildCacheConfig(logger)
+		}
+	}
 
 	envWatcher := &environmentWatcher{
 		logger:                 logger.Named("environment_watcher"),
@@ -117,8 +150,10 @@ func makeEnvironmentWatcher(
 		fissionClient:          fissionClient,
 		kubernetesClient:       kubernetesClient,
 		builderImagePullPolicy: builderImagePullPolicy,
+		builderResources:       builderResources,
 		useIstio:               useIstio,
 		fetcherConfig:          fetcherConfig,
+		buildCache:             buildCache,
 	}
 
 	go envWatcher.service()
@@ -126,6 +161,120 @@ func makeEnvironmentWatcher(
 	return envWatcher
 }
 
+// getBuilderResources returns the default resource requests and limits of
+// builder containers, which the builder container of an environment overrides.
+func getBuilderResources(logger *zap.Logger) apiv1.ResourceRequirements {
+	resources := apiv1.ResourceRequirements{
+		Requests: make(apiv1.ResourceList),
+		Limits:   make(apiv1.ResourceList),
+	}
+	for _, r := range []struct {
+		env  string
+		list apiv1.ResourceList
+		name apiv1.ResourceName
+	}{
+		{"BUILDER_MINCPU", resources.Requests, apiv1.ResourceCPU},
+		{"BUILDER_MINMEM", resources.Requests, apiv1.ResourceMemory},
+		{"BUILDER_MAXCPU", resources.Limits, apiv1.ResourceCPU},
+		{"BUILDER_MAXMEM", resources.Limits, apiv1.ResourceMemory},
+	} {
+		val := os.Getenv(r.env)
+		if len(val) == 0 {
+			continue
+		}
+		q, err := resource.ParseQuantity(val)
+		if err != nil {
+			logger.Error("Failed to parse builder resource, ignoring it", zap.Error(err), zap.String("env", r.env))
+			continue
+		}
+		r.list[r.name] = q
+	}
+	return resources
+}
+
+func makeBuildCacheConfig(logger *zap.Logger) *buildCacheConfig {
+	cfg := &buildCacheConfig{
+		claimName: os.Getenv("BUILDER_CACHE_PVC"),
+	}
+	if sizeLimit := os.Getenv("BUILDER_CACHE_SIZE_LIMIT"); len(sizeLimit) > 0 && len(cfg.claimName) == 0 {
+		q, err := resource.ParseQuantity(sizeLimit)
+		if err != nil {
+			logger.Error("Failed to parse BUILDER_CACHE_SIZE_LIMIT, ignoring it", zap.Error(err))
+		} else {
+			cfg.sizeLimit = &q
+		}
+	}
+	return cfg
+}
+
+// claim returns the persistent volume claim of the build cache in the
+// builder namespace, if configured and shareable by builder pods on any node.
+// The builders fall back to an emptyDir otherwise. A claim buildermgr isn't
+// allowed to get is mounted without checking it.
+func (cfg *buildCacheConfig) claim(logger *zap.Logger, kubernetesClient kubernetes.Interface, ns string) string {
+	if len(cfg.claimName) == 0 {
+		return ""
+	}
+	pvc, err := kubernetesClient.CoreV1().PersistentVolumeClaims(ns).Get(context.TODO(), cfg.claimName, metav1.GetOptions{})
+	if k8serrors.IsForbidden(err) {
+		logger.Warn("not allowed to get build cache persistent volume claim, mounting it without checking its access mode",
+			zap.Error(err), zap.String("claim", cfg.claimName), zap.String("namespace", ns))
+		return cfg.claimName
+	}
+	if err != nil {
+		logger.Error("error getting build cache persistent volume claim, using an emptyDir", zap.Error(err),
+			zap.String("claim", cfg.claimName), zap.String("namespace", ns))
+		return ""
+	}
+	for _, mode := range pvc.Spec.AccessModes {
+		if mode == apiv1.ReadWriteMany {
+			return cfg.claimName
+		}
+	}
+	logger.Error("build cache persistent volume claim doesn't have the ReadWriteMany access mode, using an emptyDir",
+		zap.String("claim", cfg.claimName), zap.String("namespace", ns))
+	return ""
+}
+
+// buildCacheSubPath returns the directory of the build cache volume the
+// builder of an environment uses, so that the builders of different
+// environments, or of different images of an environment, don't share
+// dependencies.
+func buildCacheSubPath(env *fv1.Environment) string {
+	sum := sha256.Sum256([]byte(env.Spec.Builder.Image))
+	return path.Join(env.ObjectMeta.Namespace, env.ObjectMeta.Name, hex.EncodeToString(sum[:8]))
+}
+
+// addToPodSpec mounts the directory of the environment in the build cache
+// volume in the builder container.
+func (cfg *buildCacheConfig) addToPodSpec(podSpec *apiv1.PodSpec, container *apiv1.Container, claimName string, subPath string) {
+	volume := apiv1.Volume{Name: buildCacheVolume}
+	if len(claimName) > 0 {
+		volume.VolumeSource = apiv1.VolumeSource{
+			PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claimName,
+			},
+		}
+	} else {
+		volume.VolumeSource = apiv1.VolumeSource{
+			EmptyDir: &apiv1.EmptyDirVolumeSource{
+				SizeLimit: cfg.sizeLimit,
+			},
+		}
+	}
+	podSpec.Volumes = append(podSpec.Volumes, volume)
+
+	container.VolumeMounts = append(container.VolumeMounts, apiv1.VolumeMount{
+		Name:      buildCacheVolume,
+		MountPath: buildCacheMountPath,
+		SubPath:   subPath,
+	})
+	container.Env = append(container.Env, apiv1.EnvVar{
+		Name:  "BUILDER_CACHE_DIR",
+		Value: buildCacheMountPath,
+	})
+}
+
 func (envw *environmentWatcher) getCacheKey(envName string, envNamespace string, envResourceVersion string) string {
 	return fmt.Sprintf("%v-%v-%v", envName, envNamespace, envResourceVersion)
 }
@@ -474,6 +623,7 @@ func (envw *environmentWatcher) createBuilderDeployment(env *fv1.Environment, ns
 		ImagePullPolicy:        envw.builderImagePullPolicy,
 		TerminationMessagePath: "/dev/termination-log",
 		Command:                []string{"/builder", envw.fetcherConfig.SharedMountPath()},
+		Resources:              *envw.builderResources.DeepCopy(),
 		ReadinessProbe: &apiv1.Probe{
 			InitialDelaySeconds: 5,
 			PeriodSeconds:       2,
@@ -503,6 +653,11 @@ func (envw *environmentWatcher) createBuilderDeployment(env *fv1.Environment, ns
 		},
 	}
 
+	if envw.buildCache != nil {
+		envw.buildCache.addToPodSpec(&pod.Spec, &pod.Spec.Containers[0],
+			envw.buildCache.claim(envw.logger, envw.kubernetesClient, ns), buildCacheSubPath(env))
+	}
+
 	pod.Spec = *(util.ApplyImagePullSecret(env.Spec.ImagePullSecret, pod.Spec))
 
 	deployment := &appsv1.Deployment{
@@ -0,0 +1,68 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildermgr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	apiv1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestBuildCacheClaim(t *testing.T) {
+	claim := func(accessMode apiv1.PersistentVolumeAccessMode) *apiv1.PersistentVolumeClaim {
+		return &apiv1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "fission-builder"},
+			Spec: apiv1.PersistentVolumeClaimSpec{
+				AccessModes: []apiv1.PersistentVolumeAccessMode{accessMode},
+			},
+		}
+	}
+	cfg := &buildCacheConfig{claimName: "cache"}
+
+	for _, test := range []struct {
+		name      string
+		objects   []runtime.Object
+		forbidden bool
+		expected  string
+	}{
+		{name: "shared claim", objects: []runtime.Object{claim(apiv1.ReadWriteMany)}, expected: "cache"},
+		{name: "claim of a single node", objects: []runtime.Object{claim(apiv1.ReadWriteOnce)}, expected: ""},
+		{name: "missing claim", expected: ""},
+		{name: "forbidden", forbidden: true, expected: "cache"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset(test.objects...)
+			if test.forbidden {
+				kubeClient.PrependReactor("get", "persistentvolumeclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, k8serrors.NewForbidden(schema.GroupResource{Resource: "persistentvolumeclaims"}, "cache", errors.New("not allowed"))
+				})
+			}
+			require.Equal(t, test.expected, cfg.claim(zap.NewNop(), kubeClient, "fission-builder"))
+		})
+	}
+
+	require.Empty(t, (&buildCacheConfig{}).claim(zap.NewNop(), fake.NewSimpleClientset(), "fission-builder"))
+}
@@ -31,6 +31,7 @@ import (
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/cache"
 	"github.com/fission/fission/pkg/crd"
+	"github.com/fission/fission/pkg/signing"
 	"github.com/fission/fission/pkg/utils"
 )
 
@@ -44,12 +45,13 @@ type (
 		builderNamespace string
 		storageSvcUrl    string
 		buildCache       *cache.Cache
+		builds           *buildRegistry
 	}
 )
 
 func makePackageWatcher(logger *zap.Logger, fissionClient *crd.FissionClient, k8sClientSet *kubernetes.Clientset,
 	builderNamespace string, storageSvcUrl string, podInformer *k8sCache.SharedIndexInformer,
-	pkgInformer *k8sCache.SharedIndexInformer) *packageWatcher {
+	pkgInformer *k8sCache.SharedIndexInformer, builds *buildRegistry) *packageWatcher {
 	pkgw := &packageWatcher{
 		logger:           logger.Named("package_watcher"),
 		fissionClient:    fissionClient,
@@ -59,6 +61,7 @@ func makePackageWatcher(logger *zap.Logger, fissionClient *crd.FissionClient, k8
 		builderNamespace: builderNamespace,
 		storageSvcUrl:    storageSvcUrl,
 		buildCache:       cache.MakeCache(0, 0),
+		builds:           builds,
 	}
 	return pkgw
 }
@@ -89,7 +92,7 @@ func (pkgw *packageWatcher) build(srcpkg *fv1.Package) {
 
 	pkgw.logger.Info("starting build for package", zap.String("package_name", srcpkg.ObjectMeta.Name), zap.String("resource_version", srcpkg.ObjectMeta.ResourceVersion))
 
-	pkg, err := updatePackage(pkgw.logger, pkgw.fissionClient, srcpkg, fv1.BuildStatusRunning, "", nil)
+	pkg, err := updatePackage(pkgw.logger, pkgw.fissionClient, srcpkg, fv1.BuildStatusRunning, "", nil, nil)
 	if err != nil {
 		pkgw.logger.Error("error setting package pending state", zap.Error(err))
 		return
@@ -100,7 +103,7 @@ func (pkgw *packageWatcher) build(srcpkg *fv1.Package) {
 		e := "environment does not exist"
 		pkgw.logger.Error(e, zap.String("environment", pkg.Spec.Environment.Name))
 		_, er := updatePackage(pkgw.logger, pkgw.fissionClient, pkg,
-			fv1.BuildStatusFailed, fmt.Sprintf("%s: %q", e, pkg.Spec.Environment.Name), nil)
+			fv1.BuildStatusFailed, fmt.Sprintf("%s: %q", e, pkg.Spec.Environment.Name), nil, nil)
 		if er != nil {
 			pkgw.logger.Error(
 				"error updating package",
@@ -181,11 +184,45 @@ func (pkgw *packageWatcher) build(srcpkg *fv1.Package) {
 					zap.String("package", fmt.Sprintf("%s.%s", pkg.ObjectMeta.Name, pkg.ObjectMeta.Namespace)))
 			}
 
-			ctx := context.Background()
-			uploadResp, buildLogs, err := buildPackage(ctx, pkgw.logger, pkgw.fissionClient, builderNs, pkgw.storageSvcUrl, pkg)
+			// the fetcher reads the image pull secrets of OCI source archives
+			// and of the OCI repository the deployment archive is pushed to
+			repo := ociRepository(pkg, env)
+			if (pkg.Spec.Source.Type == fv1.ArchiveTypeOCI && len(pkg.Spec.Source.ImagePullSecret) > 0) ||
+				(repo != nil && len(repo.ImagePullSecret) > 0) {
+				err := utils.SetupRoleBinding(pkgw.logger, pkgw.k8sClient, fv1.SecretConfigMapGetterRB, pkg.ObjectMeta.Namespace, fv1.SecretConfigMapGetterCR, fv1.ClusterRole, fv1.FissionBuilderSA, builderNs)
+				if err != nil {
+					pkgw.logger.Error("error setting up role binding for image pull secret of package",
+						zap.Error(err),
+						zap.String("role_binding", fv1.SecretConfigMapGetterRB),
+						zap.String("package_name", pkg.ObjectMeta.Name),
+						zap.String("package_namespace", pkg.ObjectMeta.Namespace))
+					continue
+				}
+			}
+
+			// the build is tracked until the package status is updated, so
+			// that its logs can be followed and it can be cancelled
+			ctx, cancel := context.WithCancel(context.Background())
+			build := pkgw.builds.add(pkgw.logger, pkg, cancel)
+			defer func() {
+				pkgw.builds.remove(pkg, build)
+				cancel()
+			}()
+
+			provenance := newBuildProvenance(pkg, env, pod)
+			var deployment *fv1.Archive
+			var buildLogs string
+			policy, err := signing.ResolvePolicy(ctx, pkgw.k8sClient, env, true)
+			if err != nil {
+				buildLogs = fmt.Sprintf("error resolving signature policy of environment: %v\n", err)
+			} else {
+				deployment, buildLogs, err = buildPackageWithRetries(ctx, pkgw.logger, pkgw.fissionClient, builderNs, pkgw.storageSvcUrl, pkg, env, policy, build, provenance)
+			}
+			build.finish(buildLogs)
+			provenance.EndTimestamp = metav1.Time{Time: time.Now().UTC()}
 			if err != nil {
 				pkgw.logger.Error("error building package", zap.Error(err), zap.String("package_name", pkg.ObjectMeta.Name))
-				_, er := updatePackage(pkgw.logger, pkgw.fissionClient, pkg, fv1.BuildStatusFailed, buildLogs, nil)
+				_, er := updatePackage(pkgw.logger, pkgw.fissionClient, pkg, fv1.BuildStatusFailed, buildLogs, nil, provenance)
 				if er != nil {
 					pkgw.logger.Error(
 						"error updating package",
@@ -205,7 +242,7 @@ func (pkgw *packageWatcher) build(srcpkg *fv1.Package) {
 				e := "error getting function list"
 				pkgw.logger.Error(e, zap.Error(err))
 				buildLogs += fmt.Sprintf("%s: %v\n", e, err)
-				_, er := updatePackage(pkgw.logger, pkgw.fissionClient, pkg, fv1.BuildStatusFailed, buildLogs, nil)
+				_, er := updatePackage(pkgw.logger, pkgw.fissionClient, pkg, fv1.BuildStatusFailed, buildLogs, nil, provenance)
 				if er != nil {
 					pkgw.logger.Error(
 						"error updating package",
@@ -214,6 +251,7 @@ func (pkgw *packageWatcher) build(srcpkg *fv1.Package) {
 						zap.Error(er),
 					)
 				}
+				return
 			}
 
 			// A package may be used by multiple functions. Update
@@ -229,7 +267,7 @@ func (pkgw *packageWatcher) build(srcpkg *fv1.Package) {
 						e := "error updating function package resource version"
 						pkgw.logger.Error(e, zap.Error(err))
 						buildLogs += fmt.Sprintf("%s: %v\n", e, err)
-						_, er := updatePackage(pkgw.logger, pkgw.fissionClient, pkg, fv1.BuildStatusFailed, buildLogs, nil)
+						_, er := updatePackage(pkgw.logger, pkgw.fissionClient, pkg, fv1.BuildStatusFailed, buildLogs, nil, provenance)
 						if er != nil {
 							pkgw.logger.Error(
 								"error updating package",
@@ -243,11 +281,12 @@ func (pkgw *packageWatcher) build(srcpkg *fv1.Package) {
 				}
 			}
 
+			provenance.DeploymentChecksum = deployment.Checksum
 			_, err = updatePackage(pkgw.logger, pkgw.fissionClient, pkg,
-				fv1.BuildStatusSucceeded, buildLogs, uploadResp)
+				fv1.BuildStatusSucceeded, buildLogs, deployment, provenance)
 			if err != nil {
 				pkgw.logger.Error("error updating package info", zap.Error(err), zap.String("package_name", pkg.ObjectMeta.Name))
-				_, er := updatePackage(pkgw.logger, pkgw.fissionClient, pkg, fv1.BuildStatusFailed, buildLogs, nil)
+				_, er := updatePackage(pkgw.logger, pkgw.fissionClient, pkg, fv1.BuildStatusFailed, buildLogs, nil, provenance)
 				if er != nil {
 					pkgw.logger.Error(
 						"error updating package",
@@ -266,7 +305,7 @@ func (pkgw *packageWatcher) build(srcpkg *fv1.Package) {
 	}
 	// build timeout
 	_, err = updatePackage(pkgw.logger, pkgw.fissionClient, pkg,
-		fv1.BuildStatusFailed, "Build timeout due to environment builder not ready", nil)
+		fv1.BuildStatusFailed, "Build timeout due to environment builder not ready", nil, nil)
 	if err != nil {
 		pkgw.logger.Error(
 			"error updating package",
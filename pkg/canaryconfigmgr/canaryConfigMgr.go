@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"reflect"
 	"strings"
 	"time"
 
@@ -45,11 +46,40 @@ type canaryConfigMgr struct {
 	fissionClient          *crd.FissionClient
 	kubeClient             *kubernetes.Clientset
 	canaryConfigInformer   *k8sCache.SharedIndexInformer
-	promClient             *PrometheusApiClient
+	metricsProvider        MetricsProvider
+	routerURL              string
 	canaryCfgCancelFuncMap *canaryConfigCancelFuncMap
 }
 
-func MakeCanaryConfigMgr(logger *zap.Logger, fissionClient *crd.FissionClient, kubeClient *kubernetes.Clientset, prometheusSvc string) (*canaryConfigMgr, error) {
+func MakeCanaryConfigMgr(logger *zap.Logger, fissionClient *crd.FissionClient, kubeClient *kubernetes.Clientset, prometheusSvc string, routerURL string) (*canaryConfigMgr, error) {
+	configMgr := &canaryConfigMgr{
+		logger:                 logger.Named("canary_config_manager"),
+		fissionClient:          fissionClient,
+		kubeClient:             kubeClient,
+		routerURL:              strings.TrimSuffix(routerURL, "/"),
+		canaryCfgCancelFuncMap: makecanaryConfigCancelFuncMap(),
+	}
+
+	// prometheus is the default metrics provider, canary configs can use
+	// other providers if it's not installed
+	promClient, err := makeDefaultPrometheusClient(logger, prometheusSvc)
+	if err != nil {
+		logger.Warn("no default prometheus metrics provider, canary configs must set their metrics provider", zap.Error(err))
+	} else {
+		configMgr.metricsProvider = promClient
+	}
+
+	informerFactory := genInformer.NewSharedInformerFactory(fissionClient, time.Second*30)
+	informer := informerFactory.Core().V1().CanaryConfigs().Informer()
+	configMgr.canaryConfigInformer = &informer
+	configMgr.CanaryConfigEventHandlers()
+	return configMgr, nil
+}
+
+// makeDefaultPrometheusClient returns the client of the prometheus server of
+// the canary deployment feature, found from the environment variables of an
+// installed prometheus server if not set.
+func makeDefaultPrometheusClient(logger *zap.Logger, prometheusSvc string) (*PrometheusApiClient, error) {
 	if prometheusSvc == "" {
 		logger.Info("try to retrieve prometheus server information from environment variables")
 
@@ -77,27 +107,10 @@ func MakeCanaryConfigMgr(logger *zap.Logger, fissionClient *crd.FissionClient, k
 
 	_, err := url.Parse(prometheusSvc)
 	if err != nil {
-		return nil, errors.Errorf("prometheus service url not found/invalid, cant create canary config manager: %v", prometheusSvc)
+		return nil, errors.Errorf("prometheus service url invalid: %v", prometheusSvc)
 	}
 
-	promClient, err := MakePrometheusClient(logger, prometheusSvc)
-	if err != nil {
-		return nil, err
-	}
-
-	configMgr := &canaryConfigMgr{
-		logger:                 logger.Named("canary_config_manager"),
-		fissionClient:          fissionClient,
-		kubeClient:             kubeClient,
-		promClient:             promClient,
-		canaryCfgCancelFuncMap: makecanaryConfigCancelFuncMap(),
-	}
-
-	informerFactory := genInformer.NewSharedInformerFactory(fissionClient, time.Second*30)
-	informer := informerFactory.Core().V1().CanaryConfigs().Informer()
-	configMgr.canaryConfigInformer = &informer
-	configMgr.CanaryConfigEventHandlers()
-	return configMgr, nil
+	return MakePrometheusClient(logger, prometheusSvc)
 }
 
 func (canaryCfgMgr *canaryConfigMgr) CanaryConfigEventHandlers() {
@@ -115,8 +128,10 @@ func (canaryCfgMgr *canaryConfigMgr) CanaryConfigEventHandlers() {
 		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
 			oldConfig := oldObj.(*fv1.CanaryConfig)
 			newConfig := newObj.(*fv1.CanaryConfig)
+			// the status updates of pending canary configs don't restart their processing
 			if oldConfig.ObjectMeta.ResourceVersion != newConfig.ObjectMeta.ResourceVersion &&
-				newConfig.Status.Status == fv1.CanaryConfigStatusPending {
+				newConfig.Status.Status == fv1.CanaryConfigStatusPending &&
+				(oldConfig.Status.Status != fv1.CanaryConfigStatusPending || !reflect.DeepEqual(oldConfig.Spec, newConfig.Spec)) {
 				canaryCfgMgr.logger.Info("update canary config invoked",
 					zap.String("name", newConfig.ObjectMeta.Name),
 					zap.String("namespace", newConfig.ObjectMeta.Namespace),
@@ -137,8 +152,22 @@ func (canaryCfgMgr *canaryConfigMgr) Run(ctx context.Context) {
 func (canaryCfgMgr *canaryConfigMgr) addCanaryConfig(canaryConfig *fv1.CanaryConfig) {
 	canaryCfgMgr.logger.Debug("addCanaryConfig called", zap.String("canary_config", canaryConfig.ObjectMeta.Name))
 
-	// for each canary config, create a ticker with increment interval
-	interval, err := time.ParseDuration(canaryConfig.Spec.WeightIncrementDuration)
+	done, err := canaryCfgMgr.applyControls(canaryConfig)
+	if err != nil {
+		// the canary config is added again by the resync loop
+		canaryCfgMgr.logger.Error("error applying canary config controls",
+			zap.Error(err),
+			zap.String("name", canaryConfig.ObjectMeta.Name),
+			zap.String("namespace", canaryConfig.ObjectMeta.Namespace),
+			zap.String("version", canaryConfig.ObjectMeta.ResourceVersion))
+		return
+	}
+	if done {
+		return
+	}
+
+	// for each canary config, create a ticker with the increment interval of the current step
+	interval, err := stepInterval(&canaryConfig.Spec, canaryConfig.Status.Weight)
 	if err != nil {
 		canaryCfgMgr.logger.Error("error parsing duration - cant proceed with this canaryConfig",
 			zap.Error(err),
@@ -265,8 +294,20 @@ func (canaryCfgMgr *canaryConfigMgr) RollForwardOrBack(canaryConfig *fv1.CanaryC
 		return
 	}
 
+	if canaryConfig.Spec.Paused {
+		canaryCfgMgr.logger.Info("canary config is paused, keeping the weights of the functions",
+			zap.String("name", canaryConfig.ObjectMeta.Name),
+			zap.String("namespace", canaryConfig.ObjectMeta.Namespace),
+			zap.String("version", canaryConfig.ObjectMeta.ResourceVersion))
+		return
+	}
+
+	// the new function is analysed once it receives traffic, after the switch for the bluegreen strategy
+	blueGreen := canaryConfig.Spec.Strategy == fv1.CanaryStrategyBlueGreen
+	weight := triggerObj.Spec.FunctionReference.FunctionWeights[canaryConfig.Spec.NewFunction]
+	var analysis *fv1.CanaryAnalysis
 	if triggerObj.Spec.FunctionReference.Type == fv1.FunctionReferenceTypeFunctionWeights &&
-		triggerObj.Spec.FunctionReference.FunctionWeights[canaryConfig.Spec.NewFunction] != 0 {
+		weight != 0 && (!blueGreen || weight == 100) {
 		var urlPath string
 		if triggerObj.Spec.Prefix != nil && *triggerObj.Spec.Prefix != "" {
 			urlPath = *triggerObj.Spec.Prefix
@@ -286,11 +327,10 @@ func (canaryCfgMgr *canaryConfigMgr) RollForwardOrBack(canaryConfig *fv1.CanaryC
 				methods = append(methods, triggerObj.Spec.Method)
 			}
 		}
-		failurePercent, err := canaryCfgMgr.promClient.GetFunctionFailurePercentage(urlPath, methods,
-			canaryConfig.Spec.NewFunction, canaryConfig.ObjectMeta.Namespace, canaryConfig.Spec.WeightIncrementDuration)
+		err = canaryConfig.Spec.Validate()
 		if err != nil {
-			// silently ignore. wait for next window to increment weight
-			canaryCfgMgr.logger.Error("error calculating failure percentage",
+			// the config can't be analysed until it's updated
+			canaryCfgMgr.logger.Error("invalid canary config",
 				zap.Error(err),
 				zap.String("name", canaryConfig.ObjectMeta.Name),
 				zap.String("namespace", canaryConfig.ObjectMeta.Namespace),
@@ -298,28 +338,62 @@ func (canaryCfgMgr *canaryConfigMgr) RollForwardOrBack(canaryConfig *fv1.CanaryC
 			return
 		}
 
-		canaryCfgMgr.logger.Info("failure percentage calculated for canaryConfig",
-			zap.Float64("failure_percent", failurePercent),
-			zap.String("name", canaryConfig.ObjectMeta.Name),
-			zap.String("namespace", canaryConfig.ObjectMeta.Namespace),
-			zap.String("version", canaryConfig.ObjectMeta.ResourceVersion))
+		provider, err := canaryCfgMgr.getMetricsProvider(canaryConfig)
+		if err != nil {
+			canaryCfgMgr.logger.Error("error getting metrics provider",
+				zap.Error(err),
+				zap.String("name", canaryConfig.ObjectMeta.Name),
+				zap.String("namespace", canaryConfig.ObjectMeta.Namespace),
+				zap.String("version", canaryConfig.ObjectMeta.ResourceVersion))
+			return
+		}
+
+		result, err := provider.Analyze(context.TODO(), &AnalysisRequest{
+			CanaryConfig:    canaryConfig,
+			Path:            urlPath,
+			Methods:         methods,
+			FunctionWeights: triggerObj.Spec.FunctionReference.FunctionWeights,
+		})
+		if err != nil {
+			// silently ignore. wait for next window to increment weight
+			canaryCfgMgr.logger.Error("error analysing new function",
+				zap.Error(err),
+				zap.String("name", canaryConfig.ObjectMeta.Name),
+				zap.String("namespace", canaryConfig.ObjectMeta.Namespace),
+				zap.String("version", canaryConfig.ObjectMeta.ResourceVersion))
+			return
+		}
+		analysis = canaryAnalysis(result)
 
-		if failurePercent == -1 {
-			// this means there were no requests triggered to this url during this window. return here and check back
+		if result.Verdict == VerdictInconclusive {
+			// there wasn't enough data during this window to make a decision. return here and check back
 			// during next iteration
-			canaryCfgMgr.logger.Info("total requests received for url is 0", zap.String("url", urlPath))
+			canaryCfgMgr.logger.Info("analysis of new function is inconclusive",
+				zap.String("url", urlPath),
+				zap.String("reason", result.Reason),
+				zap.String("name", canaryConfig.ObjectMeta.Name),
+				zap.String("namespace", canaryConfig.ObjectMeta.Namespace))
+			err = canaryCfgMgr.updateCanaryConfigStatusWithRetries(canaryConfig.ObjectMeta.Name, canaryConfig.ObjectMeta.Namespace,
+				func(status *fv1.CanaryConfigStatus) {
+					recordStep(status, triggerObj.Spec.FunctionReference.FunctionWeights, canaryConfig.Spec.NewFunction, false, analysis)
+				})
+			if err != nil {
+				canaryCfgMgr.logger.Error("error recording analysis of canary config",
+					zap.Error(err),
+					zap.String("name", canaryConfig.ObjectMeta.Name),
+					zap.String("namespace", canaryConfig.ObjectMeta.Namespace))
+			}
 			return
 		}
 
-		if int(failurePercent) > canaryConfig.Spec.FailureThreshold {
-			canaryCfgMgr.logger.Error("failure percent crossed the threshold, so rolling back",
-				zap.Float64("failure_percent", failurePercent),
-				zap.Int("threshold", canaryConfig.Spec.FailureThreshold),
+		if result.Verdict == VerdictFail {
+			canaryCfgMgr.logger.Error("new function failed the analysis, so rolling back",
+				zap.String("reason", result.Reason),
 				zap.String("name", canaryConfig.ObjectMeta.Name),
 				zap.String("namespace", canaryConfig.ObjectMeta.Namespace),
 				zap.String("version", canaryConfig.ObjectMeta.ResourceVersion))
 			ticker.Stop()
-			err := canaryCfgMgr.rollback(canaryConfig, triggerObj)
+			err := canaryCfgMgr.rollback(canaryConfig, triggerObj, analysis)
 			if err != nil {
 				canaryCfgMgr.logger.Error("error rolling back canary config",
 					zap.Error(err),
@@ -332,6 +406,35 @@ func (canaryCfgMgr *canaryConfigMgr) RollForwardOrBack(canaryConfig *fv1.CanaryC
 		}
 	}
 
+	switching := blueGreen && weight < 100
+	if switching {
+		err = canaryCfgMgr.smokeTest(context.TODO(), canaryConfig)
+		if err != nil {
+			canaryCfgMgr.logger.Error("new function failed the smoke test, so cancelling the switch",
+				zap.Error(err),
+				zap.String("name", canaryConfig.ObjectMeta.Name),
+				zap.String("namespace", canaryConfig.ObjectMeta.Namespace),
+				zap.String("version", canaryConfig.ObjectMeta.ResourceVersion))
+			ticker.Stop()
+			reason := err.Error()
+			err = canaryCfgMgr.updateCanaryConfigStatusWithRetries(canaryConfig.ObjectMeta.Name, canaryConfig.ObjectMeta.Namespace,
+				func(status *fv1.CanaryConfigStatus) {
+					status.Status = fv1.CanaryConfigStatusFailed
+					recordTransition(status, fv1.CanaryTransitionSmokeTestFailed, triggerObj.Spec.FunctionReference.FunctionWeights,
+						canaryConfig.Spec.NewFunction, reason)
+				})
+			if err != nil {
+				canaryCfgMgr.logger.Error("error updating canary config after max retries",
+					zap.Error(err),
+					zap.String("name", canaryConfig.ObjectMeta.Name),
+					zap.String("namespace", canaryConfig.ObjectMeta.Namespace),
+					zap.String("version", canaryConfig.ObjectMeta.ResourceVersion))
+			}
+			close(quit)
+			return
+		}
+	}
+
 	doneProcessingCanaryConfig, err := canaryCfgMgr.rollForward(canaryConfig, triggerObj)
 	if err != nil {
 		// just log the error and hope that next iteration will succeed
@@ -344,20 +447,33 @@ func (canaryCfgMgr *canaryConfigMgr) RollForwardOrBack(canaryConfig *fv1.CanaryC
 		return
 	}
 
+	// record the step, and the status of canary config as done processing once the new function receives all the
+	// traffic. we don't care if we aren't able to update because resync takes care of the update
+	functionWeights := triggerObj.Spec.FunctionReference.FunctionWeights
+	err = canaryCfgMgr.updateCanaryConfigStatusWithRetries(canaryConfig.ObjectMeta.Name, canaryConfig.ObjectMeta.Namespace,
+		func(status *fv1.CanaryConfigStatus) {
+			recordStep(status, functionWeights, canaryConfig.Spec.NewFunction, functionWeights[canaryConfig.Spec.NewFunction] != weight, analysis)
+			if switching {
+				recordTransition(status, fv1.CanaryTransitionSwitched, functionWeights, canaryConfig.Spec.NewFunction,
+					"the new function is receiving all the traffic, rolling back if the analysis fails")
+			}
+			if doneProcessingCanaryConfig {
+				status.Status = fv1.CanaryConfigStatusSucceeded
+				recordTransition(status, fv1.CanaryTransitionSucceeded, functionWeights, canaryConfig.Spec.NewFunction,
+					"the new function is receiving all the traffic")
+			}
+		})
+	if err != nil {
+		// cant do much after max retries other than logging it.
+		canaryCfgMgr.logger.Error("error updating canary config after max retries",
+			zap.Error(err),
+			zap.String("name", canaryConfig.ObjectMeta.Name),
+			zap.String("namespace", canaryConfig.ObjectMeta.Namespace),
+			zap.String("version", canaryConfig.ObjectMeta.ResourceVersion))
+	}
+
 	if doneProcessingCanaryConfig {
 		ticker.Stop()
-		// update the status of canary config as done processing, we don't care if we aren't able to update because
-		// resync takes care of the update
-		err = canaryCfgMgr.updateCanaryConfigStatusWithRetries(canaryConfig.ObjectMeta.Name, canaryConfig.ObjectMeta.Namespace,
-			fv1.CanaryConfigStatusSucceeded)
-		if err != nil {
-			// cant do much after max retries other than logging it.
-			canaryCfgMgr.logger.Error("error updating canary config after max retries",
-				zap.Error(err),
-				zap.String("name", canaryConfig.ObjectMeta.Name),
-				zap.String("namespace", canaryConfig.ObjectMeta.Namespace),
-				zap.String("version", canaryConfig.ObjectMeta.ResourceVersion))
-		}
 
 		canaryCfgMgr.logger.Info("done processing canary config - the new function is receiving all the traffic",
 			zap.String("name", canaryConfig.ObjectMeta.Name),
@@ -366,6 +482,12 @@ func (canaryCfgMgr *canaryConfigMgr) RollForwardOrBack(canaryConfig *fv1.CanaryC
 		close(quit)
 		return
 	}
+
+	// the next step is taken after the interval of the current one
+	interval, err := stepInterval(&canaryConfig.Spec, functionWeights[canaryConfig.Spec.NewFunction])
+	if err == nil {
+		ticker.Reset(interval)
+	}
 }
 
 func (canaryCfgMgr *canaryConfigMgr) updateHttpTriggerWithRetries(triggerName, triggerNamespace string, fnWeights map[string]int) (err error) {
@@ -403,7 +525,8 @@ func (canaryCfgMgr *canaryConfigMgr) updateHttpTriggerWithRetries(triggerName, t
 	return err
 }
 
-func (canaryCfgMgr *canaryConfigMgr) updateCanaryConfigStatusWithRetries(cfgName, cfgNamespace string, status string) (err error) {
+// updateCanaryConfigStatusWithRetries updates the status of a canary config with the update func
+func (canaryCfgMgr *canaryConfigMgr) updateCanaryConfigStatusWithRetries(cfgName, cfgNamespace string, update func(status *fv1.CanaryConfigStatus)) (err error) {
 	for i := 0; i < maxRetries; i++ {
 		canaryCfgObj, err := canaryCfgMgr.fissionClient.CoreV1().CanaryConfigs(cfgNamespace).Get(context.TODO(), cfgName, metav1.GetOptions{})
 		if err != nil {
@@ -411,17 +534,17 @@ func (canaryCfgMgr *canaryConfigMgr) updateCanaryConfigStatusWithRetries(cfgName
 			canaryCfgMgr.logger.Error(e,
 				zap.Error(err),
 				zap.String("name", cfgName),
-				zap.String("namespace", cfgNamespace),
-				zap.String("status", status))
+				zap.String("namespace", cfgNamespace))
 			return errors.Wrap(err, e)
 		}
 
+		update(&canaryCfgObj.Status)
+
 		canaryCfgMgr.logger.Info("updating status of canary config",
 			zap.String("name", cfgName),
 			zap.String("namespace", cfgNamespace),
-			zap.String("status", status))
-
-		canaryCfgObj.Status.Status = status
+			zap.String("status", canaryCfgObj.Status.Status),
+			zap.Int("weight", canaryCfgObj.Status.Weight))
 
 		_, err = canaryCfgMgr.fissionClient.CoreV1().CanaryConfigs(cfgNamespace).Update(context.TODO(), canaryCfgObj, metav1.UpdateOptions{})
 		switch {
@@ -449,7 +572,50 @@ func (canaryCfgMgr *canaryConfigMgr) updateCanaryConfigStatusWithRetries(cfgName
 	return err
 }
 
-func (canaryCfgMgr *canaryConfigMgr) rollback(canaryConfig *fv1.CanaryConfig, trigger *fv1.HTTPTrigger) error {
+// setFunctionWeights sets the current weights of the functions in the status of a canary config
+func setFunctionWeights(status *fv1.CanaryConfigStatus, functionWeights map[string]int, newFunction string) {
+	status.FunctionWeights = make(map[string]int, len(functionWeights))
+	for fn, weight := range functionWeights {
+		status.FunctionWeights[fn] = weight
+	}
+	status.Weight = functionWeights[newFunction]
+}
+
+// recordTransition records a transition in the status of a canary config, keeping the last transitions
+func recordTransition(status *fv1.CanaryConfigStatus, transitionType fv1.CanaryTransitionType, functionWeights map[string]int, newFunction string, message string) {
+	now := metav1.Now()
+	setFunctionWeights(status, functionWeights, newFunction)
+	status.LastTransitionTime = &now
+	status.Transitions = append(status.Transitions, fv1.CanaryTransition{
+		Type:    transitionType,
+		Time:    now,
+		Weight:  status.Weight,
+		Message: message,
+	})
+	if len(status.Transitions) > fv1.MaxCanaryTransitions {
+		status.Transitions = status.Transitions[len(status.Transitions)-fv1.MaxCanaryTransitions:]
+	}
+}
+
+// recordStep records a weight increment interval in the status of a canary config with the analysis of the
+// interval, keeping the last intervals. The step number is incremented if the weights were incremented.
+func recordStep(status *fv1.CanaryConfigStatus, functionWeights map[string]int, newFunction string, incremented bool, analysis *fv1.CanaryAnalysis) {
+	if incremented {
+		status.Step++
+	}
+	setFunctionWeights(status, functionWeights, newFunction)
+	status.History = append(status.History, fv1.CanaryStep{
+		Step:            status.Step,
+		Time:            metav1.Now(),
+		FunctionWeights: status.FunctionWeights,
+		Analysis:        analysis,
+	})
+	if len(status.History) > fv1.MaxCanaryHistory {
+		status.History = status.History[len(status.History)-fv1.MaxCanaryHistory:]
+	}
+}
+
+func (canaryCfgMgr *canaryConfigMgr) rollback(canaryConfig *fv1.CanaryConfig, trigger *fv1.HTTPTrigger, analysis *fv1.CanaryAnalysis) error {
 	functionWeights := trigger.Spec.FunctionReference.FunctionWeights
 	functionWeights[canaryConfig.Spec.NewFunction] = 0
 	functionWeights[canaryConfig.Spec.OldFunction] = 100
@@ -460,26 +626,60 @@ func (canaryCfgMgr *canaryConfigMgr) rollback(canaryConfig *fv1.CanaryConfig, tr
 	}
 
 	err = canaryCfgMgr.updateCanaryConfigStatusWithRetries(canaryConfig.ObjectMeta.Name, canaryConfig.ObjectMeta.Namespace,
-		fv1.CanaryConfigStatusFailed)
+		func(status *fv1.CanaryConfigStatus) {
+			status.Status = fv1.CanaryConfigStatusFailed
+			recordStep(status, functionWeights, canaryConfig.Spec.NewFunction, false, analysis)
+			recordTransition(status, fv1.CanaryTransitionRolledBack, functionWeights, canaryConfig.Spec.NewFunction, analysis.Reason)
+		})
 
 	return err
 }
 
-func (canaryCfgMgr *canaryConfigMgr) rollForward(canaryConfig *fv1.CanaryConfig, trigger *fv1.HTTPTrigger) (bool, error) {
-	doneProcessingCanaryConfig := false
+// nextWeight returns the weight of the new version of the function after the next step of a canary config: the next
+// weight of the step schedule, the weight increased by the weight increment, or 100 for the bluegreen strategy.
+func nextWeight(spec *fv1.CanaryConfigSpec, weight int) int {
+	switch {
+	case spec.Strategy == fv1.CanaryStrategyBlueGreen:
+		return 100
+	case len(spec.Steps) > 0:
+		for _, step := range spec.Steps {
+			if step.Weight > weight {
+				return step.Weight
+			}
+		}
+		return 100
+	case weight+spec.WeightIncrement >= 100:
+		return 100
+	default:
+		return weight + spec.WeightIncrement
+	}
+}
 
+// stepInterval returns how long the new version of the function keeps a weight before the next step of a canary config
+func stepInterval(spec *fv1.CanaryConfigSpec, weight int) (time.Duration, error) {
+	duration := spec.WeightIncrementDuration
+	for _, step := range spec.Steps {
+		if step.Weight == weight && len(step.Duration) > 0 {
+			duration = step.Duration
+		}
+	}
+	return time.ParseDuration(duration)
+}
+
+// rollForward takes the next step of a canary config, returning whether it's done processing. For the bluegreen
+// strategy, the traffic is switched at once and the canary config is done after the analysis of the switch passes.
+func (canaryCfgMgr *canaryConfigMgr) rollForward(canaryConfig *fv1.CanaryConfig, trigger *fv1.HTTPTrigger) (bool, error) {
 	functionWeights := trigger.Spec.FunctionReference.FunctionWeights
-	if functionWeights[canaryConfig.Spec.NewFunction]+canaryConfig.Spec.WeightIncrement >= 100 {
-		doneProcessingCanaryConfig = true
-		functionWeights[canaryConfig.Spec.NewFunction] = 100
+	weight := functionWeights[canaryConfig.Spec.NewFunction]
+	next := nextWeight(&canaryConfig.Spec, weight)
+	doneProcessingCanaryConfig := next == 100 &&
+		(canaryConfig.Spec.Strategy != fv1.CanaryStrategyBlueGreen || weight == 100)
+
+	functionWeights[canaryConfig.Spec.NewFunction] = next
+	if next == 100 || functionWeights[canaryConfig.Spec.OldFunction]-(next-weight) < 0 {
 		functionWeights[canaryConfig.Spec.OldFunction] = 0
 	} else {
-		functionWeights[canaryConfig.Spec.NewFunction] += canaryConfig.Spec.WeightIncrement
-		if functionWeights[canaryConfig.Spec.OldFunction]-canaryConfig.Spec.WeightIncrement < 0 {
-			functionWeights[canaryConfig.Spec.OldFunction] = 0
-		} else {
-			functionWeights[canaryConfig.Spec.OldFunction] -= canaryConfig.Spec.WeightIncrement
-		}
+		functionWeights[canaryConfig.Spec.OldFunction] -= next - weight
 	}
 
 	canaryCfgMgr.logger.Info("incremented functionWeights",
@@ -491,6 +691,66 @@ func (canaryCfgMgr *canaryConfigMgr) rollForward(canaryConfig *fv1.CanaryConfig,
 	return doneProcessingCanaryConfig, err
 }
 
+// applyControls applies the manual controls of a canary config, returning whether it's done processing: promoted
+// canary configs send all the traffic to the new function, aborted ones to the old function. Pausing and resuming
+// canary configs is recorded in their status.
+func (canaryCfgMgr *canaryConfigMgr) applyControls(canaryConfig *fv1.CanaryConfig) (bool, error) {
+	spec := canaryConfig.Spec
+	if len(spec.Action) == 0 && spec.Paused == canaryConfig.Status.Paused {
+		return false, nil
+	}
+
+	trigger, err := canaryCfgMgr.fissionClient.CoreV1().HTTPTriggers(canaryConfig.ObjectMeta.Namespace).Get(context.TODO(), spec.Trigger, metav1.GetOptions{})
+	if err != nil {
+		return false, errors.Wrap(err, "error getting http trigger object")
+	}
+	if trigger.Spec.FunctionReference.Type != fv1.FunctionReferenceTypeFunctionWeights {
+		return false, errors.Errorf("http trigger %v doesn't reference functions by weights", spec.Trigger)
+	}
+	functionWeights := trigger.Spec.FunctionReference.FunctionWeights
+
+	var status string
+	var transition fv1.CanaryTransitionType
+	switch spec.Action {
+	case fv1.CanaryActionPromote:
+		functionWeights[spec.NewFunction] = 100
+		functionWeights[spec.OldFunction] = 0
+		status, transition = fv1.CanaryConfigStatusSucceeded, fv1.CanaryTransitionPromoted
+	case fv1.CanaryActionAbort:
+		functionWeights[spec.NewFunction] = 0
+		functionWeights[spec.OldFunction] = 100
+		status, transition = fv1.CanaryConfigStatusAborted, fv1.CanaryTransitionAborted
+	default:
+		transition = fv1.CanaryTransitionResumed
+		if spec.Paused {
+			transition = fv1.CanaryTransitionPaused
+		}
+		canaryCfgMgr.logger.Info("canary config "+string(transition),
+			zap.String("name", canaryConfig.ObjectMeta.Name),
+			zap.String("namespace", canaryConfig.ObjectMeta.Namespace))
+		return false, canaryCfgMgr.updateCanaryConfigStatusWithRetries(canaryConfig.ObjectMeta.Name, canaryConfig.ObjectMeta.Namespace,
+			func(s *fv1.CanaryConfigStatus) {
+				s.Paused = spec.Paused
+				recordTransition(s, transition, functionWeights, spec.NewFunction, "")
+			})
+	}
+
+	canaryCfgMgr.logger.Info("canary config "+string(transition),
+		zap.String("name", canaryConfig.ObjectMeta.Name),
+		zap.String("namespace", canaryConfig.ObjectMeta.Namespace),
+		zap.Any("function_weights", functionWeights))
+	err = canaryCfgMgr.updateHttpTriggerWithRetries(trigger.ObjectMeta.Name, trigger.ObjectMeta.Namespace, functionWeights)
+	if err != nil {
+		return false, err
+	}
+	return true, canaryCfgMgr.updateCanaryConfigStatusWithRetries(canaryConfig.ObjectMeta.Name, canaryConfig.ObjectMeta.Namespace,
+		func(s *fv1.CanaryConfigStatus) {
+			s.Status = status
+			s.Paused = false
+			recordTransition(s, transition, functionWeights, spec.NewFunction, "")
+		})
+}
+
 func (canaryCfgMgr *canaryConfigMgr) reSyncCanaryConfigs() {
 	for _, obj := range (*canaryCfgMgr.canaryConfigInformer).GetStore().List() {
 		canaryConfig := obj.(*fv1.CanaryConfig)
@@ -0,0 +1,517 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package canaryconfigmgr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/crd"
+	"github.com/fission/fission/pkg/generated/clientset/versioned/fake"
+)
+
+// prometheusServer serves the requests and latencies of functions to
+// prometheus queries.
+func prometheusServer(requests map[string]float64, latencies map[string]float64) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.FormValue("query")
+		value := 0.0
+		for fn := range requests {
+			if !strings.Contains(query, fmt.Sprintf("name=\"%v\"", fn)) {
+				continue
+			}
+			switch {
+			case strings.HasPrefix(query, "histogram_quantile"):
+				value = latencies[fn]
+			case strings.HasPrefix(query, "fission_function_calls_total") && !strings.Contains(query, "offset"):
+				value = requests[fn]
+			}
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"%v"]}]}}`, value)
+	}))
+}
+
+func TestPrometheusAnalyze(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		spec      fv1.CanaryConfigSpec
+		requests  map[string]float64
+		latencies map[string]float64
+		verdict   Verdict
+	}{
+		{
+			name:      "latency under threshold",
+			spec:      fv1.CanaryConfigSpec{FailureType: fv1.FailureTypeLatency, LatencyThreshold: "500ms"},
+			requests:  map[string]float64{"new": 100, "old": 100},
+			latencies: map[string]float64{"new": 0.4, "old": 0.1},
+			verdict:   VerdictPass,
+		},
+		{
+			name:      "latency over threshold",
+			spec:      fv1.CanaryConfigSpec{FailureType: fv1.FailureTypeLatency, LatencyThreshold: "500ms", LatencyPercentile: 90},
+			requests:  map[string]float64{"new": 100, "old": 100},
+			latencies: map[string]float64{"new": 0.6, "old": 0.1},
+			verdict:   VerdictFail,
+		},
+		{
+			name:      "relative latency under threshold",
+			spec:      fv1.CanaryConfigSpec{FailureType: fv1.FailureTypeRelativeLatency, FailureThreshold: 50},
+			requests:  map[string]float64{"new": 100, "old": 100},
+			latencies: map[string]float64{"new": 0.14, "old": 0.1},
+			verdict:   VerdictPass,
+		},
+		{
+			name:      "relative latency over threshold",
+			spec:      fv1.CanaryConfigSpec{FailureType: fv1.FailureTypeRelativeLatency, FailureThreshold: 50},
+			requests:  map[string]float64{"new": 100, "old": 100},
+			latencies: map[string]float64{"new": 0.2, "old": 0.1},
+			verdict:   VerdictFail,
+		},
+		{
+			name:      "new function under minimum requests",
+			spec:      fv1.CanaryConfigSpec{FailureType: fv1.FailureTypeLatency, LatencyThreshold: "500ms", MinRequests: 50},
+			requests:  map[string]float64{"new": 20, "old": 100},
+			latencies: map[string]float64{"new": 0.6, "old": 0.1},
+			verdict:   VerdictInconclusive,
+		},
+		{
+			name:      "old function under minimum requests",
+			spec:      fv1.CanaryConfigSpec{FailureType: fv1.FailureTypeRelativeLatency, FailureThreshold: 50, MinRequests: 50},
+			requests:  map[string]float64{"new": 100, "old": 20},
+			latencies: map[string]float64{"new": 0.2, "old": 0.1},
+			verdict:   VerdictInconclusive,
+		},
+		{
+			name: "custom query under maximum",
+			spec: fv1.CanaryConfigSpec{FailureType: fv1.FailureTypeStatusCode, FailureThreshold: 10, Queries: []fv1.CanaryMetricQuery{
+				{Name: "latency", Query: `histogram_quantile(0.5, fission_function_latency_seconds_bucket{name="{{ .Function }}"})`, Max: "0.5"},
+			}},
+			requests:  map[string]float64{"new": 100, "old": 100},
+			latencies: map[string]float64{"new": 0.4, "old": 0.1},
+			verdict:   VerdictPass,
+		},
+		{
+			name: "custom query below minimum",
+			spec: fv1.CanaryConfigSpec{FailureType: fv1.FailureTypeStatusCode, FailureThreshold: 10, Queries: []fv1.CanaryMetricQuery{
+				{Name: "latency", Query: `histogram_quantile(0.5, fission_function_latency_seconds_bucket{name="{{ .OldFunction }}"})`, Min: "0.2"},
+			}},
+			requests:  map[string]float64{"new": 100, "old": 100},
+			latencies: map[string]float64{"new": 0.4, "old": 0.1},
+			verdict:   VerdictFail,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			server := prometheusServer(test.requests, test.latencies)
+			defer server.Close()
+			promClient, err := MakePrometheusClient(zap.NewNop(), server.URL)
+			require.NoError(t, err)
+			test.spec.NewFunction = "new"
+			test.spec.OldFunction = "old"
+			test.spec.WeightIncrementDuration = "1m"
+			result, err := promClient.Analyze(context.Background(), &AnalysisRequest{
+				CanaryConfig: &fv1.CanaryConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: "canary", Namespace: "default"},
+					Spec:       test.spec,
+				},
+				Path:    "/fn",
+				Methods: []string{"GET"},
+			})
+			require.NoError(t, err)
+			require.Equal(t, test.verdict, result.Verdict, result.Reason)
+			require.NotNil(t, result.Measurements.Requests)
+			require.Equal(t, test.requests["new"], *result.Measurements.Requests)
+		})
+	}
+}
+
+func TestWebhookMetricsProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &AnalysisRequest{}
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.FunctionWeights[req.CanaryConfig.Spec.NewFunction] > 50 {
+			json.NewEncoder(w).Encode(AnalysisResult{Verdict: VerdictFail, Reason: "conversion rate dropped"})
+			return
+		}
+		json.NewEncoder(w).Encode(AnalysisResult{Verdict: VerdictPass})
+	}))
+	defer server.Close()
+
+	canaryCfgMgr := &canaryConfigMgr{logger: zap.NewNop()}
+	canaryConfig := &fv1.CanaryConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "canary", Namespace: "default"},
+		Spec: fv1.CanaryConfigSpec{
+			NewFunction:     "new",
+			OldFunction:     "old",
+			MetricsProvider: &fv1.CanaryMetricsProvider{Type: fv1.CanaryMetricsProviderWebhook, URL: server.URL},
+		},
+	}
+	provider, err := canaryCfgMgr.getMetricsProvider(canaryConfig)
+	require.NoError(t, err)
+
+	result, err := provider.Analyze(context.Background(), &AnalysisRequest{
+		CanaryConfig:    canaryConfig,
+		FunctionWeights: map[string]int{"new": 20, "old": 80},
+	})
+	require.NoError(t, err)
+	require.Equal(t, VerdictPass, result.Verdict)
+
+	result, err = provider.Analyze(context.Background(), &AnalysisRequest{
+		CanaryConfig:    canaryConfig,
+		FunctionWeights: map[string]int{"new": 60, "old": 40},
+	})
+	require.NoError(t, err)
+	require.Equal(t, VerdictFail, result.Verdict)
+	require.Equal(t, "conversion rate dropped", result.Reason)
+
+	// canary configs without metrics provider need the default prometheus server
+	canaryConfig.Spec.MetricsProvider = nil
+	_, err = canaryCfgMgr.getMetricsProvider(canaryConfig)
+	require.Error(t, err)
+}
+
+func TestRollForwardOrBack(t *testing.T) {
+	trigger := &fv1.HTTPTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "trigger", Namespace: "default"},
+		Spec: fv1.HTTPTriggerSpec{
+			RelativeURL: "/fn",
+			Methods:     []string{"GET"},
+			FunctionReference: fv1.FunctionReference{
+				Type:            fv1.FunctionReferenceTypeFunctionWeights,
+				FunctionWeights: map[string]int{"new": 20, "old": 80},
+			},
+		},
+	}
+	canaryConfig := &fv1.CanaryConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "canary", Namespace: "default"},
+		Spec: fv1.CanaryConfigSpec{
+			Trigger:                 "trigger",
+			NewFunction:             "new",
+			OldFunction:             "old",
+			WeightIncrement:         30,
+			WeightIncrementDuration: "1m",
+		},
+		Status: fv1.CanaryConfigStatus{Status: fv1.CanaryConfigStatusPending},
+	}
+	fissionClient := &crd.FissionClient{Interface: fake.NewSimpleClientset(trigger, canaryConfig)}
+	provider := MakeInMemoryMetricsProvider()
+	canaryCfgMgr := &canaryConfigMgr{
+		logger:                 zap.NewNop(),
+		fissionClient:          fissionClient,
+		metricsProvider:        provider,
+		canaryCfgCancelFuncMap: makecanaryConfigCancelFuncMap(),
+	}
+	require.NoError(t, canaryCfgMgr.canaryCfgCancelFuncMap.assign(&canaryConfig.ObjectMeta, &CanaryProcessingInfo{}))
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	weights := func() map[string]int {
+		trigger, err := fissionClient.CoreV1().HTTPTriggers("default").Get(context.Background(), "trigger", metav1.GetOptions{})
+		require.NoError(t, err)
+		return trigger.Spec.FunctionReference.FunctionWeights
+	}
+
+	// the new function passes, and gets more traffic
+	quit := make(chan struct{})
+	canaryCfgMgr.RollForwardOrBack(canaryConfig, quit, ticker)
+	require.Equal(t, map[string]int{"new": 50, "old": 50}, weights())
+	require.Len(t, provider.Requests(), 1)
+	require.Equal(t, "/fn", provider.Requests()[0].Path)
+
+	// the analysis is inconclusive, weights are unchanged
+	requests, latency := 4.0, 0.0125
+	provider.SetResult("default", "canary", AnalysisResult{
+		Verdict:      VerdictInconclusive,
+		Measurements: &Measurements{Requests: &requests, Latency: &latency},
+	})
+	canaryCfgMgr.RollForwardOrBack(canaryConfig, quit, ticker)
+	require.Equal(t, map[string]int{"new": 50, "old": 50}, weights())
+
+	// the new function fails, and is rolled back
+	provider.SetResult("default", "canary", AnalysisResult{Verdict: VerdictFail, Reason: "failed"})
+	canaryCfgMgr.RollForwardOrBack(canaryConfig, quit, ticker)
+	require.Equal(t, map[string]int{"new": 0, "old": 100}, weights())
+	_, open := <-quit
+	require.False(t, open)
+	cfg, err := fissionClient.CoreV1().CanaryConfigs("default").Get(context.Background(), "canary", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, fv1.CanaryConfigStatusFailed, cfg.Status.Status)
+	require.Len(t, cfg.Status.Transitions, 1)
+	require.Equal(t, fv1.CanaryTransitionRolledBack, cfg.Status.Transitions[0].Type)
+	require.Equal(t, "failed", cfg.Status.Transitions[0].Message)
+
+	// each interval is recorded in the history with its analysis
+	require.Equal(t, 1, cfg.Status.Step)
+	require.Equal(t, map[string]int{"new": 0, "old": 100}, cfg.Status.FunctionWeights)
+	require.Len(t, cfg.Status.History, 3)
+	require.Equal(t, 1, cfg.Status.History[0].Step)
+	require.Equal(t, map[string]int{"new": 50, "old": 50}, cfg.Status.History[0].FunctionWeights)
+	require.Equal(t, string(VerdictPass), cfg.Status.History[0].Analysis.Verdict)
+	require.Equal(t, 1, cfg.Status.History[1].Step)
+	require.Equal(t, &fv1.CanaryAnalysis{Verdict: string(VerdictInconclusive), Requests: 4, Latency: "12.5ms"}, cfg.Status.History[1].Analysis)
+	require.Equal(t, "failed", cfg.Status.History[2].Analysis.Reason)
+}
+
+func TestApplyControls(t *testing.T) {
+	trigger := &fv1.HTTPTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "trigger", Namespace: "default"},
+		Spec: fv1.HTTPTriggerSpec{
+			RelativeURL: "/fn",
+			FunctionReference: fv1.FunctionReference{
+				Type:            fv1.FunctionReferenceTypeFunctionWeights,
+				FunctionWeights: map[string]int{"new": 20, "old": 80},
+			},
+		},
+	}
+	canaryConfig := &fv1.CanaryConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "canary", Namespace: "default"},
+		Spec: fv1.CanaryConfigSpec{
+			Trigger:                 "trigger",
+			NewFunction:             "new",
+			OldFunction:             "old",
+			WeightIncrement:         30,
+			WeightIncrementDuration: "1m",
+		},
+		Status: fv1.CanaryConfigStatus{Status: fv1.CanaryConfigStatusPending},
+	}
+	fissionClient := &crd.FissionClient{Interface: fake.NewSimpleClientset(trigger, canaryConfig)}
+	canaryCfgMgr := &canaryConfigMgr{
+		logger:                 zap.NewNop(),
+		fissionClient:          fissionClient,
+		metricsProvider:        MakeInMemoryMetricsProvider(),
+		canaryCfgCancelFuncMap: makecanaryConfigCancelFuncMap(),
+	}
+	require.NoError(t, canaryCfgMgr.canaryCfgCancelFuncMap.assign(&canaryConfig.ObjectMeta, &CanaryProcessingInfo{}))
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	weights := func() map[string]int {
+		trigger, err := fissionClient.CoreV1().HTTPTriggers("default").Get(context.Background(), "trigger", metav1.GetOptions{})
+		require.NoError(t, err)
+		return trigger.Spec.FunctionReference.FunctionWeights
+	}
+	// control updates the canary config as the cli does, and applies it
+	control := func(update func(spec *fv1.CanaryConfigSpec)) (*fv1.CanaryConfig, bool) {
+		cfg, err := fissionClient.CoreV1().CanaryConfigs("default").Get(context.Background(), "canary", metav1.GetOptions{})
+		require.NoError(t, err)
+		update(&cfg.Spec)
+		cfg, err = fissionClient.CoreV1().CanaryConfigs("default").Update(context.Background(), cfg, metav1.UpdateOptions{})
+		require.NoError(t, err)
+		done, err := canaryCfgMgr.applyControls(cfg)
+		require.NoError(t, err)
+		status, err := fissionClient.CoreV1().CanaryConfigs("default").Get(context.Background(), "canary", metav1.GetOptions{})
+		require.NoError(t, err)
+		cfg.Status = status.Status
+		return cfg, done
+	}
+
+	// paused canary configs keep the weights
+	cfg, done := control(func(spec *fv1.CanaryConfigSpec) { spec.Paused = true })
+	require.False(t, done)
+	require.True(t, cfg.Status.Paused)
+	require.Equal(t, fv1.CanaryTransitionPaused, cfg.Status.Transitions[0].Type)
+	require.Equal(t, 20, cfg.Status.Transitions[0].Weight)
+	canaryCfgMgr.RollForwardOrBack(cfg, make(chan struct{}), ticker)
+	require.Equal(t, map[string]int{"new": 20, "old": 80}, weights())
+
+	// applying the controls again records nothing
+	done, err := canaryCfgMgr.applyControls(cfg)
+	require.NoError(t, err)
+	require.False(t, done)
+
+	cfg, done = control(func(spec *fv1.CanaryConfigSpec) { spec.Paused = false })
+	require.False(t, done)
+	require.False(t, cfg.Status.Paused)
+	require.Equal(t, fv1.CanaryTransitionResumed, cfg.Status.Transitions[1].Type)
+	canaryCfgMgr.RollForwardOrBack(cfg, make(chan struct{}), ticker)
+	require.Equal(t, map[string]int{"new": 50, "old": 50}, weights())
+
+	// promoted canary configs send all the traffic to the new function
+	cfg, done = control(func(spec *fv1.CanaryConfigSpec) { spec.Action = fv1.CanaryActionPromote })
+	require.True(t, done)
+	require.Equal(t, map[string]int{"new": 100, "old": 0}, weights())
+	require.Equal(t, fv1.CanaryConfigStatusSucceeded, cfg.Status.Status)
+	require.Equal(t, 100, cfg.Status.Weight)
+	require.Len(t, cfg.Status.Transitions, 3)
+	require.Equal(t, fv1.CanaryTransitionPromoted, cfg.Status.Transitions[2].Type)
+	require.NotNil(t, cfg.Status.LastTransitionTime)
+}
+
+func TestStepSchedule(t *testing.T) {
+	spec := &fv1.CanaryConfigSpec{
+		WeightIncrement:         20,
+		WeightIncrementDuration: "2m",
+		Steps: []fv1.CanaryWeightStep{
+			{Weight: 1, Duration: "1m"},
+			{Weight: 5, Duration: "5m"},
+			{Weight: 25},
+			{Weight: 100},
+		},
+	}
+	for _, test := range []struct {
+		weight   int
+		next     int
+		interval time.Duration
+	}{
+		{weight: 0, next: 1, interval: 2 * time.Minute},
+		{weight: 1, next: 5, interval: time.Minute},
+		{weight: 5, next: 25, interval: 5 * time.Minute},
+		{weight: 10, next: 25, interval: 2 * time.Minute},
+		{weight: 25, next: 100, interval: 2 * time.Minute},
+	} {
+		require.Equal(t, test.next, nextWeight(spec, test.weight), "weight %v", test.weight)
+		interval, err := stepInterval(spec, test.weight)
+		require.NoError(t, err)
+		require.Equal(t, test.interval, interval, "weight %v", test.weight)
+	}
+
+	spec.Steps = nil
+	require.Equal(t, 20, nextWeight(spec, 0))
+	require.Equal(t, 100, nextWeight(spec, 90))
+	spec.Strategy = fv1.CanaryStrategyBlueGreen
+	require.Equal(t, 100, nextWeight(spec, 0))
+}
+
+func TestBlueGreen(t *testing.T) {
+	smokeTestStatus := http.StatusOK
+	var smokeTests []SmokeTestRequest
+	router := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fission-function/smoke" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		req := SmokeTestRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		smokeTests = append(smokeTests, req)
+		w.WriteHeader(smokeTestStatus)
+	}))
+	defer router.Close()
+
+	// setup returns a canary config manager with a bluegreen canary config, and the weights of its http trigger
+	setup := func() (*canaryConfigMgr, *fv1.CanaryConfig, *InMemoryMetricsProvider, func() map[string]int) {
+		trigger := &fv1.HTTPTrigger{
+			ObjectMeta: metav1.ObjectMeta{Name: "trigger", Namespace: "default"},
+			Spec: fv1.HTTPTriggerSpec{
+				RelativeURL: "/fn",
+				FunctionReference: fv1.FunctionReference{
+					Type:            fv1.FunctionReferenceTypeFunctionWeights,
+					FunctionWeights: map[string]int{"new": 0, "old": 100},
+				},
+			},
+		}
+		canaryConfig := &fv1.CanaryConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "canary", Namespace: "default"},
+			Spec: fv1.CanaryConfigSpec{
+				Trigger:                 "trigger",
+				NewFunction:             "new",
+				OldFunction:             "old",
+				WeightIncrementDuration: "1m",
+				Strategy:                fv1.CanaryStrategyBlueGreen,
+				SmokeTestFunction:       "smoke",
+			},
+			Status: fv1.CanaryConfigStatus{Status: fv1.CanaryConfigStatusPending},
+		}
+		fissionClient := &crd.FissionClient{Interface: fake.NewSimpleClientset(trigger, canaryConfig)}
+		provider := MakeInMemoryMetricsProvider()
+		canaryCfgMgr := &canaryConfigMgr{
+			logger:                 zap.NewNop(),
+			fissionClient:          fissionClient,
+			metricsProvider:        provider,
+			routerURL:              router.URL,
+			canaryCfgCancelFuncMap: makecanaryConfigCancelFuncMap(),
+		}
+		require.NoError(t, canaryCfgMgr.canaryCfgCancelFuncMap.assign(&canaryConfig.ObjectMeta, &CanaryProcessingInfo{}))
+		weights := func() map[string]int {
+			trigger, err := fissionClient.CoreV1().HTTPTriggers("default").Get(context.Background(), "trigger", metav1.GetOptions{})
+			require.NoError(t, err)
+			return trigger.Spec.FunctionReference.FunctionWeights
+		}
+		return canaryCfgMgr, canaryConfig, provider, weights
+	}
+	status := func(canaryCfgMgr *canaryConfigMgr) fv1.CanaryConfigStatus {
+		cfg, err := canaryCfgMgr.fissionClient.CoreV1().CanaryConfigs("default").Get(context.Background(), "canary", metav1.GetOptions{})
+		require.NoError(t, err)
+		return cfg.Status
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	t.Run("switch and rollback", func(t *testing.T) {
+		canaryCfgMgr, canaryConfig, provider, weights := setup()
+
+		// the smoke test passes, and the traffic is switched at once
+		quit := make(chan struct{})
+		canaryCfgMgr.RollForwardOrBack(canaryConfig, quit, ticker)
+		require.Equal(t, map[string]int{"new": 100, "old": 0}, weights())
+		require.Len(t, smokeTests, 1)
+		require.Equal(t, router.URL+"/fission-function/new", smokeTests[0].FunctionURL)
+		require.Empty(t, provider.Requests())
+		s := status(canaryCfgMgr)
+		require.Equal(t, fv1.CanaryConfigStatusPending, s.Status)
+		require.Equal(t, fv1.CanaryTransitionSwitched, s.Transitions[0].Type)
+
+		// the analysis after the switch fails, and the traffic is rolled back
+		provider.SetResult("default", "canary", AnalysisResult{Verdict: VerdictFail, Reason: "failed"})
+		canaryCfgMgr.RollForwardOrBack(canaryConfig, quit, ticker)
+		require.Equal(t, map[string]int{"new": 0, "old": 100}, weights())
+		_, open := <-quit
+		require.False(t, open)
+		s = status(canaryCfgMgr)
+		require.Equal(t, fv1.CanaryConfigStatusFailed, s.Status)
+		require.Equal(t, fv1.CanaryTransitionRolledBack, s.Transitions[1].Type)
+	})
+
+	t.Run("switch and succeed", func(t *testing.T) {
+		canaryCfgMgr, canaryConfig, _, weights := setup()
+		quit := make(chan struct{})
+		canaryCfgMgr.RollForwardOrBack(canaryConfig, quit, ticker)
+		canaryCfgMgr.RollForwardOrBack(canaryConfig, quit, ticker)
+		require.Equal(t, map[string]int{"new": 100, "old": 0}, weights())
+		_, open := <-quit
+		require.False(t, open)
+		s := status(canaryCfgMgr)
+		require.Equal(t, fv1.CanaryConfigStatusSucceeded, s.Status)
+		require.Equal(t, 1, s.Step)
+	})
+
+	t.Run("smoke test failure", func(t *testing.T) {
+		smokeTestStatus = http.StatusInternalServerError
+		canaryCfgMgr, canaryConfig, _, weights := setup()
+		quit := make(chan struct{})
+		canaryCfgMgr.RollForwardOrBack(canaryConfig, quit, ticker)
+		require.Equal(t, map[string]int{"new": 0, "old": 100}, weights())
+		_, open := <-quit
+		require.False(t, open)
+		s := status(canaryCfgMgr)
+		require.Equal(t, fv1.CanaryConfigStatusFailed, s.Status)
+		require.Equal(t, fv1.CanaryTransitionSmokeTestFailed, s.Transitions[0].Type)
+	})
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package canaryconfigmgr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/net/context/ctxhttp"
+	"k8s.io/apimachinery/pkg/types"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+const (
+	// verdicts of the analysis of the new version of a function
+	VerdictPass         Verdict = "pass"
+	VerdictFail         Verdict = "fail"
+	VerdictInconclusive Verdict = "inconclusive"
+
+	webhookTimeout = 30 * time.Second
+)
+
+type (
+	// Verdict is the result of the analysis of the new version of a function
+	Verdict string
+
+	// MetricsProvider analyses the new version of the function of a canary config
+	// over the last weight increment interval.
+	MetricsProvider interface {
+		Analyze(ctx context.Context, req *AnalysisRequest) (*AnalysisResult, error)
+	}
+
+	// AnalysisRequest is the canary config analysed, with the url and methods
+	// of its http trigger and the current weights of the functions.
+	AnalysisRequest struct {
+		CanaryConfig    *fv1.CanaryConfig `json:"canaryConfig"`
+		Path            string            `json:"path"`
+		Methods         []string          `json:"methods"`
+		FunctionWeights map[string]int    `json:"functionWeights"`
+	}
+
+	// AnalysisResult is the verdict of an analysis. The new version of the
+	// function is rolled back if it fails, and analysed again in the next
+	// interval if the analysis is inconclusive.
+	AnalysisResult struct {
+		Verdict Verdict `json:"verdict"`
+		Reason  string  `json:"reason,omitempty"`
+
+		// Measurements of the analysis, recorded in the canary config status
+		Measurements *Measurements `json:"measurements,omitempty"`
+	}
+
+	// Measurements are the metrics of the new version of a function measured
+	// by an analysis, omitted when not measured. Latencies are in seconds.
+	Measurements struct {
+		Requests          *float64           `json:"requests,omitempty"`
+		FailurePercentage *float64           `json:"failurePercentage,omitempty"`
+		Latency           *float64           `json:"latency,omitempty"`
+		OldLatency        *float64           `json:"oldLatency,omitempty"`
+		Queries           map[string]float64 `json:"queries,omitempty"`
+	}
+
+	// webhookMetricsProvider posts analysis requests to a webhook, returning
+	// the analysis result, such as to gate rollouts on business metrics.
+	webhookMetricsProvider struct {
+		logger     *zap.Logger
+		url        string
+		httpClient *http.Client
+	}
+
+	// InMemoryMetricsProvider returns the analysis results set for canary
+	// configs, to test canary deployments.
+	InMemoryMetricsProvider struct {
+		lock     sync.Mutex
+		results  map[types.NamespacedName]AnalysisResult
+		requests []AnalysisRequest
+	}
+)
+
+func makeWebhookMetricsProvider(logger *zap.Logger, url string) *webhookMetricsProvider {
+	return &webhookMetricsProvider{
+		logger:     logger.Named("webhook_metrics_provider"),
+		url:        url,
+		httpClient: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (provider *webhookMetricsProvider) Analyze(ctx context.Context, req *AnalysisRequest) (*AnalysisResult, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling analysis request")
+	}
+
+	resp, err := ctxhttp.Post(ctx, provider.httpClient, provider.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error calling metrics provider webhook %v", provider.url)
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading metrics provider webhook %v response", provider.url)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, errors.Errorf("metrics provider webhook %v returned status %v: %s", provider.url, resp.StatusCode, data)
+	}
+
+	result := &AnalysisResult{}
+	err = json.Unmarshal(data, result)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error unmarshaling metrics provider webhook %v response", provider.url)
+	}
+	switch result.Verdict {
+	case VerdictPass, VerdictFail, VerdictInconclusive:
+	default:
+		return nil, errors.Errorf("metrics provider webhook %v returned invalid verdict %q", provider.url, result.Verdict)
+	}
+
+	provider.logger.Info("metrics provider webhook analysis",
+		zap.String("verdict", string(result.Verdict)),
+		zap.String("reason", result.Reason),
+		zap.String("name", req.CanaryConfig.ObjectMeta.Name),
+		zap.String("namespace", req.CanaryConfig.ObjectMeta.Namespace))
+	return result, nil
+}
+
+// canaryAnalysis returns the analysis recorded in the canary config status for an analysis result
+func canaryAnalysis(result *AnalysisResult) *fv1.CanaryAnalysis {
+	analysis := &fv1.CanaryAnalysis{
+		Verdict: string(result.Verdict),
+		Reason:  result.Reason,
+	}
+	m := result.Measurements
+	if m == nil {
+		return analysis
+	}
+
+	latency := func(seconds float64) string {
+		return time.Duration(seconds * float64(time.Second)).Round(time.Microsecond).String()
+	}
+	if m.Requests != nil {
+		analysis.Requests = int(math.Round(*m.Requests))
+	}
+	if m.FailurePercentage != nil {
+		analysis.FailurePercentage = strconv.FormatFloat(*m.FailurePercentage, 'f', 2, 64)
+	}
+	if m.Latency != nil {
+		analysis.Latency = latency(*m.Latency)
+	}
+	if m.OldLatency != nil {
+		analysis.OldLatency = latency(*m.OldLatency)
+	}
+	if len(m.Queries) > 0 {
+		analysis.Queries = make(map[string]string, len(m.Queries))
+		for name, value := range m.Queries {
+			analysis.Queries[name] = strconv.FormatFloat(value, 'g', 6, 64)
+		}
+	}
+	return analysis
+}
+
+// MakeInMemoryMetricsProvider returns a metrics provider passing canary
+// configs until another result is set.
+func MakeInMemoryMetricsProvider() *InMemoryMetricsProvider {
+	return &InMemoryMetricsProvider{
+		results: make(map[types.NamespacedName]AnalysisResult),
+	}
+}
+
+// SetResult sets the analysis result of a canary config.
+func (provider *InMemoryMetricsProvider) SetResult(namespace, name string, result AnalysisResult) {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+	provider.results[types.NamespacedName{Namespace: namespace, Name: name}] = result
+}
+
+// Requests returns the analysis requests received.
+func (provider *InMemoryMetricsProvider) Requests() []AnalysisRequest {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+	return append([]AnalysisRequest(nil), provider.requests...)
+}
+
+func (provider *InMemoryMetricsProvider) Analyze(ctx context.Context, req *AnalysisRequest) (*AnalysisResult, error) {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+	provider.requests = append(provider.requests, *req)
+	result, ok := provider.results[types.NamespacedName{
+		Namespace: req.CanaryConfig.ObjectMeta.Namespace,
+		Name:      req.CanaryConfig.ObjectMeta.Name,
+	}]
+	if !ok {
+		return &AnalysisResult{Verdict: VerdictPass}, nil
+	}
+	return &result, nil
+}
+
+// getMetricsProvider returns the metrics provider of a canary config, the
+// prometheus server of the canary deployment feature by default.
+func (canaryCfgMgr *canaryConfigMgr) getMetricsProvider(canaryConfig *fv1.CanaryConfig) (MetricsProvider, error) {
+	provider := canaryConfig.Spec.MetricsProvider
+	switch {
+	case provider != nil && provider.Type == fv1.CanaryMetricsProviderWebhook:
+		return makeWebhookMetricsProvider(canaryCfgMgr.logger, provider.URL), nil
+	case provider != nil && provider.Type == fv1.CanaryMetricsProviderPrometheus && len(provider.URL) > 0:
+		return MakePrometheusClient(canaryCfgMgr.logger, provider.URL)
+	case provider != nil && provider.Type != fv1.CanaryMetricsProviderPrometheus:
+		return nil, fmt.Errorf("unknown metrics provider %q", provider.Type)
+	}
+
+	if canaryCfgMgr.metricsProvider == nil {
+		return nil, errors.New("no prometheus server is configured for the canary deployment feature")
+	}
+	return canaryCfgMgr.metricsProvider, nil
+}
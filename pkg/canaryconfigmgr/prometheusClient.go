@@ -17,7 +17,12 @@ limitations under the License.
 package canaryconfigmgr
 
 import (
+	"bytes"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/pkg/errors"
@@ -26,6 +31,8 @@ import (
 	"github.com/prometheus/common/model"
 	"go.uber.org/zap"
 	"golang.org/x/net/context"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 )
 
 type PrometheusApiClient struct {
@@ -52,14 +59,11 @@ func MakePrometheusClient(logger *zap.Logger, prometheusSvc string) (*Prometheus
 }
 
 func (promApiClient *PrometheusApiClient) GetFunctionFailurePercentage(path string, methods []string, funcName, funcNs string, window string) (float64, error) {
-	var reqs, failedReqs float64
+	var failedReqs float64
 	// first get a total count of requests to this url in a time window
-	for _, method := range methods {
-		mreqs, err := promApiClient.GetRequestsToFuncInWindow(path, method, funcName, funcNs, window)
-		if err != nil {
-			return 0, err
-		}
-		reqs += mreqs
+	reqs, err := promApiClient.GetFunctionRequestsInWindow(path, methods, funcName, funcNs, window)
+	if err != nil {
+		return 0, err
 	}
 
 	if reqs <= 0 {
@@ -81,6 +85,41 @@ func (promApiClient *PrometheusApiClient) GetFunctionFailurePercentage(path stri
 	return failurePercentForFunc, nil
 }
 
+// GetFunctionRequestsInWindow returns the number of requests to the function with any of the methods in a time window
+func (promApiClient *PrometheusApiClient) GetFunctionRequestsInWindow(path string, methods []string, funcName, funcNs string, window string) (float64, error) {
+	var reqs float64
+	for _, method := range methods {
+		mreqs, err := promApiClient.GetRequestsToFuncInWindow(path, method, funcName, funcNs, window)
+		if err != nil {
+			return 0, err
+		}
+		reqs += mreqs
+	}
+	return reqs, nil
+}
+
+// GetFunctionLatencyPercentile returns the latency percentile, in seconds, of the requests to the function with any
+// of the methods in a time window, computed from the latency histogram of the router
+func (promApiClient *PrometheusApiClient) GetFunctionLatencyPercentile(path string, methods []string, funcName, funcNs string, window string, percentile int) (float64, error) {
+	queryString := fmt.Sprintf("histogram_quantile(%v, sum by (le) (rate(fission_function_latency_seconds_bucket{path=\"%s\",method=~\"%s\",name=\"%s\",namespace=\"%s\"}[%v])))",
+		float64(percentile)/100, path, strings.Join(methods, "|"), funcName, funcNs, window)
+
+	latency, err := promApiClient.executeQuery(queryString)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error executing query: %s", queryString)
+	}
+	if math.IsNaN(latency) {
+		return 0, fmt.Errorf("no latency data for url %v and method %v in the window: %v", path, methods, window)
+	}
+
+	promApiClient.logger.Info("function latency",
+		zap.Int("percentile", percentile),
+		zap.Float64("latency_seconds", latency),
+		zap.String("function", funcName))
+
+	return latency, nil
+}
+
 func (promApiClient *PrometheusApiClient) GetRequestsToFuncInWindow(path string, method string, funcName string, funcNs string, window string) (float64, error) {
 	queryString := fmt.Sprintf("fission_function_calls_total{path=\"%s\",method=\"%s\",name=\"%s\",namespace=\"%s\"}[%v]", path, method, funcName, funcNs, window)
 
@@ -168,3 +207,162 @@ func (promApiClient *PrometheusApiClient) executeQuery(queryString string) (floa
 		return 0, nil
 	}
 }
+
+type queryTemplateData struct {
+	Function    string
+	OldFunction string
+	Namespace   string
+	Path        string
+	Methods     string
+	Window      string
+}
+
+// Analyze analyses the new version of the function with the failure type and custom queries of the canary config.
+// The analysis is inconclusive until the functions receive the minimum number of requests.
+func (promApiClient *PrometheusApiClient) Analyze(ctx context.Context, req *AnalysisRequest) (*AnalysisResult, error) {
+	spec := req.CanaryConfig.Spec
+	namespace := req.CanaryConfig.ObjectMeta.Namespace
+	window := spec.WeightIncrementDuration
+
+	minRequests := spec.MinRequests
+	if minRequests < 1 {
+		minRequests = 1
+	}
+	percentile := spec.LatencyPercentile
+	if percentile == 0 {
+		percentile = fv1.DefaultCanaryLatencyPercentile
+	}
+
+	measurements := &Measurements{}
+	enoughRequests := func(fn string) (bool, error) {
+		reqs, err := promApiClient.GetFunctionRequestsInWindow(req.Path, req.Methods, fn, namespace, window)
+		if err != nil {
+			return false, err
+		}
+		if fn == spec.NewFunction {
+			measurements.Requests = &reqs
+		}
+		return reqs >= float64(minRequests), nil
+	}
+	latency := func(fn string) (float64, error) {
+		return promApiClient.GetFunctionLatencyPercentile(req.Path, req.Methods, fn, namespace, window, percentile)
+	}
+	inconclusive := func(reason string, args ...interface{}) (*AnalysisResult, error) {
+		return &AnalysisResult{Verdict: VerdictInconclusive, Reason: fmt.Sprintf(reason, args...), Measurements: measurements}, nil
+	}
+	fail := func(reason string, args ...interface{}) (*AnalysisResult, error) {
+		return &AnalysisResult{Verdict: VerdictFail, Reason: fmt.Sprintf(reason, args...), Measurements: measurements}, nil
+	}
+
+	ok, err := enoughRequests(spec.NewFunction)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return inconclusive("function %v received fewer than %v requests", spec.NewFunction, minRequests)
+	}
+
+	switch spec.FailureType {
+	case fv1.FailureTypeLatency:
+		threshold, _ := time.ParseDuration(spec.LatencyThreshold)
+		newLatency, err := latency(spec.NewFunction)
+		if err != nil {
+			return nil, err
+		}
+		measurements.Latency = &newLatency
+		if newLatency > threshold.Seconds() {
+			return fail("p%v latency %vs crossed the threshold %v", percentile, newLatency, threshold)
+		}
+
+	case fv1.FailureTypeRelativeLatency:
+		ok, err = enoughRequests(spec.OldFunction)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return inconclusive("function %v received fewer than %v requests", spec.OldFunction, minRequests)
+		}
+		newLatency, err := latency(spec.NewFunction)
+		if err != nil {
+			return nil, err
+		}
+		measurements.Latency = &newLatency
+		oldLatency, err := latency(spec.OldFunction)
+		if err != nil {
+			return nil, err
+		}
+		measurements.OldLatency = &oldLatency
+		if newLatency > oldLatency*(1+float64(spec.FailureThreshold)/100) {
+			return fail("p%v latency %vs is more than %v%% over the old function latency %vs",
+				percentile, newLatency, spec.FailureThreshold, oldLatency)
+		}
+
+	default:
+		failurePercent, err := promApiClient.GetFunctionFailurePercentage(req.Path, req.Methods, spec.NewFunction, namespace, window)
+		if err != nil {
+			return nil, err
+		}
+		measurements.FailurePercentage = &failurePercent
+		if int(failurePercent) > spec.FailureThreshold {
+			return fail("failure percent %v crossed the threshold %v", failurePercent, spec.FailureThreshold)
+		}
+	}
+
+	data := queryTemplateData{
+		Function:    spec.NewFunction,
+		OldFunction: spec.OldFunction,
+		Namespace:   namespace,
+		Path:        req.Path,
+		Methods:     strings.Join(req.Methods, "|"),
+		Window:      window,
+	}
+	for _, query := range spec.Queries {
+		value, err := promApiClient.executeQueryTemplate(query.Query, data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error executing query %v", query.Name)
+		}
+		promApiClient.logger.Info("custom query executed",
+			zap.String("query", query.Name),
+			zap.Float64("value", value),
+			zap.String("function", spec.NewFunction))
+		if math.IsNaN(value) {
+			return inconclusive("query %v has no value", query.Name)
+		}
+		if measurements.Queries == nil {
+			measurements.Queries = make(map[string]float64)
+		}
+		measurements.Queries[query.Name] = value
+		if len(query.Min) > 0 {
+			min, _ := strconv.ParseFloat(query.Min, 64)
+			if value < min {
+				return fail("query %v value %v is below the minimum %v", query.Name, value, min)
+			}
+		}
+		if len(query.Max) > 0 {
+			max, _ := strconv.ParseFloat(query.Max, 64)
+			if value > max {
+				return fail("query %v value %v crossed the maximum %v", query.Name, value, max)
+			}
+		}
+	}
+
+	return &AnalysisResult{Verdict: VerdictPass, Measurements: measurements}, nil
+}
+
+func (promApiClient *PrometheusApiClient) executeQueryTemplate(query string, data queryTemplateData) (float64, error) {
+	tmpl, err := template.New("query").Parse(query)
+	if err != nil {
+		return 0, errors.Wrap(err, "error parsing query template")
+	}
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return 0, errors.Wrap(err, "error executing query template")
+	}
+
+	value, err := promApiClient.executeQuery(buf.String())
+	if err != nil {
+		return 0, errors.Wrapf(err, "error executing query: %s", buf.String())
+	}
+	return value, nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package canaryconfigmgr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"golang.org/x/net/context/ctxhttp"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/utils"
+)
+
+const smokeTestTimeout = 60 * time.Second
+
+// SmokeTestRequest is posted to the smoke test function of a canary config
+// before the traffic is switched to the new version of the function.
+type SmokeTestRequest struct {
+	CanaryConfig *fv1.CanaryConfig `json:"canaryConfig"`
+
+	// URL of the new version of the function in the router
+	FunctionURL string `json:"functionUrl"`
+}
+
+// smokeTest calls the smoke test function of a canary config through the
+// router, returning an error unless it responds with a 2xx status code.
+func (canaryCfgMgr *canaryConfigMgr) smokeTest(ctx context.Context, canaryConfig *fv1.CanaryConfig) error {
+	fn := canaryConfig.Spec.SmokeTestFunction
+	if len(fn) == 0 {
+		return nil
+	}
+	if len(canaryCfgMgr.routerURL) == 0 {
+		return errors.New("no router url is configured for the canary deployment feature")
+	}
+
+	ns := canaryConfig.ObjectMeta.Namespace
+	body, err := json.Marshal(&SmokeTestRequest{
+		CanaryConfig: canaryConfig,
+		FunctionURL:  canaryCfgMgr.routerURL + utils.UrlForFunction(canaryConfig.Spec.NewFunction, ns),
+	})
+	if err != nil {
+		return errors.Wrap(err, "error marshaling smoke test request")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, smokeTestTimeout)
+	defer cancel()
+	resp, err := ctxhttp.Post(ctx, nil, canaryCfgMgr.routerURL+utils.UrlForFunction(fn, ns), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "error calling smoke test function %v", fn)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("smoke test function %v returned status %v: %s", fn, resp.StatusCode, data)
+	}
+
+	canaryCfgMgr.logger.Info("smoke test passed",
+		zap.String("function", fn),
+		zap.String("name", canaryConfig.ObjectMeta.Name),
+		zap.String("namespace", ns))
+	return nil
+}
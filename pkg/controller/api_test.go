@@ -374,7 +374,7 @@ func TestMain(m *testing.M) {
 
 	panicIf(err)
 
-	go Start(logger, 8888, true)
+	go Start(logger, 8888, "", true)
 
 	time.Sleep(5 * time.Second)
 
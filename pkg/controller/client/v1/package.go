@@ -19,6 +19,7 @@ package v1
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/fission/fission/pkg/controller/client/rest"
 
@@ -38,6 +39,8 @@ type (
 		Update(f *fv1.Package) (*metav1.ObjectMeta, error)
 		Delete(m *metav1.ObjectMeta) error
 		List(pkgNamespace string) ([]fv1.Package, error)
+		BuildLogs(m *metav1.ObjectMeta, follow bool) (io.ReadCloser, error)
+		CancelBuild(m *metav1.ObjectMeta) error
 	}
 
 	Package struct {
@@ -162,3 +165,37 @@ func (c *Package) List(pkgNamespace string) ([]fv1.Package, error) {
 
 	return funcs, nil
 }
+
+// BuildLogs returns the build logs of a package. With follow set, the logs
+// of a pending or running build are streamed until the build completes.
+func (c *Package) BuildLogs(m *metav1.ObjectMeta, follow bool) (io.ReadCloser, error) {
+	relativeUrl := fmt.Sprintf("packages/%v/buildlogs", m.Name)
+	relativeUrl += fmt.Sprintf("?namespace=%v&follow=%v", m.Namespace, follow)
+
+	resp, err := c.client.Get(relativeUrl)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		defer resp.Body.Close()
+		_, err = handleResponse(resp)
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// CancelBuild cancels the running build of a package, which is then marked
+// failed.
+func (c *Package) CancelBuild(m *metav1.ObjectMeta) error {
+	relativeUrl := fmt.Sprintf("packages/%v/cancel", m.Name)
+	relativeUrl += fmt.Sprintf("?namespace=%v", m.Namespace)
+
+	resp, err := c.client.Create(relativeUrl, "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = handleResponse(resp)
+	return err
+}
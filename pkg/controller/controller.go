@@ -24,7 +24,7 @@ import (
 	"github.com/fission/fission/pkg/crd"
 )
 
-func Start(logger *zap.Logger, port int, unitTestFlag bool) {
+func Start(logger *zap.Logger, port int, routerURL string, unitTestFlag bool) {
 	cLogger := logger.Named("controller")
 
 	fc, kc, apiExtClient, _, err := crd.MakeFissionClient()
@@ -43,7 +43,7 @@ func Start(logger *zap.Logger, port int, unitTestFlag bool) {
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
-	featureStatus, err := ConfigureFeatures(ctx, cLogger, unitTestFlag, fc, kc)
+	featureStatus, err := ConfigureFeatures(ctx, cLogger, unitTestFlag, fc, kc, routerURL)
 	if err != nil {
 		cLogger.Error("error configuring features - proceeding without optional features", zap.Error(err))
 	}
@@ -22,12 +22,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
 
 	"github.com/dustin/go-humanize"
 	"github.com/emicklei/go-restful"
 	restfulspec "github.com/emicklei/go-restful-openapi"
 	"github.com/go-openapi/spec"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
@@ -99,6 +102,30 @@ func RegisterPackageRoute(ws *restful.WebService) {
 			Param(ws.QueryParameter("namespace", "Namespace of package").DataType("string").DefaultValue(metav1.NamespaceAll).Required(false)).
 			Produces(restful.MIME_JSON).
 			Returns(http.StatusOK, "Only HTTP status returned", nil))
+
+	ws.Route(
+		ws.GET("/v2/packages/{package}/buildlogs").
+			Doc("Get build logs of package").
+			Metadata(restfulspec.KeyOpenAPITags, tags).
+			To(func(req *restful.Request, resp *restful.Response) {
+				resp.ResponseWriter.WriteHeader(http.StatusOK)
+			}).
+			Param(ws.PathParameter("package", "Package name").DataType("string").DefaultValue("").Required(true)).
+			Param(ws.QueryParameter("namespace", "Namespace of package").DataType("string").DefaultValue(metav1.NamespaceAll).Required(false)).
+			Param(ws.QueryParameter("follow", "Stream the logs until the build completes").DataType("boolean").DefaultValue("false").Required(false)).
+			Produces(restful.MIME_OCTET).
+			Returns(http.StatusOK, "Build logs", nil))
+
+	ws.Route(
+		ws.POST("/v2/packages/{package}/cancel").
+			Doc("Cancel build of package").
+			Metadata(restfulspec.KeyOpenAPITags, tags).
+			To(func(req *restful.Request, resp *restful.Response) {
+				resp.ResponseWriter.WriteHeader(http.StatusOK)
+			}).
+			Param(ws.PathParameter("package", "Package name").DataType("string").DefaultValue("").Required(true)).
+			Param(ws.QueryParameter("namespace", "Namespace of package").DataType("string").DefaultValue(metav1.NamespaceAll).Required(false)).
+			Returns(http.StatusOK, "Only HTTP status returned", nil))
 }
 
 func (a *API) PackageApiList(w http.ResponseWriter, r *http.Request) {
@@ -253,3 +280,42 @@ func (a *API) PackageApiDelete(w http.ResponseWriter, r *http.Request) {
 
 	a.respondWithSuccess(w, []byte(""))
 }
+
+// PackageApiBuildLogs proxies the build logs of a package from the builder
+// manager, streaming them as the build runs if follow is set.
+func (a *API) PackageApiBuildLogs(w http.ResponseWriter, r *http.Request) {
+	a.proxyToBuilderManager(w, r, "logs")
+}
+
+// PackageApiCancelBuild asks the builder manager to cancel the running
+// build of a package.
+func (a *API) PackageApiCancelBuild(w http.ResponseWriter, r *http.Request) {
+	a.proxyToBuilderManager(w, r, "cancel")
+}
+
+func (a *API) proxyToBuilderManager(w http.ResponseWriter, r *http.Request, action string) {
+	vars := mux.Vars(r)
+	name := vars["package"]
+	ns := a.extractQueryParamFromRequest(r, "namespace")
+	if len(ns) == 0 {
+		ns = metav1.NamespaceDefault
+	}
+
+	u, err := url.Parse(a.builderManagerUrl)
+	if err != nil {
+		a.respondWithError(w, errors.Wrapf(err, "error parsing builder manager url %q", a.builderManagerUrl))
+		return
+	}
+	director := func(req *http.Request) {
+		req.URL.Scheme = u.Scheme
+		req.URL.Host = u.Host
+		req.URL.Path = fmt.Sprintf("/v1/builds/%v/%v/%v", url.PathEscape(ns), url.PathEscape(name), action)
+		req.Host = u.Host
+	}
+	proxy := &httputil.ReverseProxy{
+		Director: director,
+		// flush streamed logs right away
+		FlushInterval: -1,
+	}
+	proxy.ServeHTTP(w, r)
+}
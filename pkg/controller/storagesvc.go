@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strings"
 
 	"github.com/emicklei/go-restful"
 	restfulspec "github.com/emicklei/go-restful-openapi"
@@ -54,6 +55,68 @@ func RegisterStorageServiceProxyRoute(ws *restful.WebService) {
 			To(func(req *restful.Request, resp *restful.Response) {
 				resp.ResponseWriter.WriteHeader(http.StatusOK)
 			}))
+	ws.Route(
+		ws.GET("/proxy/storage/v1/archive/checksums/{checksum}").
+			Doc("Get archive by checksum").
+			Metadata(restfulspec.KeyOpenAPITags, tags).
+			Param(ws.PathParameter("checksum", "Archive sha256 checksum").DataType("string").DefaultValue("").Required(true)).
+			To(func(req *restful.Request, resp *restful.Response) {
+				resp.ResponseWriter.WriteHeader(http.StatusOK)
+			}))
+	ws.Route(
+		ws.GET("/proxy/storage/v1/archive/orphans").
+			Doc("List orphan archives and what the archive pruner does with them").
+			Metadata(restfulspec.KeyOpenAPITags, tags).
+			To(func(req *restful.Request, resp *restful.Response) {
+				resp.ResponseWriter.WriteHeader(http.StatusOK)
+			}))
+	ws.Route(
+		ws.POST("/proxy/storage/v1/archive/rewrap").
+			Doc("Rewrap archive data keys with the primary encryption key").
+			Metadata(restfulspec.KeyOpenAPITags, tags).
+			To(func(req *restful.Request, resp *restful.Response) {
+				resp.ResponseWriter.WriteHeader(http.StatusOK)
+			}))
+	ws.Route(
+		ws.POST("/proxy/storage/v1/archive/uploads").
+			Doc("Initiate chunked archive upload").
+			Metadata(restfulspec.KeyOpenAPITags, tags).
+			To(func(req *restful.Request, resp *restful.Response) {
+				resp.ResponseWriter.WriteHeader(http.StatusOK)
+			}))
+	ws.Route(
+		ws.GET("/proxy/storage/v1/archive/uploads/{upload}").
+			Doc("Get chunked archive upload").
+			Metadata(restfulspec.KeyOpenAPITags, tags).
+			Param(ws.PathParameter("upload", "Upload ID").DataType("string").DefaultValue("").Required(true)).
+			To(func(req *restful.Request, resp *restful.Response) {
+				resp.ResponseWriter.WriteHeader(http.StatusOK)
+			}))
+	ws.Route(
+		ws.PUT("/proxy/storage/v1/archive/uploads/{upload}/parts/{part}").
+			Doc("Upload part of chunked archive upload").
+			Metadata(restfulspec.KeyOpenAPITags, tags).
+			Param(ws.PathParameter("upload", "Upload ID").DataType("string").DefaultValue("").Required(true)).
+			Param(ws.PathParameter("part", "Part number").DataType("integer").DefaultValue("0").Required(true)).
+			To(func(req *restful.Request, resp *restful.Response) {
+				resp.ResponseWriter.WriteHeader(http.StatusOK)
+			}))
+	ws.Route(
+		ws.POST("/proxy/storage/v1/archive/uploads/{upload}/complete").
+			Doc("Complete chunked archive upload").
+			Metadata(restfulspec.KeyOpenAPITags, tags).
+			Param(ws.PathParameter("upload", "Upload ID").DataType("string").DefaultValue("").Required(true)).
+			To(func(req *restful.Request, resp *restful.Response) {
+				resp.ResponseWriter.WriteHeader(http.StatusOK)
+			}))
+	ws.Route(
+		ws.DELETE("/proxy/storage/v1/archive/uploads/{upload}").
+			Doc("Abort chunked archive upload").
+			Metadata(restfulspec.KeyOpenAPITags, tags).
+			Param(ws.PathParameter("upload", "Upload ID").DataType("string").DefaultValue("").Required(true)).
+			To(func(req *restful.Request, resp *restful.Response) {
+				resp.ResponseWriter.WriteHeader(http.StatusOK)
+			}))
 }
 
 func (api *API) StorageServiceProxy(w http.ResponseWriter, r *http.Request) {
@@ -68,7 +131,7 @@ func (api *API) StorageServiceProxy(w http.ResponseWriter, r *http.Request) {
 	director := func(req *http.Request) {
 		req.URL.Scheme = ssUrl.Scheme
 		req.URL.Host = ssUrl.Host
-		req.URL.Path = "/v1/archive"
+		req.URL.Path = strings.TrimPrefix(req.URL.Path, "/proxy/storage")
 		req.Host = ssUrl.Host
 	}
 	proxy := &httputil.ReverseProxy{
@@ -69,7 +69,7 @@ func refreshPods(logger *zap.Logger, funcs []fv1.Function, types map[fv1.Executo
 		}
 
 		if err != nil {
-			logger.Error("Failed to recycle pods for function after configmap/secret changed",
+			logger.Error("Failed to refresh pods for function after configmap/secret changed",
 				zap.Error(err),
 				zap.Any("function", f))
 		}
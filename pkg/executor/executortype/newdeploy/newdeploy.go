@@ -36,6 +36,7 @@ import (
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/executor/util"
+	"github.com/fission/fission/pkg/signing"
 	"github.com/fission/fission/pkg/utils"
 )
 
@@ -310,12 +311,18 @@ func (deploy *NewDeploy) getDeploymentSpec(fn *fv1.Function, env *fv1.Environmen
 		},
 	}
 
+	policy, err := signing.ResolvePolicy(context.TODO(), deploy.kubernetesClient, env, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "error resolving signature policy of environment")
+	}
+
 	// Order of merging is important here - first fetcher, then containers and lastly pod spec
 	err = deploy.fetcherConfig.AddSpecializingFetcherToPodSpec(
 		&deployment.Spec.Template.Spec,
 		env.ObjectMeta.Name,
 		fn,
 		env,
+		policy,
 	)
 	if err != nil {
 		return nil, err
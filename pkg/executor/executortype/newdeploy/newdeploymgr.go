@@ -43,6 +43,7 @@ import (
 	"github.com/fission/fission/pkg/executor/executortype"
 	"github.com/fission/fission/pkg/executor/fscache"
 	"github.com/fission/fission/pkg/executor/reaper"
+	"github.com/fission/fission/pkg/executor/util"
 	fetcherConfig "github.com/fission/fission/pkg/fetcher/config"
 	"github.com/fission/fission/pkg/throttler"
 	"github.com/fission/fission/pkg/utils"
@@ -253,7 +254,8 @@ func (deploy *NewDeploy) IsValid(fsvc *fscache.FuncSvc) bool {
 	return true
 }
 
-// RefreshFuncPods deletes pods related to the function so that new pods are replenished
+// RefreshFuncPods refreshes the secrets and configmaps of the pods related to the
+// function in place, or rolls the pods out so that new pods are replenished
 func (deploy *NewDeploy) RefreshFuncPods(logger *zap.Logger, f fv1.Function) error {
 
 	env, err := deploy.fissionClient.CoreV1().Environments(f.Spec.Environment.Namespace).Get(context.TODO(), f.Spec.Environment.Name, metav1.GetOptions{})
@@ -277,6 +279,17 @@ func (deploy *NewDeploy) RefreshFuncPods(logger *zap.Logger, f fv1.Function) err
 
 	// Ideally there should be only one deployment but for now we rely on label/selector to ensure that condition
 	for _, deployment := range dep.Items {
+		podList, err := deploy.kubernetesClient.CoreV1().Pods(deployment.ObjectMeta.Namespace).List(context.TODO(), metav1.ListOptions{
+			LabelSelector: labels.Set(deployment.Spec.Selector.MatchLabels).AsSelector().String(),
+		})
+		if err != nil {
+			return err
+		}
+		if failed := util.RefreshPodsInPlace(logger, env, &f, podList.Items); len(failed) == 0 {
+			logger.Info("refreshed secrets and configmaps of function pods in place", zap.String("function", f.ObjectMeta.Name))
+			continue
+		}
+
 		rvCount, err := referencedResourcesRVSum(deploy.kubernetesClient, deployment.Namespace, f.Spec.Secrets, f.Spec.ConfigMaps)
 		if err != nil {
 			return err
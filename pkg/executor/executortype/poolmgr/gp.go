@@ -49,6 +49,7 @@ import (
 	"github.com/fission/fission/pkg/executor/util"
 	fetcherClient "github.com/fission/fission/pkg/fetcher/client"
 	fetcherConfig "github.com/fission/fission/pkg/fetcher/config"
+	"github.com/fission/fission/pkg/signing"
 	"github.com/fission/fission/pkg/utils"
 	"github.com/fission/fission/pkg/utils/maps"
 )
@@ -388,13 +389,17 @@ func (gp *GenericPool) specializePod(ctx context.Context, pod *apiv1.Pod, fn *fv
 	fetcherURL := gp.getFetcherURL(podIP)
 	gp.logger.Info("calling fetcher to copy function", zap.String("function", fn.ObjectMeta.Name), zap.String("url", fetcherURL))
 
-	specializeReq := gp.fetcherConfig.NewSpecializeRequest(fn, gp.env)
+	policy, err := signing.ResolvePolicy(ctx, gp.kubernetesClient, gp.env, false)
+	if err != nil {
+		return errors.Wrap(err, "error resolving signature policy of environment")
+	}
+	specializeReq := gp.fetcherConfig.NewSpecializeRequest(fn, gp.env, policy)
 
 	gp.logger.Info("specializing pod", zap.String("function", fn.ObjectMeta.Name))
 
 	// Fetcher will download user function to share volume of pod, and
 	// invoke environment specialize api for pod specialization.
-	err := fetcherClient.MakeClient(gp.logger, fetcherURL).Specialize(ctx, &specializeReq)
+	err = fetcherClient.MakeClient(gp.logger, fetcherURL).Specialize(ctx, &specializeReq)
 	if err != nil {
 		return err
 	}
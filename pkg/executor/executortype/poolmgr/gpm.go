@@ -45,6 +45,7 @@ import (
 	"github.com/fission/fission/pkg/executor/executortype"
 	"github.com/fission/fission/pkg/executor/fscache"
 	"github.com/fission/fission/pkg/executor/reaper"
+	"github.com/fission/fission/pkg/executor/util"
 	fetcherConfig "github.com/fission/fission/pkg/fetcher/config"
 	"github.com/fission/fission/pkg/utils"
 )
@@ -263,8 +264,6 @@ func (gpm *GenericPoolManager) RefreshFuncPods(logger *zap.Logger, f fv1.Functio
 		return err
 	}
 
-	gp.fsCache.DeleteEntry(funcSvc)
-
 	funcLabels := gp.labelsForFunction(&f.ObjectMeta)
 
 	podList, err := gpm.kubernetesClient.CoreV1().Pods(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
@@ -275,10 +274,25 @@ func (gpm *GenericPoolManager) RefreshFuncPods(logger *zap.Logger, f fv1.Functio
 		return err
 	}
 
-	for _, po := range podList.Items {
+	// refresh the pods in place if possible, and recycle the pods that couldn't be
+	failed := util.RefreshPodsInPlace(logger, env, &f, podList.Items)
+	if len(failed) == 0 {
+		logger.Info("refreshed secrets and configmaps of function pods in place", zap.String("function", f.ObjectMeta.Name))
+		return nil
+	}
+
+	for _, po := range failed {
+		for _, obj := range funcSvc.KubernetesObjects {
+			if obj.Kind == "pod" && obj.Name == po.ObjectMeta.Name && obj.Namespace == po.ObjectMeta.Namespace {
+				gp.fsCache.DeleteEntry(funcSvc)
+			}
+		}
+	}
+
+	for _, po := range failed {
 		err := gpm.kubernetesClient.CoreV1().Pods(po.ObjectMeta.Namespace).Delete(context.TODO(), po.ObjectMeta.Name, metav1.DeleteOptions{})
 		if k8serrors.IsNotFound(err) {
-			return nil
+			continue
 		}
 		if err != nil {
 			return err
@@ -0,0 +1,86 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	apiv1 "k8s.io/api/core/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/fetcher"
+	fetcherClient "github.com/fission/fission/pkg/fetcher/client"
+	"github.com/fission/fission/pkg/utils"
+)
+
+// refreshTimeout bounds refreshing the secrets and configmaps of a pod.
+const refreshTimeout = 10 * time.Second
+
+// RefreshPodsInPlace asks the fetchers of the pods of a function to rewrite
+// the secrets and configmaps of the function and have the environment
+// reload them, which avoids recycling the pods. It returns the pods that
+// couldn't be refreshed, such as the pods not ready yet, or all the pods
+// if the environment doesn't opt in to reloading with Runtime.Reload.
+func RefreshPodsInPlace(logger *zap.Logger, env *fv1.Environment, fn *fv1.Function, pods []apiv1.Pod) []apiv1.Pod {
+	req := &fetcher.FunctionRefreshRequest{
+		Secrets:    fn.Spec.Secrets,
+		ConfigMaps: fn.Spec.ConfigMaps,
+	}
+
+	var lock sync.Mutex
+	var failed []apiv1.Pod
+	wg := &sync.WaitGroup{}
+	for i := range pods {
+		pod := pods[i]
+		if pod.ObjectMeta.DeletionTimestamp != nil {
+			continue
+		}
+		if !env.Spec.Runtime.Reload || !utils.IsReadyPod(&pod) {
+			lock.Lock()
+			failed = append(failed, pod)
+			lock.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+			defer cancel()
+
+			fetcherURL := fmt.Sprintf("http://%v/", net.JoinHostPort(pod.Status.PodIP, "8000"))
+			err := fetcherClient.MakeClient(logger, fetcherURL).Refresh(ctx, req)
+			if err != nil {
+				logger.Info("failed to refresh secrets and configmaps of pod in place",
+					zap.Error(err),
+					zap.String("function", fn.ObjectMeta.Name),
+					zap.String("pod", pod.ObjectMeta.Name),
+					zap.String("pod_namespace", pod.ObjectMeta.Namespace))
+				lock.Lock()
+				failed = append(failed, pod)
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return failed
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fetcher
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+// staleTempFileAge is the age after which temporary files of archives
+// being added to the cache are considered abandoned.
+const staleTempFileAge = time.Hour
+
+var (
+	archiveCacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "fission_fetcher_archive_cache_hits_total",
+			Help: "Number of archives fetched from the node archive cache.",
+		},
+	)
+	archiveCacheMisses = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "fission_fetcher_archive_cache_misses_total",
+			Help: "Number of archives downloaded because they were not in the node archive cache.",
+		},
+	)
+	archiveCacheEvictions = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "fission_fetcher_archive_cache_evictions_total",
+			Help: "Number of archives evicted from the node archive cache.",
+		},
+	)
+	archiveCacheSize = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "fission_fetcher_archive_cache_size_bytes",
+			Help: "Size of the archives in the node archive cache, as of the last eviction.",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(archiveCacheHits)
+	prometheus.MustRegister(archiveCacheMisses)
+	prometheus.MustRegister(archiveCacheEvictions)
+	prometheus.MustRegister(archiveCacheSize)
+}
+
+// archiveCache is a directory of archives keyed by their checksum, shared
+// by the fetchers of a node so that an archive is downloaded once per node.
+// Archives are evicted least recently used first once the cache grows over
+// its maximum size. The fetchers coordinate through the file system only:
+// archives are added by renaming complete files, and their modification
+// time records their last use.
+type archiveCache struct {
+	dir     string
+	maxSize int64
+
+	// lock serializes evictions within the fetcher
+	lock sync.Mutex
+}
+
+func makeArchiveCache(dir string, maxSize int64) (*archiveCache, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating archive cache directory %q", dir)
+	}
+	return &archiveCache{
+		dir:     dir,
+		maxSize: maxSize,
+	}, nil
+}
+
+// cacheable returns whether an archive can be cached, which requires a
+// checksum to key it.
+func cacheable(checksum fv1.Checksum) bool {
+	return checksum.Type == fv1.ChecksumTypeSHA256 && len(checksum.Sum) > 0 &&
+		!strings.ContainsAny(checksum.Sum, `/\.`)
+}
+
+func (c *archiveCache) path(checksum fv1.Checksum) string {
+	return filepath.Join(c.dir, string(checksum.Type)+"-"+checksum.Sum)
+}
+
+// get copies the cached archive with the checksum to dst. It returns false
+// if the archive isn't cached.
+func (c *archiveCache) get(checksum fv1.Checksum, dst string) (bool, error) {
+	path := c.path(checksum)
+	in, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			archiveCacheMisses.Inc()
+			return false, nil
+		}
+		return false, errors.Wrap(err, "error opening cached archive")
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return false, errors.Wrap(err, "error creating archive file")
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return false, errors.Wrap(err, "error copying cached archive")
+	}
+
+	now := time.Now()
+	os.Chtimes(path, now, now) //nolint: errCheck
+	archiveCacheHits.Inc()
+	return true, nil
+}
+
+// put adds the archive at src, whose checksum was verified, to the cache
+// and evicts the least recently used archives over the maximum size.
+func (c *archiveCache) put(checksum fv1.Checksum, src string) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return errors.Wrap(err, "error reading archive file")
+	}
+	if fi.Size() > c.maxSize {
+		return nil
+	}
+
+	path := c.path(checksum)
+	if _, err := os.Stat(path); err == nil {
+		// another fetcher of the node added it
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.Wrap(err, "error opening archive file")
+	}
+	defer in.Close()
+
+	tmp, err := ioutil.TempFile(c.dir, ".tmp-")
+	if err != nil {
+		return errors.Wrap(err, "error creating cached archive")
+	}
+	_, err = io.Copy(tmp, in)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "error writing cached archive")
+	}
+
+	return c.evict()
+}
+
+// evict removes the least recently used archives until the cache fits its
+// maximum size.
+func (c *archiveCache) evict() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	fis, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return errors.Wrap(err, "error reading archive cache directory")
+	}
+
+	var archives []os.FileInfo
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		if strings.HasPrefix(fi.Name(), ".") {
+			// leftover of a fetcher that died adding an archive
+			if time.Since(fi.ModTime()) > staleTempFileAge {
+				os.Remove(filepath.Join(c.dir, fi.Name()))
+			}
+			continue
+		}
+		archives = append(archives, fi)
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].ModTime().After(archives[j].ModTime())
+	})
+
+	var size int64
+	for _, fi := range archives {
+		if size+fi.Size() <= c.maxSize {
+			size += fi.Size()
+			continue
+		}
+		err = os.Remove(filepath.Join(c.dir, fi.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "error evicting cached archive")
+		}
+		archiveCacheEvictions.Inc()
+	}
+	archiveCacheSize.Set(float64(size))
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fetcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+func TestArchiveCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive-cache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cache, err := makeArchiveCache(filepath.Join(dir, "cache"), 10)
+	require.NoError(t, err)
+
+	checksum := func(sum string) fv1.Checksum {
+		return fv1.Checksum{Type: fv1.ChecksumTypeSHA256, Sum: sum}
+	}
+	put := func(sum string, content string) {
+		src := filepath.Join(dir, sum)
+		require.NoError(t, ioutil.WriteFile(src, []byte(content), 0600))
+		require.NoError(t, cache.put(checksum(sum), src))
+	}
+	get := func(sum string) (string, bool) {
+		dst := filepath.Join(dir, "dst")
+		defer os.Remove(dst)
+		found, err := cache.get(checksum(sum), dst)
+		require.NoError(t, err)
+		if !found {
+			return "", false
+		}
+		content, err := ioutil.ReadFile(dst)
+		require.NoError(t, err)
+		return string(content), true
+	}
+
+	_, found := get("aaaa")
+	require.False(t, found)
+
+	put("aaaa", "12345")
+	content, found := get("aaaa")
+	require.True(t, found)
+	require.Equal(t, "12345", content)
+
+	// archives larger than the cache aren't cached
+	put("bbbb", "12345678901")
+	_, found = get("bbbb")
+	require.False(t, found)
+
+	// the least recently used archive is evicted
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(cache.path(checksum("aaaa")), past, past))
+	put("cccc", "123")
+	put("dddd", "1234")
+	_, found = get("aaaa")
+	require.False(t, found)
+	_, found = get("cccc")
+	require.True(t, found)
+	_, found = get("dddd")
+	require.True(t, found)
+
+	require.False(t, cacheable(fv1.Checksum{Type: fv1.ChecksumTypeSHA256, Sum: "../aaaa"}))
+	require.False(t, cacheable(fv1.Checksum{}))
+}
@@ -48,14 +48,31 @@ func (c *Client) getUploadUrl() string {
 	return c.url + "/upload"
 }
 
+func (c *Client) getRefreshUrl() string {
+	return c.url + "/refresh"
+}
+
 func (c *Client) Specialize(ctx context.Context, req *fetcher.FunctionSpecializeRequest) error {
 	_, err := sendRequest(c.logger, ctx, c.httpClient, req, c.getSpecializeUrl())
 	return err
 }
 
-func (c *Client) Fetch(ctx context.Context, fr *fetcher.FunctionFetchRequest) error {
-	_, err := sendRequest(c.logger, ctx, c.httpClient, fr, c.getFetchUrl())
-	return err
+func (c *Client) Fetch(ctx context.Context, fr *fetcher.FunctionFetchRequest) (*fetcher.FunctionFetchResponse, error) {
+	body, err := sendRequest(c.logger, ctx, c.httpClient, fr, c.getFetchUrl())
+	if err != nil {
+		return nil, err
+	}
+
+	fetchResp := fetcher.FunctionFetchResponse{}
+	// fetchers of older versions respond without a body
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &fetchResp)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &fetchResp, nil
 }
 
 func (c *Client) Upload(ctx context.Context, fr *fetcher.ArchiveUploadRequest) (*fetcher.ArchiveUploadResponse, error) {
@@ -73,6 +90,25 @@ func (c *Client) Upload(ctx context.Context, fr *fetcher.ArchiveUploadRequest) (
 	return &uploadResp, nil
 }
 
+// Refresh asks the fetcher to refresh the secrets and configmaps of the
+// specialized pod in place. Unlike the other requests it isn't retried,
+// as callers recycle the pod instead.
+func (c *Client) Refresh(ctx context.Context, req *fetcher.FunctionRefreshRequest) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	resp, err := ctxhttp.Post(ctx, c.httpClient, c.getRefreshUrl(), "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ferror.MakeErrorFromHTTP(resp)
+	}
+	return nil
+}
+
 func sendRequest(logger *zap.Logger, ctx context.Context, httpClient *http.Client, req interface{}, url string) ([]byte, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
@@ -95,6 +131,11 @@ func sendRequest(logger *zap.Logger, ctx context.Context, httpClient *http.Clien
 				return body, err
 			}
 			err = ferror.MakeErrorFromHTTP(resp)
+
+			// archives refused by the signature policy are refused again on retry
+			if resp.StatusCode == http.StatusForbidden {
+				return nil, err
+			}
 		}
 
 		// skip retry and return directly due to context deadline exceeded
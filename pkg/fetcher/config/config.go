@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
@@ -17,9 +18,15 @@ import (
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/fetcher"
+	"github.com/fission/fission/pkg/signing"
 	"github.com/fission/fission/pkg/utils"
 )
 
+const (
+	archiveCacheVolume    = "archive-cache"
+	archiveCacheMountPath = "/archive-cache"
+)
+
 type Config struct {
 	fetcherImage           string
 	fetcherImagePullPolicy apiv1.PullPolicy
@@ -35,6 +42,11 @@ type Config struct {
 	serviceAccount string
 
 	jaegerCollectorEndpoint string
+
+	// host directory of the archive cache shared by the fetchers of a
+	// node; the cache is disabled if empty
+	archiveCacheHostPath string
+	archiveCacheSize     string
 }
 
 func getFetcherResources() (apiv1.ResourceRequirements, error) {
@@ -80,6 +92,21 @@ func MakeFetcherConfig(sharedMountPath string) (*Config, error) {
 		fetcherImagePullPolicy = "IfNotPresent"
 	}
 
+	var archiveCacheHostPath, archiveCacheSize string
+	if enabled, _ := strconv.ParseBool(os.Getenv("FETCHER_ARCHIVE_CACHE_ENABLED")); enabled {
+		archiveCacheHostPath = os.Getenv("FETCHER_ARCHIVE_CACHE_HOST_PATH")
+		if len(archiveCacheHostPath) == 0 {
+			archiveCacheHostPath = "/var/lib/fission/archive-cache"
+		}
+		archiveCacheSize = os.Getenv("FETCHER_ARCHIVE_CACHE_SIZE")
+		if len(archiveCacheSize) > 0 {
+			_, err := resource.ParseQuantity(archiveCacheSize)
+			if err != nil {
+				return nil, errors.Wrap(err, "error parsing FETCHER_ARCHIVE_CACHE_SIZE")
+			}
+		}
+	}
+
 	return &Config{
 		resourceRequirements:    resources,
 		fetcherImage:            fetcherImage,
@@ -89,6 +116,8 @@ func MakeFetcherConfig(sharedMountPath string) (*Config, error) {
 		sharedCfgMapPath:        "/configs",
 		jaegerCollectorEndpoint: os.Getenv("TRACE_JAEGER_COLLECTOR_ENDPOINT"),
 		serviceAccount:          fv1.FissionFetcherSA,
+		archiveCacheHostPath:    archiveCacheHostPath,
+		archiveCacheSize:        archiveCacheSize,
 	}, nil
 }
 
@@ -106,7 +135,10 @@ func (cfg *Config) SharedMountPath() string {
 	return cfg.sharedMountPath
 }
 
-func (cfg *Config) NewSpecializeRequest(fn *fv1.Function, env *fv1.Environment) fetcher.FunctionSpecializeRequest {
+// NewSpecializeRequest returns the request specializing a pod for the
+// function. If the environment has a signature policy, policy holds its
+// keys and the fetcher is asked to verify the function archive.
+func (cfg *Config) NewSpecializeRequest(fn *fv1.Function, env *fv1.Environment, policy *signing.Policy) fetcher.FunctionSpecializeRequest {
 	targetFilename := "user"
 	if env.Spec.Version >= 2 {
 		if env.Spec.AllowedFunctionsPerContainer == fv1.AllowedFunctionsPerContainerInfinite {
@@ -121,18 +153,24 @@ func (cfg *Config) NewSpecializeRequest(fn *fv1.Function, env *fv1.Environment)
 		}
 	}
 
-	return fetcher.FunctionSpecializeRequest{
-		FetchReq: fetcher.FunctionFetchRequest{
-			FetchType: fv1.FETCH_DEPLOYMENT,
-			Package: metav1.ObjectMeta{
-				Namespace: fn.Spec.Package.PackageRef.Namespace,
-				Name:      fn.Spec.Package.PackageRef.Name,
-			},
-			Filename:    targetFilename,
-			Secrets:     fn.Spec.Secrets,
-			ConfigMaps:  fn.Spec.ConfigMaps,
-			KeepArchive: env.Spec.KeepArchive,
+	fetchReq := fetcher.FunctionFetchRequest{
+		FetchType: fv1.FETCH_DEPLOYMENT,
+		Package: metav1.ObjectMeta{
+			Namespace: fn.Spec.Package.PackageRef.Namespace,
+			Name:      fn.Spec.Package.PackageRef.Name,
 		},
+		Filename:    targetFilename,
+		Secrets:     fn.Spec.Secrets,
+		ConfigMaps:  fn.Spec.ConfigMaps,
+		KeepArchive: env.Spec.KeepArchive,
+	}
+	if policy != nil {
+		fetchReq.RequireSignature = true
+		fetchReq.SignatureKeys = policy.PublicKeys
+	}
+
+	return fetcher.FunctionSpecializeRequest{
+		FetchReq: fetchReq,
 		LoadReq: fetcher.FunctionLoadRequest{
 			FilePath:         filepath.Join(cfg.sharedMountPath, targetFilename),
 			FunctionName:     fn.Spec.Package.FunctionName,
@@ -146,8 +184,8 @@ func (cfg *Config) AddFetcherToPodSpec(podSpec *apiv1.PodSpec, mainContainerName
 	return cfg.addFetcherToPodSpecWithCommand(podSpec, mainContainerName, cfg.fetcherCommand())
 }
 
-func (cfg *Config) AddSpecializingFetcherToPodSpec(podSpec *apiv1.PodSpec, mainContainerName string, fn *fv1.Function, env *fv1.Environment) error {
-	specializeReq := cfg.NewSpecializeRequest(fn, env)
+func (cfg *Config) AddSpecializingFetcherToPodSpec(podSpec *apiv1.PodSpec, mainContainerName string, fn *fv1.Function, env *fv1.Environment, policy *signing.Policy) error {
+	specializeReq := cfg.NewSpecializeRequest(fn, env, policy)
 	specializePayload, err := json.Marshal(specializeReq)
 	if err != nil {
 		return err
@@ -169,6 +207,12 @@ func (cfg *Config) fetcherCommand(extraArgs ...string) []string {
 		"-cfgmap-dir", cfg.sharedCfgMapPath,
 		"-jaeger-collector-endpoint", cfg.jaegerCollectorEndpoint,
 	}
+	if len(cfg.archiveCacheHostPath) > 0 {
+		command = append(command, "-archive-cache-dir", archiveCacheMountPath)
+		if len(cfg.archiveCacheSize) > 0 {
+			command = append(command, "-archive-cache-size", cfg.archiveCacheSize)
+		}
+	}
 
 	command = append(command, extraArgs...)
 	command = append(command, cfg.sharedMountPath)
@@ -323,6 +367,24 @@ func (cfg *Config) addFetcherToPodSpecWithCommand(podSpec *apiv1.PodSpec, mainCo
 			existingContainerNames)
 	}
 
+	// the archive cache is only mounted into the fetcher
+	if len(cfg.archiveCacheHostPath) > 0 {
+		hostPathType := apiv1.HostPathDirectoryOrCreate
+		volumes = append(volumes, apiv1.Volume{
+			Name: archiveCacheVolume,
+			VolumeSource: apiv1.VolumeSource{
+				HostPath: &apiv1.HostPathVolumeSource{
+					Path: cfg.archiveCacheHostPath,
+					Type: &hostPathType,
+				},
+			},
+		})
+		c.VolumeMounts = append(append([]apiv1.VolumeMount{}, mounts...), apiv1.VolumeMount{
+			Name:      archiveCacheVolume,
+			MountPath: archiveCacheMountPath,
+		})
+	}
+
 	podSpec.Volumes = append(podSpec.Volumes, volumes...)
 	podSpec.Containers = append(podSpec.Containers, c)
 	if podSpec.ServiceAccountName == "" {
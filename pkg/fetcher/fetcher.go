@@ -25,6 +25,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/mholt/archiver"
@@ -47,6 +49,8 @@ import (
 	ferror "github.com/fission/fission/pkg/error"
 	"github.com/fission/fission/pkg/error/network"
 	"github.com/fission/fission/pkg/info"
+	"github.com/fission/fission/pkg/oci"
+	"github.com/fission/fission/pkg/signing"
 	storageSvcClient "github.com/fission/fission/pkg/storagesvc/client"
 	"github.com/fission/fission/pkg/utils"
 )
@@ -60,7 +64,14 @@ type (
 		fissionClient    *crd.FissionClient
 		kubeClient       *kubernetes.Clientset
 		httpClient       *http.Client
-		Info             PodInfo
+		// archiveCache holds the archives fetched on the node, if enabled
+		archiveCache *archiveCache
+		// secretsLock serializes writing the secrets and configmaps, and
+		// guards the ones the pod is specialized with, the only ones
+		// refreshed
+		secretsLock sync.Mutex
+		specialized *FunctionRefreshRequest
+		Info        PodInfo
 	}
 	PodInfo struct {
 		Name      string
@@ -68,11 +79,19 @@ type (
 	}
 )
 
+// tmpFilePrefix prefixes the temporary files of secrets and configmaps
+// being written.
+const tmpFilePrefix = ".fission-tmp-"
+
 func makeVolumeDir(dirPath string) error {
 	return os.MkdirAll(dirPath, os.ModeDir|0750)
 }
 
-func MakeFetcher(logger *zap.Logger, sharedVolumePath string, sharedSecretPath string, sharedConfigPath string) (*Fetcher, error) {
+// MakeFetcher returns a Fetcher placing archives in the shared volume. If
+// archiveCacheDir isn't empty, archives are cached there, up to
+// archiveCacheSize bytes, for the other fetchers of the node.
+func MakeFetcher(logger *zap.Logger, sharedVolumePath string, sharedSecretPath string, sharedConfigPath string,
+	archiveCacheDir string, archiveCacheSize int64) (*Fetcher, error) {
 	fLogger := logger.Named("fetcher")
 	err := makeVolumeDir(sharedVolumePath)
 	if err != nil {
@@ -102,6 +121,14 @@ func MakeFetcher(logger *zap.Logger, sharedVolumePath string, sharedSecretPath s
 		return nil, errors.Wrap(err, "error reading pod namespace from downward volume")
 	}
 
+	var cache *archiveCache
+	if len(archiveCacheDir) > 0 {
+		cache, err = makeArchiveCache(archiveCacheDir, archiveCacheSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Fetcher{
 		logger:           fLogger,
 		sharedVolumePath: sharedVolumePath,
@@ -109,6 +136,7 @@ func MakeFetcher(logger *zap.Logger, sharedVolumePath string, sharedSecretPath s
 		sharedConfigPath: sharedConfigPath,
 		fissionClient:    fissionClient,
 		kubeClient:       kubeClient,
+		archiveCache:     cache,
 		Info: PodInfo{
 			Name:      string(name),
 			Namespace: string(namespace),
@@ -129,14 +157,58 @@ func verifyChecksum(fileChecksum, checksum *fv1.Checksum) error {
 	return nil
 }
 
+// verifySignature verifies the signature of an archive placed at path
+// against the checksum of its content, so that a signature can't be
+// reused for another archive.
+func verifySignature(path string, archive *fv1.Archive, keys []string) error {
+	checksum, err := utils.GetFileChecksum(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to get checksum")
+	}
+	return signing.Verify(*checksum, archive.Signature, keys)
+}
+
+// writeSecretOrConfigMap writes each key of a secret or configmap to a
+// file of dirPath. Files are replaced atomically, so that a function
+// reading them while they are refreshed sees either the old or the new
+// value, and the files of removed keys are deleted.
 func writeSecretOrConfigMap(dataMap map[string][]byte, dirPath string) error {
 	for key, val := range dataMap {
 		writeFilePath := filepath.Join(dirPath, key)
-		err := ioutil.WriteFile(writeFilePath, val, 0750)
+		tmpFile, err := ioutil.TempFile(dirPath, tmpFilePrefix)
+		if err == nil {
+			_, err = tmpFile.Write(val)
+			if cerr := tmpFile.Close(); err == nil {
+				err = cerr
+			}
+			if err == nil {
+				err = os.Chmod(tmpFile.Name(), 0750)
+			}
+			if err == nil {
+				err = os.Rename(tmpFile.Name(), writeFilePath)
+			}
+			if err != nil {
+				os.Remove(tmpFile.Name())
+			}
+		}
 		if err != nil {
 			return errors.Wrapf(err, "Failed to write file %s", writeFilePath)
 		}
 	}
+
+	fis, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read directory %s", dirPath)
+	}
+	for _, fi := range fis {
+		if _, ok := dataMap[fi.Name()]; ok || fi.IsDir() || strings.HasPrefix(fi.Name(), tmpFilePrefix) {
+			continue
+		}
+		err = os.Remove(filepath.Join(dirPath, fi.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "Failed to remove file %s", fi.Name())
+		}
+	}
 	return nil
 }
 
@@ -182,12 +254,18 @@ func (fetcher *Fetcher) FetchHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	code, err := fetcher.Fetch(r.Context(), pkg, req)
+	resp, code, err := fetcher.Fetch(r.Context(), pkg, req)
 	if err != nil {
 		fetcher.logger.Error("error fetching", zap.Error(err))
 		http.Error(w, err.Error(), code)
 		return
 	}
+	rBody, err := json.Marshal(resp)
+	if err != nil {
+		fetcher.logger.Error("error encoding response", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	fetcher.logger.Info("checking secrets/cfgmaps")
 	code, err = fetcher.FetchSecretsAndCfgMaps(req.Secrets, req.ConfigMaps)
@@ -199,7 +277,12 @@ func (fetcher *Fetcher) FetchHandler(w http.ResponseWriter, r *http.Request) {
 
 	fetcher.logger.Info("completed fetch request")
 	// all done
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(rBody)
+	if err != nil {
+		fetcher.logger.Error("error writing response", zap.Error(err))
+	}
 }
 
 func (fetcher *Fetcher) SpecializeHandler(w http.ResponseWriter, r *http.Request) {
@@ -226,7 +309,8 @@ func (fetcher *Fetcher) SpecializeHandler(w http.ResponseWriter, r *http.Request
 	err = fetcher.SpecializePod(r.Context(), req.FetchReq, req.LoadReq)
 	if err != nil {
 		fetcher.logger.Error("error specializing pod", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		code, msg := ferror.GetHTTPError(err)
+		http.Error(w, msg, code)
 		return
 	}
 
@@ -234,14 +318,131 @@ func (fetcher *Fetcher) SpecializeHandler(w http.ResponseWriter, r *http.Request
 	w.WriteHeader(http.StatusOK)
 }
 
-// Fetch takes FetchRequest and makes the fetch call
+// RefreshHandler rewrites the secrets and configmaps of the function of a
+// specialized pod, and asks the environment to reload them. The executor
+// only calls it for environments opting in with Runtime.Reload, as other
+// runtimes would route the reload request to the function. It fails if
+// the environment doesn't support reloading, in which case the pod is
+// expected to be recycled. Only the secrets and configmaps the pod was
+// specialized with are refreshed.
+func (fetcher *Fetcher) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "only POST is supported on this endpoint", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		fetcher.logger.Error("error reading request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var req FunctionRefreshRequest
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		fetcher.logger.Error("error parsing request body", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	refresh, code, err := fetcher.refreshRefs(req)
+	if err != nil {
+		fetcher.logger.Error("refusing to refresh secrets and config maps", zap.Error(err))
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	code, err = fetcher.FetchSecretsAndCfgMaps(refresh.Secrets, refresh.ConfigMaps)
+	if err != nil {
+		fetcher.logger.Error("error refreshing secrets and config maps", zap.Error(err))
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	payload, err := json.Marshal(refresh)
+	if err != nil {
+		fetcher.logger.Error("error encoding reload request", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	code, err = fetcher.reloadRuntime(r.Context(), payload)
+	if err != nil {
+		fetcher.logger.Error("error reloading function environment", zap.Error(err))
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	fetcher.logger.Info("refreshed secrets and config maps")
+	w.WriteHeader(http.StatusOK)
+}
+
+// refreshRefs returns the secrets and configmaps the pod was specialized
+// with, to be refreshed. Requests referring to others are refused, as the
+// fetcher can read secrets of any namespace.
 // It returns the HTTP code and error if any
-func (fetcher *Fetcher) Fetch(ctx context.Context, pkg *fv1.Package, req FunctionFetchRequest) (int, error) {
+func (fetcher *Fetcher) refreshRefs(req FunctionRefreshRequest) (*FunctionRefreshRequest, int, error) {
+	fetcher.secretsLock.Lock()
+	defer fetcher.secretsLock.Unlock()
+
+	if fetcher.specialized == nil {
+		return nil, http.StatusBadRequest, errors.New("pod isn't specialized")
+	}
+	secrets := make(map[fv1.SecretReference]bool)
+	for _, secret := range fetcher.specialized.Secrets {
+		secrets[secret] = true
+	}
+	for _, secret := range req.Secrets {
+		if !secrets[secret] {
+			return nil, http.StatusForbidden, errors.Errorf("secret %v.%v isn't one of the function", secret.Name, secret.Namespace)
+		}
+	}
+	cfgmaps := make(map[fv1.ConfigMapReference]bool)
+	for _, cfgmap := range fetcher.specialized.ConfigMaps {
+		cfgmaps[cfgmap] = true
+	}
+	for _, cfgmap := range req.ConfigMaps {
+		if !cfgmaps[cfgmap] {
+			return nil, http.StatusForbidden, errors.Errorf("configmap %v.%v isn't one of the function", cfgmap.Name, cfgmap.Namespace)
+		}
+	}
+	return &FunctionRefreshRequest{
+		Secrets:    append([]fv1.SecretReference(nil), fetcher.specialized.Secrets...),
+		ConfigMaps: append([]fv1.ConfigMapReference(nil), fetcher.specialized.ConfigMaps...),
+	}, http.StatusOK, nil
+}
+
+// reloadRuntime calls the optional reload endpoint of the environment,
+// once the secrets and configmaps of the function are refreshed.
+// It returns the HTTP code and error if any
+func (fetcher *Fetcher) reloadRuntime(ctx context.Context, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://127.0.0.1:8888/reload", bytes.NewReader(payload))
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return http.StatusInternalServerError, errors.Wrap(err, "error calling reload endpoint of function environment")
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented:
+		return http.StatusNotImplemented, errors.New("function environment doesn't support reloading")
+	case resp.StatusCode >= 300:
+		return http.StatusInternalServerError, errors.Wrap(ferror.MakeErrorFromHTTP(resp), "error reloading function environment")
+	}
+	return http.StatusOK, nil
+}
+
+// Fetch takes FetchRequest and makes the fetch call
+// It returns the fetch response, the HTTP code and error if any
+func (fetcher *Fetcher) Fetch(ctx context.Context, pkg *fv1.Package, req FunctionFetchRequest) (*FunctionFetchResponse, int, error) {
 	// check that the requested filename is not an empty string and error out if so
 	if len(req.Filename) == 0 {
 		e := "fetch request received for an empty file name"
 		fetcher.logger.Error(e, zap.Any("request", req))
-		return http.StatusBadRequest, errors.New(fmt.Sprintf("%s, request: %v", e, req))
+		return nil, http.StatusBadRequest, errors.New(fmt.Sprintf("%s, request: %v", e, req))
 	}
 
 	// verify first if the file already exists.
@@ -249,19 +450,22 @@ func (fetcher *Fetcher) Fetch(ctx context.Context, pkg *fv1.Package, req Functio
 		fetcher.logger.Info("requested file already exists at shared volume - skipping fetch",
 			zap.String("requested_file", req.Filename),
 			zap.String("shared_volume_path", fetcher.sharedVolumePath))
-		return http.StatusOK, nil
+		return &FunctionFetchResponse{}, http.StatusOK, nil
 	}
 
 	tmpFile := req.Filename + ".tmp"
 	tmpPath := filepath.Join(fetcher.sharedVolumePath, tmpFile)
 
 	if req.FetchType == fv1.FETCH_URL {
+		if req.RequireSignature {
+			return nil, http.StatusForbidden, errors.New("signed archives are required, refusing to fetch unsigned url")
+		}
 		// fetch the file and save it to the tmp path
 		err := utils.DownloadUrl(ctx, fetcher.httpClient, req.Url, tmpPath)
 		if err != nil {
 			e := "failed to download url"
 			fetcher.logger.Error(e, zap.Error(err), zap.String("url", req.Url))
-			return http.StatusBadRequest, errors.Wrapf(err, "%s: %s", e, req.Url)
+			return nil, http.StatusBadRequest, errors.Wrapf(err, "%s: %s", e, req.Url)
 		}
 	} else {
 		var archive *fv1.Archive
@@ -278,11 +482,11 @@ func (fetcher *Fetcher) Fetch(ctx context.Context, pkg *fv1.Package, req Functio
 					zap.String("package_name", pkg.ObjectMeta.Name),
 					zap.String("package_namespace", pkg.ObjectMeta.Namespace),
 					zap.Any("package_build_status", pkg.Status.BuildStatus))
-				return http.StatusInternalServerError, errors.New(fmt.Sprintf("%s: pkg %s.%s has a status of %s", e, pkg.ObjectMeta.Name, pkg.ObjectMeta.Namespace, pkg.Status.BuildStatus))
+				return nil, http.StatusInternalServerError, errors.New(fmt.Sprintf("%s: pkg %s.%s has a status of %s", e, pkg.ObjectMeta.Name, pkg.ObjectMeta.Namespace, pkg.Status.BuildStatus))
 			}
 			archive = &pkg.Spec.Deployment
 		} else {
-			return http.StatusBadRequest, fmt.Errorf("unknown fetch type: %v", req.FetchType)
+			return nil, http.StatusBadRequest, fmt.Errorf("unknown fetch type: %v", req.FetchType)
 		}
 
 		// get package data as literal or by url
@@ -292,35 +496,40 @@ func (fetcher *Fetcher) Fetch(ctx context.Context, pkg *fv1.Package, req Functio
 			if err != nil {
 				e := "failed to write file"
 				fetcher.logger.Error(e, zap.Error(err), zap.String("location", tmpPath))
-				return http.StatusInternalServerError, errors.Wrapf(err, "%s %s", e, tmpPath)
+				return nil, http.StatusInternalServerError, errors.Wrapf(err, "%s %s", e, tmpPath)
 			}
 		} else {
-			// download and verify
-			err := utils.DownloadUrl(ctx, fetcher.httpClient, archive.URL, tmpPath)
+			code, err := fetcher.fetchArchive(ctx, pkg.ObjectMeta.Namespace, archive, tmpPath)
 			if err != nil {
-				e := "failed to download url"
-				fetcher.logger.Error(e, zap.Error(err), zap.String("url", req.Url))
-				return http.StatusBadRequest, errors.Wrapf(err, "%s %s", e, req.Url)
+				return nil, code, err
 			}
+		}
 
-			// check file integrity only if checksum is not empty.
-			if len(archive.Checksum.Sum) > 0 {
-				checksum, err := utils.GetFileChecksum(tmpPath)
-				if err != nil {
-					e := "failed to get checksum"
-					fetcher.logger.Error(e, zap.Error(err))
-					return http.StatusBadRequest, errors.Wrap(err, e)
-				}
-				err = verifyChecksum(checksum, &archive.Checksum)
-				if err != nil {
-					e := "failed to verify checksum"
-					fetcher.logger.Error(e, zap.Error(err))
-					return http.StatusBadRequest, errors.Wrap(err, e)
-				}
+		if req.RequireSignature {
+			err := verifySignature(tmpPath, archive, req.SignatureKeys)
+			if err != nil {
+				e := "failed to verify archive signature"
+				fetcher.logger.Error(e,
+					zap.Error(err),
+					zap.String("package_name", pkg.ObjectMeta.Name),
+					zap.String("package_namespace", pkg.ObjectMeta.Namespace))
+				os.Remove(tmpPath) //nolint: errCheck
+				return nil, http.StatusForbidden, errors.Wrap(err, e)
 			}
 		}
 	}
 
+	resp := &FunctionFetchResponse{}
+	if req.FetchType == fv1.FETCH_SOURCE {
+		sum, err := utils.GetFileChecksum(tmpPath)
+		if err != nil {
+			e := "failed to get checksum"
+			fetcher.logger.Error(e, zap.Error(err))
+			return nil, http.StatusInternalServerError, errors.Wrap(err, e)
+		}
+		resp.Checksum = sum
+	}
+
 	if archiver.Zip.Match(tmpPath) && !req.KeepArchive {
 		// unarchive tmp file to a tmp unarchive path
 		tmpUnarchivePath := filepath.Join(fetcher.sharedVolumePath, uuid.NewV4().String())
@@ -330,7 +539,7 @@ func (fetcher *Fetcher) Fetch(ctx context.Context, pkg *fv1.Package, req Functio
 				zap.Error(err),
 				zap.String("archive_location", tmpPath),
 				zap.String("target_location", tmpUnarchivePath))
-			return http.StatusInternalServerError, err
+			return nil, http.StatusInternalServerError, err
 		}
 
 		tmpPath = tmpUnarchivePath
@@ -344,16 +553,154 @@ func (fetcher *Fetcher) Fetch(ctx context.Context, pkg *fv1.Package, req Functio
 			zap.Error(err),
 			zap.String("original_path", tmpPath),
 			zap.String("rename_path", renamePath))
-		return http.StatusInternalServerError, err
+		return nil, http.StatusInternalServerError, err
 	}
 
 	fetcher.logger.Info("successfully placed", zap.String("location", renamePath))
+	return resp, http.StatusOK, nil
+}
+
+// fetchArchive places the archive at tmpPath, copying it from the node
+// archive cache if there, or downloading and verifying it otherwise.
+// OCI archives are pulled from their registry with the credentials of
+// their image pull secret, in the namespace of the package.
+// It returns the HTTP code and error if any
+func (fetcher *Fetcher) fetchArchive(ctx context.Context, namespace string, archive *fv1.Archive, tmpPath string) (int, error) {
+	useCache := fetcher.archiveCache != nil && cacheable(archive.Checksum)
+	if useCache {
+		cached, err := fetcher.archiveCache.get(archive.Checksum, tmpPath)
+		if err != nil {
+			fetcher.logger.Warn("error getting archive from cache, downloading it", zap.Error(err), zap.String("checksum", archive.Checksum.Sum))
+		}
+		if cached {
+			fetcher.logger.Info("archive found in node cache - skipping download", zap.String("checksum", archive.Checksum.Sum))
+			return http.StatusOK, nil
+		}
+	}
+
+	// download and verify
+	if archive.Type == fv1.ArchiveTypeOCI {
+		code, err := fetcher.pullArchive(ctx, namespace, archive, tmpPath)
+		if err != nil {
+			return code, err
+		}
+	} else {
+		err := utils.DownloadUrl(ctx, fetcher.httpClient, archive.URL, tmpPath)
+		if err != nil {
+			e := "failed to download url"
+			fetcher.logger.Error(e, zap.Error(err), zap.String("url", archive.URL))
+			return http.StatusBadRequest, errors.Wrapf(err, "%s %s", e, archive.URL)
+		}
+	}
+
+	// check file integrity only if checksum is not empty.
+	if len(archive.Checksum.Sum) > 0 {
+		checksum, err := utils.GetFileChecksum(tmpPath)
+		if err != nil {
+			e := "failed to get checksum"
+			fetcher.logger.Error(e, zap.Error(err))
+			return http.StatusBadRequest, errors.Wrap(err, e)
+		}
+		err = verifyChecksum(checksum, &archive.Checksum)
+		if err != nil {
+			e := "failed to verify checksum"
+			fetcher.logger.Error(e, zap.Error(err))
+			return http.StatusBadRequest, errors.Wrap(err, e)
+		}
+	}
+
+	if useCache {
+		err := fetcher.archiveCache.put(archive.Checksum, tmpPath)
+		if err != nil {
+			fetcher.logger.Warn("error adding archive to cache", zap.Error(err), zap.String("checksum", archive.Checksum.Sum))
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// pullArchive pulls an OCI archive to tmpPath, verifying its digest.
+// It returns the HTTP code and error if any
+func (fetcher *Fetcher) pullArchive(ctx context.Context, namespace string, archive *fv1.Archive, tmpPath string) (int, error) {
+	ref, err := oci.ParseReference(archive.URL)
+	if err != nil {
+		e := "invalid OCI archive reference"
+		fetcher.logger.Error(e, zap.Error(err), zap.String("reference", archive.URL))
+		return http.StatusBadRequest, errors.Wrapf(err, "%s %s", e, archive.URL)
+	}
+
+	creds, code, err := fetcher.registryCredentials(ctx, namespace, archive.ImagePullSecret)
+	if err != nil {
+		return code, err
+	}
+
+	err = oci.MakeClient(fetcher.httpClient, creds).PullToFile(ctx, ref, tmpPath)
+	if err != nil {
+		e := "failed to pull OCI archive"
+		fetcher.logger.Error(e, zap.Error(err), zap.String("reference", archive.URL))
+		return http.StatusBadRequest, errors.Wrapf(err, "%s %s", e, archive.URL)
+	}
 	return http.StatusOK, nil
 }
 
+// pushArchive pushes an archive file to an OCI repository, tagged with its
+// checksum unless the repository has a tag, and returns the reference of
+// the artifact by digest.
+func (fetcher *Fetcher) pushArchive(ctx context.Context, namespace string, repo *fv1.OCIRepository, path string, sum *fv1.Checksum) (string, error) {
+	ref, err := oci.ParseReference(repo.Repository)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid OCI repository %s", repo.Repository)
+	}
+	if len(ref.Tag) == 0 {
+		ref.Tag = fmt.Sprintf("%v-%v", sum.Type, sum.Sum)
+	}
+
+	creds, _, err := fetcher.registryCredentials(ctx, namespace, repo.ImagePullSecret)
+	if err != nil {
+		return "", err
+	}
+
+	pushed, _, err := oci.MakeClient(fetcher.httpClient, creds).Push(ctx, ref, path)
+	if err != nil {
+		return "", errors.Wrapf(err, "error pushing archive to %v", ref)
+	}
+	return pushed.String(), nil
+}
+
+// registryCredentials returns the credentials of a docker registry secret,
+// none if the secret name is empty.
+// It returns the HTTP code and error if any
+func (fetcher *Fetcher) registryCredentials(ctx context.Context, namespace string, secretName string) (oci.Credentials, int, error) {
+	if len(secretName) == 0 {
+		return nil, http.StatusOK, nil
+	}
+	secret, err := fetcher.kubeClient.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		e := "error getting image pull secret from kubeapi"
+		httpCode := http.StatusInternalServerError
+		if k8serr.IsNotFound(err) {
+			httpCode = http.StatusNotFound
+			e = "image pull secret was not found in kubeapi"
+		}
+		fetcher.logger.Error(e,
+			zap.Error(err),
+			zap.String("secret_name", secretName),
+			zap.String("secret_namespace", namespace))
+		return nil, httpCode, errors.Wrap(err, e)
+	}
+	creds, err := oci.CredentialsFromSecret(secret)
+	if err != nil {
+		fetcher.logger.Error("error reading image pull secret", zap.Error(err))
+		return nil, http.StatusBadRequest, err
+	}
+	return creds, http.StatusOK, nil
+}
+
 // FetchSecretsAndCfgMaps fetches secrets and configmaps specified by user
 // It returns the HTTP code and error if any
 func (fetcher *Fetcher) FetchSecretsAndCfgMaps(secrets []fv1.SecretReference, cfgmaps []fv1.ConfigMapReference) (int, error) {
+	fetcher.secretsLock.Lock()
+	defer fetcher.secretsLock.Unlock()
+
 	if len(secrets) > 0 {
 		for _, secret := range secrets {
 			data, err := fetcher.kubeClient.CoreV1().Secrets(secret.Namespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
@@ -500,17 +847,6 @@ func (fetcher *Fetcher) UploadHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	fetcher.logger.Info("starting upload...")
-	ssClient := storageSvcClient.MakeClient(req.StorageSvcUrl)
-
-	fileID, err := ssClient.Upload(r.Context(), dstFilepath, nil)
-	if err != nil {
-		e := "error uploading zip file"
-		fetcher.logger.Error(e, zap.Error(err), zap.String("file", dstFilepath))
-		http.Error(w, fmt.Sprintf("%s: %v", e, err), http.StatusInternalServerError)
-		return
-	}
-
 	sum, err := utils.GetFileChecksum(dstFilepath)
 	if err != nil {
 		e := "error calculating checksum of zip file"
@@ -520,8 +856,32 @@ func (fetcher *Fetcher) UploadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := ArchiveUploadResponse{
-		ArchiveDownloadUrl: ssClient.GetUrl(fileID),
-		Checksum:           *sum,
+		Checksum: *sum,
+	}
+
+	if req.OCIRepository != nil {
+		fetcher.logger.Info("starting push...", zap.String("repository", req.OCIRepository.Repository))
+		ref, err := fetcher.pushArchive(r.Context(), req.Namespace, req.OCIRepository, dstFilepath, sum)
+		if err != nil {
+			e := "error pushing zip file"
+			fetcher.logger.Error(e, zap.Error(err), zap.String("file", dstFilepath))
+			http.Error(w, fmt.Sprintf("%s: %v", e, err), http.StatusInternalServerError)
+			return
+		}
+		resp.ArchiveType = fv1.ArchiveTypeOCI
+		resp.ArchiveDownloadUrl = ref
+	} else {
+		fetcher.logger.Info("starting upload...")
+		ssClient := storageSvcClient.MakeClient(req.StorageSvcUrl)
+
+		fileID, err := ssClient.Upload(r.Context(), dstFilepath, nil)
+		if err != nil {
+			e := "error uploading zip file"
+			fetcher.logger.Error(e, zap.Error(err), zap.String("file", dstFilepath))
+			http.Error(w, fmt.Sprintf("%s: %v", e, err), http.StatusInternalServerError)
+			return
+		}
+		resp.ArchiveDownloadUrl = ssClient.GetUrl(fileID)
 	}
 
 	rBody, err := json.Marshal(resp)
@@ -623,8 +983,11 @@ func (fetcher *Fetcher) SpecializePod(ctx context.Context, fetchReq FunctionFetc
 		return errors.Wrap(err, "error getting package information")
 	}
 
-	_, err = fetcher.Fetch(ctx, pkg, fetchReq)
+	_, code, err := fetcher.Fetch(ctx, pkg, fetchReq)
 	if err != nil {
+		if code == http.StatusForbidden {
+			return ferror.MakeError(ferror.ErrorNotAuthorized, fmt.Sprintf("error fetching deploy package: %v", err))
+		}
 		return errors.Wrap(err, "error fetching deploy package")
 	}
 
@@ -632,6 +995,12 @@ func (fetcher *Fetcher) SpecializePod(ctx context.Context, fetchReq FunctionFetc
 	if err != nil {
 		return errors.Wrap(err, "error fetching secrets/configs")
 	}
+	fetcher.secretsLock.Lock()
+	fetcher.specialized = &FunctionRefreshRequest{
+		Secrets:    fetchReq.Secrets,
+		ConfigMaps: fetchReq.ConfigMaps,
+	}
+	fetcher.secretsLock.Unlock()
 
 	// Specialize the pod
 
@@ -0,0 +1,120 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+func TestWriteSecretOrConfigMap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = writeSecretOrConfigMap(map[string][]byte{"a": []byte("1"), "b": []byte("2")}, dir)
+	require.NoError(t, err)
+
+	// refreshing rewrites the changed keys and removes the deleted ones
+	err = writeSecretOrConfigMap(map[string][]byte{"a": []byte("3")}, dir)
+	require.NoError(t, err)
+
+	fis, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, fis, 1)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "a"))
+	require.NoError(t, err)
+	require.Equal(t, "3", string(data))
+}
+
+func TestFetchSourceChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fetcher")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "source")
+	}))
+	defer ts.Close()
+
+	fetcher := &Fetcher{
+		logger:           zap.NewNop(),
+		sharedVolumePath: dir,
+		httpClient:       http.DefaultClient,
+	}
+	// the source archive of the package has no checksum
+	pkg := &fv1.Package{
+		Spec: fv1.PackageSpec{
+			Source: fv1.Archive{Type: fv1.ArchiveTypeUrl, URL: ts.URL},
+		},
+	}
+	resp, _, err := fetcher.Fetch(context.Background(), pkg, FunctionFetchRequest{
+		FetchType: fv1.FETCH_SOURCE,
+		Filename:  "source",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resp.Checksum)
+	require.Equal(t, fv1.ChecksumTypeSHA256, resp.Checksum.Type)
+	// sha256 of "source"
+	require.Equal(t, "41cf6794ba4200b839c53531555f0f3998df4cbb01a4d5cb0b94e3ca5e23947d", resp.Checksum.Sum)
+}
+
+func TestRefreshRefs(t *testing.T) {
+	fetcher := &Fetcher{logger: zap.NewNop()}
+	secret := fv1.SecretReference{Namespace: "default", Name: "secret"}
+	cfgmap := fv1.ConfigMapReference{Namespace: "default", Name: "cfgmap"}
+
+	_, code, err := fetcher.refreshRefs(FunctionRefreshRequest{})
+	require.Error(t, err)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	fetcher.specialized = &FunctionRefreshRequest{
+		Secrets:    []fv1.SecretReference{secret},
+		ConfigMaps: []fv1.ConfigMapReference{cfgmap},
+	}
+
+	// the references the pod was specialized with are refreshed
+	refresh, _, err := fetcher.refreshRefs(FunctionRefreshRequest{})
+	require.NoError(t, err)
+	require.Equal(t, fetcher.specialized, refresh)
+	refresh, _, err = fetcher.refreshRefs(FunctionRefreshRequest{Secrets: []fv1.SecretReference{secret}})
+	require.NoError(t, err)
+	require.Equal(t, fetcher.specialized, refresh)
+
+	// others are refused
+	_, code, err = fetcher.refreshRefs(FunctionRefreshRequest{
+		Secrets: []fv1.SecretReference{secret, {Namespace: "kube-system", Name: "secret"}},
+	})
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, code)
+	_, code, err = fetcher.refreshRefs(FunctionRefreshRequest{
+		ConfigMaps: []fv1.ConfigMapReference{{Namespace: "default", Name: "other"}},
+	})
+	require.Error(t, err)
+	require.Equal(t, http.StatusForbidden, code)
+}
@@ -44,6 +44,12 @@ type (
 		Secrets       []fv1.SecretReference    `json:"secretList"`
 		ConfigMaps    []fv1.ConfigMapReference `json:"configMapList"`
 		KeepArchive   bool                     `json:"keeparchive"`
+
+		// RequireSignature makes the fetcher refuse archives without
+		// a valid signature by one of the PEM encoded SignatureKeys,
+		// as required by the signature policy of the environment.
+		RequireSignature bool     `json:"requireSignature,omitempty"`
+		SignatureKeys    []string `json:"signatureKeys,omitempty"`
 	}
 
 	FunctionLoadRequest struct {
@@ -68,18 +74,40 @@ type (
 		EnvVersion int `json:"envVersion"`
 	}
 
+	// FunctionFetchResponse sent by the fetcher records the checksum of
+	// the fetched source archive, before it is unpacked, so that builds
+	// of sources without a checksum can be tied to them.
+	FunctionFetchResponse struct {
+		Checksum *fv1.Checksum `json:"checksum,omitempty"`
+	}
+
 	// ArchiveUploadRequest send from builder manager describes which
-	// deployment package should be upload to storage service.
+	// deployment package should be upload to storage service, or pushed
+	// to an OCI repository with the image pull secret of the repository
+	// in the given namespace.
 	ArchiveUploadRequest struct {
-		Filename       string `json:"filename"`
-		StorageSvcUrl  string `json:"storagesvcurl"`
-		ArchivePackage bool   `json:"archivepackage"`
+		Filename       string             `json:"filename"`
+		StorageSvcUrl  string             `json:"storagesvcurl"`
+		ArchivePackage bool               `json:"archivepackage"`
+		OCIRepository  *fv1.OCIRepository `json:"ociRepository,omitempty"`
+		Namespace      string             `json:"namespace,omitempty"`
 	}
 
 	// ArchiveUploadResponse defines the download url of an archive and
-	// its checksum.
+	// its checksum. For archives pushed to an OCI repository, the type is
+	// oci and the url is the reference of the artifact by digest.
 	ArchiveUploadResponse struct {
-		ArchiveDownloadUrl string       `json:"archiveDownloadUrl"`
-		Checksum           fv1.Checksum `json:"checksum"`
+		ArchiveType        fv1.ArchiveType `json:"archiveType,omitempty"`
+		ArchiveDownloadUrl string          `json:"archiveDownloadUrl"`
+		Checksum           fv1.Checksum    `json:"checksum"`
+	}
+
+	// FunctionRefreshRequest sent from executor asks the fetcher of a
+	// specialized pod to rewrite the secrets and configmaps of the
+	// function in place. The fetcher forwards it to the reload endpoint
+	// of the environment, which reloads the function configuration.
+	FunctionRefreshRequest struct {
+		Secrets    []fv1.SecretReference    `json:"secretList"`
+		ConfigMaps []fv1.ConfigMapReference `json:"configMapList"`
 	}
 )
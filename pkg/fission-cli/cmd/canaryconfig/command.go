@@ -32,7 +32,10 @@ func Commands() *cobra.Command {
 	}
 	wrapper.SetFlags(createCmd, flag.FlagSet{
 		Required: []flag.Flag{flag.CanaryName, flag.CanaryTriggerName, flag.CanaryNewFunc, flag.CanaryOldFunc},
-		Optional: []flag.Flag{flag.CanaryWeightIncrement, flag.CanaryIncrementInterval, flag.CanaryFailureThreshold, flag.NamespaceFunction},
+		Optional: []flag.Flag{flag.CanaryWeightIncrement, flag.CanaryIncrementInterval, flag.CanaryFailureThreshold,
+			flag.CanaryFailureType, flag.CanaryLatencyPercentile, flag.CanaryLatencyThreshold, flag.CanaryMinRequests,
+			flag.CanaryMetricsProvider, flag.CanaryMetricsURL, flag.CanaryQuery, flag.CanaryQueryMin, flag.CanaryQueryMax,
+			flag.CanaryStrategy, flag.CanarySchedule, flag.CanarySmokeTestFunc, flag.NamespaceFunction},
 	})
 
 	getCmd := &cobra.Command{
@@ -54,7 +57,10 @@ func Commands() *cobra.Command {
 	}
 	wrapper.SetFlags(updateCmd, flag.FlagSet{
 		Required: []flag.Flag{flag.CanaryName},
-		Optional: []flag.Flag{flag.CanaryWeightIncrement, flag.CanaryIncrementInterval, flag.CanaryFailureThreshold, flag.NamespaceCanary},
+		Optional: []flag.Flag{flag.CanaryWeightIncrement, flag.CanaryIncrementInterval, flag.CanaryFailureThreshold,
+			flag.CanaryFailureType, flag.CanaryLatencyPercentile, flag.CanaryLatencyThreshold, flag.CanaryMinRequests,
+			flag.CanaryMetricsProvider, flag.CanaryMetricsURL, flag.CanaryQuery, flag.CanaryQueryMin, flag.CanaryQueryMax,
+			flag.CanaryStrategy, flag.CanarySchedule, flag.CanarySmokeTestFunc, flag.NamespaceCanary},
 	})
 
 	deleteCmd := &cobra.Command{
@@ -79,13 +85,53 @@ func Commands() *cobra.Command {
 		Optional: []flag.Flag{flag.NamespaceCanary},
 	})
 
+	pauseCmd := &cobra.Command{
+		Use:   "pause",
+		Short: "Pause a canary config, keeping the current weights of the functions",
+		RunE:  wrapper.Wrapper(Pause),
+	}
+	wrapper.SetFlags(pauseCmd, flag.FlagSet{
+		Required: []flag.Flag{flag.CanaryName},
+		Optional: []flag.Flag{flag.NamespaceCanary},
+	})
+
+	resumeCmd := &cobra.Command{
+		Use:   "resume",
+		Short: "Resume a paused canary config",
+		RunE:  wrapper.Wrapper(Resume),
+	}
+	wrapper.SetFlags(resumeCmd, flag.FlagSet{
+		Required: []flag.Flag{flag.CanaryName},
+		Optional: []flag.Flag{flag.NamespaceCanary},
+	})
+
+	promoteCmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Promote the new version of the function of a canary config, sending it all the traffic",
+		RunE:  wrapper.Wrapper(Promote),
+	}
+	wrapper.SetFlags(promoteCmd, flag.FlagSet{
+		Required: []flag.Flag{flag.CanaryName},
+		Optional: []flag.Flag{flag.NamespaceCanary},
+	})
+
+	abortCmd := &cobra.Command{
+		Use:   "abort",
+		Short: "Abort a canary config, rolling back to the old version of the function",
+		RunE:  wrapper.Wrapper(Abort),
+	}
+	wrapper.SetFlags(abortCmd, flag.FlagSet{
+		Required: []flag.Flag{flag.CanaryName},
+		Optional: []flag.Flag{flag.NamespaceCanary},
+	})
+
 	command := &cobra.Command{
 		Use:     "canary",
 		Aliases: []string{"canary-config"},
 		Short:   "Create, Update and manage canary configs",
 	}
 
-	command.AddCommand(createCmd, getCmd, updateCmd, deleteCmd, listCmd)
+	command.AddCommand(createCmd, getCmd, updateCmd, deleteCmd, listCmd, pauseCmd, resumeCmd, promoteCmd, abortCmd)
 
 	return command
 }
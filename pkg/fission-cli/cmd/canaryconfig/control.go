@@ -0,0 +1,87 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package canaryconfig
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/fission-cli/cliwrapper/cli"
+	"github.com/fission/fission/pkg/fission-cli/cmd"
+	flagkey "github.com/fission/fission/pkg/fission-cli/flag/key"
+)
+
+type ControlSubCommand struct {
+	cmd.CommandActioner
+}
+
+// Pause keeps the current weights of the functions of a canary config.
+func Pause(input cli.Input) error {
+	return (&ControlSubCommand{}).run(input, "paused", func(spec *fv1.CanaryConfigSpec) {
+		spec.Paused = true
+	})
+}
+
+// Resume resumes a paused canary config.
+func Resume(input cli.Input) error {
+	return (&ControlSubCommand{}).run(input, "resumed", func(spec *fv1.CanaryConfigSpec) {
+		spec.Paused = false
+	})
+}
+
+// Promote sends all the traffic to the new version of the function.
+func Promote(input cli.Input) error {
+	return (&ControlSubCommand{}).run(input, "promoted", func(spec *fv1.CanaryConfigSpec) {
+		spec.Action = fv1.CanaryActionPromote
+	})
+}
+
+// Abort rolls back to the old version of the function.
+func Abort(input cli.Input) error {
+	return (&ControlSubCommand{}).run(input, "aborted", func(spec *fv1.CanaryConfigSpec) {
+		spec.Action = fv1.CanaryActionAbort
+	})
+}
+
+func (opts *ControlSubCommand) run(input cli.Input, done string, control func(spec *fv1.CanaryConfigSpec)) error {
+	canaryCfg, err := opts.Client().V1().CanaryConfig().Get(&metav1.ObjectMeta{
+		Name:      input.String(flagkey.CanaryName),
+		Namespace: input.String(flagkey.NamespaceCanary),
+	})
+	if err != nil {
+		return errors.Wrap(err, "error getting canary config")
+	}
+
+	if canaryCfg.Status.Status != fv1.CanaryConfigStatusPending {
+		return errors.Errorf("canary config '%v' is %v", canaryCfg.ObjectMeta.Name, canaryCfg.Status.Status)
+	}
+	if len(canaryCfg.Spec.Action) > 0 {
+		return errors.Errorf("canary config '%v' has a pending %v action", canaryCfg.ObjectMeta.Name, canaryCfg.Spec.Action)
+	}
+
+	control(&canaryCfg.Spec)
+	_, err = opts.Client().V1().CanaryConfig().Update(canaryCfg)
+	if err != nil {
+		return errors.Wrap(err, "error updating canary config")
+	}
+
+	fmt.Printf("canary config '%v' %v\n", canaryCfg.ObjectMeta.Name, done)
+	return nil
+}
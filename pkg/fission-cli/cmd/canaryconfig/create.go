@@ -18,6 +18,8 @@ package canaryconfig
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -97,6 +99,25 @@ func (opts *CreateSubCommand) complete(input cli.Input) error {
 		return errors.Wrap(err, "error checking functions existence")
 	}
 
+	queries, err := metricQueries(input)
+	if err != nil {
+		return err
+	}
+
+	steps, err := weightSteps(input)
+	if err != nil {
+		return err
+	}
+
+	// the smoke test function must exist in the same namespace
+	smokeTestFunc := input.String(flagkey.CanarySmokeTestFunc)
+	if len(smokeTestFunc) > 0 {
+		err = util.CheckFunctionExistence(opts.Client(), []string{smokeTestFunc}, fnNs)
+		if err != nil {
+			return errors.Wrap(err, "error checking smoke test function existence")
+		}
+	}
+
 	// finally create canaryCfg in the same namespace as the functions referenced
 	opts.canary = &fv1.CanaryConfig{
 		ObjectMeta: metav1.ObjectMeta{
@@ -110,13 +131,26 @@ func (opts *CreateSubCommand) complete(input cli.Input) error {
 			WeightIncrement:         incrementStep,
 			WeightIncrementDuration: incrementInterval,
 			FailureThreshold:        failureThreshold,
-			FailureType:             fv1.FailureTypeStatusCode,
+			FailureType:             fv1.FailureType(input.String(flagkey.CanaryFailureType)),
+			LatencyPercentile:       input.Int(flagkey.CanaryLatencyPercentile),
+			LatencyThreshold:        input.String(flagkey.CanaryLatencyThreshold),
+			MinRequests:             input.Int(flagkey.CanaryMinRequests),
+			MetricsProvider:         metricsProvider(input),
+			Queries:                 queries,
+			Strategy:                fv1.CanaryStrategy(input.String(flagkey.CanaryStrategy)),
+			Steps:                   steps,
+			SmokeTestFunction:       smokeTestFunc,
 		},
 		Status: fv1.CanaryConfigStatus{
 			Status: fv1.CanaryConfigStatusPending,
 		},
 	}
 
+	err = opts.canary.Validate()
+	if err != nil {
+		return fv1.AggregateValidationErrors("CanaryConfig", err)
+	}
+
 	return nil
 }
 
@@ -129,3 +163,80 @@ func (opts *CreateSubCommand) run(input cli.Input) error {
 	fmt.Printf("canary config '%v' created\n", opts.canary.ObjectMeta.Name)
 	return nil
 }
+
+// metricsProvider returns the metrics provider set with the flags, or nil
+// for the default one.
+func metricsProvider(input cli.Input) *fv1.CanaryMetricsProvider {
+	providerType := input.String(flagkey.CanaryMetricsProvider)
+	url := input.String(flagkey.CanaryMetricsURL)
+	if len(providerType) == 0 && len(url) == 0 {
+		return nil
+	}
+	if len(providerType) == 0 {
+		providerType = string(fv1.CanaryMetricsProviderPrometheus)
+	}
+	return &fv1.CanaryMetricsProvider{
+		Type: fv1.CanaryMetricsProviderType(providerType),
+		URL:  url,
+	}
+}
+
+// metricQueries returns the custom queries set with the query flags.
+func metricQueries(input cli.Input) ([]fv1.CanaryMetricQuery, error) {
+	var queries []fv1.CanaryMetricQuery
+	index := make(map[string]int)
+	for _, q := range input.StringSlice(flagkey.CanaryQuery) {
+		name, query, err := splitNameValue(q, flagkey.CanaryQuery)
+		if err != nil {
+			return nil, err
+		}
+		index[name] = len(queries)
+		queries = append(queries, fv1.CanaryMetricQuery{Name: name, Query: query})
+	}
+
+	for _, key := range []string{flagkey.CanaryQueryMin, flagkey.CanaryQueryMax} {
+		for _, b := range input.StringSlice(key) {
+			name, value, err := splitNameValue(b, key)
+			if err != nil {
+				return nil, err
+			}
+			i, ok := index[name]
+			if !ok {
+				return nil, errors.Errorf("--%v refers to unknown query %q", key, name)
+			}
+			if key == flagkey.CanaryQueryMin {
+				queries[i].Min = value
+			} else {
+				queries[i].Max = value
+			}
+		}
+	}
+
+	return queries, nil
+}
+
+// weightSteps returns the step schedule set with the schedule flags.
+func weightSteps(input cli.Input) ([]fv1.CanaryWeightStep, error) {
+	var steps []fv1.CanaryWeightStep
+	for _, s := range input.StringSlice(flagkey.CanarySchedule) {
+		kv := strings.SplitN(s, "=", 2)
+		weight, err := strconv.Atoi(strings.TrimSuffix(kv[0], "%"))
+		if err != nil {
+			return nil, errors.Errorf("--%v must be in the format of <weight>[=<duration>]: %q", flagkey.CanarySchedule, s)
+		}
+		step := fv1.CanaryWeightStep{Weight: weight}
+		if len(kv) == 2 {
+			step.Duration = kv[1]
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func splitNameValue(s string, key string) (string, string, error) {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 || len(kv[0]) == 0 {
+		return "", "", errors.Errorf("--%v must be in the format of <name>=<value>: %q", key, s)
+	}
+	return kv[0], kv[1], nil
+}
@@ -19,11 +19,16 @@ package canaryconfig
 import (
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/fission-cli/cliwrapper/cli"
 	"github.com/fission/fission/pkg/fission-cli/cmd"
 	flagkey "github.com/fission/fission/pkg/fission-cli/flag/key"
@@ -47,11 +52,169 @@ func (opts *GetSubCommand) run(input cli.Input) error {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
-	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", "NAME", "TRIGGER", "FUNCTION-N", "FUNCTION-N-1", "WEIGHT-INCREMENT", "INTERVAL", "FAILURE-THRESHOLD", "FAILURE-TYPE", "STATUS")
-	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
-		canaryCfg.ObjectMeta.Name, canaryCfg.Spec.Trigger, canaryCfg.Spec.NewFunction, canaryCfg.Spec.OldFunction, canaryCfg.Spec.WeightIncrement, canaryCfg.Spec.WeightIncrementDuration,
-		canaryCfg.Spec.FailureThreshold, canaryCfg.Spec.FailureType, canaryCfg.Status.Status)
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", "NAME", "TRIGGER", "FUNCTION-N", "FUNCTION-N-1", "STRATEGY", "WEIGHT-INCREMENT", "INTERVAL", "FAILURE-THRESHOLD", "FAILURE-TYPE", "LATENCY", "MIN-REQUESTS", "STATUS")
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+		canaryCfg.ObjectMeta.Name, canaryCfg.Spec.Trigger, canaryCfg.Spec.NewFunction, canaryCfg.Spec.OldFunction, strategy(canaryCfg.Spec), weightIncrement(canaryCfg.Spec), canaryCfg.Spec.WeightIncrementDuration,
+		canaryCfg.Spec.FailureThreshold, canaryCfg.Spec.FailureType, latency(canaryCfg.Spec), canaryCfg.Spec.MinRequests, status(canaryCfg))
+	w.Flush()
+
+	events := timeline(&canaryCfg.Status)
+	if len(canaryCfg.Status.FunctionWeights) == 0 && len(events) == 0 {
+		return nil
+	}
 
+	fmt.Printf("\nWeights: %v (step %v)\n\n", weights(canaryCfg.Status.FunctionWeights), canaryCfg.Status.Step)
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", "TIME", "EVENT", "WEIGHTS", "REQUESTS", "FAILURE-PERCENTAGE", "LATENCY", "DETAILS")
+	for _, e := range events {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			e.time.Format(time.RFC3339), e.event, weights(e.weights), e.requests, e.failurePercentage, e.latency, e.details)
+	}
 	w.Flush()
 	return nil
 }
+
+// timelineEvent is a weight increment interval or a transition of a canary config
+type timelineEvent struct {
+	time              time.Time
+	event             string
+	weights           map[string]int
+	requests          string
+	failurePercentage string
+	latency           string
+	details           string
+}
+
+// timeline returns the weight increment intervals and transitions of a canary config, the oldest first.
+func timeline(status *fv1.CanaryConfigStatus) []timelineEvent {
+	var events []timelineEvent
+	for _, step := range status.History {
+		e := timelineEvent{
+			time:              step.Time.Time,
+			event:             fmt.Sprintf("step %v", step.Step),
+			weights:           step.FunctionWeights,
+			requests:          "-",
+			failurePercentage: "-",
+			latency:           "-",
+		}
+		if a := step.Analysis; a != nil {
+			e.event = fmt.Sprintf("step %v (%v)", step.Step, a.Verdict)
+			e.requests = strconv.Itoa(a.Requests)
+			if len(a.FailurePercentage) > 0 {
+				e.failurePercentage = a.FailurePercentage + "%"
+			}
+			if len(a.Latency) > 0 {
+				e.latency = a.Latency
+			}
+			var details []string
+			if len(a.Reason) > 0 {
+				details = append(details, a.Reason)
+			}
+			if len(a.OldLatency) > 0 {
+				details = append(details, "old latency "+a.OldLatency)
+			}
+			for _, name := range sortedKeys(a.Queries) {
+				details = append(details, fmt.Sprintf("%v=%v", name, a.Queries[name]))
+			}
+			e.details = strings.Join(details, ", ")
+		}
+		events = append(events, e)
+	}
+	for _, t := range status.Transitions {
+		events = append(events, timelineEvent{
+			time:              t.Time.Time,
+			event:             string(t.Type),
+			requests:          "-",
+			failurePercentage: "-",
+			latency:           "-",
+			details:           t.Message,
+		})
+		if len(events[len(events)-1].details) == 0 {
+			events[len(events)-1].details = fmt.Sprintf("new function weight %v", t.Weight)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].time.Before(events[j].time)
+	})
+	return events
+}
+
+// weights returns the weights of functions sorted by name, such as "fn-v1=80,fn-v2=20".
+func weights(functionWeights map[string]int) string {
+	if len(functionWeights) == 0 {
+		return "-"
+	}
+	var s []string
+	for fn, weight := range functionWeights {
+		s = append(s, fmt.Sprintf("%v=%v", fn, weight))
+	}
+	sort.Strings(s)
+	return strings.Join(s, ",")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// latency returns the latency percentile and threshold analysed by the
+// latency failure types, such as "p99<500ms".
+func latency(spec fv1.CanaryConfigSpec) string {
+	percentile := spec.LatencyPercentile
+	if percentile == 0 {
+		percentile = fv1.DefaultCanaryLatencyPercentile
+	}
+	switch spec.FailureType {
+	case fv1.FailureTypeLatency:
+		return fmt.Sprintf("p%v<%v", percentile, spec.LatencyThreshold)
+	case fv1.FailureTypeRelativeLatency:
+		return fmt.Sprintf("p%v<+%v%%", percentile, spec.FailureThreshold)
+	default:
+		return "-"
+	}
+}
+
+// strategy returns the strategy of a canary config, with the smoke test
+// function of the bluegreen strategy, such as "bluegreen (smoke-test: fn)".
+func strategy(spec fv1.CanaryConfigSpec) string {
+	switch {
+	case spec.Strategy == fv1.CanaryStrategyBlueGreen && len(spec.SmokeTestFunction) > 0:
+		return fmt.Sprintf("%v (smoke-test: %v)", spec.Strategy, spec.SmokeTestFunction)
+	case len(spec.Strategy) > 0:
+		return string(spec.Strategy)
+	default:
+		return string(fv1.CanaryStrategyCanary)
+	}
+}
+
+// weightIncrement returns the weight increment of a canary config, or its
+// step schedule, such as "1%=1m,5%=5m,25%,100%".
+func weightIncrement(spec fv1.CanaryConfigSpec) string {
+	switch {
+	case spec.Strategy == fv1.CanaryStrategyBlueGreen:
+		return "-"
+	case len(spec.Steps) > 0:
+		var steps []string
+		for _, step := range spec.Steps {
+			s := fmt.Sprintf("%v%%", step.Weight)
+			if len(step.Duration) > 0 {
+				s += "=" + step.Duration
+			}
+			steps = append(steps, s)
+		}
+		return strings.Join(steps, ",")
+	default:
+		return strconv.Itoa(spec.WeightIncrement)
+	}
+}
+
+// status returns the status of a canary config, and whether it's paused.
+func status(canaryCfg *fv1.CanaryConfig) string {
+	if canaryCfg.Spec.Paused && canaryCfg.Status.Status == fv1.CanaryConfigStatusPending {
+		return canaryCfg.Status.Status + " (paused)"
+	}
+	return canaryCfg.Status.Status
+}
@@ -82,6 +82,55 @@ func (opts *UpdateSubCommand) complete(input cli.Input) error {
 		canaryCfg.Spec.WeightIncrementDuration = incrementInterval
 	}
 
+	if input.IsSet(flagkey.CanaryFailureType) {
+		canaryCfg.Spec.FailureType = fv1.FailureType(input.String(flagkey.CanaryFailureType))
+	}
+
+	if input.IsSet(flagkey.CanaryLatencyPercentile) {
+		canaryCfg.Spec.LatencyPercentile = input.Int(flagkey.CanaryLatencyPercentile)
+	}
+
+	if input.IsSet(flagkey.CanaryLatencyThreshold) {
+		canaryCfg.Spec.LatencyThreshold = input.String(flagkey.CanaryLatencyThreshold)
+	}
+
+	if input.IsSet(flagkey.CanaryMinRequests) {
+		canaryCfg.Spec.MinRequests = input.Int(flagkey.CanaryMinRequests)
+	}
+
+	if input.IsSet(flagkey.CanaryMetricsProvider) || input.IsSet(flagkey.CanaryMetricsURL) {
+		canaryCfg.Spec.MetricsProvider = metricsProvider(input)
+	}
+
+	if input.IsSet(flagkey.CanaryQuery) || input.IsSet(flagkey.CanaryQueryMin) || input.IsSet(flagkey.CanaryQueryMax) {
+		queries, err := metricQueries(input)
+		if err != nil {
+			return err
+		}
+		canaryCfg.Spec.Queries = queries
+	}
+
+	if input.IsSet(flagkey.CanaryStrategy) {
+		canaryCfg.Spec.Strategy = fv1.CanaryStrategy(input.String(flagkey.CanaryStrategy))
+	}
+
+	if input.IsSet(flagkey.CanarySchedule) {
+		steps, err := weightSteps(input)
+		if err != nil {
+			return err
+		}
+		canaryCfg.Spec.Steps = steps
+	}
+
+	if input.IsSet(flagkey.CanarySmokeTestFunc) {
+		canaryCfg.Spec.SmokeTestFunction = input.String(flagkey.CanarySmokeTestFunc)
+	}
+
+	err = canaryCfg.Validate()
+	if err != nil {
+		return fv1.AggregateValidationErrors("CanaryConfig", err)
+	}
+
 	if updateNeeded {
 		canaryCfg.Status.Status = fv1.CanaryConfigStatusPending
 	}
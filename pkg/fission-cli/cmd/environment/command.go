@@ -32,9 +32,10 @@ func Commands() *cobra.Command {
 	wrapper.SetFlags(createCmd, flag.FlagSet{
 		Required: []flag.Flag{flag.EnvName, flag.EnvImage},
 		Optional: []flag.Flag{
-			flag.EnvPoolsize, flag.EnvBuilderImage, flag.EnvBuildCmd,
+			flag.EnvPoolsize, flag.EnvBuilderImage, flag.EnvBuildCmd, flag.EnvBuildTimeout, flag.EnvBuildRetries, flag.EnvOCIRepository,
 			flag.RunTimeMinCPU, flag.RunTimeMaxCPU, flag.RunTimeMinMemory, flag.RunTimeMaxMemory,
-			flag.EnvTerminationGracePeriod, flag.EnvVersion, flag.EnvImagePullSecret, flag.EnvKeepArchive,
+			flag.EnvTerminationGracePeriod, flag.EnvVersion, flag.EnvImagePullSecret, flag.EnvKeepArchive, flag.EnvReload,
+			flag.EnvSignatureKey, flag.EnvBuilderSignKey,
 			flag.NamespaceEnvironment, flag.EnvExternalNetwork,
 			flag.Labels, flag.Annotation,
 			flag.SpecSave, flag.SpecDry},
@@ -58,9 +59,9 @@ func Commands() *cobra.Command {
 	wrapper.SetFlags(updateCmd, flag.FlagSet{
 		Required: []flag.Flag{flag.EnvName},
 		Optional: []flag.Flag{flag.EnvImage, flag.EnvPoolsize,
-			flag.EnvBuilderImage, flag.EnvBuildCmd, flag.EnvImagePullSecret,
+			flag.EnvBuilderImage, flag.EnvBuildCmd, flag.EnvBuildTimeout, flag.EnvBuildRetries, flag.EnvOCIRepository, flag.EnvImagePullSecret,
 			flag.RunTimeMinCPU, flag.RunTimeMaxCPU, flag.RunTimeMinMemory, flag.RunTimeMaxMemory,
-			flag.EnvTerminationGracePeriod, flag.EnvKeepArchive,
+			flag.EnvTerminationGracePeriod, flag.EnvKeepArchive, flag.EnvReload, flag.EnvSignatureKey, flag.EnvBuilderSignKey,
 			flag.NamespaceEnvironment, flag.EnvExternalNetwork,
 			flag.Labels, flag.Annotation},
 	})
@@ -143,6 +143,14 @@ func createEnvironmentFromCmd(input cli.Input) (*fv1.Environment, error) {
 		}
 	}
 
+	var ociRepository *fv1.OCIRepository
+	if repository := input.String(flagkey.EnvOCIRepository); len(repository) > 0 {
+		ociRepository = &fv1.OCIRepository{
+			Repository:      repository,
+			ImagePullSecret: pullSecret,
+		}
+	}
+
 	resourceReq, err := util.GetResourceReqs(input, nil)
 	if err != nil {
 		e = multierror.Append(e, err)
@@ -164,11 +172,15 @@ func createEnvironmentFromCmd(input cli.Input) (*fv1.Environment, error) {
 		Spec: fv1.EnvironmentSpec{
 			Version: envVersion,
 			Runtime: fv1.Runtime{
-				Image: envImg,
+				Image:  envImg,
+				Reload: input.Bool(flagkey.EnvReload),
 			},
 			Builder: fv1.Builder{
-				Image:   envBuilderImg,
-				Command: envBuildCmd,
+				Image:         envBuilderImg,
+				Command:       envBuildCmd,
+				BuildTimeout:  input.Int(flagkey.EnvBuildTimeout),
+				BuildRetries:  input.Int(flagkey.EnvBuildRetries),
+				OCIRepository: ociRepository,
 			},
 			Poolsize:                     poolsize,
 			Resources:                    *resourceReq,
@@ -179,6 +191,15 @@ func createEnvironmentFromCmd(input cli.Input) (*fv1.Environment, error) {
 		},
 	}
 
+	if keySecrets := input.StringSlice(flagkey.EnvSignatureKey); len(keySecrets) > 0 {
+		env.Spec.SignaturePolicy = &fv1.SignaturePolicy{
+			KeySecrets:       keySecrets,
+			BuilderKeySecret: input.String(flagkey.EnvBuilderSignKey),
+		}
+	} else if input.IsSet(flagkey.EnvBuilderSignKey) {
+		return nil, errors.Errorf("--%v requires trusted keys given with --%v", flagkey.EnvBuilderSignKey, flagkey.EnvSignatureKey)
+	}
+
 	err = util.ApplyLabelsAndAnnotations(input, &env.ObjectMeta)
 	if err != nil {
 		return nil, err
@@ -93,6 +93,10 @@ func updateExistingEnvironmentWithCmd(env *fv1.Environment, input cli.Input) (*f
 		env.Spec.Runtime.Image = input.String(flagkey.EnvImage)
 	}
 
+	if input.IsSet(flagkey.EnvReload) {
+		env.Spec.Runtime.Reload = input.Bool(flagkey.EnvReload)
+	}
+
 	if input.IsSet(flagkey.EnvBuilderImage) {
 		env.Spec.Builder.Image = input.String(flagkey.EnvBuilderImage)
 	}
@@ -101,6 +105,14 @@ func updateExistingEnvironmentWithCmd(env *fv1.Environment, input cli.Input) (*f
 		env.Spec.Builder.Command = input.String(flagkey.EnvBuildcommand)
 	}
 
+	if input.IsSet(flagkey.EnvBuildTimeout) {
+		env.Spec.Builder.BuildTimeout = input.Int(flagkey.EnvBuildTimeout)
+	}
+
+	if input.IsSet(flagkey.EnvBuildRetries) {
+		env.Spec.Builder.BuildRetries = input.Int(flagkey.EnvBuildRetries)
+	}
+
 	if env.Spec.Version == 1 && (len(env.Spec.Builder.Image) > 0 || len(env.Spec.Builder.Command) > 0) {
 		e = multierror.Append(e, errors.New("version 1 Environments do not support builders. Must specify --version=2"))
 	}
@@ -127,6 +139,41 @@ func updateExistingEnvironmentWithCmd(env *fv1.Environment, input cli.Input) (*f
 		env.Spec.ImagePullSecret = input.String(flagkey.EnvImagePullSecret)
 	}
 
+	if input.IsSet(flagkey.EnvOCIRepository) {
+		env.Spec.Builder.OCIRepository = nil
+		if repository := input.String(flagkey.EnvOCIRepository); len(repository) > 0 {
+			env.Spec.Builder.OCIRepository = &fv1.OCIRepository{
+				Repository:      repository,
+				ImagePullSecret: env.Spec.ImagePullSecret,
+			}
+		}
+	}
+
+	if input.IsSet(flagkey.EnvSignatureKey) {
+		var keySecrets []string
+		for _, name := range input.StringSlice(flagkey.EnvSignatureKey) {
+			if len(name) > 0 {
+				keySecrets = append(keySecrets, name)
+			}
+		}
+		if len(keySecrets) == 0 {
+			env.Spec.SignaturePolicy = nil
+		} else {
+			if env.Spec.SignaturePolicy == nil {
+				env.Spec.SignaturePolicy = &fv1.SignaturePolicy{}
+			}
+			env.Spec.SignaturePolicy.KeySecrets = keySecrets
+		}
+	}
+
+	if input.IsSet(flagkey.EnvBuilderSignKey) {
+		if env.Spec.SignaturePolicy == nil {
+			e = multierror.Append(e, errors.Errorf("--%v requires trusted keys given with --%v", flagkey.EnvBuilderSignKey, flagkey.EnvSignatureKey))
+		} else {
+			env.Spec.SignaturePolicy.BuilderKeySecret = input.String(flagkey.EnvBuilderSignKey)
+		}
+	}
+
 	if input.IsSet(flagkey.RuntimeMincpu) {
 		mincpu := input.Int(flagkey.RuntimeMincpu)
 		cpuRequest, err := resource.ParseQuantity(strconv.Itoa(mincpu) + "m")
@@ -18,10 +18,12 @@ package kubewatch
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/fission-cli/cliwrapper/cli"
@@ -60,6 +62,20 @@ func (opts *CreateSubCommand) complete(input cli.Input) error {
 	namespace := input.String(flagkey.KwNamespace)
 	objType := input.String(flagkey.KwObjType)
 
+	labelSelector, err := labels.ConvertSelectorToLabelsMap(input.String(flagkey.KwLabels))
+	if err != nil {
+		return errors.Wrap(err, "error parsing label selector")
+	}
+
+	var predicates []fv1.WatchPredicate
+	for _, p := range input.StringSlice(flagkey.KwPredicate) {
+		predicate, err := parsePredicate(p)
+		if err != nil {
+			return err
+		}
+		predicates = append(predicates, predicate)
+	}
+
 	if input.Bool(flagkey.SpecSave) {
 		specDir := util.GetSpecDir(input)
 		fr, err := spec.ReadSpecs(specDir)
@@ -88,9 +104,15 @@ func (opts *CreateSubCommand) complete(input cli.Input) error {
 			Namespace: fnNamespace,
 		},
 		Spec: fv1.KubernetesWatchTriggerSpec{
-			Namespace: namespace,
-			Type:      objType,
-			//LabelSelector: labels,
+			Namespace:     namespace,
+			Type:          objType,
+			Group:         input.String(flagkey.KwGroup),
+			Version:       input.String(flagkey.KwVersion),
+			Resource:      input.String(flagkey.KwResource),
+			LabelSelector: labelSelector,
+			FieldSelector: input.String(flagkey.KwFields),
+			EventTypes:    input.StringSlice(flagkey.KwEventType),
+			Predicates:    predicates,
 			FunctionReference: fv1.FunctionReference{
 				Name: fnName,
 				Type: fv1.FunctionReferenceTypeFunctionName,
@@ -98,7 +120,58 @@ func (opts *CreateSubCommand) complete(input cli.Input) error {
 		},
 	}
 
-	return nil
+	return opts.watcher.Spec.Validate()
+}
+
+// parsePredicate parses a predicate given as '{.path}=a|b', '{.path}!=a|b',
+// '{.path}' or '!{.path}'. The braces around the path may be omitted.
+func parsePredicate(s string) (fv1.WatchPredicate, error) {
+	var p fv1.WatchPredicate
+	negated := strings.HasPrefix(s, "!")
+	path, rest := strings.TrimPrefix(s, "!"), ""
+	if strings.HasPrefix(path, "{") {
+		// the path may contain operators in filter expressions
+		depth := 0
+		for i, c := range path {
+			if c == '{' {
+				depth++
+			} else if c == '}' {
+				depth--
+				if depth == 0 {
+					path, rest = path[:i+1], path[i+1:]
+					break
+				}
+			}
+		}
+	} else if i := strings.Index(path, "="); i >= 0 {
+		path, rest = path[:i], path[i:]
+		if strings.HasSuffix(path, "!") {
+			path, rest = path[:len(path)-1], "!"+rest
+		}
+	}
+	if !strings.HasPrefix(path, "{") {
+		path = "{" + path + "}"
+	}
+	p.Path = path
+
+	switch {
+	case len(rest) == 0 && negated:
+		p.Operator = fv1.WatchPredicateOpDoesNotExist
+	case len(rest) == 0:
+		p.Operator = fv1.WatchPredicateOpExists
+	case negated:
+		return p, errors.Errorf("error parsing predicate %q: '!' is only allowed before a path without values", s)
+	case strings.HasPrefix(rest, "!="):
+		p.Operator = fv1.WatchPredicateOpNotIn
+		p.Values = strings.Split(rest[2:], "|")
+	case strings.HasPrefix(rest, "="):
+		p.Operator = fv1.WatchPredicateOpIn
+		p.Values = strings.Split(rest[1:], "|")
+	default:
+		return p, errors.Errorf("error parsing predicate %q: unexpected %q after path", s, rest)
+	}
+
+	return p, p.Validate()
 }
 
 func (opts *CreateSubCommand) run(input cli.Input) error {
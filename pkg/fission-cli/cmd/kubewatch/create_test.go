@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+func TestParsePredicate(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected fv1.WatchPredicate
+		err      bool
+	}{
+		{
+			input:    "{.status.phase}=Failed|Unknown",
+			expected: fv1.WatchPredicate{Path: "{.status.phase}", Operator: fv1.WatchPredicateOpIn, Values: []string{"Failed", "Unknown"}},
+		},
+		{
+			input:    ".status.phase!=Running",
+			expected: fv1.WatchPredicate{Path: "{.status.phase}", Operator: fv1.WatchPredicateOpNotIn, Values: []string{"Running"}},
+		},
+		{
+			input:    "{.metadata.labels.app}",
+			expected: fv1.WatchPredicate{Path: "{.metadata.labels.app}", Operator: fv1.WatchPredicateOpExists},
+		},
+		{
+			input:    "!{.metadata.deletionTimestamp}",
+			expected: fv1.WatchPredicate{Path: "{.metadata.deletionTimestamp}", Operator: fv1.WatchPredicateOpDoesNotExist},
+		},
+		{
+			input:    `{.spec.containers[?(@.name=="web")].image}=nginx`,
+			expected: fv1.WatchPredicate{Path: `{.spec.containers[?(@.name=="web")].image}`, Operator: fv1.WatchPredicateOpIn, Values: []string{"nginx"}},
+		},
+		{input: "!{.status.phase}=Running", err: true},
+		{input: "{.status.phase}~Running", err: true},
+	} {
+		t.Run(test.input, func(t *testing.T) {
+			p, err := parsePredicate(test.input)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, p)
+		})
+	}
+}
@@ -61,8 +61,15 @@ func (opts *ListSubCommand) run(input cli.Input) error {
 	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n",
 		"NAME", "NAMESPACE", "OBJTYPE", "LABELS", "FUNCTION_NAME")
 	for _, wa := range ws {
+		objType := wa.Spec.Type
+		if len(wa.Spec.Resource) > 0 {
+			objType = wa.Spec.Resource
+		}
+		if len(wa.Spec.Group) > 0 {
+			objType += "." + wa.Spec.Group
+		}
 		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n",
-			wa.ObjectMeta.Name, wa.Spec.Namespace, wa.Spec.Type, wa.Spec.LabelSelector, wa.Spec.FunctionReference.Name)
+			wa.ObjectMeta.Name, wa.Spec.Namespace, objType, wa.Spec.LabelSelector, wa.Spec.FunctionReference.Name)
 	}
 	w.Flush()
 
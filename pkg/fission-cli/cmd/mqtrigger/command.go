@@ -74,13 +74,39 @@ func Commands() *cobra.Command {
 		Optional: []flag.Flag{flag.NamespaceTrigger},
 	})
 
+	dlqListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List messages in the error topic of a message queue trigger",
+		RunE:  wrapper.Wrapper(DlqList),
+	}
+	wrapper.SetFlags(dlqListCmd, flag.FlagSet{
+		Required: []flag.Flag{flag.MqtName, flag.MqtDlqServer},
+		Optional: []flag.Flag{flag.NamespaceTrigger, flag.MqtDlqSecret, flag.MqtDlqSince, flag.MqtDlqUntil, flag.MqtDlqCount},
+	})
+
+	dlqReplayCmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Re-publish messages in the error topic to the topic of a message queue trigger",
+		RunE:  wrapper.Wrapper(DlqReplay),
+	}
+	wrapper.SetFlags(dlqReplayCmd, flag.FlagSet{
+		Required: []flag.Flag{flag.MqtName, flag.MqtDlqServer},
+		Optional: []flag.Flag{flag.NamespaceTrigger, flag.MqtDlqSecret, flag.MqtDlqSince, flag.MqtDlqUntil, flag.MqtDlqCount, flag.MqtDlqID},
+	})
+
+	dlqCmd := &cobra.Command{
+		Use:   "dlq",
+		Short: "Inspect and replay messages in the error topic of a message queue trigger",
+	}
+	dlqCmd.AddCommand(dlqListCmd, dlqReplayCmd)
+
 	command := &cobra.Command{
 		Use:     "mqtrigger",
 		Aliases: []string{"mqt"},
 		Short:   "Create, update and manage message queue triggers",
 	}
 
-	command.AddCommand(createCmd, updateCmd, deleteCmd, listCmd)
+	command.AddCommand(createCmd, updateCmd, deleteCmd, listCmd, dlqCmd)
 
 	return command
 }
@@ -0,0 +1,214 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtrigger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/fission-cli/cliwrapper/cli"
+	"github.com/fission/fission/pkg/fission-cli/cmd"
+	flagkey "github.com/fission/fission/pkg/fission-cli/flag/key"
+	"github.com/fission/fission/pkg/fission-cli/util"
+	"github.com/fission/fission/pkg/mqtrigger/factory"
+	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+	_ "github.com/fission/fission/pkg/mqtrigger/messageQueue/kafka"
+	"github.com/fission/fission/pkg/mqtrigger/validator"
+)
+
+type DlqSubCommand struct {
+	cmd.CommandActioner
+	trigger *fv1.MessageQueueTrigger
+	dlq     messageQueue.DeadLetterQueue
+	filter  messageQueue.DeadLetterFilter
+	ids     []string
+}
+
+func DlqList(input cli.Input) error {
+	return (&DlqSubCommand{}).doList(input)
+}
+
+func DlqReplay(input cli.Input) error {
+	return (&DlqSubCommand{}).doReplay(input)
+}
+
+func (opts *DlqSubCommand) doList(input cli.Input) error {
+	err := opts.complete(input)
+	if err != nil {
+		return err
+	}
+	defer opts.dlq.Close()
+	return opts.runList(input)
+}
+
+func (opts *DlqSubCommand) doReplay(input cli.Input) error {
+	err := opts.complete(input)
+	if err != nil {
+		return err
+	}
+	defer opts.dlq.Close()
+	return opts.runReplay(input)
+}
+
+func (opts *DlqSubCommand) complete(input cli.Input) error {
+	mqt, err := opts.Client().V1().MessageQueueTrigger().Get(&metav1.ObjectMeta{
+		Name:      input.String(flagkey.MqtName),
+		Namespace: input.String(flagkey.NamespaceTrigger),
+	})
+	if err != nil {
+		return errors.Wrap(err, "error getting message queue trigger")
+	}
+	if len(mqt.Spec.ErrorTopic) == 0 {
+		return errors.Errorf("message queue trigger %q has no error topic", mqt.ObjectMeta.Name)
+	}
+	if !validator.SupportsDeadLetterQueue(string(mqt.Spec.MessageQueueType), mqt.Spec.MqtKind) {
+		return errors.Errorf("the error topics of %v message queue triggers of kind %v can't be replayed", mqt.Spec.MessageQueueType, mqt.Spec.MqtKind)
+	}
+	opts.trigger = mqt
+
+	secrets, err := readSecrets(input, mqt)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if since := input.Duration(flagkey.MqtDlqSince); since > 0 {
+		opts.filter.Since = now.Add(-since)
+	}
+	if until := input.Duration(flagkey.MqtDlqUntil); until > 0 {
+		opts.filter.Until = now.Add(-until)
+	}
+	opts.filter.Count = input.Int(flagkey.MqtDlqCount)
+	opts.ids = input.StringSlice(flagkey.MqtDlqID)
+
+	opts.dlq, err = factory.CreateDeadLetterQueue(zap.NewNop(), mqt.Spec.MessageQueueType, messageQueue.Config{
+		MQType:  string(mqt.Spec.MessageQueueType),
+		Url:     input.String(flagkey.MqtDlqServer),
+		Secrets: secrets,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error connecting to message queue")
+	}
+
+	return nil
+}
+
+// readSecrets reads the secret to connect to the message queue server with,
+// given with the flags or referenced by the trigger.
+func readSecrets(input cli.Input, mqt *fv1.MessageQueueTrigger) (map[string][]byte, error) {
+	ref := input.String(flagkey.MqtDlqSecret)
+	if len(ref) == 0 {
+		ref = mqt.Spec.Secret
+	}
+	if len(ref) == 0 {
+		return nil, nil
+	}
+
+	ns, name := mqt.ObjectMeta.Namespace, ref
+	if parts := strings.SplitN(ref, "/", 2); len(parts) == 2 {
+		ns, name = parts[0], parts[1]
+	}
+
+	_, kubeClient, err := util.GetKubernetesClient(input.String(flagkey.KubeContext))
+	if err != nil {
+		return nil, err
+	}
+	secret, err := kubeClient.CoreV1().Secrets(ns).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting secret %v/%v", ns, name)
+	}
+	return secret.Data, nil
+}
+
+// selectMessages reads the error topic and keeps the messages
+// matching the IDs given by the user, if any.
+func (opts *DlqSubCommand) selectMessages() ([]messageQueue.DeadLetterMessage, error) {
+	msgs, err := opts.dlq.ListDeadLetters(opts.trigger, opts.filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading error topic")
+	}
+	if len(opts.ids) == 0 {
+		return msgs, nil
+	}
+
+	wanted := make(map[string]bool, len(opts.ids))
+	for _, id := range opts.ids {
+		wanted[id] = true
+	}
+	var selected []messageQueue.DeadLetterMessage
+	for _, msg := range msgs {
+		if wanted[msg.ID] {
+			selected = append(selected, msg)
+		}
+	}
+	return selected, nil
+}
+
+func (opts *DlqSubCommand) runList(input cli.Input) error {
+	msgs, err := opts.selectMessages()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", "ID", "TIMESTAMP", "REPLAYABLE", "HEADERS", "ERROR")
+	for _, msg := range msgs {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n",
+			msg.ID, msg.Timestamp.Format(time.RFC3339), len(msg.Source) > 0 || msg.Payload != nil, formatHeaders(msg.Headers), msg.Error)
+	}
+	w.Flush()
+
+	return nil
+}
+
+func (opts *DlqSubCommand) runReplay(input cli.Input) error {
+	msgs, err := opts.selectMessages()
+	if err != nil {
+		return err
+	}
+	if len(msgs) == 0 {
+		fmt.Println("no messages to replay")
+		return nil
+	}
+
+	err = opts.dlq.ReplayDeadLetters(opts.trigger, msgs)
+	if err != nil {
+		return errors.Wrap(err, "error replaying messages")
+	}
+
+	fmt.Printf("%v message(s) replayed to topic '%v'\n", len(msgs), opts.trigger.Spec.Topic)
+	return nil
+}
+
+func formatHeaders(headers map[string]string) string {
+	pairs := make([]string, 0, len(headers))
+	for k, v := range headers {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package _package
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/fission/fission/pkg/fission-cli/cliwrapper/cli"
+	"github.com/fission/fission/pkg/fission-cli/cmd"
+	flagkey "github.com/fission/fission/pkg/fission-cli/flag/key"
+)
+
+type CancelSubCommand struct {
+	cmd.CommandActioner
+	name      string
+	namespace string
+}
+
+func Cancel(input cli.Input) error {
+	return (&CancelSubCommand{}).do(input)
+}
+
+func (opts *CancelSubCommand) do(input cli.Input) error {
+	err := opts.complete(input)
+	if err != nil {
+		return err
+	}
+	return opts.run(input)
+}
+
+func (opts *CancelSubCommand) complete(input cli.Input) error {
+	opts.name = input.String(flagkey.PkgName)
+	opts.namespace = input.String(flagkey.NamespacePackage)
+	return nil
+}
+
+func (opts *CancelSubCommand) run(input cli.Input) error {
+	err := opts.Client().V1().Package().CancelBuild(&metav1.ObjectMeta{
+		Name:      opts.name,
+		Namespace: opts.namespace,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error cancelling build of package %s", opts.name)
+	}
+
+	fmt.Printf("Cancelled build for pkg %v. Use \"fission pkg info --name %v\" to view status.\n", opts.name, opts.name)
+	return nil
+}
@@ -33,7 +33,8 @@ func Commands() *cobra.Command {
 		Required: []flag.Flag{flag.PkgEnvironment},
 		Optional: []flag.Flag{flag.PkgName, flag.PkgCode, flag.PkgSrcArchive, flag.PkgDeployArchive,
 			flag.PkgSrcChecksum, flag.PkgDeployChecksum, flag.PkgInsecure, flag.PkgBuildCmd,
-			flag.NamespacePackage, flag.NamespaceEnvironment, flag.SpecSave, flag.SpecDry},
+			flag.PkgBuildTimeout, flag.PkgBuildRetries, flag.PkgOCIRepository, flag.PkgPullSecret,
+			flag.PkgSignKey, flag.NamespacePackage, flag.NamespaceEnvironment, flag.SpecSave, flag.SpecDry},
 	})
 
 	getSrcCmd := &cobra.Command{
@@ -65,7 +66,8 @@ func Commands() *cobra.Command {
 		Required: []flag.Flag{flag.PkgName},
 		Optional: []flag.Flag{flag.PkgEnvironment, flag.PkgCode, flag.PkgSrcArchive, flag.PkgDeployArchive,
 			flag.PkgSrcChecksum, flag.PkgDeployChecksum, flag.PkgInsecure, flag.PkgBuildCmd, flag.PkgForce,
-			flag.NamespacePackage, flag.NamespaceEnvironment},
+			flag.PkgBuildTimeout, flag.PkgBuildRetries, flag.PkgOCIRepository, flag.PkgPullSecret,
+			flag.PkgSignKey, flag.NamespacePackage, flag.NamespaceEnvironment},
 	})
 
 	deleteCmd := &cobra.Command{
@@ -94,7 +96,7 @@ func Commands() *cobra.Command {
 	}
 	wrapper.SetFlags(infoCmd, flag.FlagSet{
 		Required: []flag.Flag{flag.PkgName},
-		Optional: []flag.Flag{flag.NamespacePackage},
+		Optional: []flag.Flag{flag.NamespacePackage, flag.PkgFollow},
 	})
 
 	rebuildCmd := &cobra.Command{
@@ -103,17 +105,44 @@ func Commands() *cobra.Command {
 		RunE:  wrapper.Wrapper(Rebuild),
 	}
 	wrapper.SetFlags(rebuildCmd, flag.FlagSet{
+		Required: []flag.Flag{flag.PkgName},
+		Optional: []flag.Flag{flag.NamespacePackage, flag.PkgFollow},
+	})
+
+	cancelCmd := &cobra.Command{
+		Use:   "cancel",
+		Short: "Cancel the running build of a package",
+		RunE:  wrapper.Wrapper(Cancel),
+	}
+	wrapper.SetFlags(cancelCmd, flag.FlagSet{
+		Required: []flag.Flag{flag.PkgName},
+		Optional: []flag.Flag{flag.NamespacePackage},
+	})
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the deployment archive of a package against its build provenance",
+		RunE:  wrapper.Wrapper(Verify),
+	}
+	wrapper.SetFlags(verifyCmd, flag.FlagSet{
 		Required: []flag.Flag{flag.PkgName},
 		Optional: []flag.Flag{flag.NamespacePackage},
 	})
 
+	orphansCmd := &cobra.Command{
+		Use:   "orphans",
+		Short: "List archives in storage not referenced by any package, and what would be pruned",
+		RunE:  wrapper.Wrapper(Orphans),
+	}
+	wrapper.SetFlags(orphansCmd, flag.FlagSet{})
+
 	command := &cobra.Command{
 		Use:     "package",
 		Aliases: []string{"pkg"},
 		Short:   "Create, update and manage packages",
 	}
 
-	command.AddCommand(createCmd, getSrcCmd, getDeployCmd, updateCmd, deleteCmd, listCmd, infoCmd, rebuildCmd)
+	command.AddCommand(createCmd, getSrcCmd, getDeployCmd, updateCmd, deleteCmd, listCmd, infoCmd, rebuildCmd, cancelCmd, verifyCmd, orphansCmd)
 
 	return command
 }
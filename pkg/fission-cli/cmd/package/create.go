@@ -155,10 +155,32 @@ func CreatePackage(input cli.Input, client client.Interface, pkgName string, pkg
 		}
 	}
 
+	err := signArchives(input, &pkgSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "error signing archives")
+	}
+
 	if len(buildcmd) > 0 {
 		pkgSpec.BuildCommand = buildcmd
 	}
 
+	if input.IsSet(flagkey.PkgBuildTimeout) {
+		buildTimeout := input.Int(flagkey.PkgBuildTimeout)
+		pkgSpec.BuildTimeout = &buildTimeout
+	}
+
+	if input.IsSet(flagkey.PkgBuildRetries) {
+		buildRetries := input.Int(flagkey.PkgBuildRetries)
+		pkgSpec.BuildRetries = &buildRetries
+	}
+
+	if repository := input.String(flagkey.PkgOCIRepository); len(repository) > 0 {
+		pkgSpec.OCIRepository = &fv1.OCIRepository{
+			Repository:      repository,
+			ImagePullSecret: input.String(flagkey.PkgPullSecret),
+		}
+	}
+
 	if len(pkgName) == 0 {
 		pkgName = strings.ToLower(uuid.NewV4().String())
 	}
@@ -18,6 +18,7 @@ package _package
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
 
@@ -80,10 +81,17 @@ func (opts *GetSubCommand) run(input cli.Input) error {
 		archive = pkg.Spec.Deployment
 	}
 
-	if pkg.Spec.Deployment.Type == fv1.ArchiveTypeLiteral {
+	if archive.Type == fv1.ArchiveTypeLiteral {
 		reader = bytes.NewReader(archive.Literal)
-	} else if pkg.Spec.Deployment.Type == fv1.ArchiveTypeUrl {
-		readCloser, err := pkgutil.DownloadStoragesvcURL(opts.Client(), archive.URL)
+	} else if archive.Type == fv1.ArchiveTypeUrl {
+		readCloser, err := pkgutil.DownloadStoragesvcURL(context.Background(), opts.Client(), archive.URL)
+		if err != nil {
+			return err
+		}
+		defer readCloser.Close()
+		reader = readCloser
+	} else if archive.Type == fv1.ArchiveTypeOCI {
+		readCloser, err := pkgutil.PullOCIArchive(context.Background(), archive)
 		if err != nil {
 			return err
 		}
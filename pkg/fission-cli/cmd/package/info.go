@@ -17,11 +17,14 @@ limitations under the License.
 package _package
 
 import (
+	"io"
 	"os"
 
 	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/controller/client"
 	"github.com/fission/fission/pkg/fission-cli/cliwrapper/cli"
 	"github.com/fission/fission/pkg/fission-cli/cmd"
 	pkgutil "github.com/fission/fission/pkg/fission-cli/cmd/package/util"
@@ -32,6 +35,7 @@ type InfoSubCommand struct {
 	cmd.CommandActioner
 	name      string
 	namespace string
+	follow    bool
 }
 
 func Info(input cli.Input) error {
@@ -49,6 +53,7 @@ func (opts *InfoSubCommand) do(input cli.Input) error {
 func (opts *InfoSubCommand) complete(input cli.Input) error {
 	opts.name = input.String(flagkey.PkgName)
 	opts.namespace = input.String(flagkey.NamespacePackage)
+	opts.follow = input.Bool(flagkey.PkgFollow)
 	return nil
 }
 
@@ -60,6 +65,37 @@ func (opts *InfoSubCommand) run(input cli.Input) error {
 	if err != nil {
 		return errors.Wrapf(err, "error finding package %s", opts.name)
 	}
+
+	if opts.follow && (pkg.Status.BuildStatus == fv1.BuildStatusPending || pkg.Status.BuildStatus == fv1.BuildStatusRunning) {
+		pkg.Status.BuildLog = ""
+		pkgutil.PrintPackageSummary(os.Stdout, pkg)
+		return followBuildLogs(opts.Client(), pkg)
+	}
+
 	pkgutil.PrintPackageSummary(os.Stdout, pkg)
 	return nil
 }
+
+// followBuildLogs streams the build logs of a package until the build
+// completes, and reports a failed build as an error.
+func followBuildLogs(client client.Interface, pkg *fv1.Package) error {
+	logs, err := client.V1().Package().BuildLogs(&pkg.ObjectMeta, true)
+	if err != nil {
+		return errors.Wrap(err, "error getting build logs")
+	}
+	defer logs.Close()
+
+	_, err = io.Copy(os.Stdout, logs)
+	if err != nil {
+		return errors.Wrap(err, "error streaming build logs")
+	}
+
+	latest, err := client.V1().Package().Get(&pkg.ObjectMeta)
+	if err != nil {
+		return errors.Wrapf(err, "error finding package %s", pkg.ObjectMeta.Name)
+	}
+	if latest.Status.BuildStatus == fv1.BuildStatusFailed {
+		return errors.Errorf("build of package %v failed", pkg.ObjectMeta.Name)
+	}
+	return nil
+}
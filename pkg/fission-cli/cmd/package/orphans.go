@@ -0,0 +1,69 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package _package
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fission/fission/pkg/fission-cli/cliwrapper/cli"
+	"github.com/fission/fission/pkg/fission-cli/cmd"
+	pkgutil "github.com/fission/fission/pkg/fission-cli/cmd/package/util"
+	"github.com/fission/fission/pkg/storagesvc"
+)
+
+type OrphansSubCommand struct {
+	cmd.CommandActioner
+}
+
+func Orphans(input cli.Input) error {
+	return (&OrphansSubCommand{}).do(input)
+}
+
+func (opts *OrphansSubCommand) do(input cli.Input) error {
+	return opts.run(input)
+}
+
+// run lists the archives in the storage service no package references,
+// and whether the archive pruner prunes, keeps or waits to prune them.
+func (opts *OrphansSubCommand) run(input cli.Input) error {
+	report, err := pkgutil.ListOrphanArchives(context.Background(), opts.Client())
+	if err != nil {
+		return err
+	}
+
+	if report.DryRun {
+		fmt.Println("Archive pruner runs in dry-run mode, archives are not deleted.")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", "ID", "LASTMODIFIED", "ACTION", "PRUNEAFTER", "REASON")
+	for _, orphan := range report.Archives {
+		pruneAfter := ""
+		if orphan.PruneAfter != nil && orphan.Action != storagesvc.PruneActionRetain {
+			pruneAfter = orphan.PruneAfter.Format(time.RFC822)
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", orphan.ID, orphan.LastModified.Format(time.RFC822),
+			orphan.Action, pruneAfter, orphan.Reason)
+	}
+	w.Flush()
+
+	return nil
+}
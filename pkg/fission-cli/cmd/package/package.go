@@ -42,10 +42,14 @@ import (
 	"github.com/fission/fission/pkg/utils"
 )
 
+// ociArchivePrefix prefixes archives referencing OCI artifacts by digest.
+const ociArchivePrefix = "oci://"
+
 // CreateArchive returns a fv1.Archive made from an archive .  If specFile, then
 // create an archive upload spec in the specs directory; otherwise
-// upload the archive using client.  noZip avoids zipping the
-// includeFiles, but is ignored if there's more than one includeFile.
+// upload the archive using client, or push it to the OCI repository
+// if given.  noZip avoids zipping the includeFiles, but is ignored if
+// there's more than one includeFile.
 func CreateArchive(client client.Interface, input cli.Input, includeFiles []string, noZip bool, insecure bool, checksum string, specDir string, specFile string) (*fv1.Archive, error) {
 	// get root dir
 	var rootDir string
@@ -57,6 +61,22 @@ func CreateArchive(client client.Interface, input cli.Input, includeFiles []stri
 			return nil, errors.Wrapf(err, "error getting root directory of spec directory")
 		}
 	}
+	// reference an existing OCI artifact
+	if len(includeFiles) == 1 && strings.HasPrefix(includeFiles[0], ociArchivePrefix) {
+		archive := &fv1.Archive{
+			Type:            fv1.ArchiveTypeOCI,
+			URL:             strings.TrimPrefix(includeFiles[0], ociArchivePrefix),
+			ImagePullSecret: input.String(flagkey.PkgPullSecret),
+		}
+		if len(checksum) > 0 {
+			archive.Checksum = fv1.Checksum{
+				Type: fv1.ChecksumTypeSHA256,
+				Sum:  checksum,
+			}
+		}
+		return archive, archive.Validate()
+	}
+
 	errs := utils.MultiErrorWithFormat()
 	fileURL := ""
 
@@ -190,9 +210,41 @@ func CreateArchive(client client.Interface, input cli.Input, includeFiles []stri
 	}
 
 	ctx := context.Background()
+	if repository := input.String(flagkey.PkgOCIRepository); len(repository) > 0 {
+		return pkgutil.PushArchiveFile(ctx, repository, archivePath, input.String(flagkey.PkgPullSecret))
+	}
 	return pkgutil.UploadArchiveFile(ctx, client, archivePath)
 }
 
+// signArchives signs the non-empty archives of a package with the key of
+// the --signkey flag, if set. Archives of specs are signed when applying
+// the specs instead.
+func signArchives(input cli.Input, pkgSpec *fv1.PackageSpec) error {
+	keyRef := input.String(flagkey.PkgSignKey)
+	if len(keyRef) == 0 {
+		return nil
+	}
+
+	key, err := pkgutil.LoadSigningKey(context.Background(), keyRef, input.String(flagkey.KubeContext))
+	if err != nil {
+		return err
+	}
+	for _, archive := range []*fv1.Archive{&pkgSpec.Source, &pkgSpec.Deployment} {
+		if len(archive.Literal) == 0 && len(archive.URL) == 0 {
+			continue
+		}
+		if strings.HasPrefix(archive.URL, spec.ARCHIVE_URL_PREFIX) {
+			return errors.Errorf("archive %v of the specs can't be signed before it's uploaded, use --%v with spec apply instead",
+				archive.URL, flagkey.PkgSignKey)
+		}
+		err = pkgutil.SignArchive(archive, key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // makeArchiveFile creates a zip file from the given list of input files,
 // unless that list has only one item and that item is a zip file.
 //
@@ -32,6 +32,7 @@ type RebuildSubCommand struct {
 	cmd.CommandActioner
 	name      string
 	namespace string
+	follow    bool
 }
 
 func Rebuild(input cli.Input) error {
@@ -49,6 +50,7 @@ func (opts *RebuildSubCommand) do(input cli.Input) error {
 func (opts *RebuildSubCommand) complete(input cli.Input) error {
 	opts.name = input.String(flagkey.PkgName)
 	opts.namespace = input.String(flagkey.NamespacePackage)
+	opts.follow = input.Bool(flagkey.PkgFollow)
 	return nil
 }
 
@@ -71,6 +73,11 @@ func (opts *RebuildSubCommand) run(input cli.Input) error {
 		return errors.Wrap(err, "update package status")
 	}
 
+	if opts.follow {
+		fmt.Printf("Retrying build for pkg %v.\n", pkg.ObjectMeta.Name)
+		return followBuildLogs(opts.Client(), pkg)
+	}
+
 	fmt.Printf("Retrying build for pkg %v. Use \"fission pkg info --name %v\" to view status.\n", pkg.ObjectMeta.Name, pkg.ObjectMeta.Name)
 
 	return nil
@@ -131,6 +131,29 @@ func UpdatePackage(input cli.Input, client client.Interface, pkg *fv1.Package) (
 		needToUpdate = true
 	}
 
+	if input.IsSet(flagkey.PkgBuildTimeout) {
+		buildTimeout := input.Int(flagkey.PkgBuildTimeout)
+		pkg.Spec.BuildTimeout = &buildTimeout
+		needToUpdate = true
+	}
+
+	if input.IsSet(flagkey.PkgBuildRetries) {
+		buildRetries := input.Int(flagkey.PkgBuildRetries)
+		pkg.Spec.BuildRetries = &buildRetries
+		needToUpdate = true
+	}
+
+	if input.IsSet(flagkey.PkgOCIRepository) {
+		pkg.Spec.OCIRepository = nil
+		if repository := input.String(flagkey.PkgOCIRepository); len(repository) > 0 {
+			pkg.Spec.OCIRepository = &fv1.OCIRepository{
+				Repository:      repository,
+				ImagePullSecret: input.String(flagkey.PkgPullSecret),
+			}
+		}
+		needToUpdate = true
+	}
+
 	if input.IsSet(flagkey.PkgSrcArchive) {
 		srcArchive, err := CreateArchive(client, input, srcArchiveFiles, noZip, insecure, srcChecksum, "", "")
 		if err != nil {
@@ -144,6 +167,8 @@ func UpdatePackage(input cli.Input, client client.Interface, pkg *fv1.Package) (
 			Type: fv1.ChecksumTypeSHA256,
 			Sum:  srcChecksum,
 		}
+		// the signature of the old checksum is no longer valid
+		pkg.Spec.Source.Signature = nil
 		needToUpdate = true
 	}
 
@@ -162,6 +187,15 @@ func UpdatePackage(input cli.Input, client client.Interface, pkg *fv1.Package) (
 			Type: fv1.ChecksumTypeSHA256,
 			Sum:  deployChecksum,
 		}
+		pkg.Spec.Deployment.Signature = nil
+		needToUpdate = true
+	}
+
+	if input.IsSet(flagkey.PkgSignKey) {
+		err := signArchives(input, &pkg.Spec)
+		if err != nil {
+			return nil, errors.Wrap(err, "error signing archives")
+		}
 		needToUpdate = true
 	}
 
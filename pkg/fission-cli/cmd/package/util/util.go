@@ -16,7 +16,9 @@ limitations under the License.
 package util
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -32,10 +34,17 @@ import (
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/controller/client"
+	"github.com/fission/fission/pkg/fission-cli/util"
+	"github.com/fission/fission/pkg/oci"
+	"github.com/fission/fission/pkg/signing"
+	"github.com/fission/fission/pkg/storagesvc"
 	storageSvcClient "github.com/fission/fission/pkg/storagesvc/client"
 	"github.com/fission/fission/pkg/utils"
 )
 
+// signingKeySecretPrefix prefixes references to signing key secrets.
+const signingKeySecretPrefix = "k8s://"
+
 func UploadArchiveFile(ctx context.Context, client client.Interface, fileName string) (*fv1.Archive, error) {
 	var archive fv1.Archive
 
@@ -51,6 +60,21 @@ func UploadArchiveFile(ctx context.Context, client client.Interface, fileName st
 			return nil, err
 		}
 	} else {
+		csum, err := utils.GetFileChecksum(fileName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "calculate checksum for file %v", fileName)
+		}
+
+		// the storage service stores archives by content, so an archive
+		// it has already isn't uploaded again
+		existing, err := FindStoredArchive(ctx, client, *csum)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return existing, nil
+		}
+
 		u := strings.TrimSuffix(client.ServerURL(), "/") + "/proxy/storage"
 		ssClient := storageSvcClient.MakeClient(u)
 
@@ -60,29 +84,166 @@ func UploadArchiveFile(ctx context.Context, client client.Interface, fileName st
 			return nil, errors.Wrapf(err, "error uploading file %v", fileName)
 		}
 
-		storageSvc, err := client.V1().Misc().GetSvcURL("application=fission-storage")
-		storageSvcURL := "http://" + storageSvc
+		archiveURL, err := storageSvcArchiveURL(client, id)
 		if err != nil {
-			return nil, errors.Wrapf(err, "error getting fission storage service name")
+			return nil, err
 		}
 
-		// We make a new client with actual URL of Storage service so that the URL is not
-		// pointing to 127.0.0.1 i.e. proxy. DON'T reuse previous ssClient
-		pkgClient := storageSvcClient.MakeClient(storageSvcURL)
-		archiveURL := pkgClient.GetUrl(id)
-
 		archive.Type = fv1.ArchiveTypeUrl
 		archive.URL = archiveURL
+		archive.Checksum = *csum
+	}
 
-		csum, err := utils.GetFileChecksum(fileName)
+	return &archive, nil
+}
+
+// FindStoredArchive returns the archive with the given checksum if the
+// storage service has it already, or nil.
+func FindStoredArchive(ctx context.Context, client client.Interface, checksum fv1.Checksum) (*fv1.Archive, error) {
+	if checksum.Type != fv1.ChecksumTypeSHA256 || len(checksum.Sum) == 0 {
+		return nil, nil
+	}
+	ssClient := storageSvcClient.MakeClient(strings.TrimSuffix(client.ServerURL(), "/") + "/proxy/storage")
+	id, ok, err := ssClient.Exists(ctx, checksum.Sum)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error looking up archive with checksum %v", checksum.Sum)
+	}
+	if !ok {
+		return nil, nil
+	}
+	archiveURL, err := storageSvcArchiveURL(client, id)
+	if err != nil {
+		return nil, err
+	}
+	return &fv1.Archive{
+		Type:     fv1.ArchiveTypeUrl,
+		URL:      archiveURL,
+		Checksum: checksum,
+	}, nil
+}
+
+// ListOrphanArchives lists the archives in the storage service that no
+// package references, and what the archive pruner does with them.
+func ListOrphanArchives(ctx context.Context, client client.Interface) (*storagesvc.OrphanReport, error) {
+	ssClient := storageSvcClient.MakeClient(strings.TrimSuffix(client.ServerURL(), "/") + "/proxy/storage")
+	report, err := ssClient.ListOrphans(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing orphan archives")
+	}
+	return report, nil
+}
+
+// storageSvcArchiveURL returns the in-cluster URL of a stored archive.
+func storageSvcArchiveURL(client client.Interface, id string) (string, error) {
+	storageSvc, err := client.V1().Misc().GetSvcURL("application=fission-storage")
+	if err != nil {
+		return "", errors.Wrapf(err, "error getting fission storage service name")
+	}
+	storageSvcURL := "http://" + storageSvc
+
+	// We make a new client with actual URL of Storage service so that the URL is not
+	// pointing to 127.0.0.1 i.e. proxy. DON'T reuse the proxy client
+	return storageSvcClient.MakeClient(storageSvcURL).GetUrl(id), nil
+}
+
+// PushArchiveFile pushes an archive file to a registry repository as an
+// OCI artifact, with the credentials of the local docker config. The
+// artifact is tagged with the checksum of the archive unless the
+// repository has a tag. The returned archive references the artifact by
+// digest and is pulled with the given image pull secret.
+func PushArchiveFile(ctx context.Context, repository string, fileName string, imagePullSecret string) (*fv1.Archive, error) {
+	ref, err := oci.ParseReference(repository)
+	if err != nil {
+		return nil, err
+	}
+	if len(ref.Digest) > 0 {
+		return nil, errors.Errorf("repository %v to push archives to must not have a digest", repository)
+	}
+
+	csum, err := utils.GetFileChecksum(fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "calculate checksum for file %v", fileName)
+	}
+	if len(ref.Tag) == 0 {
+		ref.Tag = fmt.Sprintf("%v-%v", csum.Type, csum.Sum)
+	}
+
+	creds, err := oci.LocalCredentials()
+	if err != nil {
+		return nil, err
+	}
+	pushed, _, err := oci.MakeClient(nil, creds).Push(ctx, ref, fileName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error pushing file %v to %v", fileName, ref)
+	}
+
+	return &fv1.Archive{
+		Type:            fv1.ArchiveTypeOCI,
+		URL:             pushed.String(),
+		Checksum:        *csum,
+		ImagePullSecret: imagePullSecret,
+	}, nil
+}
+
+// PullOCIArchive returns a reader of an OCI archive, pulled with the
+// credentials of the local docker config.
+func PullOCIArchive(ctx context.Context, archive fv1.Archive) (io.ReadCloser, error) {
+	ref, err := oci.ParseReference(archive.URL)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := oci.LocalCredentials()
+	if err != nil {
+		return nil, err
+	}
+	reader, _, err := oci.MakeClient(nil, creds).Pull(ctx, ref)
+	return reader, err
+}
+
+// LoadSigningKey loads the ed25519 private key to sign archives with,
+// from a PEM file or, for k8s://<namespace>/<name> references, from a
+// signing key secret.
+func LoadSigningKey(ctx context.Context, keyRef string, kubeContext string) (ed25519.PrivateKey, error) {
+	if strings.HasPrefix(keyRef, signingKeySecretPrefix) {
+		parts := strings.Split(strings.TrimPrefix(keyRef, signingKeySecretPrefix), "/")
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return nil, errors.Errorf("invalid signing key secret %q, expected %v<namespace>/<name>", keyRef, signingKeySecretPrefix)
+		}
+		_, kubeClient, err := util.GetKubernetesClient(kubeContext)
 		if err != nil {
-			return nil, errors.Wrapf(err, "calculate checksum for file %v", fileName)
+			return nil, err
 		}
+		return signing.ReadPrivateKey(ctx, kubeClient, parts[0], parts[1])
+	}
 
-		archive.Checksum = *csum
+	data, err := ioutil.ReadFile(keyRef)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading signing key")
 	}
+	return signing.ParsePrivateKey(data)
+}
 
-	return &archive, nil
+// SignArchive signs an archive with the key. Literal archives are signed
+// with the checksum of their contents, and other archives with their
+// checksum, which must be set.
+func SignArchive(archive *fv1.Archive, key ed25519.PrivateKey) error {
+	checksum := &archive.Checksum
+	if len(archive.Literal) > 0 {
+		var err error
+		checksum, err = utils.GetChecksum(bytes.NewReader(archive.Literal))
+		if err != nil {
+			return errors.Wrap(err, "error calculating checksum of archive")
+		}
+	} else if len(checksum.Sum) == 0 {
+		return errors.Errorf("archive %v has no checksum to sign", archive.URL)
+	}
+
+	signature, err := signing.Sign(key, *checksum)
+	if err != nil {
+		return err
+	}
+	archive.Signature = signature
+	return nil
 }
 
 func GetContents(filePath string) ([]byte, error) {
@@ -159,21 +320,51 @@ func WriteArchiveToFile(fileName string, reader io.Reader) error {
 	return nil
 }
 
-// DownloadStoragesvcURL downloads and return archive content with given storage service url
-func DownloadStoragesvcURL(client client.Interface, fileUrl string) (io.ReadCloser, error) {
+// DownloadStoragesvcURL downloads and return archive content with given storage service url.
+// The archive is downloaded to a temporary file, resuming interrupted transfers, which is
+// removed when the returned reader is closed.
+func DownloadStoragesvcURL(ctx context.Context, client client.Interface, fileUrl string) (io.ReadCloser, error) {
 	u, err := url.ParseRequestURI(fileUrl)
 	if err != nil {
 		return nil, err
 	}
+	id := u.Query().Get("id")
+	if len(id) == 0 {
+		return nil, errors.Errorf("storage service url %v has no archive id", fileUrl)
+	}
+
+	dir, err := ioutil.TempDir("", "fission-archive")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "archive")
 
 	// replace in-cluster storage service host with controller server url
-	fileDownloadUrl := strings.TrimSuffix(client.ServerURL(), "/") + "/proxy/storage/" + u.RequestURI()
-	reader, err := DownloadURL(fileDownloadUrl)
+	ssClient := storageSvcClient.MakeClient(strings.TrimSuffix(client.ServerURL(), "/") + "/proxy/storage")
+	err = ssClient.Download(ctx, id, path)
 	if err != nil {
-		return nil, errors.Wrapf(err, fmt.Sprintf("error downloading from storage service url: %v", fileUrl))
+		os.RemoveAll(dir)
+		return nil, errors.Wrapf(err, "error downloading from storage service url: %v", fileUrl)
 	}
 
-	return reader, nil
+	f, err := os.Open(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &tempFileReader{File: f, dir: dir}, nil
+}
+
+// tempFileReader removes the temporary directory of a file on Close.
+type tempFileReader struct {
+	*os.File
+	dir string
+}
+
+func (r *tempFileReader) Close() error {
+	err := r.File.Close()
+	os.RemoveAll(r.dir)
+	return err
 }
 
 // PrintPackageSummary prints package information and build logs.
@@ -184,6 +375,47 @@ func PrintPackageSummary(writer io.Writer, pkg *fv1.Package) {
 	fmt.Fprintf(w, "%v\t%v\n", "Name:", pkg.ObjectMeta.Name)
 	fmt.Fprintf(w, "%v\t%v\n", "Environment:", pkg.Spec.Environment.Name)
 	fmt.Fprintf(w, "%v\t%v\n", "Status:", pkg.Status.BuildStatus)
+	if pkg.Status.Provenance != nil {
+		printBuildProvenance(w, pkg.Status.Provenance)
+	}
 	fmt.Fprintf(w, "%v\n%v", "Build Logs:", buildlog)
 	w.Flush()
 }
+
+// printBuildProvenance prints what the deployment archive of a package
+// was built from.
+func printBuildProvenance(w io.Writer, provenance *fv1.BuildProvenance) {
+	fmt.Fprintf(w, "%v\t%v\n", "Source Checksum:", provenance.SourceChecksum.Sum)
+	fmt.Fprintf(w, "%v\t%v\n", "Builder Image:", provenance.BuilderImage)
+	fmt.Fprintf(w, "%v\t%v\n", "Builder Image ID:", provenance.BuilderImageDigest)
+	fmt.Fprintf(w, "%v\t%v\n", "Build Command:", provenance.BuildCommand)
+	fmt.Fprintf(w, "%v\t%v\n", "Environment Version:", provenance.EnvironmentResourceVersion)
+	fmt.Fprintf(w, "%v\t%v\n", "Build Started:", provenance.StartTimestamp.Time)
+	fmt.Fprintf(w, "%v\t%v\n", "Build Completed:", provenance.EndTimestamp.Time)
+	fmt.Fprintf(w, "%v\t%v\n", "Deployment Checksum:", provenance.DeploymentChecksum.Sum)
+}
+
+// GetArchiveChecksum downloads an archive of a package and returns its
+// checksum.
+func GetArchiveChecksum(client client.Interface, archive fv1.Archive) (*fv1.Checksum, error) {
+	switch archive.Type {
+	case fv1.ArchiveTypeLiteral:
+		return utils.GetChecksum(bytes.NewReader(archive.Literal))
+	case fv1.ArchiveTypeUrl:
+		reader, err := DownloadStoragesvcURL(context.Background(), client, archive.URL)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return utils.GetChecksum(reader)
+	case fv1.ArchiveTypeOCI:
+		reader, err := PullOCIArchive(context.Background(), archive)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return utils.GetChecksum(reader)
+	default:
+		return nil, errors.Errorf("unsupported archive type %q", archive.Type)
+	}
+}
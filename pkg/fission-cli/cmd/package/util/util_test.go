@@ -32,3 +32,23 @@ func TestPrintPackageSummary(t *testing.T) {
 		t.Errorf("PrintPackageBuildLog() = %v, want %v", gotWriter, expected)
 	}
 }
+
+func TestGetArchiveChecksum(t *testing.T) {
+	archive := fv1.Archive{
+		Type:    fv1.ArchiveTypeLiteral,
+		Literal: []byte("dummy-deployment"),
+	}
+	checksum, err := GetArchiveChecksum(nil, archive)
+	if err != nil {
+		t.Fatalf("GetArchiveChecksum() error = %v", err)
+	}
+	expected := "a82f9ba34e92a90194b75c7c12c0eec6f0fee31a47733214d2b4f8396d27603f"
+	if checksum.Type != fv1.ChecksumTypeSHA256 || checksum.Sum != expected {
+		t.Errorf("GetArchiveChecksum() = %v, want %v", checksum.Sum, expected)
+	}
+
+	_, err = GetArchiveChecksum(nil, fv1.Archive{})
+	if err == nil {
+		t.Errorf("GetArchiveChecksum() of an archive without type should fail")
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package _package
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/fission-cli/cliwrapper/cli"
+	"github.com/fission/fission/pkg/fission-cli/cmd"
+	pkgutil "github.com/fission/fission/pkg/fission-cli/cmd/package/util"
+	flagkey "github.com/fission/fission/pkg/fission-cli/flag/key"
+)
+
+type VerifySubCommand struct {
+	cmd.CommandActioner
+	name      string
+	namespace string
+}
+
+func Verify(input cli.Input) error {
+	return (&VerifySubCommand{}).do(input)
+}
+
+func (opts *VerifySubCommand) do(input cli.Input) error {
+	err := opts.complete(input)
+	if err != nil {
+		return err
+	}
+	return opts.run(input)
+}
+
+func (opts *VerifySubCommand) complete(input cli.Input) error {
+	opts.name = input.String(flagkey.PkgName)
+	opts.namespace = input.String(flagkey.NamespacePackage)
+	return nil
+}
+
+// run checks that the deployment archive of the package is the one its
+// build produced, as recorded in the build provenance.
+func (opts *VerifySubCommand) run(input cli.Input) error {
+	pkg, err := opts.Client().V1().Package().Get(&metav1.ObjectMeta{
+		Namespace: opts.namespace,
+		Name:      opts.name,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error finding package %s", opts.name)
+	}
+
+	// failed builds record their provenance too, but no deployment archive
+	if pkg.Status.BuildStatus != fv1.BuildStatusSucceeded {
+		return errors.Errorf("package %v has build status %v", opts.name, pkg.Status.BuildStatus)
+	}
+
+	provenance := pkg.Status.Provenance
+	if provenance == nil || len(provenance.DeploymentChecksum.Sum) == 0 {
+		return errors.Errorf("package %v has no build provenance recorded", opts.name)
+	}
+
+	if len(pkg.Spec.Deployment.Checksum.Sum) > 0 && pkg.Spec.Deployment.Checksum.Sum != provenance.DeploymentChecksum.Sum {
+		return errors.Errorf("deployment archive of package %v was replaced after the build: checksum %v, built %v",
+			opts.name, pkg.Spec.Deployment.Checksum.Sum, provenance.DeploymentChecksum.Sum)
+	}
+
+	checksum, err := pkgutil.GetArchiveChecksum(opts.Client(), pkg.Spec.Deployment)
+	if err != nil {
+		return errors.Wrap(err, "error getting deployment archive checksum")
+	}
+	if checksum.Sum != provenance.DeploymentChecksum.Sum {
+		return errors.Errorf("deployment archive of package %v doesn't match its build provenance: checksum %v, built %v",
+			opts.name, checksum.Sum, provenance.DeploymentChecksum.Sum)
+	}
+
+	fmt.Printf("Deployment archive of package %v matches its build provenance.\n", opts.name)
+	fmt.Printf("Built from source checksum %v with %v at %v.\n",
+		provenance.SourceChecksum.Sum, provenance.BuilderImage, provenance.EndTimestamp.Time)
+	return nil
+}
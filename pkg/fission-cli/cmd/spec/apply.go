@@ -18,6 +18,7 @@ package spec
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -73,6 +74,15 @@ func (opts *ApplySubCommand) run(input cli.Input) error {
 	waitForBuild := input.Bool(flagkey.SpecWait)
 	validateSpecs := util.GetValidationFlag(input)
 
+	var signKey ed25519.PrivateKey
+	if keyRef := input.String(flagkey.PkgSignKey); len(keyRef) > 0 {
+		var err error
+		signKey, err = pkgutil.LoadSigningKey(context.Background(), keyRef, input.String(flagkey.KubeContext))
+		if err != nil {
+			return errors.Wrap(err, "error loading signing key")
+		}
+	}
+
 	var watcher *fsnotify.Watcher
 	var pbw *packageBuildWatcher
 
@@ -130,7 +140,7 @@ func (opts *ApplySubCommand) run(input cli.Input) error {
 		}
 
 		// make changes to the cluster based on the specs
-		pkgMetas, as, err := applyResources(opts.Client(), specDir, fr, deleteResources)
+		pkgMetas, as, err := applyResources(opts.Client(), specDir, fr, deleteResources, signKey)
 		if err != nil {
 			return errors.Wrap(err, "error applying specs")
 		}
@@ -283,7 +293,8 @@ func pluralize(num int, word string) string {
 }
 
 // applyArchives figures out the set of archives that need to be uploaded, and uploads them.
-func applyArchives(fclient client.Interface, specDir string, fr *FissionResources) error {
+// The archives of the packages are signed with signKey, if not nil.
+func applyArchives(fclient client.Interface, specDir string, fr *FissionResources, signKey ed25519.PrivateKey) error {
 
 	// archive:// URL -> archive map.
 	archiveFiles := make(map[string]fv1.Archive)
@@ -326,10 +337,21 @@ func applyArchives(fclient client.Interface, specDir string, fr *FissionResource
 			ar.URL = url
 			archiveFiles[name] = ar
 		} else {
+			ctx := context.Background()
+			// the storage service may have it without any package referencing it
+			storedAr, err := pkgutil.FindStoredArchive(ctx, fclient, ar.Checksum)
+			if err != nil {
+				return err
+			}
+			if storedAr != nil {
+				fmt.Printf("archive %v exists in storage, not uploading\n", name)
+				archiveFiles[name] = *storedAr
+				continue
+			}
+
 			// doesn't exist, upload
 			fmt.Printf("uploading archive %v\n", name)
 			// ar.URL is actually a local filename at this stage
-			ctx := context.Background()
 			uploadedAr, err := pkgutil.UploadArchiveFile(ctx, fclient, ar.URL)
 			if err != nil {
 				return err
@@ -351,18 +373,26 @@ func applyArchives(fclient client.Interface, specDir string, fr *FissionResource
 				ar.URL = availableAr.URL
 				ar.Checksum = availableAr.Checksum
 			}
+
+			if signKey != nil && (len(ar.Literal) > 0 || len(ar.URL) > 0) {
+				err := pkgutil.SignArchive(ar, signKey)
+				if err != nil {
+					return errors.Wrapf(err, "error signing archive of package %v", fr.Packages[i].ObjectMeta.Name)
+				}
+			}
 		}
 	}
 	return nil
 }
 
-// applyResources applies the given set of fission resources.
-func applyResources(fclient client.Interface, specDir string, fr *FissionResources, delete bool) (map[string]metav1.ObjectMeta, map[string]ResourceApplyStatus, error) {
+// applyResources applies the given set of fission resources, signing the
+// package archives with signKey if not nil.
+func applyResources(fclient client.Interface, specDir string, fr *FissionResources, delete bool, signKey ed25519.PrivateKey) (map[string]metav1.ObjectMeta, map[string]ResourceApplyStatus, error) {
 
 	applyStatus := make(map[string]ResourceApplyStatus)
 
 	// upload archives that need to be uploaded. Changes archive references in fr.Packages.
-	err := applyArchives(fclient, specDir, fr)
+	err := applyArchives(fclient, specDir, fr, signKey)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -31,7 +31,9 @@ func Commands() *cobra.Command {
 	}
 	wrapper.SetFlags(createCmd, flag.FlagSet{
 		Optional: []flag.Flag{flag.TtName, flag.TtFnName,
-			flag.TtCron, flag.NamespaceFunction, flag.SpecSave, flag.SpecDry},
+			flag.TtCron, flag.TtTimezone, flag.TtBody, flag.TtContentType,
+			flag.TtJitter, flag.TtConcurrencyPolicy, flag.TtStartingDeadline, flag.TtCatchUpPolicy,
+			flag.NamespaceFunction, flag.SpecSave, flag.SpecDry},
 	})
 
 	updateCmd := &cobra.Command{
@@ -42,7 +44,9 @@ func Commands() *cobra.Command {
 	}
 	wrapper.SetFlags(updateCmd, flag.FlagSet{
 		Required: []flag.Flag{flag.TtName},
-		Optional: []flag.Flag{flag.TtFnName, flag.TtCron, flag.NamespaceTrigger},
+		Optional: []flag.Flag{flag.TtFnName, flag.TtCron, flag.TtTimezone,
+			flag.TtBody, flag.TtContentType, flag.TtJitter, flag.TtConcurrencyPolicy,
+			flag.TtStartingDeadline, flag.TtCatchUpPolicy, flag.NamespaceTrigger},
 	})
 
 	deleteCmd := &cobra.Command{
@@ -74,7 +78,7 @@ func Commands() *cobra.Command {
 		RunE:    wrapper.Wrapper(Show),
 	}
 	wrapper.SetFlags(showCmd, flag.FlagSet{
-		Optional: []flag.Flag{flag.TtCron, flag.TtRound},
+		Optional: []flag.Flag{flag.TtCron, flag.TtTimezone, flag.TtRound},
 	})
 
 	command := &cobra.Command{
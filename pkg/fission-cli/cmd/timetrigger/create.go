@@ -107,7 +107,12 @@ func (opts *CreateSubCommand) complete(input cli.Input) error {
 		},
 	}
 
-	return nil
+	_, err := setRunOptions(input, &opts.trigger.Spec)
+	if err != nil {
+		return err
+	}
+
+	return opts.trigger.Spec.Validate()
 }
 
 func (opts *CreateSubCommand) run(input cli.Input) error {
@@ -138,7 +143,7 @@ func (opts *CreateSubCommand) run(input cli.Input) error {
 		return err
 	}
 
-	err = getCronNextNActivationTime(opts.trigger.Spec.Cron, t, 1)
+	err = getCronNextNActivationTime(opts.trigger.Spec.Cron, opts.trigger.Spec.Timezone, t, 1)
 	if err != nil {
 		return errors.Wrap(err, "error passing cron spec examination")
 	}
@@ -146,6 +151,50 @@ func (opts *CreateSubCommand) run(input cli.Input) error {
 	return nil
 }
 
+// setRunOptions sets the run options given with flags on the trigger spec,
+// and returns whether any was set.
+func setRunOptions(input cli.Input, spec *fv1.TimeTriggerSpec) (bool, error) {
+	updated := false
+	if input.IsSet(flagkey.TtTimezone) {
+		spec.Timezone = input.String(flagkey.TtTimezone)
+		updated = true
+	}
+	if input.IsSet(flagkey.TtBody) {
+		spec.Body = input.String(flagkey.TtBody)
+		updated = true
+	}
+	if input.IsSet(flagkey.TtContentType) {
+		spec.ContentType = input.String(flagkey.TtContentType)
+		updated = true
+	}
+	if input.IsSet(flagkey.TtJitter) {
+		jitter := input.Duration(flagkey.TtJitter)
+		if jitter < 0 {
+			return false, errors.New("jitter must not be negative")
+		}
+		spec.JitterSeconds = int32(jitter.Round(time.Second) / time.Second)
+		updated = true
+	}
+	if input.IsSet(flagkey.TtConcurrencyPolicy) {
+		spec.ConcurrencyPolicy = fv1.ConcurrencyPolicy(input.String(flagkey.TtConcurrencyPolicy))
+		updated = true
+	}
+	if input.IsSet(flagkey.TtStartingDeadline) {
+		deadline := input.Duration(flagkey.TtStartingDeadline)
+		if deadline < 0 {
+			return false, errors.New("starting deadline must not be negative")
+		}
+		seconds := int64(deadline.Round(time.Second) / time.Second)
+		spec.StartingDeadlineSeconds = &seconds
+		updated = true
+	}
+	if input.IsSet(flagkey.TtCatchUpPolicy) {
+		spec.CatchUpPolicy = fv1.CatchUpPolicy(input.String(flagkey.TtCatchUpPolicy))
+		updated = true
+	}
+	return updated, nil
+}
+
 func getAPITimeInfo(client client.Interface) (time.Time, error) {
 	serverInfo, err := client.V1().Misc().ServerInfo()
 	if err != nil {
@@ -154,12 +203,20 @@ func getAPITimeInfo(client client.Interface) (time.Time, error) {
 	return serverInfo.ServerTime.CurrentTime, nil
 }
 
-func getCronNextNActivationTime(cronSpec string, serverTime time.Time, round int) error {
+func getCronNextNActivationTime(cronSpec string, timezone string, serverTime time.Time, round int) error {
 	sched, err := cron.Parse(cronSpec)
 	if err != nil {
 		return err
 	}
 
+	if len(timezone) > 0 {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return err
+		}
+		serverTime = serverTime.In(loc)
+	}
+
 	fmt.Printf("Current Server Time: \t%v\n", serverTime.Format(time.RFC3339))
 
 	for i := 0; i < round; i++ {
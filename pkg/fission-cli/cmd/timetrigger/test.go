@@ -48,7 +48,7 @@ func (opts *ShowSubCommand) run(flaginput cli.Input) error {
 		return err
 	}
 
-	err = getCronNextNActivationTime(cronSpec, t, round)
+	err = getCronNextNActivationTime(cronSpec, flaginput.String(flagkey.TtTimezone), t, round)
 	if err != nil {
 		return errors.Wrap(err, "error passing cron spec examination")
 	}
@@ -70,13 +70,19 @@ func (opts *UpdateSubCommand) complete(input cli.Input) error {
 		updated = true
 	}
 
+	optsUpdated, err := setRunOptions(input, &tt.Spec)
+	if err != nil {
+		return err
+	}
+	updated = updated || optsUpdated
+
 	if !updated {
-		return errors.New("nothing to update. Use --cron or --function")
+		return errors.New("nothing to update. Use --cron, --function, --timezone, --body, --contenttype, --jitter, --concurrencypolicy, --startingdeadline or --catchuppolicy")
 	}
 
 	opts.trigger = tt
 
-	return nil
+	return tt.Spec.Validate()
 }
 
 func (opts *UpdateSubCommand) run(input cli.Input) error {
@@ -92,7 +98,7 @@ func (opts *UpdateSubCommand) run(input cli.Input) error {
 		return err
 	}
 
-	err = getCronNextNActivationTime(opts.trigger.Spec.Cron, t, 1)
+	err = getCronNextNActivationTime(opts.trigger.Spec.Cron, opts.trigger.Spec.Timezone, t, 1)
 	if err != nil {
 		return errors.Wrap(err, "error passing cron spec examination")
 	}
@@ -135,10 +135,17 @@ var (
 	HtFnFilter          = Flag{Type: String, Name: flagkey.HtFilter, Usage: "Name of the function for trigger(s)"}
 	HtPrefix            = Flag{Type: String, Name: flagkey.HtPrefix, Usage: "Prefix with which functions are exposed. NOTE: Prefix takes precedence over URL/RelativeURL"}
 
-	TtName   = Flag{Type: String, Name: flagkey.TtName, Usage: "Time Trigger name"}
-	TtCron   = Flag{Type: String, Name: flagkey.TtCron, Usage: "Time trigger cron spec with each asterisk representing respectively second, minute, hour, the day of the month, month and day of the week. Also supports readable formats like '@every 5m', '@hourly'"}
-	TtFnName = Flag{Type: String, Name: flagkey.TtFnName, Usage: "Function name"}
-	TtRound  = Flag{Type: Int, Name: flagkey.TtRound, Usage: "Get next N rounds of invocation time", DefaultValue: 1}
+	TtName              = Flag{Type: String, Name: flagkey.TtName, Usage: "Time Trigger name"}
+	TtCron              = Flag{Type: String, Name: flagkey.TtCron, Usage: "Time trigger cron spec with each asterisk representing respectively second, minute, hour, the day of the month, month and day of the week. Also supports readable formats like '@every 5m', '@hourly'"}
+	TtFnName            = Flag{Type: String, Name: flagkey.TtFnName, Usage: "Function name"}
+	TtRound             = Flag{Type: Int, Name: flagkey.TtRound, Usage: "Get next N rounds of invocation time", DefaultValue: 1}
+	TtTimezone          = Flag{Type: String, Name: flagkey.TtTimezone, Usage: "IANA time zone the cron spec is evaluated in, e.g. 'Europe/Berlin'. Defaults to the time zone of the timer"}
+	TtBody              = Flag{Type: String, Name: flagkey.TtBody, Usage: "Request body sent to the function on every run"}
+	TtContentType       = Flag{Type: String, Name: flagkey.TtContentType, Usage: "Content type of the request body"}
+	TtJitter            = Flag{Type: Duration, Name: flagkey.TtJitter, Usage: "Delay each run by a random duration of up to the given one (rounded to seconds), e.g. 30s"}
+	TtConcurrencyPolicy = Flag{Type: String, Name: flagkey.TtConcurrencyPolicy, Usage: "How to treat a run that is due while the previous one is still running: Allow, Forbid or Replace"}
+	TtStartingDeadline  = Flag{Type: Duration, Name: flagkey.TtStartingDeadline, Usage: "Fire runs missed while the timer was down if they were scheduled within the given duration, e.g. 1h. Missed runs are skipped if not set"}
+	TtCatchUpPolicy     = Flag{Type: String, Name: flagkey.TtCatchUpPolicy, Usage: "Which missed runs within the starting deadline are fired: Once (the latest one) or All"}
 
 	MqtName            = Flag{Type: String, Name: flagkey.MqtName, Usage: "Message queue trigger name"}
 	MqtFnName          = Flag{Type: String, Name: flagkey.MqtFnName, Usage: "Function name"}
@@ -155,35 +162,59 @@ var (
 	MqtMetadata        = Flag{Type: StringSlice, Name: flagkey.MqtMetadata, Usage: "Metadata needed for connecting to source system in format: --metadata key1=value1 --metadata key2=value2"}
 	MqtSecret          = Flag{Type: String, Name: flagkey.MqtSecret, Usage: "Name of secret object", DefaultValue: ""}
 	MqtKind            = Flag{Type: String, Name: flagkey.MqtKind, Usage: "Kind of Message Queue Trigger, e.g. fission, keda", DefaultValue: "fission"}
+	MqtDlqServer       = Flag{Type: String, Name: flagkey.MqtDlqServer, Usage: "Address of the message queue server holding the error topic, e.g. kafka brokers host1:9092,host2:9092"}
+	MqtDlqSecret       = Flag{Type: String, Name: flagkey.MqtDlqSecret, Usage: "Secret holding the TLS certificates (caCert, userCert, userKey) to connect to the message queue server with, in the format of [<namespace>/]<name>. Defaults to the secret of the trigger, if any"}
+	MqtDlqSince        = Flag{Type: Duration, Name: flagkey.MqtDlqSince, Usage: "Only select messages published to the error topic within the given duration, e.g. 1h"}
+	MqtDlqUntil        = Flag{Type: Duration, Name: flagkey.MqtDlqUntil, Usage: "Only select messages published to the error topic before the given duration ago, e.g. 10m"}
+	MqtDlqCount        = Flag{Type: Int, Name: flagkey.MqtDlqCount, Usage: "Maximum number of messages to select, oldest first (0 means no limit)", DefaultValue: 0}
+	MqtDlqID           = Flag{Type: StringSlice, Name: flagkey.MqtDlqID, Usage: "ID of the message to replay, as shown by 'dlq list'. To replay multiple messages: --id 0-12 --id 1-3"}
 
 	EnvName                   = Flag{Type: String, Name: flagkey.EnvName, Usage: "Environment name"}
 	EnvPoolsize               = Flag{Type: Int, Name: flagkey.EnvPoolsize, Usage: "Size of the pool", DefaultValue: 3}
 	EnvImage                  = Flag{Type: String, Name: flagkey.EnvImage, Usage: "Environment image URL"}
 	EnvBuilderImage           = Flag{Type: String, Name: flagkey.EnvBuilderImage, Usage: "Environment builder image URL"}
 	EnvBuildCmd               = Flag{Type: String, Name: flagkey.EnvBuildcommand, Usage: "Build command for environment builder to build source package"}
+	EnvBuildTimeout           = Flag{Type: Int, Name: flagkey.EnvBuildTimeout, Usage: "Default maximum duration (in seconds) of package build commands (no timeout if 0 is given)"}
+	EnvBuildRetries           = Flag{Type: Int, Name: flagkey.EnvBuildRetries, Usage: "Default number of retries of package builds failing on transient errors"}
+	EnvOCIRepository          = Flag{Type: String, Name: flagkey.EnvOCIRepository, Usage: "Registry repository, such as registry.example.com/team/repo[:tag], to push the deployment archives built by the builder to as OCI artifacts, with the credentials of --imagepullsecret. An empty value uploads them to the cluster on update"}
+	EnvReload                 = Flag{Type: Bool, Name: flagkey.EnvReload, Usage: "Refresh the secrets and configmaps of function pods in place through the /reload endpoint of the runtime instead of recycling the pods. Only set it for runtimes implementing the endpoint"}
 	EnvKeepArchive            = Flag{Type: Bool, Name: flagkey.EnvKeeparchive, Usage: "Keep the archive instead of extracting it into a directory (mainly for the JVM environment because .jar is one kind of zip archive)"}
 	EnvExternalNetwork        = Flag{Type: Bool, Name: flagkey.EnvExternalNetwork, Usage: "Allow pod to access external network (only works when istio feature is enabled)"}
 	EnvTerminationGracePeriod = Flag{Type: Int64, Name: flagkey.EnvGracePeriod, Aliases: []string{"period"}, Usage: "Grace time (in seconds) for pod to perform connection draining before termination (default value will be used if 0 is given)", DefaultValue: 360}
 	EnvVersion                = Flag{Type: Int, Name: flagkey.EnvVersion, Usage: "Environment API version (1 means v1 interface)", DefaultValue: 1}
 	EnvImagePullSecret        = Flag{Type: String, Name: flagkey.EnvImagePullSecret, Usage: "Secret for Kubernetes to pull an image from a private registry"}
+	EnvSignatureKey           = Flag{Type: StringSlice, Name: flagkey.EnvSignatureKey, Usage: "Secret holding a trusted public key; functions only load archives signed by a trusted key. To trust multiple keys: --signaturekey key1 --signaturekey key2. An empty value removes the signature policy on update"}
+	EnvBuilderSignKey         = Flag{Type: String, Name: flagkey.EnvBuilderSignKey, Usage: "Secret holding the private key to sign the deployment archives built by the builder with, when the environment has trusted keys"}
 
 	KwName      = Flag{Type: String, Name: flagkey.KwName, Usage: "Watch name"}
 	KwFnName    = Flag{Type: String, Name: flagkey.KwFnName, Usage: "Function name"}
 	KwNamespace = Flag{Type: String, Name: flagkey.KwNamespace, Aliases: []string{"ns"}, Usage: "Namespace of resource to watch", DefaultValue: metav1.NamespaceDefault}
 	KwObjType   = Flag{Type: String, Name: flagkey.KwObjType, Usage: "Type of resource to watch (Pod, Service, etc.)", DefaultValue: "pod"}
 	KwLabels    = Flag{Type: String, Name: flagkey.KwLabels, Usage: "Label selector of the form a=b,c=d"}
+	KwGroup     = Flag{Type: String, Name: flagkey.KwGroup, Usage: "API group of the resource to watch, e.g. apps. Empty for the core group"}
+	KwVersion   = Flag{Type: String, Name: flagkey.KwVersion, Usage: "API version of the resource to watch, defaults to the preferred version of the group"}
+	KwResource  = Flag{Type: String, Name: flagkey.KwResource, Usage: "Plural name of the resource to watch, e.g. deployments. Takes precedence over --type"}
+	KwFields    = Flag{Type: String, Name: flagkey.KwFields, Usage: "Field selector of the form status.phase=Running"}
+	KwEventType = Flag{Type: StringSlice, Name: flagkey.KwEventType, Usage: "Event type to invoke the function for: ADDED, MODIFIED or DELETED. All event types if not set. To mention multiple event types --eventtype ADDED --eventtype DELETED"}
+	KwPredicate = Flag{Type: StringSlice, Name: flagkey.KwPredicate, Usage: "Predicate on the watched object, with a JSONPath: '{.status.phase}=Failed|Unknown' (in), '{.status.phase}!=Running' (not in), '{.metadata.labels.app}' (exists) or '!{.metadata.labels.app}' (does not exist). All predicates have to match"}
 
 	PkgName           = Flag{Type: String, Name: flagkey.PkgName, Usage: "Package name"}
 	PkgForce          = Flag{Type: Bool, Name: flagkey.PkgForce, Short: "f", Usage: "Force update a package even if it is used by one or more functions"}
 	PkgEnvironment    = Flag{Type: String, Name: flagkey.PkgEnvironment, Usage: "Environment name"}
 	PkgBuildCmd       = Flag{Type: String, Name: flagkey.PkgBuildCmd, Usage: "Build command for builder to run with"}
+	PkgBuildTimeout   = Flag{Type: Int, Name: flagkey.PkgBuildTimeout, Usage: "Maximum duration (in seconds) of the build command, defaults to the build timeout of the environment"}
+	PkgBuildRetries   = Flag{Type: Int, Name: flagkey.PkgBuildRetries, Usage: "Number of retries of a build failing on transient errors, defaults to the build retries of the environment"}
 	PkgOutput         = Flag{Type: String, Name: flagkey.PkgOutput, Short: "o", Usage: "Output filename to save archive content"}
 	PkgStatus         = Flag{Type: String, Name: flagkey.PkgStatus, Usage: `Filter packages by status`}
 	PkgOrphan         = Flag{Type: Bool, Name: flagkey.PkgOrphan, Usage: "Orphan packages that are not referenced by any function"}
+	PkgFollow         = Flag{Type: Bool, Name: flagkey.PkgFollow, Usage: "Stream the build logs until the build completes"}
+	PkgOCIRepository  = Flag{Type: String, Name: flagkey.PkgOCIRepository, Usage: "Registry repository, such as registry.example.com/team/repo[:tag], to push local archives and the deployment archive built from the source archive to as OCI artifacts instead of uploading them to the cluster. An empty value uploads built archives to the cluster on update"}
+	PkgPullSecret     = Flag{Type: String, Name: flagkey.PkgPullSecret, Usage: "Docker registry secret, in the package namespace, to pull OCI archives and push built archives with"}
+	PkgSignKey        = Flag{Type: String, Name: flagkey.PkgSignKey, Usage: "Ed25519 private key to sign archives with, either a PEM file or k8s://<namespace>/<secret> for a signing key secret"}
 	PkgCode           = Flag{Type: String, Name: flagkey.PkgCode, Usage: "URL or local path for single file source code"}
-	PkgDeployArchive  = Flag{Type: StringSlice, Name: flagkey.PkgDeployArchive, Aliases: []string{"deploy"}, Usage: "URL or local paths for binary archive"}
+	PkgDeployArchive  = Flag{Type: StringSlice, Name: flagkey.PkgDeployArchive, Aliases: []string{"deploy"}, Usage: "URL, local paths or oci://<OCI artifact reference by digest> for binary archive"}
 	PkgDeployChecksum = Flag{Type: String, Name: flagkey.PkgDeployChecksum, Usage: "SHA256 checksum of deploy archive when providing URL"}
-	PkgSrcArchive     = Flag{Type: StringSlice, Name: flagkey.PkgSrcArchive, Aliases: []string{"source", "src"}, Usage: "URL or local paths for source archive"}
+	PkgSrcArchive     = Flag{Type: StringSlice, Name: flagkey.PkgSrcArchive, Aliases: []string{"source", "src"}, Usage: "URL, local paths or oci://<OCI artifact reference by digest> for source archive"}
 	PkgSrcChecksum    = Flag{Type: String, Name: flagkey.PkgSrcChecksum, Usage: "SHA256 checksum of source archive when providing URL"}
 	PkgInsecure       = Flag{Type: Bool, Name: flagkey.PkgInsecure, Usage: "Skip generating SHA256 checksum for file integrity validation"}
 
@@ -206,5 +237,17 @@ var (
 	CanaryOldFunc           = Flag{Type: String, Name: flagkey.CanaryOldFunc, Aliases: []string{"oldfn"}, Usage: "Old stable version of the function"}
 	CanaryWeightIncrement   = Flag{Type: Int, Name: flagkey.CanaryWeightIncrement, Aliases: []string{"step"}, Usage: "Weight increment step for function", DefaultValue: 20}
 	CanaryIncrementInterval = Flag{Type: String, Name: flagkey.CanaryIncrementInterval, Aliases: []string{"internal"}, Usage: "Weight increment interval, string representation of time.Duration, ex : 1m, 2h, 2d", DefaultValue: "2m"}
-	CanaryFailureThreshold  = Flag{Type: Int, Name: flagkey.CanaryFailureThreshold, Aliases: []string{"threshold"}, Usage: "Threshold in percentage beyond which the new version of the function is considered unstable", DefaultValue: 10}
+	CanaryFailureThreshold  = Flag{Type: Int, Name: flagkey.CanaryFailureThreshold, Aliases: []string{"threshold"}, Usage: "Threshold in percentage beyond which the new version of the function is considered unstable: the percentage of failed requests, or of latency increase over the old function for the relative-latency failure type", DefaultValue: 10}
+	CanaryFailureType       = Flag{Type: String, Name: flagkey.CanaryFailureType, Usage: "Failure type analysed: status-code, latency or relative-latency", DefaultValue: string(fv1.FailureTypeStatusCode)}
+	CanaryLatencyPercentile = Flag{Type: Int, Name: flagkey.CanaryLatencyPercentile, Usage: "Latency percentile compared by the latency failure types, ex: 99 for the p99 latency", DefaultValue: fv1.DefaultCanaryLatencyPercentile}
+	CanaryLatencyThreshold  = Flag{Type: String, Name: flagkey.CanaryLatencyThreshold, Usage: "Latency beyond which the new version of the function is considered unstable for the latency failure type, ex: 500ms"}
+	CanaryMinRequests       = Flag{Type: Int, Name: flagkey.CanaryMinRequests, Usage: "Minimum number of requests to the functions in an increment interval before the new version of the function is analysed", DefaultValue: 1}
+	CanaryMetricsProvider   = Flag{Type: String, Name: flagkey.CanaryMetricsProvider, Usage: "Metrics provider analysing the new version of the function: prometheus or webhook (default: the prometheus server of the canary deployment feature)"}
+	CanaryMetricsURL        = Flag{Type: String, Name: flagkey.CanaryMetricsURL, Usage: "URL of the prometheus server, or of the webhook returning whether the new version of the function passes"}
+	CanaryQuery             = Flag{Type: StringSlice, Name: flagkey.CanaryQuery, Usage: "Custom query template the new version of the function is analysed with, in the format of <name>=<query>, ex: --query 'errors=sum(rate(app_errors_total{function=\"{{ .Function }}\"}[{{ .Window }}]))'"}
+	CanaryQueryMin          = Flag{Type: StringSlice, Name: flagkey.CanaryQueryMin, Usage: "Minimum value of a custom query result, in the format of <name>=<value>"}
+	CanaryQueryMax          = Flag{Type: StringSlice, Name: flagkey.CanaryQueryMax, Usage: "Maximum value of a custom query result, in the format of <name>=<value>"}
+	CanaryStrategy          = Flag{Type: String, Name: flagkey.CanaryStrategy, Usage: "Strategy shifting the traffic to the new version of the function: canary shifts it gradually, bluegreen switches it at once and rolls back if the analysis after the switch fails", DefaultValue: string(fv1.CanaryStrategyCanary)}
+	CanarySchedule          = Flag{Type: StringSlice, Name: flagkey.CanarySchedule, Usage: "Weight of the new version of the function and how long it's kept before the next one, in the format of <weight>[=<duration>], replacing the weight increment step, ex: --schedule 1=1m --schedule 5=5m --schedule 25=10m --schedule 100"}
+	CanarySmokeTestFunc     = Flag{Type: String, Name: flagkey.CanarySmokeTestFunc, Usage: "Function called before the traffic is switched to the new version of the function for the bluegreen strategy, cancelling the switch unless it responds with a 2xx status code"}
 )
@@ -88,10 +88,17 @@ const (
 	HtFilter            = HtFnName
 	HtPrefix            = "prefix"
 
-	TtName   = resourceName
-	TtCron   = "cron"
-	TtFnName = "function"
-	TtRound  = "round"
+	TtName              = resourceName
+	TtCron              = "cron"
+	TtFnName            = "function"
+	TtRound             = "round"
+	TtTimezone          = "timezone"
+	TtBody              = "body"
+	TtContentType       = "contenttype"
+	TtJitter            = "jitter"
+	TtConcurrencyPolicy = "concurrencypolicy"
+	TtStartingDeadline  = "startingdeadline"
+	TtCatchUpPolicy     = "catchuppolicy"
 
 	MqtName            = resourceName
 	MqtFnName          = "function"
@@ -108,23 +115,41 @@ const (
 	MqtMetadata        = "metadata"
 	MqtSecret          = "secret"
 	MqtKind            = "mqtkind"
+	MqtDlqServer       = "mqserver"
+	MqtDlqSecret       = "mqsecret"
+	MqtDlqSince        = "since"
+	MqtDlqUntil        = "until"
+	MqtDlqCount        = "count"
+	MqtDlqID           = "id"
 
 	EnvName            = resourceName
 	EnvPoolsize        = "poolsize"
 	EnvImage           = "image"
 	EnvBuilderImage    = "builder"
 	EnvBuildcommand    = "buildcmd"
+	EnvBuildTimeout    = "buildtimeout"
+	EnvBuildRetries    = "buildretries"
+	EnvOCIRepository   = "ocirepository"
 	EnvKeeparchive     = "keeparchive"
+	EnvReload          = "reload"
 	EnvExternalNetwork = "externalnetwork"
 	EnvGracePeriod     = "graceperiod"
 	EnvVersion         = "version"
 	EnvImagePullSecret = "imagepullsecret"
+	EnvSignatureKey    = "signaturekey"
+	EnvBuilderSignKey  = "buildersignkey"
 
 	KwName      = resourceName
 	KwFnName    = "function"
 	KwNamespace = "namespace"
 	KwObjType   = "type"
 	KwLabels    = "labels"
+	KwGroup     = "group"
+	KwVersion   = "apiversion"
+	KwResource  = "resource"
+	KwFields    = "fieldselector"
+	KwEventType = "eventtype"
+	KwPredicate = "predicate"
 
 	PkgName           = resourceName
 	PkgForce          = force
@@ -136,9 +161,15 @@ const (
 	PkgDeployChecksum = "deploychecksum"
 	PkgInsecure       = "insecure"
 	PkgBuildCmd       = "buildcmd"
+	PkgBuildTimeout   = "buildtimeout"
+	PkgBuildRetries   = "buildretries"
 	PkgOutput         = Output
 	PkgStatus         = "status"
 	PkgOrphan         = "orphan"
+	PkgFollow         = "follow"
+	PkgOCIRepository  = "ocirepository"
+	PkgPullSecret     = "imagepullsecret"
+	PkgSignKey        = "signkey"
 
 	SpecSave     = "spec"
 	SpecDir      = "specdir"
@@ -160,6 +191,18 @@ const (
 	CanaryWeightIncrement   = "increment-step"
 	CanaryIncrementInterval = "increment-interval"
 	CanaryFailureThreshold  = "failure-threshold"
+	CanaryFailureType       = "failure-type"
+	CanaryLatencyPercentile = "latency-percentile"
+	CanaryLatencyThreshold  = "latency-threshold"
+	CanaryMinRequests       = "min-requests"
+	CanaryMetricsProvider   = "metrics-provider"
+	CanaryMetricsURL        = "metrics-provider-url"
+	CanaryQuery             = "query"
+	CanaryQueryMin          = "query-min"
+	CanaryQueryMax          = "query-max"
+	CanaryStrategy          = "strategy"
+	CanarySchedule          = "schedule"
+	CanarySmokeTestFunc     = "smoke-test-function"
 
 	DefaultSpecOutputDir = "fission-dump"
 )
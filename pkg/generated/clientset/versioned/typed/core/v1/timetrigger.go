@@ -40,6 +40,7 @@ type TimeTriggersGetter interface {
 type TimeTriggerInterface interface {
 	Create(ctx context.Context, _timeTrigger *v1.TimeTrigger, opts metav1.CreateOptions) (*v1.TimeTrigger, error)
 	Update(ctx context.Context, _timeTrigger *v1.TimeTrigger, opts metav1.UpdateOptions) (*v1.TimeTrigger, error)
+	UpdateStatus(ctx context.Context, _timeTrigger *v1.TimeTrigger, opts metav1.UpdateOptions) (*v1.TimeTrigger, error)
 	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error
 	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1.TimeTrigger, error)
@@ -135,6 +136,22 @@ func (c *timeTriggers) Update(ctx context.Context, _timeTrigger *v1.TimeTrigger,
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *timeTriggers) UpdateStatus(ctx context.Context, _timeTrigger *v1.TimeTrigger, opts metav1.UpdateOptions) (result *v1.TimeTrigger, err error) {
+	result = &v1.TimeTrigger{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("timetriggers").
+		Name(_timeTrigger.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(_timeTrigger).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the _timeTrigger and deletes it. Returns an error if one occurs.
 func (c *timeTriggers) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
 	return c.client.Delete().
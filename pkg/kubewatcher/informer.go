@@ -0,0 +1,200 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+)
+
+type (
+	// informerKey identifies the objects listed and watched by an informer.
+	// Triggers watching the same objects share an informer.
+	informerKey struct {
+		gvr           schema.GroupVersionResource
+		namespace     string
+		labelSelector string
+		fieldSelector string
+	}
+
+	// sharedInformer fans out the events of an informer to the
+	// subscriptions of the triggers. refs is only accessed by the
+	// KubeWatcher service loop.
+	sharedInformer struct {
+		logger   *zap.Logger
+		key      informerKey
+		informer cache.SharedIndexInformer
+		stopCh   chan struct{}
+		refs     int
+
+		lock        sync.RWMutex
+		subscribers map[types.UID]*watchSubscription
+		// subscriptions waiting for the informer cache to sync
+		waiting map[types.UID]*watchSubscription
+	}
+)
+
+func makeSharedInformer(logger *zap.Logger, dynamicClient dynamic.Interface, key informerKey, resyncPeriod time.Duration) *sharedInformer {
+	tweakListOptions := func(options *metav1.ListOptions) {
+		options.LabelSelector = key.labelSelector
+		options.FieldSelector = key.fieldSelector
+	}
+	si := &sharedInformer{
+		logger: logger.Named("informer").With(zap.String("resource", key.gvr.String()),
+			zap.String("namespace", key.namespace)),
+		key:         key,
+		stopCh:      make(chan struct{}),
+		subscribers: make(map[types.UID]*watchSubscription),
+		waiting:     make(map[types.UID]*watchSubscription),
+	}
+	// the reflector only logs list and watch errors, they are checked here
+	// to report denials on the triggers
+	resource := dynamicClient.Resource(key.gvr).Namespace(key.namespace)
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			tweakListOptions(&options)
+			list, err := resource.List(context.TODO(), options)
+			si.listed(err)
+			return list, err
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			tweakListOptions(&options)
+			w, err := resource.Watch(context.TODO(), options)
+			if err != nil {
+				si.listed(err)
+			}
+			return w, err
+		},
+	}
+	si.informer = cache.NewSharedIndexInformer(lw, &unstructured.Unstructured{}, resyncPeriod,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	si.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			si.dispatch(watch.Added, obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			// resyncs deliver the same object version, which subscriptions
+			// skip unless they missed it
+			si.dispatch(watch.Modified, obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				// the delete was missed, e.g. while the watch was
+				// re-established; the object is its last known state
+				obj = tombstone.Obj
+			}
+			si.dispatch(watch.Deleted, obj)
+		},
+	})
+	go si.informer.Run(si.stopCh)
+	return si
+}
+
+func (si *sharedInformer) dispatch(eventType watch.EventType, obj interface{}) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		si.logger.Warn("unexpected object type in informer event", zap.Any("object", obj))
+		return
+	}
+	si.lock.RLock()
+	defer si.lock.RUnlock()
+	for _, ws := range si.subscribers {
+		ws.handle(eventType, u)
+	}
+}
+
+// listed records the outcome of listing or watching the objects on the
+// subscriptions. Denials are reported in the status of the triggers, as
+// the informer retries without giving up; they are cleared by the next
+// successful list.
+func (si *sharedInformer) listed(err error) {
+	if err != nil && !k8serrors.IsForbidden(err) && !k8serrors.IsUnauthorized(err) {
+		return
+	}
+	if err != nil {
+		si.logger.Error("not allowed to list or watch the watched objects", zap.Error(err))
+	}
+	si.lock.RLock()
+	defer si.lock.RUnlock()
+	for _, subscribers := range []map[types.UID]*watchSubscription{si.waiting, si.subscribers} {
+		for _, ws := range subscribers {
+			ws.setError(err)
+		}
+	}
+}
+
+// subscribe starts delivering events to a subscription once the informer
+// cache is synced. The subscription is first reconciled with the cache to
+// catch up on the changes it missed.
+func (si *sharedInformer) subscribe(ws *watchSubscription) {
+	err := ws.load()
+	if err != nil {
+		ws.logger.Error("error loading watched objects, objects deleted while not watching are missed",
+			zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
+	}
+
+	si.lock.Lock()
+	si.waiting[ws.watch.ObjectMeta.UID] = ws
+	si.lock.Unlock()
+	if !cache.WaitForCacheSync(ws.stopCh, si.informer.HasSynced) {
+		return
+	}
+
+	// the write lock holds back events until the subscription is reconciled
+	si.lock.Lock()
+	defer si.lock.Unlock()
+	delete(si.waiting, ws.watch.ObjectMeta.UID)
+	select {
+	case <-ws.stopCh:
+		return
+	default:
+	}
+
+	var objs []*unstructured.Unstructured
+	for _, obj := range si.informer.GetStore().List() {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			objs = append(objs, u)
+		}
+	}
+	ws.reconcile(objs)
+	si.subscribers[ws.watch.ObjectMeta.UID] = ws
+	ws.logger.Info("listening to informer", zap.String("watch_name", ws.watch.ObjectMeta.Name),
+		zap.String("resource", si.key.gvr.String()))
+}
+
+func (si *sharedInformer) unsubscribe(ws *watchSubscription) {
+	si.lock.Lock()
+	defer si.lock.Unlock()
+	delete(si.waiting, ws.watch.ObjectMeta.UID)
+	delete(si.subscribers, ws.watch.ObjectMeta.UID)
+}
+
+func (si *sharedInformer) stop() {
+	close(si.stopCh)
+}
@@ -0,0 +1,247 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/crd"
+	"github.com/fission/fission/pkg/generated/clientset/versioned/fake"
+	"github.com/fission/fission/pkg/publisher"
+)
+
+// recordingPublisher records the headers of published events.
+type recordingPublisher struct {
+	headers []map[string]string
+}
+
+func (p *recordingPublisher) Publish(body string, headers map[string]string, target string) {}
+
+func (p *recordingPublisher) PublishEvent(event publisher.CloudEvent, body string, headers map[string]string, target string) {
+	p.headers = append(p.headers, headers)
+}
+
+func (p *recordingPublisher) PublishEventTracked(ctx context.Context, event publisher.CloudEvent, body string, headers map[string]string, target string, done func(error)) {
+	p.PublishEvent(event, body, headers, target)
+	done(nil)
+}
+
+func (p *recordingPublisher) events() []string {
+	var events []string
+	for _, h := range p.headers {
+		e := h["X-Kubernetes-Event-Type"]
+		if h["X-Fission-Tombstone"] == "true" {
+			e += " tombstone"
+		}
+		events = append(events, e)
+	}
+	p.headers = nil
+	return events
+}
+
+func namedPod(name, uid, resourceVersion string) *unstructured.Unstructured {
+	pod := testPod()
+	pod.SetName(name)
+	pod.SetUID(types.UID(uid))
+	pod.SetResourceVersion(resourceVersion)
+	return pod
+}
+
+func TestWatchSubscriptionResume(t *testing.T) {
+	lastUpdate := metav1.Now()
+	trigger := &fv1.KubernetesWatchTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "watch", Namespace: "default"},
+		Spec: fv1.KubernetesWatchTriggerSpec{
+			Namespace: "default",
+			Type:      "pod",
+			FunctionReference: fv1.FunctionReference{
+				Type: fv1.FunctionReferenceTypeFunctionName,
+				Name: "hello",
+			},
+		},
+		Status: fv1.KubernetesWatchTriggerStatus{
+			LastResourceVersion: "12",
+			LastUpdateTime:      &lastUpdate,
+		},
+	}
+	pub := &recordingPublisher{}
+	ws, err := MakeWatchSubscription(zap.NewNop(), trigger, nil, nil, schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, pub)
+	require.NoError(t, err)
+	ws.objects = map[types.UID]fv1.WatchedObject{
+		"1": {Namespace: "default", Name: "unchanged", UID: "1", ResourceVersion: "10"},
+		"2": {Namespace: "default", Name: "changed", UID: "2", ResourceVersion: "11"},
+		"3": {Namespace: "default", Name: "deleted", UID: "3", ResourceVersion: "12"},
+	}
+
+	created := namedPod("created", "4", "14")
+	created.SetCreationTimestamp(metav1.NewTime(lastUpdate.Add(time.Minute)))
+	// changed while down, but not processed before
+	old := namedPod("old", "5", "13")
+	old.SetCreationTimestamp(metav1.NewTime(lastUpdate.Add(-time.Hour)))
+	ws.reconcile([]*unstructured.Unstructured{
+		namedPod("unchanged", "1", "10"),
+		namedPod("changed", "2", "15"),
+		created,
+		old,
+	})
+	require.Equal(t, []string{"MODIFIED", "ADDED", "MODIFIED", "DELETED tombstone"}, pub.events())
+	require.Len(t, ws.objects, 4)
+	require.Equal(t, "15", ws.lastResourceVersion)
+	require.True(t, ws.dirty)
+
+	// events replayed by the informer are skipped
+	ws.handle(watch.Added, created)
+	ws.handle(watch.Modified, namedPod("changed", "2", "15"))
+	require.Empty(t, pub.events())
+
+	ws.handle(watch.Modified, namedPod("created", "4", "16"))
+	ws.handle(watch.Deleted, namedPod("unchanged", "1", "17"))
+	ws.handle(watch.Deleted, namedPod("unknown", "6", "18"))
+	require.Equal(t, []string{"MODIFIED", "DELETED"}, pub.events())
+	require.Equal(t, "17", ws.lastResourceVersion)
+	require.Len(t, ws.objects, 3)
+	require.Equal(t, "16", ws.objects["4"].ResourceVersion)
+}
+
+func TestWatchSubscriptionSavedObjects(t *testing.T) {
+	trigger := &fv1.KubernetesWatchTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "watch", Namespace: "default", UID: "trigger"},
+		Spec: fv1.KubernetesWatchTriggerSpec{
+			Namespace: "default",
+			Type:      "pod",
+			FunctionReference: fv1.FunctionReference{
+				Type: fv1.FunctionReferenceTypeFunctionName,
+				Name: "hello",
+			},
+		},
+	}
+	fissionClient := &crd.FissionClient{Interface: fake.NewSimpleClientset(trigger)}
+	kubeClient := kubefake.NewSimpleClientset()
+	kind := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+	ws, err := MakeWatchSubscription(zap.NewNop(), trigger, fissionClient, kubeClient, kind, &recordingPublisher{})
+	require.NoError(t, err)
+	require.NoError(t, ws.load())
+	var pods []*unstructured.Unstructured
+	for i := 0; i < 500; i++ {
+		pods = append(pods, namedPod(fmt.Sprint("pod", i), fmt.Sprint(i), fmt.Sprint(i+1)))
+	}
+	ws.reconcile(pods)
+	ws.persist()
+
+	cm, err := kubeClient.CoreV1().ConfigMaps("default").Get(context.TODO(), watchStateConfigMap("trigger"), metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, types.UID("trigger"), cm.OwnerReferences[0].UID)
+	trigger, err = fissionClient.CoreV1().KubernetesWatchTriggers("default").Get(context.TODO(), "watch", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "500", trigger.Status.LastResourceVersion)
+
+	// every object deleted while the trigger wasn't watching is delivered
+	pub := &recordingPublisher{}
+	ws, err = MakeWatchSubscription(zap.NewNop(), trigger, fissionClient, kubeClient, kind, pub)
+	require.NoError(t, err)
+	require.NoError(t, ws.load())
+	require.Len(t, ws.objects, 500)
+	ws.reconcile(pods[:100])
+	events := pub.events()
+	require.Len(t, events, 400)
+	require.Equal(t, "DELETED tombstone", events[0])
+	require.Len(t, ws.objects, 100)
+}
+
+func TestResourceVersionAfter(t *testing.T) {
+	require.True(t, resourceVersionAfter("100", "99"))
+	require.False(t, resourceVersionAfter("99", "100"))
+	require.True(t, resourceVersionAfter("b", "a"))
+}
+
+func TestWatchSubscriptionForbidden(t *testing.T) {
+	trigger := &fv1.KubernetesWatchTrigger{
+		ObjectMeta: metav1.ObjectMeta{Name: "watch", Namespace: "default", UID: "1"},
+		Spec: fv1.KubernetesWatchTriggerSpec{
+			Namespace: "default",
+			Type:      "pod",
+			FunctionReference: fv1.FunctionReference{
+				Type: fv1.FunctionReferenceTypeFunctionName,
+				Name: "hello",
+			},
+		},
+	}
+	fissionClient := &crd.FissionClient{Interface: fake.NewSimpleClientset(trigger)}
+
+	var lock sync.Mutex
+	allowed := false
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dynamicClient.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if !allowed {
+			return true, nil, k8serrors.NewForbidden(schema.GroupResource{Resource: "pods"}, "", errors.New("not allowed"))
+		}
+		list := &unstructured.UnstructuredList{}
+		list.SetAPIVersion("v1")
+		list.SetKind("PodList")
+		return true, list, nil
+	})
+
+	ws, err := MakeWatchSubscription(zap.NewNop(), trigger, fissionClient, nil, schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, &recordingPublisher{})
+	require.NoError(t, err)
+	key := informerKey{gvr: schema.GroupVersionResource{Version: "v1", Resource: "pods"}, namespace: "default"}
+	si := makeSharedInformer(zap.NewNop(), dynamicClient, key, 0)
+	defer si.stop()
+	defer ws.stop()
+	go si.subscribe(ws)
+
+	status := func() fv1.KubernetesWatchTriggerStatus {
+		ws.persist()
+		w, err := fissionClient.CoreV1().KubernetesWatchTriggers("default").Get(context.TODO(), "watch", metav1.GetOptions{})
+		require.NoError(t, err)
+		return w.Status
+	}
+
+	// the denial is reported on the trigger, and cleared once allowed
+	require.Eventually(t, func() bool {
+		return len(status().LastError) > 0
+	}, 10*time.Second, 50*time.Millisecond)
+	require.Contains(t, status().LastError, "forbidden")
+
+	lock.Lock()
+	allowed = true
+	lock.Unlock()
+	require.Eventually(t, func() bool {
+		return len(status().LastError) == 0
+	}, 10*time.Second, 50*time.Millisecond)
+}
@@ -23,20 +23,29 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
-	"sync/atomic"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
-	"k8s.io/apimachinery/pkg/api/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/crd"
 	ferror "github.com/fission/fission/pkg/error"
 	"github.com/fission/fission/pkg/publisher"
 	"github.com/fission/fission/pkg/utils"
@@ -48,23 +57,55 @@ const (
 	SYNC requestType = iota
 )
 
+// statusPersistInterval is how often the progress of a trigger is recorded
+// in its status
+const statusPersistInterval = 5 * time.Second
+
+// cloudEventTypePrefix prefixes the lower cased watch event type to form
+// the CloudEvents type, e.g. io.fission.kubewatcher.added
+const cloudEventTypePrefix = "io.fission.kubewatcher."
+
 type (
+	// resettable is implemented by REST mappers caching discovery
+	resettable interface {
+		Reset()
+	}
+
 	KubeWatcher struct {
-		logger           *zap.Logger
-		watches          map[types.UID]watchSubscription
-		kubernetesClient *kubernetes.Clientset
-		requestChannel   chan *kubeWatcherRequest
-		publisher        publisher.Publisher
+		logger         *zap.Logger
+		watches        map[types.UID]*watchSubscription
+		informers      map[informerKey]*sharedInformer
+		fissionClient  *crd.FissionClient
+		kubeClient     kubernetes.Interface
+		dynamicClient  dynamic.Interface
+		restMapper     meta.RESTMapper
+		resyncPeriod   time.Duration
+		requestChannel chan *kubeWatcherRequest
+		publisher      publisher.Publisher
 	}
 
 	watchSubscription struct {
-		logger              *zap.Logger
-		watch               fv1.KubernetesWatchTrigger
-		kubeWatch           watch.Interface
+		logger        *zap.Logger
+		watch         fv1.KubernetesWatchTrigger
+		fissionClient *crd.FissionClient
+		kubeClient    kubernetes.Interface
+		informer      *sharedInformer
+		kind          schema.GroupVersionKind
+		eventTypes    map[watch.EventType]bool
+		predicates    []predicate
+		publisher     publisher.Publisher
+		stopCh        chan struct{}
+
+		// lock protects the state below. The processed objects are kept in
+		// a ConfigMap of the trigger, its status records the last resource
+		// version.
+		lock                sync.Mutex
+		objects             map[types.UID]fv1.WatchedObject
 		lastResourceVersion string
-		stopped             *int32
-		kubernetesClient    *kubernetes.Clientset
-		publisher           publisher.Publisher
+		lastUpdateTime      *metav1.Time
+		lastErrorTime       *metav1.Time
+		lastError           string
+		dirty               bool
 	}
 
 	kubeWatcherRequest struct {
@@ -77,13 +118,21 @@ type (
 	}
 )
 
-func MakeKubeWatcher(logger *zap.Logger, kubernetesClient *kubernetes.Clientset, publisher publisher.Publisher) *KubeWatcher {
+// MakeKubeWatcher returns a KubeWatcher delivering the events of shared
+// informers to the functions of the watch triggers. A non-zero resyncPeriod
+// periodically reconciles the watched objects with the informer caches.
+func MakeKubeWatcher(logger *zap.Logger, fissionClient *crd.FissionClient, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, restMapper meta.RESTMapper, publisher publisher.Publisher, resyncPeriod time.Duration) *KubeWatcher {
 	kw := &KubeWatcher{
-		logger:           logger.Named("kube_watcher"),
-		watches:          make(map[types.UID]watchSubscription),
-		kubernetesClient: kubernetesClient,
-		publisher:        publisher,
-		requestChannel:   make(chan *kubeWatcherRequest),
+		logger:         logger.Named("kube_watcher"),
+		watches:        make(map[types.UID]*watchSubscription),
+		informers:      make(map[informerKey]*sharedInformer),
+		fissionClient:  fissionClient,
+		kubeClient:     kubeClient,
+		dynamicClient:  dynamicClient,
+		restMapper:     restMapper,
+		resyncPeriod:   resyncPeriod,
+		publisher:      publisher,
+		requestChannel: make(chan *kubeWatcherRequest),
 	}
 	go kw.svc()
 	return kw
@@ -118,7 +167,11 @@ func (kw *KubeWatcher) svc() {
 			// Add new watches
 			for _, w := range req.watches {
 				if _, ok := kw.watches[w.ObjectMeta.UID]; !ok {
-					kw.addWatch(&w) //nolint: errCheck
+					err := kw.addWatch(&w)
+					if err != nil {
+						// retried on the next sync
+						kw.logger.Error("error adding watch", zap.Error(err), zap.String("name", w.ObjectMeta.Name))
+					}
 				}
 			}
 			req.responseChannel <- &kubeWatcherResponse{error: nil}
@@ -149,40 +202,74 @@ func printKubernetesObject(obj runtime.Object, w io.Writer) error {
 	return err
 }
 
-func createKubernetesWatch(kubeClient *kubernetes.Clientset, w *fv1.KubernetesWatchTrigger, resourceVersion string) (watch.Interface, error) {
-	var wi watch.Interface
-	var err error
-	var watchTimeoutSec int64 = 120
-
-	// TODO populate labelselector and fieldselector
-	listOptions := metav1.ListOptions{
-		ResourceVersion: resourceVersion,
-		TimeoutSeconds:  &watchTimeoutSec,
-	}
-
-	// TODO handle the full list of types
-	switch strings.ToUpper(w.Spec.Type) {
-	case "POD":
-		wi, err = kubeClient.CoreV1().Pods(w.Spec.Namespace).Watch(context.TODO(), listOptions)
-	case "SERVICE":
-		wi, err = kubeClient.CoreV1().Services(w.Spec.Namespace).Watch(context.TODO(), listOptions)
-	case "REPLICATIONCONTROLLER":
-		wi, err = kubeClient.CoreV1().ReplicationControllers(w.Spec.Namespace).Watch(context.TODO(), listOptions)
-	case "JOB":
-		wi, err = kubeClient.BatchV1().Jobs(w.Spec.Namespace).Watch(context.TODO(), listOptions)
-	default:
-		err = errors.NewBadRequest(fmt.Sprintf("Error: unknown obj type '%v'", w.Spec.Type))
+// resolveResource returns the resource watched by the trigger, its kind and
+// whether it is namespaced. The resource is looked up by kind or resource
+// name through discovery, so that any resource served by the API server can
+// be watched.
+func resolveResource(restMapper meta.RESTMapper, spec fv1.KubernetesWatchTriggerSpec) (schema.GroupVersionResource, schema.GroupVersionKind, bool, error) {
+	resource := spec.Resource
+	if len(resource) == 0 {
+		resource = strings.ToLower(spec.Type)
+	}
+
+	partial := schema.GroupVersionResource{
+		Group:    spec.Group,
+		Version:  spec.Version,
+		Resource: resource,
+	}
+	gvr, err := restMapper.ResourceFor(partial)
+	if r, ok := restMapper.(resettable); ok && meta.IsNoMatchError(err) {
+		// the resource may have been installed after discovery was cached
+		r.Reset()
+		gvr, err = restMapper.ResourceFor(partial)
 	}
-	return wi, err
+	if err != nil {
+		return gvr, schema.GroupVersionKind{}, false, errors.Wrapf(err, "error finding resource %q", resource)
+	}
+	gvk, err := restMapper.KindFor(gvr)
+	if err != nil {
+		return gvr, gvk, false, errors.Wrapf(err, "error finding kind of resource %v", gvr)
+	}
+	mapping, err := restMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return gvr, gvk, false, errors.Wrapf(err, "error finding scope of resource %v", gvr)
+	}
+	return gvr, gvk, mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
 }
 
 func (kw *KubeWatcher) addWatch(w *fv1.KubernetesWatchTrigger) error {
 	kw.logger.Info("adding watch", zap.String("name", w.ObjectMeta.Name), zap.Any("function", w.Spec.FunctionReference))
-	ws, err := MakeWatchSubscription(kw.logger.Named("watchsubscription"), w, kw.kubernetesClient, kw.publisher)
+
+	gvr, gvk, namespaced, err := resolveResource(kw.restMapper, w.Spec)
 	if err != nil {
 		return err
 	}
-	kw.watches[w.ObjectMeta.UID] = *ws
+	ws, err := MakeWatchSubscription(kw.logger, w, kw.fissionClient, kw.kubeClient, gvk, kw.publisher)
+	if err != nil {
+		return err
+	}
+
+	key := informerKey{
+		gvr:           gvr,
+		fieldSelector: w.Spec.FieldSelector,
+	}
+	if namespaced {
+		key.namespace = w.Spec.Namespace
+	}
+	if len(w.Spec.LabelSelector) > 0 {
+		key.labelSelector = labels.SelectorFromSet(w.Spec.LabelSelector).String()
+	}
+	informer, ok := kw.informers[key]
+	if !ok {
+		informer = makeSharedInformer(kw.logger, kw.dynamicClient, key, kw.resyncPeriod)
+		kw.informers[key] = informer
+	}
+	informer.refs++
+	ws.informer = informer
+	kw.watches[w.ObjectMeta.UID] = ws
+
+	go ws.persistLoop()
+	go informer.subscribe(ws)
 	return nil
 }
 
@@ -195,143 +282,378 @@ func (kw *KubeWatcher) removeWatch(w *fv1.KubernetesWatchTrigger) error {
 	}
 	delete(kw.watches, w.ObjectMeta.UID)
 	ws.stop()
+
+	informer := ws.informer
+	informer.unsubscribe(ws)
+	informer.refs--
+	if informer.refs == 0 {
+		delete(kw.informers, informer.key)
+		informer.stop()
+	}
 	return nil
 }
 
-func MakeWatchSubscription(logger *zap.Logger, w *fv1.KubernetesWatchTrigger, kubeClient *kubernetes.Clientset, publisher publisher.Publisher) (*watchSubscription, error) {
-	var stopped int32 = 0
-	ws := &watchSubscription{
+// MakeWatchSubscription returns the subscription of a trigger to the events
+// of objects of the given kind. It resumes from the progress recorded in the
+// status of the trigger and the objects saved in its ConfigMap.
+func MakeWatchSubscription(logger *zap.Logger, w *fv1.KubernetesWatchTrigger, fissionClient *crd.FissionClient, kubeClient kubernetes.Interface, kind schema.GroupVersionKind, publisher publisher.Publisher) (*watchSubscription, error) {
+	predicates, err := compilePredicates(w.Spec.Predicates)
+	if err != nil {
+		return nil, err
+	}
+
+	var eventTypes map[watch.EventType]bool
+	if len(w.Spec.EventTypes) > 0 {
+		eventTypes = make(map[watch.EventType]bool)
+		for _, t := range w.Spec.EventTypes {
+			eventTypes[watch.EventType(strings.ToUpper(t))] = true
+		}
+	}
+
+	return &watchSubscription{
 		logger:              logger.Named("watch_subscription"),
 		watch:               *w,
-		kubeWatch:           nil,
-		stopped:             &stopped,
-		kubernetesClient:    kubeClient,
+		fissionClient:       fissionClient,
+		kubeClient:          kubeClient,
+		kind:                kind,
+		eventTypes:          eventTypes,
+		predicates:          predicates,
 		publisher:           publisher,
-		lastResourceVersion: "",
-	}
+		stopCh:              make(chan struct{}),
+		objects:             make(map[types.UID]fv1.WatchedObject),
+		lastResourceVersion: w.Status.LastResourceVersion,
+		lastUpdateTime:      w.Status.LastUpdateTime,
+		lastErrorTime:       w.Status.LastErrorTime,
+		lastError:           w.Status.LastError,
+	}, nil
+}
 
-	err := ws.restartWatch()
-	if err != nil {
-		return nil, err
+// setError records the error of listing or watching the watched objects,
+// or clears it when nil.
+func (ws *watchSubscription) setError(err error) {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+	if err == nil {
+		if len(ws.lastError) > 0 {
+			ws.lastError = ""
+			ws.dirty = true
+		}
+		return
 	}
-
-	go ws.eventDispatchLoop()
-	return ws, nil
+	now := metav1.Now()
+	ws.lastErrorTime = &now
+	ws.lastError = err.Error()
+	ws.dirty = true
 }
 
-func (ws *watchSubscription) restartWatch() error {
-	retries := 60
-	for {
-		ws.logger.Info("(re)starting watch",
-			zap.Any("watch", ws.watch.ObjectMeta),
-			zap.String("namespace", ws.watch.Spec.Namespace),
-			zap.String("type", ws.watch.Spec.Type),
-			zap.String("last_resource_version", ws.lastResourceVersion))
-		wi, err := createKubernetesWatch(ws.kubernetesClient, &ws.watch, ws.lastResourceVersion)
-		if err != nil {
-			retries--
-			if retries > 0 {
-				time.Sleep(500 * time.Millisecond)
-				continue
-			} else {
-				return err
-			}
+// handle processes an event of the informer. Events of object versions
+// already processed, e.g. replayed after the informer relisted, are
+// skipped.
+func (ws *watchSubscription) handle(eventType watch.EventType, obj *unstructured.Unstructured) {
+	ws.lock.Lock()
+	known, ok := ws.objects[obj.GetUID()]
+	switch eventType {
+	case watch.Deleted:
+		if !ok {
+			ws.lock.Unlock()
+			return
+		}
+		delete(ws.objects, obj.GetUID())
+	default:
+		if ok && known.ResourceVersion == obj.GetResourceVersion() {
+			ws.lock.Unlock()
+			return
+		}
+		if !ok {
+			eventType = watch.Added
+		} else {
+			eventType = watch.Modified
 		}
-		ws.kubeWatch = wi
-		return nil
+		ws.objects[obj.GetUID()] = watchedObject(obj)
 	}
+	ws.lastResourceVersion = obj.GetResourceVersion()
+	ws.dirty = true
+	ws.lock.Unlock()
+
+	ws.dispatch(eventType, obj, false)
 }
 
-func getResourceVersion(obj runtime.Object) (string, error) {
-	m, err := meta.Accessor(obj)
+// load restores the objects processed before the trigger stopped
+// watching, saved in its ConfigMap.
+func (ws *watchSubscription) load() error {
+	var objects map[types.UID]fv1.WatchedObject
+	err := retry.OnError(retry.DefaultBackoff, func(err error) bool { return true }, func() error {
+		var err error
+		objects, err = ws.loadObjects(context.TODO())
+		return err
+	})
 	if err != nil {
-		return "", err
+		return err
 	}
-	return m.GetResourceVersion(), nil
+	ws.lock.Lock()
+	ws.objects = objects
+	ws.lock.Unlock()
+	return nil
 }
 
-func (ws *watchSubscription) eventDispatchLoop() {
-	ws.logger.Info("listening to watch", zap.String("name", ws.watch.ObjectMeta.Name))
-	for {
-		// check watchSubscription is stopped or not before waiting for event
-		// comes from the kubeWatch.ResultChan(). This fix the edge case that
-		// new kubewatch is created in the restartWatch() while the old kubewatch
-		// is being used in watchSubscription.stop().
-		if ws.isStopped() {
-			break
+// reconcile dispatches the events missed while the trigger wasn't
+// watching: objects of the informer cache changed since the last processed
+// resource version, and processed objects no longer in the cache, delivered
+// as tombstones. A trigger without status gets every object as added.
+func (ws *watchSubscription) reconcile(objs []*unstructured.Unstructured) {
+	ws.lock.Lock()
+	since := ws.lastResourceVersion
+	known := ws.objects
+	ws.objects = make(map[types.UID]fv1.WatchedObject, len(objs))
+
+	type missedEvent struct {
+		eventType watch.EventType
+		obj       *unstructured.Unstructured
+	}
+	var missed []missedEvent
+	for _, obj := range objs {
+		ws.objects[obj.GetUID()] = watchedObject(obj)
+		if len(since) > 0 && !resourceVersionAfter(obj.GetResourceVersion(), since) {
+			continue
 		}
-		ev, more := <-ws.kubeWatch.ResultChan()
-		if !more {
-			if ws.isStopped() {
-				// watch is removed by user.
-				ws.logger.Warn("watch stopped", zap.String("watch_name", ws.watch.ObjectMeta.Name))
-				return
-			} else {
-				// watch closed due to timeout, restart it.
-				ws.logger.Warn("watch timed out - restarting", zap.String("watch_name", ws.watch.ObjectMeta.Name))
-				err := ws.restartWatch()
-				if err != nil {
-					ws.logger.Panic("failed to restart watch", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
-				}
-				continue
-			}
+		eventType := watch.Added
+		if _, ok := known[obj.GetUID()]; ok || (ws.lastUpdateTime != nil && obj.GetCreationTimestamp().Time.Before(ws.lastUpdateTime.Time)) {
+			eventType = watch.Modified
 		}
-
-		if ev.Type == watch.Error {
-			e := errors.FromObject(ev.Object)
-			ws.logger.Warn("watch error - retrying after one second", zap.Error(e), zap.String("watch_name", ws.watch.ObjectMeta.Name))
-			// Start from the beginning to get around "too old resource version"
-			ws.lastResourceVersion = ""
-			time.Sleep(time.Second)
-			err := ws.restartWatch()
-			if err != nil {
-				ws.logger.Panic("failed to restart watch", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
-			}
-			continue
+		missed = append(missed, missedEvent{eventType: eventType, obj: obj})
+	}
+	sort.Slice(missed, func(i, j int) bool {
+		return resourceVersionAfter(missed[j].obj.GetResourceVersion(), missed[i].obj.GetResourceVersion())
+	})
+
+	var deleted []fv1.WatchedObject
+	for uid, o := range known {
+		if _, ok := ws.objects[uid]; !ok {
+			deleted = append(deleted, o)
 		}
-		rv, err := getResourceVersion(ev.Object)
-		if err != nil {
-			ws.logger.Error("error getting resourceVersion from object", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
-		} else {
-			ws.lastResourceVersion = rv
+	}
+	for _, e := range missed {
+		if resourceVersionAfter(e.obj.GetResourceVersion(), ws.lastResourceVersion) {
+			ws.lastResourceVersion = e.obj.GetResourceVersion()
 		}
+	}
+	if len(missed) > 0 || len(deleted) > 0 || len(known) != len(ws.objects) {
+		ws.dirty = true
+	}
+	ws.lock.Unlock()
 
-		// Serialize the object
-		var buf bytes.Buffer
-		err = printKubernetesObject(ev.Object, &buf)
+	for _, e := range missed {
+		ws.dispatch(e.eventType, e.obj, false)
+	}
+	sortWatchedObjects(deleted)
+	for _, o := range deleted {
+		ws.dispatch(watch.Deleted, ws.tombstone(o), true)
+	}
+}
+
+// resourceVersionAfter reports whether the resource version rv is later than
+// since. Resource versions are opaque, but in practice they are increasing
+// etcd revisions; versions that aren't numbers are taken as later if they differ.
+func resourceVersionAfter(rv, since string) bool {
+	v, err1 := strconv.ParseUint(rv, 10, 64)
+	s, err2 := strconv.ParseUint(since, 10, 64)
+	if err1 != nil || err2 != nil {
+		return rv != since
+	}
+	return v > s
+}
+
+// tombstone returns an object standing in for a watched object deleted
+// while the trigger wasn't watching; only its metadata is known.
+func (ws *watchSubscription) tombstone(o fv1.WatchedObject) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(ws.kind)
+	obj.SetName(o.Name)
+	obj.SetNamespace(o.Namespace)
+	obj.SetUID(o.UID)
+	obj.SetResourceVersion(o.ResourceVersion)
+	return obj
+}
+
+// dispatch invokes the function of the trigger with an event.
+func (ws *watchSubscription) dispatch(eventType watch.EventType, obj *unstructured.Unstructured, tombstone bool) {
+	if !ws.matches(eventType, obj, tombstone) {
+		return
+	}
+
+	// Serialize the object
+	var buf bytes.Buffer
+	err := printKubernetesObject(obj, &buf)
+	if err != nil {
+		ws.logger.Error("failed to serialize object", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
+		// TODO send a POST request indicating error
+	}
+
+	// Event and object type aren't in the serialized object
+	headers := map[string]string{
+		"Content-Type":             "application/json",
+		"X-Kubernetes-Event-Type":  string(eventType),
+		"X-Kubernetes-Object-Type": objectType(obj),
+	}
+	if tombstone {
+		headers["X-Fission-Tombstone"] = "true"
+	}
+
+	// TODO support other function ref types. Or perhaps delegate to router?
+	if ws.watch.Spec.FunctionReference.Type != fv1.FunctionReferenceTypeFunctionName {
+		ws.logger.Error("unsupported function ref type - cannot publish event",
+			zap.Any("type", ws.watch.Spec.FunctionReference.Type),
+			zap.String("watch_name", ws.watch.ObjectMeta.Name))
+		return
+	}
+
+	// with the addition of multi-tenancy, the users can create functions in any namespace. however,
+	// the triggers can only be created in the same namespace as the function.
+	// so essentially, function namespace = trigger namespace.
+	url := utils.UrlForFunction(ws.watch.Spec.FunctionReference.Name, ws.watch.ObjectMeta.Namespace)
+	ws.publisher.PublishEvent(ws.cloudEvent(eventType, obj), buf.String(), headers, url)
+}
+
+// matches reports whether the function is invoked for the event, according
+// to the event types and predicates of the trigger. Predicates aren't
+// evaluated for tombstones, whose fields other than metadata are unknown.
+func (ws *watchSubscription) matches(eventType watch.EventType, obj *unstructured.Unstructured, tombstone bool) bool {
+	if ws.eventTypes != nil && !ws.eventTypes[eventType] {
+		return false
+	}
+	if tombstone {
+		return true
+	}
+	for _, p := range ws.predicates {
+		match, err := p.matches(obj.Object)
 		if err != nil {
-			ws.logger.Error("failed to serialize object", zap.Error(err), zap.String("watch_name", ws.watch.ObjectMeta.Name))
-			// TODO send a POST request indicating error
+			ws.logger.Warn("error evaluating predicate, skipping event", zap.Error(err),
+				zap.String("watch_name", ws.watch.ObjectMeta.Name), zap.String("path", p.Path))
+			return false
 		}
-
-		// Event and object type aren't in the serialized object
-		headers := map[string]string{
-			"Content-Type":             "application/json",
-			"X-Kubernetes-Event-Type":  string(ev.Type),
-			"X-Kubernetes-Object-Type": reflect.TypeOf(ev.Object).Elem().Name(),
+		if !match {
+			return false
 		}
+	}
+	return true
+}
 
-		// TODO support other function ref types. Or perhaps delegate to router?
-		if ws.watch.Spec.FunctionReference.Type != fv1.FunctionReferenceTypeFunctionName {
-			ws.logger.Error("unsupported function ref type - cannot publish event",
-				zap.Any("type", ws.watch.Spec.FunctionReference.Type),
-				zap.String("watch_name", ws.watch.ObjectMeta.Name))
-			continue
-		}
+// objectType returns the kind of a watched object, e.g. Pod
+func objectType(obj runtime.Object) string {
+	if kind := obj.GetObjectKind().GroupVersionKind().Kind; len(kind) > 0 {
+		return kind
+	}
+	return reflect.TypeOf(obj).Elem().Name()
+}
 
-		// with the addition of multi-tenancy, the users can create functions in any namespace. however,
-		// the triggers can only be created in the same namespace as the function.
-		// so essentially, function namespace = trigger namespace.
-		url := utils.UrlForFunction(ws.watch.Spec.FunctionReference.Name, ws.watch.ObjectMeta.Namespace)
-		ws.publisher.Publish(buf.String(), headers, url)
+// cloudEvent returns the CloudEvents attributes of a watch event. The
+// object UID and resource version identify the event.
+func (ws *watchSubscription) cloudEvent(eventType watch.EventType, objMeta metav1.Object) publisher.CloudEvent {
+	event := publisher.NewCloudEvent(
+		publisher.TriggerSource("kuberneteswatchtriggers", ws.watch.ObjectMeta.Namespace, ws.watch.ObjectMeta.Name),
+		cloudEventTypePrefix+strings.ToLower(string(eventType)), "")
+	if objMeta != nil {
+		event.ID = fmt.Sprintf("%s-%s", objMeta.GetUID(), objMeta.GetResourceVersion())
+		event.Subject = objMeta.GetNamespace() + "/" + objMeta.GetName()
 	}
+	return event
 }
 
 func (ws *watchSubscription) stop() {
-	atomic.StoreInt32(ws.stopped, 1)
-	ws.kubeWatch.Stop()
+	close(ws.stopCh)
+}
+
+// persistLoop periodically records the progress of the subscription in the
+// status of the trigger, and once more when the subscription is stopped.
+func (ws *watchSubscription) persistLoop() {
+	ticker := time.NewTicker(statusPersistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ws.persist()
+		case <-ws.stopCh:
+			ws.persist()
+			return
+		}
+	}
+}
+
+// persist updates the status of the trigger if events were processed since
+// the last update.
+func (ws *watchSubscription) persist() {
+	ws.lock.Lock()
+	if !ws.dirty || ws.fissionClient == nil {
+		ws.lock.Unlock()
+		return
+	}
+	now := metav1.Now()
+	status := fv1.KubernetesWatchTriggerStatus{
+		LastResourceVersion: ws.lastResourceVersion,
+		LastUpdateTime:      &now,
+		LastErrorTime:       ws.lastErrorTime,
+		LastError:           ws.lastError,
+	}
+	objects := make([]fv1.WatchedObject, 0, len(ws.objects))
+	for _, o := range ws.objects {
+		objects = append(objects, o)
+	}
+	ws.dirty = false
+	ws.lock.Unlock()
+
+	// the objects are saved first, so that the status never records a
+	// resource version later than them
+	sortWatchedObjects(objects)
+	err := ws.saveObjects(context.TODO(), objects)
+	if err != nil {
+		ws.logger.Error("error saving watched objects", zap.Error(err),
+			zap.String("watch_name", ws.watch.ObjectMeta.Name))
+		ws.lock.Lock()
+		ws.dirty = true
+		ws.lock.Unlock()
+		return
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		client := ws.fissionClient.CoreV1().KubernetesWatchTriggers(ws.watch.ObjectMeta.Namespace)
+		trigger, err := client.Get(context.TODO(), ws.watch.ObjectMeta.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if trigger.ObjectMeta.UID != ws.watch.ObjectMeta.UID {
+			// the trigger was deleted and recreated
+			return nil
+		}
+		trigger.Status = status
+		_, err = client.UpdateStatus(context.TODO(), trigger, metav1.UpdateOptions{})
+		return err
+	})
+	if k8serrors.IsNotFound(err) {
+		return
+	}
+	if err != nil {
+		ws.logger.Error("error updating watch trigger status", zap.Error(err),
+			zap.String("watch_name", ws.watch.ObjectMeta.Name))
+		ws.lock.Lock()
+		ws.dirty = true
+		ws.lock.Unlock()
+	}
 }
 
-func (ws *watchSubscription) isStopped() bool {
-	return atomic.LoadInt32(ws.stopped) == 1
+func watchedObject(obj metav1.Object) fv1.WatchedObject {
+	return fv1.WatchedObject{
+		Namespace:       obj.GetNamespace(),
+		Name:            obj.GetName(),
+		UID:             obj.GetUID(),
+		ResourceVersion: obj.GetResourceVersion(),
+	}
+}
+
+func sortWatchedObjects(objects []fv1.WatchedObject) {
+	sort.Slice(objects, func(i, j int) bool {
+		if objects[i].Namespace != objects[j].Namespace {
+			return objects[i].Namespace < objects[j].Namespace
+		}
+		return objects[i].Name < objects[j].Name
+	})
 }
@@ -17,8 +17,13 @@ limitations under the License.
 package kubewatcher
 
 import (
+	"os"
+	"time"
+
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/restmapper"
 
 	"github.com/fission/fission/pkg/crd"
 	"github.com/fission/fission/pkg/publisher"
@@ -35,8 +40,30 @@ func Start(logger *zap.Logger, routerUrl string) error {
 		return errors.Wrap(err, "error waiting for CRDs")
 	}
 
-	poster := publisher.MakeWebhookPublisher(logger, routerUrl)
-	kubeWatch := MakeKubeWatcher(logger, kubeClient, poster)
+	publisherConfig, err := publisher.WebhookPublisherConfigFromEnv()
+	if err != nil {
+		return errors.Wrap(err, "error reading publisher config")
+	}
+
+	poster, err := publisher.MakeWebhookPublisher(logger, routerUrl, publisherConfig)
+	if err != nil {
+		return errors.Wrap(err, "error creating webhook publisher")
+	}
+	dynamicClient, err := crd.GetDynamicClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to get dynamic client")
+	}
+	restMapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(kubeClient.Discovery()))
+
+	var resyncPeriod time.Duration
+	if v := os.Getenv("KUBEWATCHER_RESYNC_PERIOD"); len(v) > 0 {
+		resyncPeriod, err = time.ParseDuration(v)
+		if err != nil {
+			return errors.Wrap(err, "error parsing KUBEWATCHER_RESYNC_PERIOD")
+		}
+	}
+
+	kubeWatch := MakeKubeWatcher(logger, fissionClient, kubeClient, dynamicClient, restMapper, poster, resyncPeriod)
 	MakeWatchSync(logger, fissionClient, kubeWatch)
 
 	return nil
@@ -0,0 +1,107 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/jsonpath"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+// predicate is a watch predicate with its JSONPath parsed.
+type predicate struct {
+	fv1.WatchPredicate
+	path *jsonpath.JSONPath
+}
+
+func compilePredicates(predicates []fv1.WatchPredicate) ([]predicate, error) {
+	result := make([]predicate, 0, len(predicates))
+	for _, p := range predicates {
+		path := jsonpath.New("predicate").AllowMissingKeys(true)
+		err := path.Parse(p.Path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing predicate path %q", p.Path)
+		}
+		result = append(result, predicate{WatchPredicate: p, path: path})
+	}
+	return result, nil
+}
+
+// matches reports whether the object, as decoded from JSON, satisfies the predicate.
+func (p predicate) matches(obj map[string]interface{}) (bool, error) {
+	results, err := p.path.FindResults(obj)
+	if err != nil {
+		return false, err
+	}
+
+	var values []string
+	for _, result := range results {
+		for _, v := range result {
+			if !v.IsValid() || (v.Kind() == reflect.Interface && v.IsNil()) {
+				continue
+			}
+			s, err := valueString(v.Interface())
+			if err != nil {
+				return false, err
+			}
+			values = append(values, s)
+		}
+	}
+
+	switch p.Operator {
+	case fv1.WatchPredicateOpExists:
+		return len(values) > 0, nil
+	case fv1.WatchPredicateOpDoesNotExist:
+		return len(values) == 0, nil
+	case fv1.WatchPredicateOpIn:
+		return containsAny(p.Values, values), nil
+	case fv1.WatchPredicateOpNotIn:
+		return !containsAny(p.Values, values), nil
+	default:
+		return false, errors.Errorf("unknown predicate operator %q", p.Operator)
+	}
+}
+
+// valueString formats a field value the way it is written in predicate
+// values: scalars as is, objects and arrays as JSON.
+func valueString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		return string(data), err
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+func containsAny(list []string, values []string) bool {
+	for _, v := range values {
+		for _, item := range list {
+			if v == item {
+				return true
+			}
+		}
+	}
+	return false
+}
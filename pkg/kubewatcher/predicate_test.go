@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+func testPod() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":      "foo",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"app": "web"},
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "web", "image": "nginx"},
+				map[string]interface{}{"name": "sidecar", "image": "envoy"},
+			},
+		},
+		"status": map[string]interface{}{
+			"phase":        "Failed",
+			"restartCount": int64(3),
+		},
+	}}
+}
+
+func TestPredicateMatches(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		predicate fv1.WatchPredicate
+		expected  bool
+	}{
+		{"in", fv1.WatchPredicate{Path: "{.status.phase}", Operator: fv1.WatchPredicateOpIn, Values: []string{"Failed", "Unknown"}}, true},
+		{"not in", fv1.WatchPredicate{Path: "{.status.phase}", Operator: fv1.WatchPredicateOpNotIn, Values: []string{"Running"}}, true},
+		{"in number", fv1.WatchPredicate{Path: "{.status.restartCount}", Operator: fv1.WatchPredicateOpIn, Values: []string{"3"}}, true},
+		{"in list", fv1.WatchPredicate{Path: "{.spec.containers[*].image}", Operator: fv1.WatchPredicateOpIn, Values: []string{"envoy"}}, true},
+		{"in filter", fv1.WatchPredicate{Path: `{.spec.containers[?(@.name=="web")].image}`, Operator: fv1.WatchPredicateOpIn, Values: []string{"envoy"}}, false},
+		{"exists", fv1.WatchPredicate{Path: "{.metadata.labels.app}", Operator: fv1.WatchPredicateOpExists}, true},
+		{"missing exists", fv1.WatchPredicate{Path: "{.metadata.labels.tier}", Operator: fv1.WatchPredicateOpExists}, false},
+		{"does not exist", fv1.WatchPredicate{Path: "{.metadata.deletionTimestamp}", Operator: fv1.WatchPredicateOpDoesNotExist}, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			require.NoError(t, test.predicate.Validate())
+			predicates, err := compilePredicates([]fv1.WatchPredicate{test.predicate})
+			require.NoError(t, err)
+			match, err := predicates[0].matches(testPod().Object)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, match)
+		})
+	}
+}
+
+func TestWatchSubscriptionMatches(t *testing.T) {
+	predicates, err := compilePredicates([]fv1.WatchPredicate{
+		{Path: "{.status.phase}", Operator: fv1.WatchPredicateOpIn, Values: []string{"Failed"}},
+	})
+	require.NoError(t, err)
+
+	ws := &watchSubscription{
+		logger:     zap.NewNop(),
+		eventTypes: map[watch.EventType]bool{watch.Modified: true},
+		predicates: predicates,
+	}
+	require.True(t, ws.matches(watch.Modified, testPod(), false))
+	require.False(t, ws.matches(watch.Added, testPod(), false))
+
+	running := testPod()
+	require.NoError(t, unstructured.SetNestedField(running.Object, "Running", "status", "phase"))
+	require.False(t, ws.matches(watch.Modified, running, false))
+	require.True(t, ws.matches(watch.Modified, running, true))
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubewatcher
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	apiv1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+// watchStateKey is the key of the watched objects in the ConfigMap of a
+// trigger.
+const watchStateKey = "objects"
+
+// watchStateConfigMap returns the name of the ConfigMap, in the namespace of
+// a trigger, the objects it watches are kept in.
+func watchStateConfigMap(uid types.UID) string {
+	return "kubewatcher-" + string(uid)
+}
+
+// loadObjects returns the watched objects saved for the trigger, or none if
+// none are.
+func (ws *watchSubscription) loadObjects(ctx context.Context) (map[types.UID]fv1.WatchedObject, error) {
+	objects := make(map[types.UID]fv1.WatchedObject)
+	if ws.kubeClient == nil {
+		return objects, nil
+	}
+	cm, err := ws.kubeClient.CoreV1().ConfigMaps(ws.watch.ObjectMeta.Namespace).Get(ctx,
+		watchStateConfigMap(ws.watch.ObjectMeta.UID), metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return objects, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "error getting watched objects")
+	}
+	if data, ok := cm.Data[watchStateKey]; ok {
+		var list []fv1.WatchedObject
+		err = json.Unmarshal([]byte(data), &list)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing watched objects")
+		}
+		for _, o := range list {
+			objects[o.UID] = o
+		}
+	}
+	return objects, nil
+}
+
+// saveObjects writes the watched objects of the trigger, creating the
+// ConfigMap if it doesn't exist. The ConfigMap is owned by the trigger, so
+// that it is deleted along with it.
+func (ws *watchSubscription) saveObjects(ctx context.Context, objects []fv1.WatchedObject) error {
+	if ws.kubeClient == nil {
+		return nil
+	}
+	data, err := json.Marshal(objects)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"data": map[string]string{watchStateKey: string(data)},
+	})
+	if err != nil {
+		return err
+	}
+	name := watchStateConfigMap(ws.watch.ObjectMeta.UID)
+	configMaps := ws.kubeClient.CoreV1().ConfigMaps(ws.watch.ObjectMeta.Namespace)
+	_, err = configMaps.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: ws.watch.ObjectMeta.Namespace,
+				Labels: map[string]string{
+					"kuberneteswatchtrigger": ws.watch.ObjectMeta.Name,
+				},
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						Kind:       "KubernetesWatchTrigger",
+						APIVersion: "fission.io/v1",
+						Name:       ws.watch.ObjectMeta.Name,
+						UID:        ws.watch.ObjectMeta.UID,
+					},
+				},
+			},
+			Data: map[string]string{watchStateKey: string(data)},
+		}, metav1.CreateOptions{})
+	}
+	return errors.Wrap(err, "error saving watched objects")
+}
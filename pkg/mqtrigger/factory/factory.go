@@ -35,6 +35,12 @@ type (
 	MessageQueueFactory interface {
 		Create(logger *zap.Logger, config messageQueue.Config, routerURL string) (messageQueue.MessageQueue, error)
 	}
+
+	// DeadLetterQueueFactory is implemented by message queue factories
+	// whose message queue supports reading and replaying error topics.
+	DeadLetterQueueFactory interface {
+		CreateDeadLetterQueue(logger *zap.Logger, config messageQueue.Config) (messageQueue.DeadLetterQueue, error)
+	}
 )
 
 func Register(mqType fv1.MessageQueueType, factory MessageQueueFactory) {
@@ -60,3 +66,15 @@ func Create(logger *zap.Logger, mqType fv1.MessageQueueType, mqConfig messageQue
 	}
 	return factory.Create(logger, mqConfig, routerUrl)
 }
+
+func CreateDeadLetterQueue(logger *zap.Logger, mqType fv1.MessageQueueType, mqConfig messageQueue.Config) (messageQueue.DeadLetterQueue, error) {
+	factory, registered := messageQueueFactories[mqType]
+	if !registered {
+		return nil, errors.Errorf("no supported message queue type found for %q", mqType)
+	}
+	dlqFactory, ok := factory.(DeadLetterQueueFactory)
+	if !ok {
+		return nil, errors.Errorf("message queue type %q does not support dead letter operations", mqType)
+	}
+	return dlqFactory.CreateDeadLetterQueue(logger, mqConfig)
+}
@@ -37,6 +37,7 @@ import (
 	"github.com/fission/fission/pkg/mqtrigger/factory"
 	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
 	"github.com/fission/fission/pkg/mqtrigger/validator"
+	"github.com/fission/fission/pkg/publisher"
 	"github.com/fission/fission/pkg/utils"
 )
 
@@ -71,10 +72,13 @@ type AzureStorageConnection struct {
 	routerURL  string
 	service    AzureQueueService
 	httpClient AzureHTTPClient
+
+	cloudEventsMode publisher.CloudEventsMode
 }
 
 // AzureQueueSubscription represents an Azure storage message queue subscription.
 type AzureQueueSubscription struct {
+	trigger         *fv1.MessageQueueTrigger
 	queue           AzureQueue
 	queueName       string
 	outputQueueName string
@@ -203,6 +207,11 @@ func New(logger *zap.Logger, mqCfg messageQueue.Config, routerUrl string) (messa
 		return nil, errors.New("Required environment variable 'AZURE_STORAGE_ACCOUNT_KEY' is not set")
 	}
 
+	cloudEventsMode, err := publisher.CloudEventsModeFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
 	logger.Info("creating Azure storage connection to storage account", zap.String("account", account))
 
 	client, err := storage.NewBasicClient(account, key)
@@ -216,6 +225,7 @@ func New(logger *zap.Logger, mqCfg messageQueue.Config, routerUrl string) (messa
 		httpClient: &http.Client{
 			Timeout: AzureFunctionInvocationTimeout,
 		},
+		cloudEventsMode: cloudEventsMode,
 	}, nil
 }
 
@@ -227,6 +237,7 @@ func (asc AzureStorageConnection) Subscribe(trigger *fv1.MessageQueueTrigger) (m
 	}
 
 	subscription := &AzureQueueSubscription{
+		trigger:         trigger,
 		queue:           asc.service.GetQueue(trigger.Spec.Topic),
 		queueName:       trigger.Spec.Topic,
 		outputQueueName: trigger.Spec.ResponseTopic,
@@ -323,24 +334,36 @@ func invokeTriggeredFunction(conn AzureStorageConnection, sub *AzureQueueSubscri
 
 	conn.logger.Info("making HTTP request to invoke function", zap.String("function_url", sub.functionURL))
 
+	headers := map[string]string{
+		"X-Fission-MQTrigger-Topic": sub.queueName,
+		"Content-Type":              sub.contentType,
+	}
+	if len(sub.outputQueueName) > 0 {
+		headers["X-Fission-MQTrigger-RespTopic"] = sub.outputQueueName
+	}
+	event := messageQueue.NewCloudEvent(sub.trigger, "", time.Time{})
+	reqBody, headers, err := event.Encode(conn.cloudEventsMode, message.Bytes(), headers)
+	if err != nil {
+		conn.logger.Error("failed to encode message as CloudEvent", zap.Error(err), zap.String("function_url", sub.functionURL))
+		return
+	}
+
 	for i := 0; i <= AzureQueueRetryLimit; i++ {
 		if i > 0 {
 			conn.logger.Info("retrying function invocation", zap.Int("retry", i), zap.String("function_url", sub.functionURL))
 		}
-		request, err := http.NewRequest("POST", sub.functionURL, bytes.NewReader(message.Bytes()))
+		request, err := http.NewRequest("POST", sub.functionURL, bytes.NewReader(reqBody))
 		if err != nil {
 			conn.logger.Error("failed to create HTTP request to invoke function", zap.Error(err), zap.String("function_url", sub.functionURL))
 			continue
 		}
 
-		request.Header.Set("X-Fission-MQTrigger-Topic", sub.queueName)
-		if len(sub.outputQueueName) > 0 {
-			request.Header.Set("X-Fission-MQTrigger-RespTopic", sub.outputQueueName)
+		for k, v := range headers {
+			request.Header.Set(k, v)
 		}
 		if i > 0 {
 			request.Header.Set("X-Fission-MQTrigger-RetryCount", strconv.Itoa(i))
 		}
-		request.Header.Set("Content-Type", sub.contentType)
 
 		response, err := conn.httpClient.Do(request)
 		if err != nil {
@@ -394,7 +417,7 @@ func invokeTriggeredFunction(conn AzureStorageConnection, sub *AzureQueueSubscri
 
 	poisonQueueName := sub.queueName + AzurePoisonQueueSuffix
 	poisonQueue := conn.service.GetQueue(poisonQueueName)
-	err := poisonQueue.Create(nil)
+	err = poisonQueue.Create(nil)
 	if err != nil {
 		conn.logger.Error("failed to create poison queue",
 			zap.Error(err),
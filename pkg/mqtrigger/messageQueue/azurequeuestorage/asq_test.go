@@ -110,6 +110,18 @@ func (m *azureHTTPClientMock) Do(req *http.Request) (*http.Response, error) {
 	return res, err
 }
 
+// TestValidateErrorTopic checks that triggers with error topics, which
+// Azure Queue Storage ignores, are still accepted.
+func TestValidateErrorTopic(t *testing.T) {
+	spec := fv1.MessageQueueTriggerSpec{
+		FunctionReference: fv1.FunctionReference{Type: fv1.FunctionReferenceTypeFunctionName, Name: "fn"},
+		MessageQueueType:  fv1.MessageQueueTypeASQ,
+		Topic:             "queue",
+		ErrorTopic:        "Errors",
+	}
+	require.NoError(t, spec.Validate())
+}
+
 func TestNewStorageConnectionMissingAccountName(t *testing.T) {
 	config := zap.NewDevelopmentConfig()
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
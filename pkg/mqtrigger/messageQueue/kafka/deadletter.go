@@ -0,0 +1,306 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sarama "github.com/Shopify/sarama"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
+)
+
+// deadLetterReadTimeout is the maximum time to wait for the next
+// message of an error topic partition before giving up on it.
+const deadLetterReadTimeout = 10 * time.Second
+
+type (
+	// DeadLetterQueue reads messages published to the error topic of
+	// a trigger and re-publishes them to the topic of the trigger.
+	DeadLetterQueue struct {
+		kafka  Kafka
+		client sarama.Client
+	}
+)
+
+func NewDeadLetterQueue(logger *zap.Logger, mqCfg messageQueue.Config) (messageQueue.DeadLetterQueue, error) {
+	if len(mqCfg.Url) == 0 {
+		return nil, errors.New("the MQ URL is empty")
+	}
+	kafka, err := newKafka(logger, mqCfg)
+	if err != nil {
+		return nil, err
+	}
+	// the certificates of the brokers are given with the secrets
+	// outside of the message queue trigger deployment
+	if !kafka.tls && len(mqCfg.Secrets) > 0 {
+		kafka.setAuthKeys(mqCfg.Secrets)
+	}
+	if !kafka.version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, errors.Errorf("dead letter operations need record headers, which are not supported by kafka version %v", kafka.version)
+	}
+
+	config := kafka.newProducerConfig()
+	config.Consumer.Return.Errors = true
+	if kafka.tls {
+		tlsConfig, err := kafka.getTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+
+	client, err := sarama.NewClient(kafka.brokers, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kafka client")
+	}
+
+	return &DeadLetterQueue{
+		kafka:  kafka,
+		client: client,
+	}, nil
+}
+
+func (dlq *DeadLetterQueue) ListDeadLetters(trigger *fv1.MessageQueueTrigger, filter messageQueue.DeadLetterFilter) ([]messageQueue.DeadLetterMessage, error) {
+	topic := trigger.Spec.ErrorTopic
+	if len(topic) == 0 {
+		return nil, errors.Errorf("message queue trigger %q has no error topic", trigger.ObjectMeta.Name)
+	}
+
+	partitions, err := dlq.client.Partitions(topic)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting partitions of topic %q", topic)
+	}
+
+	consumer, err := sarama.NewConsumerFromClient(dlq.client)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating kafka consumer")
+	}
+	defer consumer.Close()
+
+	var messages []messageQueue.DeadLetterMessage
+	for _, partition := range partitions {
+		msgs, err := dlq.readPartition(consumer, topic, partition, filter)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msgs...)
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Timestamp.Before(messages[j].Timestamp)
+	})
+	if filter.Count > 0 && len(messages) > filter.Count {
+		messages = messages[:filter.Count]
+	}
+
+	return messages, nil
+}
+
+// readPartition reads the messages of a single partition, starting from the
+// first offset matching filter.Since up to the newest offset at call time.
+func (dlq *DeadLetterQueue) readPartition(consumer sarama.Consumer, topic string, partition int32,
+	filter messageQueue.DeadLetterFilter) ([]messageQueue.DeadLetterMessage, error) {
+
+	newest, err := dlq.client.GetOffset(topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting newest offset of topic %q partition %d", topic, partition)
+	}
+
+	start := sarama.OffsetOldest
+	if !filter.Since.IsZero() {
+		start, err = dlq.client.GetOffset(topic, partition, filter.Since.UnixNano()/int64(time.Millisecond))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error getting offset of topic %q partition %d", topic, partition)
+		}
+		// no message was published after the given time
+		if start < 0 {
+			return nil, nil
+		}
+	}
+	if start == sarama.OffsetOldest {
+		start, err = dlq.client.GetOffset(topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error getting oldest offset of topic %q partition %d", topic, partition)
+		}
+	}
+	if start >= newest {
+		return nil, nil
+	}
+
+	pc, err := consumer.ConsumePartition(topic, partition, start)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error consuming topic %q partition %d", topic, partition)
+	}
+	defer pc.Close()
+
+	var messages []messageQueue.DeadLetterMessage
+	for {
+		select {
+		case msg := <-pc.Messages():
+			if filter.Until.IsZero() || !msg.Timestamp.After(filter.Until) {
+				messages = append(messages, toDeadLetterMessage(msg))
+			}
+			if msg.Offset >= newest-1 {
+				return messages, nil
+			}
+		case err := <-pc.Errors():
+			return nil, errors.Wrapf(err, "error reading topic %q partition %d", topic, partition)
+		case <-time.After(deadLetterReadTimeout):
+			dlq.kafka.logger.Warn("timed out reading error topic partition",
+				zap.String("topic", topic), zap.Int32("partition", partition))
+			return messages, nil
+		}
+	}
+}
+
+func (dlq *DeadLetterQueue) ReplayDeadLetters(trigger *fv1.MessageQueueTrigger, messages []messageQueue.DeadLetterMessage) error {
+	producer, err := sarama.NewSyncProducerFromClient(dlq.client)
+	if err != nil {
+		return errors.Wrap(err, "error creating kafka producer")
+	}
+	defer producer.Close()
+
+	consumer, err := sarama.NewConsumerFromClient(dlq.client)
+	if err != nil {
+		return errors.Wrap(err, "error creating kafka consumer")
+	}
+	defer consumer.Close()
+
+	var result *multierror.Error
+	for _, msg := range messages {
+		replay := &sarama.ProducerMessage{Topic: trigger.Spec.Topic}
+		switch {
+		case len(msg.Source) > 0:
+			original, err := dlq.readSource(consumer, trigger.Spec.Topic, msg.Source)
+			if err != nil {
+				result = multierror.Append(result, errors.Wrapf(err, "error reading original message of %v", msg.ID))
+				continue
+			}
+			replay.Key = sarama.ByteEncoder(original.Key)
+			replay.Value = sarama.ByteEncoder(original.Value)
+			for _, h := range original.Headers {
+				if h != nil {
+					replay.Headers = append(replay.Headers, *h)
+				}
+			}
+		case msg.Payload != nil:
+			replay.Value = sarama.ByteEncoder(msg.Payload)
+		default:
+			result = multierror.Append(result, errors.Errorf("message %v has no recorded original message to replay", msg.ID))
+			continue
+		}
+
+		_, _, err := producer.SendMessage(replay)
+		if err != nil {
+			result = multierror.Append(result, errors.Wrapf(err, "error replaying message %v to topic %q", msg.ID, trigger.Spec.Topic))
+			continue
+		}
+		dlq.kafka.logger.Debug("replayed message from error topic",
+			zap.String("id", msg.ID),
+			zap.String("topic", trigger.Spec.Topic),
+			zap.String("trigger", trigger.ObjectMeta.Name))
+	}
+
+	return result.ErrorOrNil()
+}
+
+// readSource reads the original message of a dead letter from the trigger
+// topic, given its source reference in the format of <partition>-<offset>.
+func (dlq *DeadLetterQueue) readSource(consumer sarama.Consumer, topic string, source string) (*sarama.ConsumerMessage, error) {
+	partition, offset, err := parseSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	oldest, err := dlq.client.GetOffset(topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting oldest offset of topic %q partition %d", topic, partition)
+	}
+	if offset < oldest {
+		return nil, errors.Errorf("offset %d of topic %q partition %d is no longer retained", offset, topic, partition)
+	}
+
+	pc, err := consumer.ConsumePartition(topic, partition, offset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error consuming topic %q partition %d", topic, partition)
+	}
+	defer pc.Close()
+
+	select {
+	case msg := <-pc.Messages():
+		if msg.Offset != offset {
+			return nil, errors.Errorf("offset %d of topic %q partition %d is no longer retained", offset, topic, partition)
+		}
+		return msg, nil
+	case err := <-pc.Errors():
+		return nil, errors.Wrapf(err, "error reading topic %q partition %d", topic, partition)
+	case <-time.After(deadLetterReadTimeout):
+		return nil, errors.Errorf("timed out reading offset %d of topic %q partition %d", offset, topic, partition)
+	}
+}
+
+func parseSource(source string) (int32, int64, error) {
+	parts := strings.SplitN(source, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, errors.Errorf("invalid message source %q", source)
+	}
+	partition, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "invalid message source %q", source)
+	}
+	offset, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "invalid message source %q", source)
+	}
+	return int32(partition), offset, nil
+}
+
+func (dlq *DeadLetterQueue) Close() error {
+	return dlq.client.Close()
+}
+
+func toDeadLetterMessage(msg *sarama.ConsumerMessage) messageQueue.DeadLetterMessage {
+	dlm := messageQueue.DeadLetterMessage{
+		ID:        fmt.Sprintf("%d-%d", msg.Partition, msg.Offset),
+		Timestamp: msg.Timestamp,
+		Headers:   make(map[string]string),
+		Error:     string(msg.Value),
+	}
+	for _, h := range msg.Headers {
+		if string(h.Key) == legacyErrorHeaderMessageValue {
+			dlm.Payload = h.Value
+			continue
+		}
+		dlm.Headers[string(h.Key)] = string(h.Value)
+	}
+	partition, hasPartition := dlm.Headers[errorHeaderSourcePartition]
+	offset, hasOffset := dlm.Headers[errorHeaderSourceOffset]
+	if hasPartition && hasOffset {
+		dlm.Source = partition + "-" + offset
+	}
+	return dlm
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	sarama "github.com/Shopify/sarama"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToDeadLetterMessage(t *testing.T) {
+	msg := &sarama.ConsumerMessage{
+		Partition: 2,
+		Offset:    7,
+		Timestamp: time.Unix(1600000000, 0),
+		Value:     []byte("request returned failure: 500"),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(errorHeaderMessageSource), Value: []byte("input")},
+			{Key: []byte(errorHeaderSourcePartition), Value: []byte("1")},
+			{Key: []byte(errorHeaderSourceOffset), Value: []byte("42")},
+		},
+	}
+	dlm := toDeadLetterMessage(msg)
+	require.Equal(t, "2-7", dlm.ID)
+	require.Equal(t, "1-42", dlm.Source)
+	require.Nil(t, dlm.Payload)
+	require.Equal(t, "request returned failure: 500", dlm.Error)
+
+	partition, offset, err := parseSource(dlm.Source)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), partition)
+	require.Equal(t, int64(42), offset)
+	_, _, err = parseSource("42")
+	require.Error(t, err)
+
+	// error topics written by earlier versions recorded the message value
+	msg.Headers = []*sarama.RecordHeader{
+		{Key: []byte(legacyErrorHeaderMessageValue), Value: []byte("payload")},
+	}
+	dlm = toDeadLetterMessage(msg)
+	require.Empty(t, dlm.Source)
+	require.Equal(t, []byte("payload"), dlm.Payload)
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package kafka
 
 import (
+	"bytes"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -36,14 +37,28 @@ import (
 	"github.com/fission/fission/pkg/mqtrigger/factory"
 	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
 	"github.com/fission/fission/pkg/mqtrigger/validator"
+	"github.com/fission/fission/pkg/publisher"
 	"github.com/fission/fission/pkg/utils"
 )
 
 func init() {
 	factory.Register(fv1.MessageQueueTypeKafka, &Factory{})
 	validator.Register(fv1.MessageQueueTypeKafka, IsTopicValid)
+	validator.RegisterErrorTopic(fv1.MessageQueueTypeKafka, true)
 }
 
+// Record headers attached to messages published to the error topic. The
+// partition and offset reference the original message in the trigger topic,
+// which is read back from it to be replayed. Error topics written by earlier
+// versions recorded the whole original message value instead.
+const (
+	errorHeaderMessageSource      = "MessageSource"
+	errorHeaderRecycleCounter     = "RecycleCounter"
+	errorHeaderSourcePartition    = "MessageSourcePartition"
+	errorHeaderSourceOffset       = "MessageSourceOffset"
+	legacyErrorHeaderMessageValue = "MessageValue"
+)
+
 var (
 	// Need to use raw string to support escape sequence for - & . chars
 	validKafkaTopicName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9\-\._]*[a-zA-Z0-9]$`)
@@ -60,6 +75,8 @@ type (
 		version   sarama.KafkaVersion
 		authKeys  map[string][]byte
 		tls       bool
+
+		cloudEventsMode publisher.CloudEventsMode
 	}
 
 	Factory struct{}
@@ -69,10 +86,28 @@ func (factory *Factory) Create(logger *zap.Logger, mqCfg messageQueue.Config, ro
 	return New(logger, mqCfg, routerUrl)
 }
 
+func (factory *Factory) CreateDeadLetterQueue(logger *zap.Logger, mqCfg messageQueue.Config) (messageQueue.DeadLetterQueue, error) {
+	return NewDeadLetterQueue(logger, mqCfg)
+}
+
 func New(logger *zap.Logger, mqCfg messageQueue.Config, routerUrl string) (messageQueue.MessageQueue, error) {
 	if len(routerUrl) == 0 || len(mqCfg.Url) == 0 {
 		return nil, errors.New("the router URL or MQ URL is empty")
 	}
+	kafka, err := newKafka(logger, mqCfg)
+	if err != nil {
+		return nil, err
+	}
+	kafka.routerUrl = routerUrl
+
+	logger.Info("created kafka queue", zap.Any("kafka brokers", kafka.brokers),
+		zap.Any("kafka version", kafka.version))
+	return kafka, nil
+}
+
+// newKafka parses the broker, version and TLS settings shared by the
+// trigger subscriber and the dead letter queue.
+func newKafka(logger *zap.Logger, mqCfg messageQueue.Config) (Kafka, error) {
 	mqKafkaVersion := os.Getenv("MESSAGE_QUEUE_KAFKA_VERSION")
 
 	// Parse version string
@@ -84,30 +119,25 @@ func New(logger *zap.Logger, mqCfg messageQueue.Config, routerUrl string) (messa
 			zap.Any("default_version", kafkaVersion))
 	}
 
+	cloudEventsMode, err := publisher.CloudEventsModeFromEnv()
+	if err != nil {
+		return Kafka{}, err
+	}
+
 	kafka := Kafka{
-		logger:    logger.Named("kafka"),
-		routerUrl: routerUrl,
-		brokers:   strings.Split(mqCfg.Url, ","),
-		version:   kafkaVersion,
+		logger:          logger.Named("kafka"),
+		brokers:         strings.Split(mqCfg.Url, ","),
+		version:         kafkaVersion,
+		cloudEventsMode: cloudEventsMode,
 	}
 
 	if tls, _ := strconv.ParseBool(os.Getenv("TLS_ENABLED")); tls {
-		kafka.tls = true
-
-		authKeys := make(map[string][]byte)
-
 		if mqCfg.Secrets == nil {
-			return nil, errors.New("no secrets were loaded")
+			return kafka, errors.New("no secrets were loaded")
 		}
-
-		authKeys["caCert"] = mqCfg.Secrets["caCert"]
-		authKeys["userCert"] = mqCfg.Secrets["userCert"]
-		authKeys["userKey"] = mqCfg.Secrets["userKey"]
-		kafka.authKeys = authKeys
+		kafka.setAuthKeys(mqCfg.Secrets)
 	}
 
-	logger.Info("created kafka queue", zap.Any("kafka brokers", kafka.brokers),
-		zap.Any("kafka version", kafka.version))
 	return kafka, nil
 }
 
@@ -122,11 +152,7 @@ func (kafka Kafka) Subscribe(trigger *fv1.MessageQueueTrigger) (messageQueue.Sub
 	consumerConfig.Config.Version = kafka.version
 
 	// Create new producer
-	producerConfig := sarama.NewConfig()
-	producerConfig.Producer.RequiredAcks = sarama.WaitForAll
-	producerConfig.Producer.Retry.Max = 10
-	producerConfig.Producer.Return.Successes = true
-	producerConfig.Version = kafka.version
+	producerConfig := kafka.newProducerConfig()
 
 	// Setup TLS for both producer and consumer
 	if kafka.tls {
@@ -192,6 +218,25 @@ func (kafka Kafka) Subscribe(trigger *fv1.MessageQueueTrigger) (messageQueue.Sub
 	return consumer, nil
 }
 
+// setAuthKeys enables TLS authentication with the certificates of the secrets
+func (kafka *Kafka) setAuthKeys(secrets map[string][]byte) {
+	kafka.tls = true
+	kafka.authKeys = map[string][]byte{
+		"caCert":   secrets["caCert"],
+		"userCert": secrets["userCert"],
+		"userKey":  secrets["userKey"],
+	}
+}
+
+func (kafka Kafka) newProducerConfig() *sarama.Config {
+	producerConfig := sarama.NewConfig()
+	producerConfig.Producer.RequiredAcks = sarama.WaitForAll
+	producerConfig.Producer.Retry.Max = 10
+	producerConfig.Producer.Return.Successes = true
+	producerConfig.Version = kafka.version
+	return producerConfig
+}
+
 func (kafka Kafka) getTLSConfig() (*tls.Config, error) {
 	tlsConfig := tls.Config{}
 	cert, err := tls.X509KeyPair(kafka.authKeys["userCert"], kafka.authKeys["userKey"])
@@ -221,7 +266,6 @@ func (kafka Kafka) Unsubscribe(subscription messageQueue.Subscription) error {
 }
 
 func kafkaMsgHandler(kafka *Kafka, producer sarama.SyncProducer, trigger *fv1.MessageQueueTrigger, msg *sarama.ConsumerMessage, consumer *cluster.Consumer) {
-	var value string = string(msg.Value[:])
 	// Support other function ref types
 	if trigger.Spec.FunctionReference.Type != fv1.FunctionReferenceTypeFunctionName {
 		kafka.logger.Fatal("unsupported function reference type for trigger",
@@ -240,8 +284,17 @@ func kafkaMsgHandler(kafka *Kafka, producer sarama.SyncProducer, trigger *fv1.Me
 		"Content-Type":                   trigger.Spec.ContentType,
 	}
 
+	event := messageQueue.NewCloudEvent(trigger, fmt.Sprintf("%s-%d-%d", msg.Topic, msg.Partition, msg.Offset), msg.Timestamp)
+	reqBody, fissionHeaders, err := event.Encode(kafka.cloudEventsMode, msg.Value, fissionHeaders)
+	if err != nil {
+		kafka.logger.Error("failed to encode message as CloudEvent",
+			zap.Error(err),
+			zap.String("trigger", trigger.ObjectMeta.Name))
+		return
+	}
+
 	// Create request
-	req, err := http.NewRequest("POST", url, strings.NewReader(value))
+	req, err := http.NewRequest("POST", url, bytes.NewReader(reqBody))
 	if err != nil {
 		kafka.logger.Error("failed to create HTTP request to invoke function",
 			zap.Error(err),
@@ -293,8 +346,11 @@ func kafkaMsgHandler(kafka *Kafka, producer sarama.SyncProducer, trigger *fv1.Me
 			} else {
 				errorMessageMap[errString] = 1
 			}
-			errorHeaders = append(errorHeaders, sarama.RecordHeader{Key: []byte("MessageSource"), Value: []byte(trigger.Spec.Topic)})
-			errorHeaders = append(errorHeaders, sarama.RecordHeader{Key: []byte("RecycleCounter"), Value: []byte(strconv.Itoa(errorMessageMap[errString]))})
+			errorHeaders = append(errorHeaders, sarama.RecordHeader{Key: []byte(errorHeaderMessageSource), Value: []byte(trigger.Spec.Topic)})
+			errorHeaders = append(errorHeaders, sarama.RecordHeader{Key: []byte(errorHeaderRecycleCounter), Value: []byte(strconv.Itoa(errorMessageMap[errString]))})
+			// Reference the original message so that it can be replayed from the trigger topic
+			errorHeaders = append(errorHeaders, sarama.RecordHeader{Key: []byte(errorHeaderSourcePartition), Value: []byte(strconv.Itoa(int(msg.Partition)))})
+			errorHeaders = append(errorHeaders, sarama.RecordHeader{Key: []byte(errorHeaderSourceOffset), Value: []byte(strconv.FormatInt(msg.Offset, 10))})
 		}
 		return errorHeaders
 	}
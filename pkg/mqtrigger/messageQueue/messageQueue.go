@@ -17,7 +17,10 @@ limitations under the License.
 package messageQueue
 
 import (
+	"time"
+
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/publisher"
 )
 
 type (
@@ -33,4 +36,60 @@ type (
 		Subscribe(trigger *fv1.MessageQueueTrigger) (Subscription, error)
 		Unsubscribe(triggerSub Subscription) error
 	}
+
+	// DeadLetterMessage is a message read back from the error topic of a trigger.
+	DeadLetterMessage struct {
+		// ID identifies the message within the error topic.
+		ID string
+
+		// Timestamp is the time the message was published to the error topic.
+		Timestamp time.Time
+
+		// Headers recorded along with the message by the message queue trigger.
+		Headers map[string]string
+
+		// Error is the error published to the error topic.
+		Error string
+
+		// Source references the original message in the trigger topic, which
+		// is read back from it to be replayed, if the message queue recorded it.
+		Source string
+
+		// Payload is the original message consumed from the trigger topic, for
+		// messages recorded along with it.
+		Payload []byte
+	}
+
+	// DeadLetterFilter narrows down the dead letter messages to read.
+	// Zero values mean no restriction.
+	DeadLetterFilter struct {
+		Since time.Time
+		Until time.Time
+		Count int
+	}
+
+	// DeadLetterQueue is implemented by message queues that can read messages
+	// back from the error topic of a trigger and re-publish them to the topic
+	// the trigger subscribes to.
+	DeadLetterQueue interface {
+		ListDeadLetters(trigger *fv1.MessageQueueTrigger, filter DeadLetterFilter) ([]DeadLetterMessage, error)
+		ReplayDeadLetters(trigger *fv1.MessageQueueTrigger, messages []DeadLetterMessage) error
+		Close() error
+	}
 )
+
+// NewCloudEvent returns the CloudEvents attributes of a message delivered by
+// the given trigger. If id is empty, a random one is generated.
+func NewCloudEvent(trigger *fv1.MessageQueueTrigger, id string, timestamp time.Time) publisher.CloudEvent {
+	event := publisher.NewCloudEvent(
+		publisher.TriggerSource("messagequeuetriggers", trigger.ObjectMeta.Namespace, trigger.ObjectMeta.Name),
+		"io.fission.mqtrigger."+string(trigger.Spec.MessageQueueType),
+		trigger.Spec.Topic)
+	if len(id) > 0 {
+		event.ID = id
+	}
+	if !timestamp.IsZero() {
+		event.Time = timestamp
+	}
+	return event
+}
@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	nsUtil "github.com/nats-io/nats-streaming-server/util"
 	ns "github.com/nats-io/stan.go"
@@ -32,6 +33,7 @@ import (
 	"github.com/fission/fission/pkg/mqtrigger/factory"
 	"github.com/fission/fission/pkg/mqtrigger/messageQueue"
 	"github.com/fission/fission/pkg/mqtrigger/validator"
+	"github.com/fission/fission/pkg/publisher"
 	"github.com/fission/fission/pkg/utils"
 )
 
@@ -54,6 +56,8 @@ func init() {
 	}
 	factory.Register(fv1.MessageQueueTypeNats, &Factory{})
 	validator.Register(fv1.MessageQueueTypeNats, IsTopicValid)
+	// only the function errors are published to error topics
+	validator.RegisterErrorTopic(fv1.MessageQueueTypeNats, false)
 }
 
 const (
@@ -67,6 +71,8 @@ type (
 		logger    *zap.Logger
 		nsConn    ns.Conn
 		routerUrl string
+
+		cloudEventsMode publisher.CloudEventsMode
 	}
 
 	Factory struct{}
@@ -77,6 +83,10 @@ func (factory *Factory) Create(logger *zap.Logger, mqCfg messageQueue.Config, ro
 }
 
 func New(logger *zap.Logger, mqCfg messageQueue.Config, routerUrl string) (messageQueue.MessageQueue, error) {
+	cloudEventsMode, err := publisher.CloudEventsModeFromEnv()
+	if err != nil {
+		return nil, err
+	}
 	conn, err := ns.Connect(natsClusterID, natsClientID, ns.NatsURL(mqCfg.Url),
 		ns.SetConnectionLostHandler(func(conn ns.Conn, reason error) {
 			// TODO: Better way to handle connection lost problem.
@@ -91,9 +101,10 @@ func New(logger *zap.Logger, mqCfg messageQueue.Config, routerUrl string) (messa
 		return nil, err
 	}
 	nats := Nats{
-		logger:    logger.Named("nats"),
-		nsConn:    conn,
-		routerUrl: routerUrl,
+		logger:          logger.Named("nats"),
+		nsConn:          conn,
+		routerUrl:       routerUrl,
+		cloudEventsMode: cloudEventsMode,
 	}
 	return nats, nil
 }
@@ -149,8 +160,17 @@ func msgHandler(nats *Nats, trigger *fv1.MessageQueueTrigger) func(*ns.Msg) {
 			"Content-Type":                   trigger.Spec.ContentType,
 		}
 
+		event := messageQueue.NewCloudEvent(trigger, fmt.Sprintf("%s-%d", msg.Subject, msg.Sequence), time.Unix(0, msg.Timestamp))
+		reqBody, headers, err := event.Encode(nats.cloudEventsMode, msg.Data, headers)
+		if err != nil {
+			nats.logger.Error("failed to encode message as CloudEvent",
+				zap.Error(err),
+				zap.String("trigger", trigger.ObjectMeta.Name))
+			return
+		}
+
 		// Create request
-		req, err := http.NewRequest("POST", url, bytes.NewReader(msg.Data))
+		req, err := http.NewRequest("POST", url, bytes.NewReader(reqBody))
 
 		if err != nil {
 			nats.logger.Error("failed to create HTTP request to invoke function",
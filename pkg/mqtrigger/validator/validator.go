@@ -22,6 +22,7 @@ import (
 
 var (
 	topicValidators = make(map[string]TopicValidator)
+	errorTopics     = make(map[string]bool)
 	lock            = sync.Mutex{}
 )
 
@@ -45,6 +46,37 @@ func Register(mqType string, validator TopicValidator) {
 	topicValidators[mqType] = validator
 }
 
+// RegisterErrorTopic registers a message queue type publishing function
+// errors to error topics, and whether the messages of its error topics can
+// be read back and replayed.
+func RegisterErrorTopic(mqType string, deadLetters bool) {
+	lock.Lock()
+	defer lock.Unlock()
+	errorTopics[mqType] = deadLetters
+}
+
+// SupportsErrorTopic returns whether a message queue type publishes function errors to error topics.
+func SupportsErrorTopic(mqType, mqtKind string) bool {
+	if mqtKind == "keda" {
+		return true
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	_, registered := errorTopics[mqType]
+	return registered
+}
+
+// SupportsDeadLetterQueue returns whether the messages of the error topics of
+// a message queue type can be read back and replayed.
+func SupportsDeadLetterQueue(mqType, mqtKind string) bool {
+	if mqtKind == "keda" {
+		return false
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	return errorTopics[mqType]
+}
+
 func IsValidTopic(mqType, topic, mqtKind string) bool {
 	if mqtKind == "keda" {
 		return true
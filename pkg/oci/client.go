@@ -0,0 +1,510 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MediaTypeManifest is the media type of OCI image manifests.
+	MediaTypeManifest = "application/vnd.oci.image.manifest.v1+json"
+
+	// MediaTypeDockerManifest is the media type of docker image manifests,
+	// which some registries convert OCI manifests to.
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+
+	// MediaTypePackageConfig is the media type of the config of package
+	// archive artifacts.
+	MediaTypePackageConfig = "application/vnd.fission.package.config.v1+json"
+
+	// MediaTypePackageArchive is the media type of the layer holding the
+	// archive of package archive artifacts.
+	MediaTypePackageArchive = "application/vnd.fission.package.archive.v1"
+
+	// annotationTitle is the annotation holding the file name of a layer.
+	annotationTitle = "org.opencontainers.image.title"
+
+	// maxManifestSize bounds the size of the manifests read.
+	maxManifestSize = 4 * 1024 * 1024
+)
+
+type (
+	// Descriptor describes a blob of an artifact.
+	Descriptor struct {
+		MediaType   string            `json:"mediaType"`
+		Digest      string            `json:"digest"`
+		Size        int64             `json:"size"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+	}
+
+	// Manifest is the manifest of an artifact.
+	Manifest struct {
+		SchemaVersion int          `json:"schemaVersion"`
+		MediaType     string       `json:"mediaType,omitempty"`
+		Config        Descriptor   `json:"config"`
+		Layers        []Descriptor `json:"layers"`
+	}
+
+	// Client pulls and pushes package archives as single layer artifacts
+	// with the registry API. Registries are accessed over HTTPS, except
+	// on the loopback interface.
+	Client struct {
+		httpClient  *http.Client
+		credentials Credentials
+
+		lock sync.Mutex
+		// auth is the authorization header by registry and repository
+		auth map[string]string
+	}
+
+	// body returns a new reader of a request body, so that requests can
+	// be sent again once authorized.
+	body func() (io.ReadCloser, error)
+)
+
+// MakeClient returns a registry client authenticating with the given
+// credentials, which can be nil.
+func MakeClient(httpClient *http.Client, credentials Credentials) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		httpClient:  httpClient,
+		credentials: credentials,
+		auth:        make(map[string]string),
+	}
+}
+
+// Pull returns a reader of the archive of an artifact. The reader fails
+// at the end of the archive if the archive doesn't match its digest.
+func (c *Client) Pull(ctx context.Context, ref *Reference) (io.ReadCloser, *Descriptor, error) {
+	manifest, err := c.getManifest(ctx, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(manifest.Layers) != 1 {
+		return nil, nil, errors.Errorf("artifact %v has %v layers, expected a single archive layer", ref, len(manifest.Layers))
+	}
+	layer := manifest.Layers[0]
+	if !strings.HasPrefix(layer.Digest, "sha256:") {
+		return nil, nil, errors.Errorf("unsupported digest %q of artifact %v", layer.Digest, ref)
+	}
+
+	resp, err := c.do(ctx, ref, http.MethodGet, c.url(ref, "/blobs/"+layer.Digest), nil, nil)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error pulling archive of artifact %v", ref)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, nil, errors.Wrapf(responseError(resp), "error pulling archive of artifact %v", ref)
+	}
+	return &verifyingReader{
+		ReadCloser: resp.Body,
+		hash:       sha256.New(),
+		digest:     layer.Digest,
+	}, &layer, nil
+}
+
+// PullToFile pulls the archive of an artifact to a file.
+func (c *Client) PullToFile(ctx context.Context, ref *Reference, path string) error {
+	reader, _, err := c.Pull(ctx, ref)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.Wrap(err, "error creating archive file")
+	}
+	_, err = io.Copy(f, reader)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return errors.Wrapf(err, "error pulling archive of artifact %v", ref)
+	}
+	return nil
+}
+
+// Push pushes an archive file as an artifact with the tag of ref, and
+// returns the reference of the artifact by digest along with the
+// descriptor of the archive layer.
+func (c *Client) Push(ctx context.Context, ref *Reference, path string) (*Reference, *Descriptor, error) {
+	layer, err := fileDescriptor(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	layer.MediaType = MediaTypePackageArchive
+	layer.Annotations = map[string]string{annotationTitle: filepath.Base(path)}
+	err = c.pushBlob(ctx, ref, layer, func() (io.ReadCloser, error) {
+		return os.Open(path)
+	})
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error pushing archive %v", path)
+	}
+
+	configData := []byte("{}")
+	config := bytesDescriptor(MediaTypePackageConfig, configData)
+	err = c.pushBlob(ctx, ref, config, bytesBody(configData))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error pushing artifact config")
+	}
+
+	manifestData, err := json.Marshal(Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeManifest,
+		Config:        *config,
+		Layers:        []Descriptor{*layer},
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error encoding artifact manifest")
+	}
+	manifest := bytesDescriptor(MediaTypeManifest, manifestData)
+
+	pushed := *ref
+	pushed.Digest = ""
+	resp, err := c.do(ctx, ref, http.MethodPut, c.url(ref, "/manifests/"+pushed.version()),
+		http.Header{
+			"Content-Type":   {MediaTypeManifest},
+			"Content-Length": {strconv.Itoa(len(manifestData))},
+		}, bytesBody(manifestData))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error pushing artifact manifest")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, nil, errors.Wrap(responseError(resp), "error pushing artifact manifest")
+	}
+
+	pushed.Digest = manifest.Digest
+	return &pushed, layer, nil
+}
+
+// getManifest gets the manifest of an artifact, verifying its digest if
+// referenced by digest.
+func (c *Client) getManifest(ctx context.Context, ref *Reference) (*Manifest, error) {
+	resp, err := c.do(ctx, ref, http.MethodGet, c.url(ref, "/manifests/"+ref.version()),
+		http.Header{"Accept": {MediaTypeManifest + ", " + MediaTypeDockerManifest}}, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting manifest of artifact %v", ref)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrapf(responseError(resp), "error getting manifest of artifact %v", ref)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading manifest of artifact %v", ref)
+	}
+	if len(ref.Digest) > 0 {
+		if digest := bytesDescriptor("", data).Digest; digest != ref.Digest {
+			return nil, errors.Errorf("manifest of artifact %v has digest %v", ref, digest)
+		}
+	}
+
+	manifest := &Manifest{}
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing manifest of artifact %v", ref)
+	}
+	return manifest, nil
+}
+
+// pushBlob uploads a blob to the repository of ref in a single request,
+// unless the repository has it already.
+func (c *Client) pushBlob(ctx context.Context, ref *Reference, desc *Descriptor, newBody body) error {
+	resp, err := c.do(ctx, ref, http.MethodHead, c.url(ref, "/blobs/"+desc.Digest), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = c.do(ctx, ref, http.MethodPost, c.url(ref, "/blobs/uploads/"), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return errors.Wrap(responseError(resp), "error starting blob upload")
+	}
+
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return errors.Wrap(err, "error parsing blob upload location")
+	}
+	query := location.Query()
+	query.Set("digest", desc.Digest)
+	location.RawQuery = query.Encode()
+
+	resp, err = c.do(ctx, ref, http.MethodPut, location.String(), http.Header{
+		"Content-Type":   {"application/octet-stream"},
+		"Content-Length": {strconv.FormatInt(desc.Size, 10)},
+	}, newBody)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return errors.Wrap(responseError(resp), "error uploading blob")
+	}
+	return nil
+}
+
+// url returns the URL of a registry API path of the repository of ref.
+func (c *Client) url(ref *Reference, path string) string {
+	scheme := "https"
+	host := ref.Registry
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%v://%v/v2/%v%v", scheme, ref.Registry, ref.Repository, path)
+}
+
+// do sends a request to the registry of ref, authorizing it with the
+// scheme the registry challenges with if needed.
+func (c *Client) do(ctx context.Context, ref *Reference, method string, u string, header http.Header, newBody body) (*http.Response, error) {
+	authKey := ref.Registry + "/" + ref.Repository
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if newBody != nil {
+			b, err := newBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = b
+			req.ContentLength, _ = strconv.ParseInt(req.Header.Get("Content-Length"), 10, 64)
+		}
+		c.lock.Lock()
+		auth := c.auth[authKey]
+		c.lock.Unlock()
+		if len(auth) > 0 {
+			req.Header.Set("Authorization", auth)
+		}
+		return c.httpClient.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	auth, err := c.authorize(ctx, ref, method, challenge)
+	if err != nil {
+		return nil, err
+	}
+	c.lock.Lock()
+	c.auth[authKey] = auth
+	c.lock.Unlock()
+	return send()
+}
+
+// authorize returns the authorization header answering the challenge of
+// a registry, with a basic credential or a bearer token from the
+// registry token service.
+func (c *Client) authorize(ctx context.Context, ref *Reference, method string, challenge string) (string, error) {
+	cred, hasCred := c.credentials.get(ref.Registry)
+	scheme, params := parseChallenge(challenge)
+	switch scheme {
+	case "basic":
+		if !hasCred {
+			return "", errors.Errorf("registry %v requires credentials", ref.Registry)
+		}
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(cred.Username, cred.Password)
+		return req.Header.Get("Authorization"), nil
+
+	case "bearer":
+		realm, err := url.Parse(params["realm"])
+		if err != nil || len(params["realm"]) == 0 {
+			return "", errors.Errorf("invalid token realm in challenge %q of registry %v", challenge, ref.Registry)
+		}
+		scope := params["scope"]
+		if len(scope) == 0 {
+			scope = fmt.Sprintf("repository:%v:pull", ref.Repository)
+			if method != http.MethodGet && method != http.MethodHead {
+				scope += ",push"
+			}
+		}
+		query := realm.Query()
+		if service, ok := params["service"]; ok {
+			query.Set("service", service)
+		}
+		query.Set("scope", scope)
+		realm.RawQuery = query.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		if hasCred {
+			req.SetBasicAuth(cred.Username, cred.Password)
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return "", errors.Wrapf(err, "error getting token of registry %v", ref.Registry)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", errors.Wrapf(responseError(resp), "error getting token of registry %v", ref.Registry)
+		}
+		token := struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}{}
+		err = json.NewDecoder(resp.Body).Decode(&token)
+		if err != nil {
+			return "", errors.Wrapf(err, "error parsing token of registry %v", ref.Registry)
+		}
+		if len(token.Token) == 0 {
+			token.Token = token.AccessToken
+		}
+		return "Bearer " + token.Token, nil
+
+	default:
+		return "", errors.Errorf("unsupported authentication challenge %q of registry %v", challenge, ref.Registry)
+	}
+}
+
+// parseChallenge parses a WWW-Authenticate header, such as
+// Bearer realm="https://auth.example.com/token",service="registry".
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	scheme := strings.ToLower(parts[0])
+	if len(parts) < 2 {
+		return scheme, params
+	}
+
+	s := parts[1]
+	for len(s) > 0 {
+		i := strings.Index(s, "=")
+		if i < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:i]))
+		s = strings.TrimSpace(s[i+1:])
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value, s = s[1:end+1], s[end+2:]
+		} else {
+			end := strings.Index(s, ",")
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = strings.TrimSpace(s[:end]), s[end:]
+		}
+		params[key] = value
+		s = strings.TrimPrefix(strings.TrimSpace(s), ",")
+	}
+	return scheme, params
+}
+
+func responseError(resp *http.Response) error {
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return errors.Errorf("registry replied %v: %v", resp.Status, strings.TrimSpace(string(msg)))
+}
+
+func fileDescriptor(path string) (*Descriptor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening archive")
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading archive")
+	}
+	return &Descriptor{
+		Digest: "sha256:" + hex.EncodeToString(h.Sum(nil)),
+		Size:   size,
+	}, nil
+}
+
+func bytesDescriptor(mediaType string, data []byte) *Descriptor {
+	sum := sha256.Sum256(data)
+	return &Descriptor{
+		MediaType: mediaType,
+		Digest:    "sha256:" + hex.EncodeToString(sum[:]),
+		Size:      int64(len(data)),
+	}
+}
+
+func bytesBody(data []byte) body {
+	return func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+// verifyingReader fails at EOF if the content read doesn't match the
+// digest.
+type verifyingReader struct {
+	io.ReadCloser
+	hash   hash.Hash
+	digest string
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n]) //nolint: errCheck
+	if err == io.EOF {
+		if digest := "sha256:" + hex.EncodeToString(r.hash.Sum(nil)); digest != r.digest {
+			return n, errors.Errorf("archive has digest %v, expected %v", digest, r.digest)
+		}
+	}
+	return n, err
+}
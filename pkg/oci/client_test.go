@@ -0,0 +1,165 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testRegistry is an in-memory registry requiring bearer tokens, issued
+// to the user "user" with password "password".
+type testRegistry struct {
+	server *httptest.Server
+	lock   sync.Mutex
+	blobs  map[string][]byte
+	tags   map[string][]byte
+}
+
+func newTestRegistry() *testRegistry {
+	r := &testRegistry{
+		blobs: make(map[string][]byte),
+		tags:  make(map[string][]byte),
+	}
+	r.server = httptest.NewServer(http.HandlerFunc(r.serve))
+	return r
+}
+
+func (r *testRegistry) serve(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if req.URL.Path == "/token" {
+		user, password, _ := req.BasicAuth()
+		if user != "user" || password != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"token": "secret"}`)) //nolint: errCheck
+		return
+	}
+	if req.Header.Get("Authorization") != "Bearer secret" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+r.server.URL+`/token",service="test"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/repo")
+	switch {
+	case req.Method == http.MethodHead && strings.HasPrefix(path, "/blobs/"):
+		if _, ok := r.blobs[strings.TrimPrefix(path, "/blobs/")]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case req.Method == http.MethodGet && strings.HasPrefix(path, "/blobs/"):
+		data, ok := r.blobs[strings.TrimPrefix(path, "/blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data) //nolint: errCheck
+	case req.Method == http.MethodPost && path == "/blobs/uploads/":
+		w.Header().Set("Location", "/v2/repo/blobs/uploads/1?state=x")
+		w.WriteHeader(http.StatusAccepted)
+	case req.Method == http.MethodPut && path == "/blobs/uploads/1":
+		data, _ := ioutil.ReadAll(req.Body)
+		r.blobs[req.URL.Query().Get("digest")] = data
+		w.WriteHeader(http.StatusCreated)
+	case req.Method == http.MethodPut && strings.HasPrefix(path, "/manifests/"):
+		data, _ := ioutil.ReadAll(req.Body)
+		r.tags[strings.TrimPrefix(path, "/manifests/")] = data
+		r.tags[bytesDescriptor("", data).Digest] = data
+		w.WriteHeader(http.StatusCreated)
+	case req.Method == http.MethodGet && strings.HasPrefix(path, "/manifests/"):
+		data, ok := r.tags[strings.TrimPrefix(path, "/manifests/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data) //nolint: errCheck
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	ref, err := ParseReference("registry.example.com:5000/team/repo:v1@" + digest)
+	require.NoError(t, err)
+	require.Equal(t, Reference{
+		Registry:   "registry.example.com:5000",
+		Repository: "team/repo",
+		Tag:        "v1",
+		Digest:     digest,
+	}, *ref)
+	require.Equal(t, "registry.example.com:5000/team/repo@"+digest, ref.String())
+
+	for _, s := range []string{"repo:v1", "team/repo", "registry.example.com/Repo", "registry.example.com/repo@sha256:abc"} {
+		_, err = ParseReference(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestPushPull(t *testing.T) {
+	registry := newTestRegistry()
+	defer registry.server.Close()
+
+	dir, err := ioutil.TempDir("", "oci")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive.zip")
+	require.NoError(t, ioutil.WriteFile(archive, []byte("archive contents"), 0600))
+
+	config := []byte(`{"auths": {"` + strings.TrimPrefix(registry.server.URL, "http://") + `": {"auth": "` +
+		base64.StdEncoding.EncodeToString([]byte("user:password")) + `"}}}`)
+	creds, err := ParseDockerConfig(config)
+	require.NoError(t, err)
+
+	ref, err := ParseReference(strings.TrimPrefix(registry.server.URL, "http://") + "/repo:v1")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, _, err = MakeClient(nil, nil).Push(ctx, ref, archive)
+	require.Error(t, err)
+
+	pushed, layer, err := MakeClient(nil, creds).Push(ctx, ref, archive)
+	require.NoError(t, err)
+	require.NotEmpty(t, pushed.Digest)
+	require.Equal(t, int64(len("archive contents")), layer.Size)
+
+	pulled := filepath.Join(dir, "pulled.zip")
+	require.NoError(t, MakeClient(nil, creds).PullToFile(ctx, pushed, pulled))
+	data, err := ioutil.ReadFile(pulled)
+	require.NoError(t, err)
+	require.Equal(t, "archive contents", string(data))
+
+	// a corrupted archive fails the digest verification
+	registry.lock.Lock()
+	registry.blobs[layer.Digest] = []byte("tampered contents")
+	registry.lock.Unlock()
+	require.Error(t, MakeClient(nil, creds).PullToFile(ctx, pushed, pulled))
+}
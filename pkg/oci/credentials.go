@@ -0,0 +1,153 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	apiv1 "k8s.io/api/core/v1"
+)
+
+type (
+	// Credential is the username and password for a registry.
+	Credential struct {
+		Username string
+		Password string
+	}
+
+	// Credentials are the credentials by registry host.
+	Credentials map[string]Credential
+
+	dockerConfigEntry struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+
+	dockerConfig struct {
+		Auths map[string]dockerConfigEntry `json:"auths"`
+	}
+)
+
+// dockerHubRegistries are the names of Docker Hub in docker configs.
+var dockerHubRegistries = []string{"registry-1.docker.io", "index.docker.io", "docker.io"}
+
+// ParseDockerConfig parses the credentials of a docker config file, in the
+// format of ~/.docker/config.json or of the legacy ~/.dockercfg.
+func ParseDockerConfig(data []byte) (Credentials, error) {
+	var config dockerConfig
+	err := json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing docker config")
+	}
+	if config.Auths == nil {
+		// legacy format, without the auths key
+		err = json.Unmarshal(data, &config.Auths)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing docker config")
+		}
+	}
+
+	creds := make(Credentials)
+	for registry, entry := range config.Auths {
+		cred := Credential{
+			Username: entry.Username,
+			Password: entry.Password,
+		}
+		if len(entry.Auth) > 0 {
+			auth, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error decoding auth of registry %q", registry)
+			}
+			parts := strings.SplitN(string(auth), ":", 2)
+			if len(parts) != 2 {
+				return nil, errors.Errorf("invalid auth of registry %q, expected username:password", registry)
+			}
+			cred.Username, cred.Password = parts[0], parts[1]
+		}
+		creds[registryHost(registry)] = cred
+	}
+	return creds, nil
+}
+
+// CredentialsFromSecret returns the credentials of a docker registry
+// secret, of type kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg.
+func CredentialsFromSecret(secret *apiv1.Secret) (Credentials, error) {
+	if data, ok := secret.Data[apiv1.DockerConfigJsonKey]; ok {
+		return ParseDockerConfig(data)
+	}
+	if data, ok := secret.Data[apiv1.DockerConfigKey]; ok {
+		return ParseDockerConfig(data)
+	}
+	return nil, errors.Errorf("secret %v/%v is not a docker registry secret", secret.ObjectMeta.Namespace, secret.ObjectMeta.Name)
+}
+
+// LocalCredentials returns the credentials of the local docker config,
+// at $DOCKER_CONFIG/config.json or ~/.docker/config.json. Credentials
+// kept by credential helpers aren't supported.
+func LocalCredentials() (Credentials, error) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if len(dir) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, errors.Wrap(err, "error getting home directory")
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Credentials{}, nil
+		}
+		return nil, errors.Wrap(err, "error reading docker config")
+	}
+	return ParseDockerConfig(data)
+}
+
+// get returns the credential of a registry, if any.
+func (creds Credentials) get(registry string) (Credential, bool) {
+	if cred, ok := creds[registry]; ok {
+		return cred, true
+	}
+	for _, hub := range dockerHubRegistries {
+		if registry == hub {
+			for _, name := range dockerHubRegistries {
+				if cred, ok := creds[name]; ok {
+					return cred, true
+				}
+			}
+		}
+	}
+	return Credential{}, false
+}
+
+// registryHost returns the host of a registry in a docker config, which
+// can be a URL such as https://index.docker.io/v1/.
+func registryHost(registry string) string {
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	if i := strings.Index(registry, "/"); i >= 0 {
+		registry = registry[:i]
+	}
+	return registry
+}
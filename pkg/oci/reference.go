@@ -0,0 +1,101 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	repositoryRegexp = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	tagRegexp        = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	digestRegexp     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// Reference references an artifact of a repository in a registry, by tag
+// or digest, such as registry.example.com/repo:tag or
+// registry.example.com/repo@sha256:<digest>.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses an artifact reference. The registry is required,
+// as in registry.example.com/repo, and the registry host of Docker Hub is
+// registry-1.docker.io.
+func ParseReference(s string) (*Reference, error) {
+	ref := &Reference{}
+	if i := strings.Index(s, "@"); i >= 0 {
+		ref.Digest = s[i+1:]
+		s = s[:i]
+		if !digestRegexp.MatchString(ref.Digest) {
+			return nil, errors.Errorf("invalid digest %q in reference, expected sha256:<hex>", ref.Digest)
+		}
+	}
+
+	i := strings.Index(s, "/")
+	if i < 0 || !isRegistry(s[:i]) {
+		return nil, errors.Errorf("reference %q must start with a registry host, such as registry.example.com/repo", s)
+	}
+	ref.Registry, s = s[:i], s[i+1:]
+
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		ref.Tag = s[i+1:]
+		s = s[:i]
+		if !tagRegexp.MatchString(ref.Tag) {
+			return nil, errors.Errorf("invalid tag %q in reference", ref.Tag)
+		}
+	}
+	if !repositoryRegexp.MatchString(s) {
+		return nil, errors.Errorf("invalid repository %q in reference", s)
+	}
+	ref.Repository = s
+	return ref, nil
+}
+
+func isRegistry(s string) bool {
+	return strings.ContainsAny(s, ".:") || s == "localhost"
+}
+
+// String returns the reference, by digest if known.
+func (ref Reference) String() string {
+	s := ref.Registry + "/" + ref.Repository
+	if len(ref.Digest) > 0 {
+		return s + "@" + ref.Digest
+	}
+	if len(ref.Tag) > 0 {
+		return s + ":" + ref.Tag
+	}
+	return s
+}
+
+// version returns the tag or digest used to get the manifest of the
+// artifact. The digest wins over the tag.
+func (ref Reference) version() string {
+	if len(ref.Digest) > 0 {
+		return ref.Digest
+	}
+	if len(ref.Tag) > 0 {
+		return ref.Tag
+	}
+	return "latest"
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publisher
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// CloudEventsModeNone keeps the Fission specific request format.
+	CloudEventsModeNone CloudEventsMode = ""
+
+	// CloudEventsModeBinary uses the CloudEvents HTTP binary content mode:
+	// the event attributes are sent as ce-* headers and the request body
+	// is the event data.
+	CloudEventsModeBinary CloudEventsMode = "binary"
+
+	// CloudEventsModeStructured uses the CloudEvents HTTP structured content
+	// mode: the whole event is sent as an application/cloudevents+json body.
+	CloudEventsModeStructured CloudEventsMode = "structured"
+
+	// CloudEventsSpecVersion is the version of the CloudEvents specification
+	// events are encoded with.
+	CloudEventsSpecVersion = "1.0"
+
+	// CloudEventsModeEnv is the environment variable event sources read
+	// the CloudEvents mode from.
+	CloudEventsModeEnv = "CLOUDEVENTS_MODE"
+
+	cloudEventsContentType = "application/cloudevents+json"
+)
+
+type (
+	// CloudEventsMode is the CloudEvents HTTP content mode an event source
+	// delivers events in.
+	CloudEventsMode string
+
+	// CloudEvent holds the CloudEvents context attributes of an event
+	// delivered by an event source.
+	CloudEvent struct {
+		ID      string
+		Source  string
+		Type    string
+		Subject string
+		Time    time.Time
+	}
+)
+
+// ParseCloudEventsMode validates the given CloudEvents mode.
+func ParseCloudEventsMode(mode string) (CloudEventsMode, error) {
+	switch m := CloudEventsMode(strings.ToLower(strings.TrimSpace(mode))); m {
+	case CloudEventsModeNone, CloudEventsModeBinary, CloudEventsModeStructured:
+		return m, nil
+	default:
+		return CloudEventsModeNone, errors.Errorf("unknown CloudEvents mode %q, expected %q or %q",
+			mode, CloudEventsModeBinary, CloudEventsModeStructured)
+	}
+}
+
+// CloudEventsModeFromEnv returns the CloudEvents mode configured with the
+// CLOUDEVENTS_MODE environment variable.
+func CloudEventsModeFromEnv() (CloudEventsMode, error) {
+	return ParseCloudEventsMode(os.Getenv(CloudEventsModeEnv))
+}
+
+// NewCloudEvent returns an event with a random ID, happening now.
+func NewCloudEvent(source, eventType, subject string) CloudEvent {
+	return CloudEvent{
+		ID:      uuid.NewV4().String(),
+		Source:  source,
+		Type:    eventType,
+		Subject: subject,
+		Time:    time.Now(),
+	}
+}
+
+// TriggerSource returns the CloudEvents source of events delivered by a
+// Fission trigger, e.g. /apis/fission.io/v1/namespaces/default/timetriggers/foo
+func TriggerSource(resource, namespace, name string) string {
+	return fmt.Sprintf("/apis/fission.io/v1/namespaces/%s/%s/%s", namespace, resource, name)
+}
+
+// Encode returns the body and headers of an HTTP request delivering the event
+// with the given data in the given mode. The headers passed in are kept,
+// the Content-Type header is taken as the content type of the data.
+func (e CloudEvent) Encode(mode CloudEventsMode, data []byte, headers map[string]string) ([]byte, map[string]string, error) {
+	result := make(map[string]string, len(headers)+6)
+	var contentType string
+	for k, v := range headers {
+		if strings.EqualFold(k, "Content-Type") {
+			contentType = v
+			continue
+		}
+		result[k] = v
+	}
+
+	switch mode {
+	case CloudEventsModeNone:
+		if len(contentType) > 0 {
+			result["Content-Type"] = contentType
+		}
+		return data, result, nil
+
+	case CloudEventsModeBinary:
+		result["ce-specversion"] = CloudEventsSpecVersion
+		result["ce-id"] = e.ID
+		result["ce-source"] = e.Source
+		result["ce-type"] = e.Type
+		result["ce-time"] = e.Time.UTC().Format(time.RFC3339Nano)
+		if len(e.Subject) > 0 {
+			result["ce-subject"] = e.Subject
+		}
+		if len(contentType) > 0 {
+			result["Content-Type"] = contentType
+		}
+		return data, result, nil
+
+	case CloudEventsModeStructured:
+		event := map[string]interface{}{
+			"specversion": CloudEventsSpecVersion,
+			"id":          e.ID,
+			"source":      e.Source,
+			"type":        e.Type,
+			"time":        e.Time.UTC().Format(time.RFC3339Nano),
+		}
+		if len(e.Subject) > 0 {
+			event["subject"] = e.Subject
+		}
+		if len(contentType) > 0 {
+			event["datacontenttype"] = contentType
+		}
+		if len(data) > 0 {
+			switch {
+			case isJSONContentType(contentType) && json.Valid(data):
+				event["data"] = json.RawMessage(data)
+			case utf8.Valid(data):
+				event["data"] = string(data)
+			default:
+				event["data_base64"] = data
+			}
+		}
+		body, err := json.Marshal(event)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error encoding structured CloudEvent")
+		}
+		result["Content-Type"] = cloudEventsContentType
+		return body, result, nil
+
+	default:
+		return nil, nil, errors.Errorf("unknown CloudEvents mode %q", mode)
+	}
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publisher
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudEventEncode(t *testing.T) {
+	event := CloudEvent{
+		ID:      "1234",
+		Source:  TriggerSource("timetriggers", "default", "foo"),
+		Type:    "io.fission.timer.fired",
+		Subject: "bar",
+		Time:    time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+	}
+	headers := map[string]string{
+		"Content-Type":         "application/json",
+		"X-Fission-Timer-Name": "foo",
+	}
+	data := []byte(`{"hello":"world"}`)
+
+	t.Run("none", func(t *testing.T) {
+		body, h, err := event.Encode(CloudEventsModeNone, data, headers)
+		require.NoError(t, err)
+		require.Equal(t, data, body)
+		require.Equal(t, headers, h)
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		body, h, err := event.Encode(CloudEventsModeBinary, data, headers)
+		require.NoError(t, err)
+		require.Equal(t, data, body)
+		require.Equal(t, "1.0", h["ce-specversion"])
+		require.Equal(t, "1234", h["ce-id"])
+		require.Equal(t, "/apis/fission.io/v1/namespaces/default/timetriggers/foo", h["ce-source"])
+		require.Equal(t, "io.fission.timer.fired", h["ce-type"])
+		require.Equal(t, "bar", h["ce-subject"])
+		require.Equal(t, "2021-06-01T10:00:00Z", h["ce-time"])
+		require.Equal(t, "application/json", h["Content-Type"])
+		require.Equal(t, "foo", h["X-Fission-Timer-Name"])
+	})
+
+	t.Run("structured", func(t *testing.T) {
+		body, h, err := event.Encode(CloudEventsModeStructured, data, headers)
+		require.NoError(t, err)
+		require.Equal(t, "application/cloudevents+json", h["Content-Type"])
+
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &decoded))
+		require.Equal(t, "1.0", decoded["specversion"])
+		require.Equal(t, "1234", decoded["id"])
+		require.Equal(t, "application/json", decoded["datacontenttype"])
+		require.Equal(t, map[string]interface{}{"hello": "world"}, decoded["data"])
+	})
+
+	t.Run("structured binary data", func(t *testing.T) {
+		body, _, err := event.Encode(CloudEventsModeStructured, []byte{0xff, 0xfe}, map[string]string{"Content-Type": "application/octet-stream"})
+		require.NoError(t, err)
+
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &decoded))
+		require.Equal(t, "//4=", decoded["data_base64"])
+		require.NotContains(t, decoded, "data")
+	})
+}
+
+func TestParseCloudEventsMode(t *testing.T) {
+	for _, mode := range []string{"", "binary", "Structured"} {
+		_, err := ParseCloudEventsMode(mode)
+		require.NoError(t, err)
+	}
+	_, err := ParseCloudEventsMode("batched")
+	require.Error(t, err)
+}
@@ -0,0 +1,311 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publisher
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	journalFileName    = "outbox.journal"
+	deadLetterFileName = "deadletter.log"
+
+	// journalCompactThreshold is the number of records after which the
+	// journal is rewritten with the pending records only.
+	journalCompactThreshold = 1024
+
+	// maxJournalRecordSize is the size of the largest journal record read
+	// back, larger records being skipped.
+	maxJournalRecordSize = 16 * 1024 * 1024
+
+	journalOpPublish = "publish"
+	journalOpDone    = "done"
+)
+
+type (
+	// journal is an append-only file recording publish requests and their
+	// completion, so that pending requests survive a restart.
+	journal struct {
+		logger *zap.Logger
+		lock   sync.Mutex
+		dir    string
+		fsync  bool
+		file   *os.File
+
+		// records in the journal file, pending or not
+		records int
+		// pending requests by ID
+		pending map[string]*journalRecord
+	}
+
+	journalRecord struct {
+		Op        string            `json:"op"`
+		ID        string            `json:"id"`
+		Seq       uint64            `json:"seq,omitempty"`
+		Target    string            `json:"target,omitempty"`
+		Body      string            `json:"body,omitempty"`
+		Headers   map[string]string `json:"headers,omitempty"`
+		CreatedAt time.Time         `json:"createdAt,omitempty"`
+	}
+
+	deadLetterRecord struct {
+		ID        string            `json:"id"`
+		Target    string            `json:"target"`
+		Body      string            `json:"body"`
+		Headers   map[string]string `json:"headers,omitempty"`
+		CreatedAt time.Time         `json:"createdAt"`
+		DroppedAt time.Time         `json:"droppedAt"`
+		Attempts  int               `json:"attempts"`
+		Error     string            `json:"error"`
+	}
+)
+
+// openJournal opens the journal in the given directory and returns the
+// requests that were still pending, in publish order.
+func openJournal(logger *zap.Logger, dir string, fsync bool) (*journal, []*journalRecord, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error creating journal directory %q", dir)
+	}
+
+	j := &journal{
+		logger:  logger.Named("journal"),
+		dir:     dir,
+		fsync:   fsync,
+		pending: make(map[string]*journalRecord),
+	}
+
+	err = j.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	err = j.compact()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return j, j.pendingRecords(), nil
+}
+
+// load reads the journal file, if any, and rebuilds the pending requests.
+func (j *journal) load() error {
+	f, err := os.Open(filepath.Join(j.dir, journalFileName))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "error opening journal")
+	}
+	defer f.Close()
+
+	reader := bufio.NewReaderSize(f, 64*1024)
+	for {
+		line, size, err := readJournalRecord(reader)
+		if err == io.EOF {
+			if size > 0 {
+				// a partially written record of a crashed process
+				j.logger.Warn("skipping truncated journal record", zap.Int("size", size))
+			}
+			return nil
+		} else if err != nil {
+			return errors.Wrap(err, "error reading journal")
+		}
+		if line == nil {
+			j.logger.Error("skipping journal record larger than the maximum size",
+				zap.Int("size", size),
+				zap.Int("max_size", maxJournalRecordSize))
+			continue
+		}
+
+		var r journalRecord
+		err = json.Unmarshal(line, &r)
+		if err != nil {
+			j.logger.Warn("skipping corrupted journal record", zap.Error(err))
+			continue
+		}
+		switch r.Op {
+		case journalOpPublish:
+			j.pending[r.ID] = &r
+		case journalOpDone:
+			delete(j.pending, r.ID)
+		}
+	}
+}
+
+// readJournalRecord reads a line of the journal and returns it with its
+// size, or nil if it's larger than maxJournalRecordSize. At the end of the
+// journal it returns io.EOF, with the size of the last line if it wasn't
+// terminated.
+func readJournalRecord(reader *bufio.Reader) ([]byte, int, error) {
+	var line []byte
+	size := 0
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		size += len(chunk)
+		if size <= maxJournalRecordSize {
+			line = append(line, chunk...)
+		} else {
+			line = nil
+		}
+		switch err {
+		case nil:
+			return line, size, nil
+		case bufio.ErrBufferFull:
+			continue
+		default:
+			return nil, size, err
+		}
+	}
+}
+
+func (j *journal) pendingRecords() []*journalRecord {
+	records := make([]*journalRecord, 0, len(j.pending))
+	for _, r := range j.pending {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, k int) bool {
+		return records[i].Seq < records[k].Seq
+	})
+	return records
+}
+
+// compact rewrites the journal with the pending requests only.
+func (j *journal) compact() error {
+	// the compacted journal is written in append mode so that its handle
+	// can be kept for further records once it replaced the current journal
+	tmpPath := filepath.Join(j.dir, journalFileName+".tmp")
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.Wrap(err, "error creating journal")
+	}
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	records := j.pendingRecords()
+	for _, r := range records {
+		err = enc.Encode(r)
+		if err != nil {
+			tmp.Close()
+			return errors.Wrap(err, "error writing journal")
+		}
+	}
+	err = w.Flush()
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "error writing journal")
+	}
+
+	// the current journal is only closed once it has been replaced, so that
+	// it stays writable if the compaction fails
+	err = os.Rename(tmpPath, filepath.Join(j.dir, journalFileName))
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return errors.Wrap(err, "error replacing journal")
+	}
+
+	if j.file != nil {
+		j.file.Close()
+	}
+	j.file = tmp
+	j.records = len(records)
+	return nil
+}
+
+func (j *journal) write(r *journalRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = j.file.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+	j.records++
+	if j.fsync {
+		return j.file.Sync()
+	}
+	return nil
+}
+
+// add records a new pending request.
+func (j *journal) add(r *journalRecord) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	r.Op = journalOpPublish
+	err := j.write(r)
+	if err != nil {
+		return errors.Wrap(err, "error writing journal")
+	}
+	j.pending[r.ID] = r
+	return nil
+}
+
+// done records the completion, successful or not, of a request.
+func (j *journal) done(id string) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if _, ok := j.pending[id]; !ok {
+		return nil
+	}
+	delete(j.pending, id)
+	err := j.write(&journalRecord{Op: journalOpDone, ID: id})
+	if err != nil {
+		return errors.Wrap(err, "error writing journal")
+	}
+
+	if j.records > journalCompactThreshold && len(j.pending)*2 < j.records {
+		return j.compact()
+	}
+	return nil
+}
+
+// deadLetter appends a request given up on to the dead letter log.
+func (j *journal) deadLetter(r *deadLetterRecord) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	f, err := os.OpenFile(filepath.Join(j.dir, deadLetterFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.Wrap(err, "error opening dead letter log")
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if err == nil && j.fsync {
+		err = f.Sync()
+	}
+	return errors.Wrap(err, "error writing dead letter log")
+}
@@ -16,6 +16,8 @@ limitations under the License.
 
 package publisher
 
+import "context"
+
 type (
 	// Publisher interface wraps the Publish method that publishes an request
 	// with given "body" and "headers" to given "target"
@@ -24,5 +26,15 @@ type (
 		// publisher: it's a URL in the case of a webhook publisher, or a queue
 		// name in a queue-based publisher such as NATS.
 		Publish(body string, headers map[string]string, target string)
+
+		// PublishEvent publishes an event of an event source to a "target",
+		// in the CloudEvents format if the publisher is configured to.
+		PublishEvent(event CloudEvent, body string, headers map[string]string, target string)
+
+		// PublishEventTracked publishes an event like PublishEvent, and calls
+		// done with the outcome once the request is delivered or given up on.
+		// Cancelling ctx gives up on the request. Tracked requests to the
+		// same target may be delivered concurrently.
+		PublishEventTracked(ctx context.Context, event CloudEvent, body string, headers map[string]string, target string, done func(error))
 	}
 )
@@ -18,74 +18,404 @@ package publisher
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
 	"go.uber.org/zap"
 )
 
+const (
+	defaultMaxRetries    = 10
+	defaultRetryDelay    = 500 * time.Millisecond
+	defaultMaxRetryDelay = time.Minute
+
+	defaultMaxQueueLength = 10000
+)
+
 type (
+	// WebhookPublisherConfig configures the delivery of a WebhookPublisher.
+	WebhookPublisherConfig struct {
+		// CloudEventsMode is the format events are delivered in.
+		CloudEventsMode CloudEventsMode
+
+		// MaxRetries is the number of times a request is retried before it is
+		// written to the dead letter log.
+		MaxRetries int
+
+		// RetryDelay is the delay before the first retry, doubled on every
+		// following retry up to MaxRetryDelay.
+		RetryDelay    time.Duration
+		MaxRetryDelay time.Duration
+
+		// MaxQueueLength is the number of requests that can wait for delivery
+		// to a single target. Requests published to a full queue are written
+		// to the dead letter log right away.
+		MaxQueueLength int
+
+		// JournalDir is the directory of the outbox journal. If empty, requests
+		// are only kept in memory and are lost on restart.
+		JournalDir string
+
+		// JournalFsync syncs the journal to disk on every write.
+		JournalFsync bool
+	}
+
 	// WebhookPublisher for a single URL. Satisfies the Publisher interface.
+	//
+	// Requests to the same target are delivered one by one in publish order,
+	// requests to different targets are delivered in parallel. Tracked
+	// requests are not ordered: each is delivered as soon as it is published.
 	WebhookPublisher struct {
 		logger *zap.Logger
 
-		requestChannel chan *publishRequest
+		config  WebhookPublisherConfig
+		journal *journal
+		seq     uint64
 
-		maxRetries int
-		retryDelay time.Duration
+		lock   sync.Mutex
+		queues map[string]*targetQueue
 
 		baseURL string
 	}
+
+	// targetQueue holds the requests waiting to be delivered to a target.
+	targetQueue struct {
+		requests []*publishRequest
+	}
+
 	publishRequest struct {
-		body       string
-		headers    map[string]string
-		target     string
-		retries    int
-		retryDelay time.Duration
+		id        string
+		seq       uint64
+		body      string
+		headers   map[string]string
+		target    string
+		createdAt time.Time
+
+		// done is only set for tracked requests. A tracked request resumed
+		// from the journal after a restart is delivered untracked.
+		ctx  context.Context
+		done func(error)
 	}
 )
 
-// MakeWebhookPublisher creates a WebhookPublisher object for the given baseURL
-func MakeWebhookPublisher(logger *zap.Logger, baseURL string) *WebhookPublisher {
+// WebhookPublisherConfigFromEnv returns the publisher config set with
+// environment variables, falling back to defaults.
+func WebhookPublisherConfigFromEnv() (WebhookPublisherConfig, error) {
+	config := WebhookPublisherConfig{
+		MaxRetries:     defaultMaxRetries,
+		RetryDelay:     defaultRetryDelay,
+		MaxRetryDelay:  defaultMaxRetryDelay,
+		MaxQueueLength: defaultMaxQueueLength,
+		JournalDir:     os.Getenv("PUBLISHER_JOURNAL_DIR"),
+	}
+
+	var err error
+	config.CloudEventsMode, err = CloudEventsModeFromEnv()
+	if err != nil {
+		return config, err
+	}
+	if v := os.Getenv("PUBLISHER_MAX_RETRIES"); len(v) > 0 {
+		config.MaxRetries, err = strconv.Atoi(v)
+		if err != nil {
+			return config, errors.Wrap(err, "error parsing PUBLISHER_MAX_RETRIES")
+		}
+	}
+	if v := os.Getenv("PUBLISHER_RETRY_DELAY"); len(v) > 0 {
+		config.RetryDelay, err = time.ParseDuration(v)
+		if err != nil {
+			return config, errors.Wrap(err, "error parsing PUBLISHER_RETRY_DELAY")
+		}
+	}
+	if v := os.Getenv("PUBLISHER_MAX_RETRY_DELAY"); len(v) > 0 {
+		config.MaxRetryDelay, err = time.ParseDuration(v)
+		if err != nil {
+			return config, errors.Wrap(err, "error parsing PUBLISHER_MAX_RETRY_DELAY")
+		}
+	}
+	if v := os.Getenv("PUBLISHER_MAX_QUEUE_LENGTH"); len(v) > 0 {
+		config.MaxQueueLength, err = strconv.Atoi(v)
+		if err != nil {
+			return config, errors.Wrap(err, "error parsing PUBLISHER_MAX_QUEUE_LENGTH")
+		}
+	}
+	if v := os.Getenv("PUBLISHER_JOURNAL_FSYNC"); len(v) > 0 {
+		config.JournalFsync, err = strconv.ParseBool(v)
+		if err != nil {
+			return config, errors.Wrap(err, "error parsing PUBLISHER_JOURNAL_FSYNC")
+		}
+	}
+
+	return config, nil
+}
+
+// MakeWebhookPublisher creates a WebhookPublisher object for the given baseURL.
+// If the config has a journal directory, requests left pending by a previous
+// run are delivered again.
+func MakeWebhookPublisher(logger *zap.Logger, baseURL string, config WebhookPublisherConfig) (*WebhookPublisher, error) {
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = defaultRetryDelay
+	}
+	if config.MaxRetryDelay < config.RetryDelay {
+		config.MaxRetryDelay = config.RetryDelay
+	}
+	if config.MaxQueueLength <= 0 {
+		config.MaxQueueLength = defaultMaxQueueLength
+	}
+
 	p := &WebhookPublisher{
-		logger:         logger.Named("webhook_publisher"),
-		baseURL:        baseURL,
-		requestChannel: make(chan *publishRequest, 32), // buffered channel
-		// TODO make this configurable
-		maxRetries: 10,
-		retryDelay: 500 * time.Millisecond,
-	}
-	go p.svc()
-	return p
+		logger:  logger.Named("webhook_publisher"),
+		baseURL: baseURL,
+		config:  config,
+		queues:  make(map[string]*targetQueue),
+	}
+
+	if len(config.JournalDir) > 0 {
+		j, pending, err := openJournal(p.logger, config.JournalDir, config.JournalFsync)
+		if err != nil {
+			return nil, err
+		}
+		p.journal = j
+
+		if len(pending) > 0 {
+			p.logger.Info("resuming pending requests from journal", zap.Int("count", len(pending)))
+		}
+		for _, r := range pending {
+			if r.Seq > p.seq {
+				p.seq = r.Seq
+			}
+			p.enqueue(&publishRequest{
+				id:        r.ID,
+				seq:       r.Seq,
+				body:      r.Body,
+				headers:   r.Headers,
+				target:    r.Target,
+				createdAt: r.CreatedAt,
+				ctx:       context.Background(),
+			})
+		}
+	}
+
+	return p, nil
 }
 
 // Publish sends a request to the target with payload having given body and headers
 func (p *WebhookPublisher) Publish(body string, headers map[string]string, target string) {
-	// serializing the request gives user a guarantee that the request is sent in sequence order
-	p.requestChannel <- &publishRequest{
-		body:       body,
-		headers:    headers,
-		target:     target,
-		retries:    p.maxRetries,
-		retryDelay: p.retryDelay,
+	p.publish(p.newRequest(body, headers, target))
+}
+
+// PublishEvent sends an event to the target, encoded according to the CloudEvents mode of the publisher
+func (p *WebhookPublisher) PublishEvent(event CloudEvent, body string, headers map[string]string, target string) {
+	data, headers, err := event.Encode(p.config.CloudEventsMode, []byte(body), headers)
+	if err != nil {
+		p.logger.Error("error encoding event, dropping it", zap.Error(err),
+			zap.String("event_id", event.ID), zap.String("target", target))
+		return
+	}
+	p.Publish(string(data), headers, target)
+}
+
+// PublishEventTracked sends an event to the target like PublishEvent, and
+// calls done with the outcome of the request. The request doesn't wait for
+// the requests published to the target before it.
+func (p *WebhookPublisher) PublishEventTracked(ctx context.Context, event CloudEvent, body string, headers map[string]string, target string, done func(error)) {
+	data, headers, err := event.Encode(p.config.CloudEventsMode, []byte(body), headers)
+	if err != nil {
+		done(errors.Wrap(err, "error encoding event"))
+		return
 	}
+	r := p.newRequest(string(data), headers, target)
+	r.ctx = ctx
+	r.done = done
+	p.publish(r)
 }
 
-func (p *WebhookPublisher) svc() {
+func (p *WebhookPublisher) newRequest(body string, headers map[string]string, target string) *publishRequest {
+	return &publishRequest{
+		id:        uuid.NewV4().String(),
+		seq:       atomic.AddUint64(&p.seq, 1),
+		body:      body,
+		headers:   headers,
+		target:    target,
+		createdAt: time.Now(),
+		ctx:       context.Background(),
+	}
+}
+
+// publish records the request in the journal, if any, and queues it for delivery.
+func (p *WebhookPublisher) publish(r *publishRequest) {
+	if p.journal != nil {
+		err := p.journal.add(&journalRecord{
+			ID:        r.id,
+			Seq:       r.seq,
+			Target:    r.target,
+			Body:      r.body,
+			Headers:   r.headers,
+			CreatedAt: r.createdAt,
+		})
+		if err != nil {
+			// still try to deliver it, without the restart guarantee
+			p.logger.Error("error recording request in journal", zap.Error(err), zap.String("target", r.target))
+		}
+	}
+
+	// the caller of a tracked request follows its outcome, so it isn't
+	// held up behind the other requests to the target
+	if r.done != nil {
+		go p.send(r)
+		return
+	}
+	p.enqueue(r)
+}
+
+// enqueue adds the request to the queue of its target, starting a
+// delivery goroutine for the target if none is running. The request is
+// given up on if the queue is full.
+func (p *WebhookPublisher) enqueue(r *publishRequest) {
+	p.lock.Lock()
+	q, ok := p.queues[r.target]
+	if !ok {
+		q = &targetQueue{}
+		p.queues[r.target] = q
+		go p.deliver(r.target, q)
+	}
+	full := len(q.requests) >= p.config.MaxQueueLength
+	if !full {
+		q.requests = append(q.requests, r)
+	}
+	p.lock.Unlock()
+
+	if full {
+		err := errors.Errorf("queue of target has reached the maximum length of %v", p.config.MaxQueueLength)
+		p.logger.Error("dropping request", zap.Error(err), zap.String("target", r.target))
+		p.writeDeadLetter(r, 0, err)
+		p.complete(r, err)
+	}
+}
+
+// deliver sends the queued requests of a target in order, and exits once
+// the queue is drained.
+func (p *WebhookPublisher) deliver(target string, q *targetQueue) {
 	for {
-		r := <-p.requestChannel
-		p.makeHTTPRequest(r)
+		p.lock.Lock()
+		if len(q.requests) == 0 {
+			delete(p.queues, target)
+			p.lock.Unlock()
+			return
+		}
+		r := q.requests[0]
+		q.requests = q.requests[1:]
+		p.lock.Unlock()
+
+		p.send(r)
 	}
 }
 
-func (p *WebhookPublisher) makeHTTPRequest(r *publishRequest) {
+// send makes the request, retrying with backoff until it succeeds, runs
+// out of retries or is cancelled.
+func (p *WebhookPublisher) send(r *publishRequest) {
+	var err error
+	attempt := 0
+	for ; attempt <= p.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(p.retryDelay(attempt)):
+			case <-r.ctx.Done():
+			}
+		}
+		if r.ctx.Err() != nil {
+			err = r.ctx.Err()
+			break
+		}
+		var retryable bool
+		retryable, err = p.makeHTTPRequest(r)
+		if err == nil || !retryable {
+			break
+		}
+	}
+
+	switch {
+	case err == nil:
+	case r.ctx.Err() != nil:
+		err = r.ctx.Err()
+		p.logger.Info("request cancelled", zap.String("target", r.target), zap.Int("attempts", attempt))
+	default:
+		p.logger.Error("final retry failed, giving up", zap.Error(err),
+			zap.String("target", r.target), zap.Int("attempts", attempt))
+		p.writeDeadLetter(r, attempt, err)
+	}
+
+	p.complete(r, err)
+}
+
+// complete records the completion of the request in the journal, if any,
+// and reports its outcome.
+func (p *WebhookPublisher) complete(r *publishRequest, err error) {
+	if p.journal != nil {
+		jerr := p.journal.done(r.id)
+		if jerr != nil {
+			p.logger.Error("error recording request completion in journal", zap.Error(jerr), zap.String("target", r.target))
+		}
+	}
+
+	if r.done != nil {
+		r.done(err)
+	}
+}
+
+// retryDelay returns the exponential backoff delay before the given
+// attempt, with a random jitter of up to half the delay.
+func (p *WebhookPublisher) retryDelay(attempt int) time.Duration {
+	delay := p.config.RetryDelay
+	for i := 1; i < attempt && delay < p.config.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.config.MaxRetryDelay {
+		delay = p.config.MaxRetryDelay
+	}
+	half := int64(delay / 2)
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+func (p *WebhookPublisher) writeDeadLetter(r *publishRequest, attempts int, err error) {
+	record := &deadLetterRecord{
+		ID:        r.id,
+		Target:    r.target,
+		Body:      r.body,
+		Headers:   r.headers,
+		CreatedAt: r.createdAt,
+		DroppedAt: time.Now(),
+		Attempts:  attempts,
+		Error:     err.Error(),
+	}
+	if p.journal == nil {
+		p.logger.Error("dropped request", zap.Any("request", record))
+		return
+	}
+	derr := p.journal.deadLetter(record)
+	if derr != nil {
+		p.logger.Error("error writing dead letter log", zap.Error(derr), zap.Any("request", record))
+	}
+}
+
+// makeHTTPRequest makes a single attempt to deliver the request. It returns
+// whether the request is worth retrying if it failed.
+func (p *WebhookPublisher) makeHTTPRequest(r *publishRequest) (bool, error) {
 	url := p.baseURL + "/" + strings.TrimPrefix(r.target, "/")
 
 	msg := "making HTTP request"
 	level := zap.ErrorLevel
-	fields := []zap.Field{zap.String("url", url), zap.String("type", "publish_request")}
+	fields := []zap.Field{zap.String("url", url), zap.String("type", "publish_request"), zap.String("id", r.id)}
 
 	// log once for this request
 	defer func() {
@@ -94,14 +424,11 @@ func (p *WebhookPublisher) makeHTTPRequest(r *publishRequest) {
 		}
 	}()
 
-	var buf bytes.Buffer
-	buf.WriteString(r.body)
-
 	// Create request
-	req, err := http.NewRequest(http.MethodPost, url, &buf)
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodPost, url, bytes.NewBufferString(r.body))
 	if err != nil {
 		fields = append(fields, zap.Error(err))
-		return
+		return false, err
 	}
 	for k, v := range r.headers {
 		req.Header.Set(k, v)
@@ -109,36 +436,33 @@ func (p *WebhookPublisher) makeHTTPRequest(r *publishRequest) {
 	// Make the request
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		fields = append(fields, zap.Error(err), zap.Any("request", r))
-	} else {
-		var body []byte
-		body, err = ioutil.ReadAll(resp.Body)
-		if err != nil {
-			fields = append(fields, zap.Error(err), zap.Any("request", r))
-			msg = "read response body error"
-		} else {
-			fields = append(fields, zap.Int("status_code", resp.StatusCode), zap.String("body", string(body)))
-			if resp.StatusCode >= 200 && resp.StatusCode < 400 {
-				level = zap.InfoLevel
-			} else if resp.StatusCode >= 400 && resp.StatusCode < 500 {
-				msg = "request returned bad request status code"
-				level = zap.WarnLevel
-			} else {
-				msg = "request returned failure status code"
-			}
-			return
-		}
+		fields = append(fields, zap.Error(err))
+		return true, err
 	}
+	defer resp.Body.Close()
 
-	// Schedule a retry, or give up if out of retries
-	r.retries--
-	if r.retries > 0 {
-		r.retryDelay *= time.Duration(2)
-		time.AfterFunc(r.retryDelay, func() {
-			p.requestChannel <- r
-		})
-	} else {
-		msg = "final retry failed, giving up"
-		// Event dropped
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+		msg = "read response body error"
+		return true, err
+	}
+
+	fields = append(fields, zap.Int("status_code", resp.StatusCode), zap.String("body", string(body)))
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 400:
+		level = zap.InfoLevel
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests:
+		msg = "request returned retryable status code"
+		level = zap.WarnLevel
+		return true, fmt.Errorf("request returned status code %v", resp.StatusCode)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		msg = "request returned bad request status code"
+		level = zap.WarnLevel
+		return false, fmt.Errorf("request returned status code %v", resp.StatusCode)
+	default:
+		msg = "request returned failure status code"
+		return true, fmt.Errorf("request returned status code %v", resp.StatusCode)
 	}
 }
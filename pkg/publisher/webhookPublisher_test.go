@@ -0,0 +1,218 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package publisher
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type recorder struct {
+	lock     sync.Mutex
+	bodies   map[string][]string
+	failures map[string]int
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	if rec.failures[r.URL.Path] > 0 {
+		rec.failures[r.URL.Path]--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	rec.bodies[r.URL.Path] = append(rec.bodies[r.URL.Path], string(body))
+}
+
+func (rec *recorder) get(path string) []string {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+	return append([]string(nil), rec.bodies[path]...)
+}
+
+func TestWebhookPublisherOrderAndRetry(t *testing.T) {
+	rec := &recorder{
+		bodies:   make(map[string][]string),
+		failures: map[string]int{"/a": 2},
+	}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	p, err := MakeWebhookPublisher(zap.NewNop(), server.URL, WebhookPublisherConfig{
+		MaxRetries: 3,
+		RetryDelay: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	for _, body := range []string{"1", "2", "3"} {
+		p.Publish(body, nil, "/a")
+		p.Publish(body, nil, "/b")
+	}
+
+	require.Eventually(t, func() bool {
+		return len(rec.get("/a")) == 3 && len(rec.get("/b")) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"1", "2", "3"}, rec.get("/a"))
+	require.Equal(t, []string{"1", "2", "3"}, rec.get("/b"))
+}
+
+func TestWebhookPublisherTrackedConcurrent(t *testing.T) {
+	// the first request only completes once the second one arrived
+	second := make(chan struct{})
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-second:
+			case <-time.After(5 * time.Second):
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			return
+		}
+		close(second)
+	}))
+	defer server.Close()
+
+	p, err := MakeWebhookPublisher(zap.NewNop(), server.URL, WebhookPublisherConfig{})
+	require.NoError(t, err)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		p.PublishEventTracked(context.Background(), NewCloudEvent("test", "test", ""), "", nil, "/a", func(err error) {
+			errs <- err
+		})
+	}
+	require.NoError(t, <-errs)
+	require.NoError(t, <-errs)
+}
+
+func TestWebhookPublisherDeadLetter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "publisher")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	rec := &recorder{
+		bodies:   make(map[string][]string),
+		failures: map[string]int{"/a": 100},
+	}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	p, err := MakeWebhookPublisher(zap.NewNop(), server.URL, WebhookPublisherConfig{
+		MaxRetries: 1,
+		RetryDelay: time.Millisecond,
+		JournalDir: dir,
+	})
+	require.NoError(t, err)
+	p.Publish("lost", nil, "/a")
+
+	require.Eventually(t, func() bool {
+		data, err := ioutil.ReadFile(filepath.Join(dir, deadLetterFileName))
+		return err == nil && strings.Contains(string(data), `"body":"lost"`)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestJournalResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	j, pending, err := openJournal(zap.NewNop(), dir, true)
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	for i, id := range []string{"a", "b", "c"} {
+		require.NoError(t, j.add(&journalRecord{ID: id, Seq: uint64(i + 1), Target: "/fn", Body: id}))
+	}
+	require.NoError(t, j.done("b"))
+
+	// simulate a crash in the middle of a write
+	f, err := os.OpenFile(filepath.Join(dir, journalFileName), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"op":"publish","id":"d"`)
+	require.NoError(t, err)
+	f.Close()
+
+	_, pending, err = openJournal(zap.NewNop(), dir, false)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, "a", pending[0].ID)
+	require.Equal(t, "c", pending[1].ID)
+}
+
+func TestJournalOversizedRecords(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	big := strings.Repeat("x", maxJournalRecordSize)
+	records := `{"op":"publish","id":"a","seq":1,"target":"/fn"}` + "\n" +
+		`{"op":"publish","id":"big","seq":2,"target":"/fn","body":"` + big + `"}` + "\n" +
+		`{"op":"publish","id":"b","seq":3,"target":"/fn"}` + "\n" +
+		`{"op":"publish","id":"truncated","seq":4,"target":"/fn","body":"` + big
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, journalFileName), []byte(records), 0644))
+
+	// records over the limit and the truncated tail are skipped
+	j, pending, err := openJournal(zap.NewNop(), dir, false)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, "a", pending[0].ID)
+	require.Equal(t, "b", pending[1].ID)
+
+	require.NoError(t, j.add(&journalRecord{ID: "c", Seq: 5, Target: "/fn"}))
+	_, pending, err = openJournal(zap.NewNop(), dir, false)
+	require.NoError(t, err)
+	require.Len(t, pending, 3)
+	require.Equal(t, "c", pending[2].ID)
+}
+
+func TestJournalCompact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	j, _, err := openJournal(zap.NewNop(), dir, false)
+	require.NoError(t, err)
+	require.NoError(t, j.add(&journalRecord{ID: "a", Seq: 1, Target: "/fn"}))
+	require.NoError(t, j.add(&journalRecord{ID: "b", Seq: 2, Target: "/fn"}))
+	require.NoError(t, j.done("a"))
+	require.NoError(t, j.compact())
+	require.Equal(t, 1, j.records)
+
+	// the journal is still written to after the compaction
+	require.NoError(t, j.add(&journalRecord{ID: "c", Seq: 3, Target: "/fn"}))
+
+	_, pending, err := openJournal(zap.NewNop(), dir, false)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, "b", pending[0].ID)
+	require.Equal(t, "c", pending[1].ID)
+}
@@ -45,6 +45,9 @@ var (
 	// function + http labels as strings
 	labelsStrings = []string{"cached", "namespace", "name", "host", "path", "method", "code"}
 
+	// labels of the latency histogram, fewer to limit the number of series
+	latencyLabelsStrings = []string{"namespace", "name", "path", "method"}
+
 	// Function http calls count
 	// cached: true | false, is this function service address cached locally
 	// namespace: function namespace
@@ -74,6 +77,16 @@ var (
 		},
 		labelsStrings,
 	)
+	// Function call latency histogram, used to compute the latency percentiles
+	// of functions in a time window, such as for canary analysis
+	functionCallLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "fission_function_latency_seconds",
+			Help:    "Latency histogram of the Fission function.",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+		},
+		latencyLabelsStrings,
+	)
 	functionCallOverhead = prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
 			Name:       "fission_function_overhead_seconds",
@@ -96,6 +109,7 @@ func init() {
 	prometheus.MustRegister(functionCalls)
 	prometheus.MustRegister(functionCallErrors)
 	prometheus.MustRegister(functionCallDuration)
+	prometheus.MustRegister(functionCallLatency)
 	prometheus.MustRegister(functionCallOverhead)
 	prometheus.MustRegister(functionCallResponseSize)
 }
@@ -136,6 +150,7 @@ func functionCallCompleted(f *functionLabels, h *httpLabels, overhead, duration
 
 	// duration summary
 	functionCallDuration.WithLabelValues(l...).Observe(float64(duration.Nanoseconds()) / 1e9)
+	functionCallLatency.WithLabelValues(f.namespace, f.name, h.path, h.method).Observe(float64(duration.Nanoseconds()) / 1e9)
 
 	// Response size.  -1 means the size unknown, in which case we don't report it.
 	if respSize != -1 {
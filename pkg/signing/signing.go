@@ -0,0 +1,199 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package signing signs and verifies package archives with ed25519 keys.
+// An archive signature is a detached signature of the archive checksum,
+// so archives are verified against their content by checking the
+// checksum first.
+package signing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+const (
+	// PrivateKeySecretKey is the key of the PEM encoded PKCS #8 private
+	// key in signing key secrets.
+	PrivateKeySecretKey = "ed25519.key"
+
+	// PublicKeySecretKey is the key of the PEM encoded PKIX public key in
+	// signing key secrets.
+	PublicKeySecretKey = "ed25519.pub"
+)
+
+// ErrNoSignature is returned when verifying unsigned archives.
+var ErrNoSignature = errors.New("archive is not signed")
+
+// Policy is the signature policy of an environment, with its keys read
+// from their secrets.
+type Policy struct {
+	// PublicKeys are the PEM encoded keys one of which must have signed
+	// the archives.
+	PublicKeys []string
+
+	// BuilderKey signs the deployment archives built by the environment
+	// builder. It is nil if the policy has no builder key, or if it
+	// wasn't requested.
+	BuilderKey ed25519.PrivateKey
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS #8 ed25519 private key, as
+// generated by "openssl genpkey -algorithm ed25519".
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing private key")
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.Errorf("unsupported private key type %T, expected ed25519", key)
+	}
+	return edKey, nil
+}
+
+// ParsePublicKey parses a PEM encoded PKIX ed25519 public key, as
+// generated by "openssl pkey -pubout".
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded public key found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing public key")
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("unsupported public key type %T, expected ed25519", key)
+	}
+	return edKey, nil
+}
+
+// KeyID returns the fingerprint of a public key, identifying the key
+// that signed an archive.
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:16])
+}
+
+// message returns the signed message of an archive checksum.
+func message(checksum fv1.Checksum) []byte {
+	return []byte(fmt.Sprintf("%v:%v", checksum.Type, checksum.Sum))
+}
+
+// Sign returns the signature of an archive with the given checksum.
+func Sign(key ed25519.PrivateKey, checksum fv1.Checksum) (*fv1.ArchiveSignature, error) {
+	if checksum.Type != fv1.ChecksumTypeSHA256 || len(checksum.Sum) == 0 {
+		return nil, errors.New("archives without a sha256 checksum can't be signed")
+	}
+	return &fv1.ArchiveSignature{
+		KeyID:     KeyID(key.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, message(checksum))),
+	}, nil
+}
+
+// Verify verifies that the signature of an archive with the given
+// checksum was made by one of the PEM encoded public keys. The checksum
+// must have been verified against the archive content.
+func Verify(checksum fv1.Checksum, signature *fv1.ArchiveSignature, publicKeys []string) error {
+	if signature == nil {
+		return ErrNoSignature
+	}
+	if checksum.Type != fv1.ChecksumTypeSHA256 || len(checksum.Sum) == 0 {
+		return errors.New("signed archive has no sha256 checksum")
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature.Signature)
+	if err != nil {
+		return errors.Wrap(err, "error decoding archive signature")
+	}
+	for _, pub := range publicKeys {
+		key, err := ParsePublicKey([]byte(pub))
+		if err != nil {
+			return err
+		}
+		if ed25519.Verify(key, message(checksum), sig) {
+			return nil
+		}
+	}
+	return errors.Errorf("archive signature with key ID %q doesn't match any trusted key", signature.KeyID)
+}
+
+// ReadPrivateKey reads the private key of a signing key secret.
+func ReadPrivateKey(ctx context.Context, kubeClient kubernetes.Interface, namespace string, name string) (ed25519.PrivateKey, error) {
+	secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting signing key secret %v/%v", namespace, name)
+	}
+	data, ok := secret.Data[PrivateKeySecretKey]
+	if !ok {
+		return nil, errors.Errorf("signing key secret %v/%v has no %q key", namespace, name, PrivateKeySecretKey)
+	}
+	return ParsePrivateKey(data)
+}
+
+// ResolvePolicy reads the keys of the signature policy of an environment,
+// including the builder key if withBuilderKey is set. It returns nil if
+// the environment doesn't require signed archives.
+func ResolvePolicy(ctx context.Context, kubeClient kubernetes.Interface, env *fv1.Environment, withBuilderKey bool) (*Policy, error) {
+	spec := env.Spec.SignaturePolicy
+	if spec == nil {
+		return nil, nil
+	}
+	namespace := env.ObjectMeta.Namespace
+
+	policy := &Policy{}
+	for _, name := range spec.KeySecrets {
+		secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "error getting signature key secret %v/%v", namespace, name)
+		}
+		data, ok := secret.Data[PublicKeySecretKey]
+		if !ok {
+			return nil, errors.Errorf("signature key secret %v/%v has no %q key", namespace, name, PublicKeySecretKey)
+		}
+		_, err = ParsePublicKey(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid public key in secret %v/%v", namespace, name)
+		}
+		policy.PublicKeys = append(policy.PublicKeys, string(data))
+	}
+
+	if withBuilderKey && len(spec.BuilderKeySecret) > 0 {
+		key, err := ReadPrivateKey(ctx, kubeClient, namespace, spec.BuilderKeySecret)
+		if err != nil {
+			return nil, err
+		}
+		policy.BuilderKey = key
+	}
+	return policy, nil
+}
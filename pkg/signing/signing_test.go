@@ -0,0 +1,110 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+)
+
+func generateKey(t *testing.T) (ed25519.PrivateKey, []byte, []byte) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	return priv,
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+}
+
+func TestSignVerify(t *testing.T) {
+	_, privPEM, pubPEM := generateKey(t)
+	_, _, otherPubPEM := generateKey(t)
+
+	key, err := ParsePrivateKey(privPEM)
+	require.NoError(t, err)
+
+	checksum := fv1.Checksum{Type: fv1.ChecksumTypeSHA256, Sum: "0123456789abcdef"}
+	sig, err := Sign(key, checksum)
+	require.NoError(t, err)
+	require.NoError(t, fv1.Archive{Signature: sig}.Validate())
+
+	require.NoError(t, Verify(checksum, sig, []string{string(otherPubPEM), string(pubPEM)}))
+	require.Error(t, Verify(checksum, sig, []string{string(otherPubPEM)}))
+	require.Equal(t, ErrNoSignature, Verify(checksum, nil, []string{string(pubPEM)}))
+
+	// the signature doesn't verify another archive
+	tampered := fv1.Checksum{Type: fv1.ChecksumTypeSHA256, Sum: "fedcba9876543210"}
+	require.Error(t, Verify(tampered, sig, []string{string(pubPEM)}))
+
+	_, err = Sign(key, fv1.Checksum{})
+	require.Error(t, err)
+}
+
+func TestResolvePolicy(t *testing.T) {
+	_, privPEM, pubPEM := generateKey(t)
+	kubeClient := fake.NewSimpleClientset(
+		&apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "trusted", Namespace: "default"},
+			Data:       map[string][]byte{PublicKeySecretKey: pubPEM},
+		},
+		&apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "builder", Namespace: "default"},
+			Data:       map[string][]byte{PrivateKeySecretKey: privPEM, PublicKeySecretKey: pubPEM},
+		},
+	)
+	env := &fv1.Environment{
+		ObjectMeta: metav1.ObjectMeta{Name: "env", Namespace: "default"},
+	}
+	ctx := context.Background()
+
+	policy, err := ResolvePolicy(ctx, kubeClient, env, true)
+	require.NoError(t, err)
+	require.Nil(t, policy)
+
+	env.Spec.SignaturePolicy = &fv1.SignaturePolicy{
+		KeySecrets:       []string{"trusted"},
+		BuilderKeySecret: "builder",
+	}
+	policy, err = ResolvePolicy(ctx, kubeClient, env, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{string(pubPEM)}, policy.PublicKeys)
+	require.Nil(t, policy.BuilderKey)
+
+	policy, err = ResolvePolicy(ctx, kubeClient, env, true)
+	require.NoError(t, err)
+	sig, err := Sign(policy.BuilderKey, fv1.Checksum{Type: fv1.ChecksumTypeSHA256, Sum: "abc"})
+	require.NoError(t, err)
+	require.NoError(t, Verify(fv1.Checksum{Type: fv1.ChecksumTypeSHA256, Sum: "abc"}, sig, policy.PublicKeys))
+
+	env.Spec.SignaturePolicy.KeySecrets = []string{"missing"}
+	_, err = ResolvePolicy(ctx, kubeClient, env, false)
+	require.Error(t, err)
+}
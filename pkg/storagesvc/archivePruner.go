@@ -18,37 +18,191 @@ package storagesvc
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sCache "k8s.io/client-go/tools/cache"
 
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/crd"
+	genInformer "github.com/fission/fission/pkg/generated/informers/externalversions"
 )
 
-type ArchivePruner struct {
-	logger        *zap.Logger
-	crdClient     *crd.FissionClient
-	archiveChan   chan string
-	stowClient    *StowClient
-	pruneInterval time.Duration
-}
+const (
+	// PruneActionPrune is the action of orphan archives deleted by the
+	// next prune.
+	PruneActionPrune = "prune"
+	// PruneActionWait is the action of orphan archives waiting for their
+	// minimum age or grace period.
+	PruneActionWait = "wait"
+	// PruneActionRetain is the action of orphan archives kept by the
+	// retention policy.
+	PruneActionRetain = "retain"
+
+	// packageListLimit is the page size of package lists.
+	packageListLimit = 500
+)
+
+type (
+	ArchivePruner struct {
+		logger        *zap.Logger
+		crdClient     *crd.FissionClient
+		archiveChan   chan string
+		stowClient    *StowClient
+		pruneInterval time.Duration
+		policy        RetentionPolicy
+
+		// stateStore persists the tombstones and package archive history,
+		// which are kept in memory only if nil.
+		stateStore *pruneStateStore
+		// stateChanged signals the state is to be saved.
+		stateChanged chan struct{}
+
+		lock sync.Mutex
+		// tombstones holds the time orphan archives were first found
+		// orphaned, archives being deleted after a grace period.
+		tombstones map[string]time.Time
+		// history holds the archives last referenced by each package, most
+		// recent first, to keep the last ones.
+		history map[string][]string
+	}
+
+	// RetentionPolicy configures which orphan archives, the archives no
+	// package references, the archive pruner deletes.
+	RetentionPolicy struct {
+		// MinAge is the age under which archives aren't pruned, so that
+		// archives uploaded for packages being created aren't.
+		MinAge time.Duration
+		// GracePeriod is the time archives stay orphaned before being
+		// pruned.
+		GracePeriod time.Duration
+		// KeepLast is the number of archives each package referenced
+		// before its current ones that are kept.
+		KeepLast int
+		// DryRun only logs the archives that would be pruned.
+		DryRun bool
+	}
+
+	// OrphanArchive is an archive no package references, with what the
+	// archive pruner does with it.
+	OrphanArchive struct {
+		ID           string     `json:"id"`
+		LastModified time.Time  `json:"lastModified"`
+		Action       string     `json:"action"`
+		Reason       string     `json:"reason"`
+		PruneAfter   *time.Time `json:"pruneAfter,omitempty"`
+	}
+
+	// OrphanReport lists the orphan archives on storage.
+	OrphanReport struct {
+		DryRun   bool            `json:"dryRun"`
+		Archives []OrphanArchive `json:"archives"`
+	}
+)
 
 const defaultPruneInterval int = 60 // in minutes
 
-func MakeArchivePruner(logger *zap.Logger, stowClient *StowClient, pruneInterval time.Duration) (*ArchivePruner, error) {
-	crdClient, _, _, _, err := crd.MakeFissionClient()
+// RetentionPolicyFromEnv returns the retention policy set by the
+// PRUNE_MIN_AGE and PRUNE_GRACE_PERIOD (in minutes), PRUNE_KEEP_LAST and
+// PRUNE_DRY_RUN environment variables.
+func RetentionPolicyFromEnv() RetentionPolicy {
+	policy := RetentionPolicy{
+		MinAge:      10 * time.Minute,
+		GracePeriod: time.Hour,
+	}
+	if minAge, err := strconv.Atoi(os.Getenv("PRUNE_MIN_AGE")); err == nil && minAge >= 0 {
+		policy.MinAge = time.Duration(minAge) * time.Minute
+	}
+	if gracePeriod, err := strconv.Atoi(os.Getenv("PRUNE_GRACE_PERIOD")); err == nil && gracePeriod >= 0 {
+		policy.GracePeriod = time.Duration(gracePeriod) * time.Minute
+	}
+	if keepLast, err := strconv.Atoi(os.Getenv("PRUNE_KEEP_LAST")); err == nil && keepLast >= 0 {
+		policy.KeepLast = keepLast
+	}
+	policy.DryRun, _ = strconv.ParseBool(os.Getenv("PRUNE_DRY_RUN"))
+	return policy
+}
+
+func MakeArchivePruner(logger *zap.Logger, stowClient *StowClient, pruneInterval time.Duration, policy RetentionPolicy) (*ArchivePruner, error) {
+	crdClient, kubeClient, _, _, err := crd.MakeFissionClient()
 	if err != nil {
 		return nil, err
 	}
 
-	return &ArchivePruner{
+	pruner := &ArchivePruner{
 		logger:        logger.Named("archive_pruner"),
 		crdClient:     crdClient,
 		archiveChan:   make(chan string),
 		stowClient:    stowClient,
 		pruneInterval: pruneInterval,
-	}, nil
+		policy:        policy,
+		tombstones:    make(map[string]time.Time),
+		history:       make(map[string][]string),
+	}
+	if namespace := os.Getenv("POD_NAMESPACE"); len(namespace) > 0 {
+		pruner.stateStore = &pruneStateStore{
+			kubeClient: kubeClient,
+			namespace:  namespace,
+		}
+		pruner.stateChanged = make(chan struct{}, 1)
+	} else {
+		pruner.logger.Warn("POD_NAMESPACE not set, archive pruner state is kept in memory only")
+	}
+	return pruner, nil
+}
+
+// loadState restores the tombstones and package archive history saved.
+func (pruner *ArchivePruner) loadState() error {
+	if pruner.stateStore == nil {
+		return nil
+	}
+	state, err := pruner.stateStore.load(context.TODO())
+	if err != nil {
+		return err
+	}
+	pruner.lock.Lock()
+	defer pruner.lock.Unlock()
+	pruner.tombstones = state.Tombstones
+	pruner.history = state.History
+	return nil
+}
+
+// stateUpdated has the state saved, changes made meanwhile being saved
+// together.
+func (pruner *ArchivePruner) stateUpdated() {
+	select {
+	case pruner.stateChanged <- struct{}{}:
+	default:
+	}
+}
+
+// saveState saves the state each time it's updated.
+func (pruner *ArchivePruner) saveState() {
+	for range pruner.stateChanged {
+		pruner.lock.Lock()
+		state := &pruneState{
+			Tombstones: make(map[string]time.Time, len(pruner.tombstones)),
+			History:    make(map[string][]string, len(pruner.history)),
+		}
+		for id, t := range pruner.tombstones {
+			state.Tombstones[id] = t
+		}
+		for key, ids := range pruner.history {
+			state.History[key] = ids
+		}
+		pruner.lock.Unlock()
+
+		err := pruner.stateStore.save(context.TODO(), state)
+		if err != nil {
+			pruner.logger.Error("error saving archive pruner state", zap.Error(err))
+		}
+	}
 }
 
 // pruneArchives listens to archiveChannel for archive ids that need to be deleted
@@ -63,7 +217,12 @@ func (pruner *ArchivePruner) pruneArchives() {
 			pruner.logger.Error("ignoring error while deleting archive",
 				zap.Error(err),
 				zap.String("archive_id", archiveID))
+			continue
 		}
+		pruner.lock.Lock()
+		delete(pruner.tombstones, archiveID)
+		pruner.lock.Unlock()
+		pruner.stateUpdated()
 	}
 }
 
@@ -72,65 +231,273 @@ func (pruner *ArchivePruner) insertArchive(archiveID string) {
 	pruner.archiveChan <- archiveID
 }
 
-// A user may have deleted pkgs with kubectl or fission cli. That only deletes crd.Package objects from kubernetes
-// and not the archives that are referenced by them, leaving the archives as orphans.
-// getOrphanArchives reaps the orphaned archives.
-func (pruner *ArchivePruner) getOrphanArchives() {
-	pruner.logger.Debug("getting orphan archives")
-	archivesRefByPkgs := make([]string, 0)
-	var archiveID string
+// touch restarts the grace period of an orphan archive, when a client
+// is about to reference it again.
+func (pruner *ArchivePruner) touch(archiveID string) {
+	pruner.lock.Lock()
+	_, ok := pruner.tombstones[archiveID]
+	if ok {
+		pruner.tombstones[archiveID] = time.Now()
+	}
+	pruner.lock.Unlock()
+	if ok {
+		pruner.stateUpdated()
+	}
+}
 
-	// get all pkgs from kubernetes
-	pkgList, err := pruner.crdClient.CoreV1().Packages(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
-	if err != nil {
-		pruner.logger.Error("error getting package list from kubernetes", zap.Error(err))
-		return
+// packageArchives returns the archives on storage a package references.
+func packageArchives(pkg *fv1.Package) ([]string, error) {
+	var ids []string
+	for _, archive := range []fv1.Archive{pkg.Spec.Deployment, pkg.Spec.Source} {
+		if archive.URL == "" || archive.Type == fv1.ArchiveTypeOCI {
+			continue
+		}
+		archiveID, err := getQueryParamValue(archive.URL, "id")
+		if err != nil {
+			return nil, errors.Wrapf(err, "error extracting value of archiveID from url %v", archive.URL)
+		}
+		ids = append(ids, archiveID)
 	}
+	return ids, nil
+}
 
-	// extract archives referenced by these pkgs
-	for _, pkg := range pkgList.Items {
-		if pkg.Spec.Deployment.URL != "" {
-			archiveID, err = getQueryParamValue(pkg.Spec.Deployment.URL, "id")
-			if err != nil {
-				pruner.logger.Error("error extracting value of archiveID from deployment url",
-					zap.Error(err),
-					zap.String("url", pkg.Spec.Deployment.URL))
-				return
-			}
-			archivesRefByPkgs = append(archivesRefByPkgs, archiveID)
+// getPackageArchives returns the archives on storage referenced by each
+// package, listing packages page by page.
+func (pruner *ArchivePruner) getPackageArchives() (map[string][]string, error) {
+	archives := make(map[string][]string)
+	opts := metav1.ListOptions{Limit: packageListLimit}
+	for {
+		pkgList, err := pruner.crdClient.CoreV1().Packages(metav1.NamespaceAll).List(context.TODO(), opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "error getting package list from kubernetes")
 		}
-		if pkg.Spec.Source.URL != "" {
-			archiveID, err = getQueryParamValue(pkg.Spec.Source.URL, "id")
+
+		// extract archives referenced by these pkgs
+		for i := range pkgList.Items {
+			pkg := &pkgList.Items[i]
+			ids, err := packageArchives(pkg)
 			if err != nil {
-				pruner.logger.Error("error extracting value of archiveID from source url",
-					zap.Error(err),
-					zap.String("url", pkg.Spec.Source.URL))
-				return
+				return nil, err
 			}
-			archivesRefByPkgs = append(archivesRefByPkgs, archiveID)
+			archives[packageKey(pkg)] = ids
 		}
+
+		if len(pkgList.Continue) == 0 {
+			return archives, nil
+		}
+		opts.Continue = pkgList.Continue
 	}
+}
 
-	pruner.logger.Debug("archives referenced by packagese", zap.Strings("archives", archivesRefByPkgs))
+// getReferenceCounts returns the number of packages referencing each archive
+// on storage. Archives are stored by content, so packages with the same
+// archives share them.
+func (pruner *ArchivePruner) getReferenceCounts() (map[string]int, error) {
+	archives, err := pruner.getPackageArchives()
+	if err != nil {
+		return nil, err
+	}
+	refs := make(map[string]int)
+	for _, ids := range archives {
+		for _, id := range ids {
+			refs[id]++
+		}
+	}
+	return refs, nil
+}
 
-	// get all archives on storage
-	// out of them, there may be some just created but not referenced by packages yet.
-	// need to filter them out.
-	archivesInStorage, err := pruner.stowClient.getItemIDsWithFilter(pruner.stowClient.filterItemCreatedAMinuteAgo, time.Now())
+func packageKey(pkg *fv1.Package) string {
+	return fmt.Sprintf("%v/%v", pkg.ObjectMeta.Namespace, pkg.ObjectMeta.Name)
+}
+
+// updateHistory records the archives referenced by each package, and
+// forgets the packages deleted. The caller holds the lock.
+func (pruner *ArchivePruner) updateHistory(archives map[string][]string) {
+	for key := range pruner.history {
+		if _, ok := archives[key]; !ok {
+			delete(pruner.history, key)
+		}
+	}
+	for key, ids := range archives {
+		pruner.recordArchives(key, ids)
+	}
+}
+
+// recordArchives records the archives a package references at the head
+// of its history. The caller holds the lock.
+func (pruner *ArchivePruner) recordArchives(key string, ids []string) {
+	history := append([]string{}, ids...)
+	for _, id := range pruner.history[key] {
+		if !contains(ids, id) {
+			history = append(history, id)
+		}
+	}
+	if max := pruner.policy.KeepLast + len(ids); len(history) > max {
+		history = history[:max]
+	}
+	pruner.history[key] = history
+}
+
+// packageUpdated records the archives of a package as soon as it changes,
+// so that the archives it references between two prunes are kept too.
+func (pruner *ArchivePruner) packageUpdated(pkg *fv1.Package) {
+	ids, err := packageArchives(pkg)
 	if err != nil {
-		pruner.logger.Error("error getting items from storage", zap.Error(err))
+		pruner.logger.Error("error getting package archives", zap.Error(err), zap.String("package", packageKey(pkg)))
 		return
 	}
-	pruner.logger.Debug("archives in storage", zap.Strings("archives", archivesInStorage))
+	pruner.lock.Lock()
+	pruner.recordArchives(packageKey(pkg), ids)
+	pruner.lock.Unlock()
+	pruner.stateUpdated()
+}
+
+// watchPackages records the archives of packages as they change.
+func (pruner *ArchivePruner) watchPackages() {
+	informerFactory := genInformer.NewSharedInformerFactory(pruner.crdClient, 0)
+	pkgInformer := informerFactory.Core().V1().Packages().Informer()
+	pkgInformer.AddEventHandler(k8sCache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pruner.packageUpdated(obj.(*fv1.Package))
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			pruner.packageUpdated(newObj.(*fv1.Package))
+		},
+	})
+	go pkgInformer.Run(make(chan struct{}))
+}
+
+// getOrphans returns the orphan archives on storage and what to do with
+// them. If record is set, newly orphaned archives are tombstoned and the
+// package archive history is updated, else they're reported as if they
+// were tombstoned now.
+func (pruner *ArchivePruner) getOrphans(record bool) ([]OrphanArchive, error) {
+	archives, err := pruner.getPackageArchives()
+	if err != nil {
+		return nil, err
+	}
+
+	// get all archives on storage
+	items, err := pruner.stowClient.getItems()
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting items from storage")
+	}
 
-	// difference of the two lists gives us the list of orphan archives. This is just a brute force approach.
-	// need to do something more optimal at scale.
-	orphanedArchives := getDifferenceOfLists(archivesInStorage, archivesRefByPkgs)
-	pruner.logger.Debug("orphan archives", zap.Strings("archives", orphanedArchives))
+	pruner.lock.Lock()
+	defer pruner.lock.Unlock()
+
+	if record {
+		pruner.updateHistory(archives)
+	}
+	referenced := make(map[string]bool)
+	for _, ids := range archives {
+		for _, id := range ids {
+			referenced[id] = true
+		}
+	}
+	retained := make(map[string]string)
+	for key, history := range pruner.history {
+		for i, id := range history {
+			if i < pruner.policy.KeepLast+len(archives[key]) {
+				retained[id] = key
+			}
+		}
+	}
+
+	now := time.Now()
+	orphans := make([]OrphanArchive, 0)
+	tombstones := make(map[string]time.Time)
+	for _, item := range items {
+		if referenced[item.ID()] {
+			continue
+		}
+		lastMod, _ := item.LastMod()
+		orphan := OrphanArchive{
+			ID:           item.ID(),
+			LastModified: lastMod,
+		}
+
+		// archives just uploaded may be about to be referenced by packages
+		if minAgeEnd := lastMod.Add(pruner.policy.MinAge); now.Before(minAgeEnd) {
+			pruneAfter := minAgeEnd.Add(pruner.policy.GracePeriod)
+			orphan.Action = PruneActionWait
+			orphan.Reason = "younger than the minimum age"
+			orphan.PruneAfter = &pruneAfter
+			orphans = append(orphans, orphan)
+			continue
+		}
+
+		if key, ok := retained[item.ID()]; ok {
+			orphan.Action = PruneActionRetain
+			orphan.Reason = fmt.Sprintf("one of the last %v archives of package %v", pruner.policy.KeepLast, key)
+			orphans = append(orphans, orphan)
+			continue
+		}
+
+		tombstone, ok := pruner.tombstones[item.ID()]
+		if !ok {
+			tombstone = now
+		}
+		tombstones[item.ID()] = tombstone
+		pruneAfter := tombstone.Add(pruner.policy.GracePeriod)
+		orphan.PruneAfter = &pruneAfter
+		if now.Before(pruneAfter) {
+			orphan.Action = PruneActionWait
+			orphan.Reason = "in its grace period"
+		} else {
+			orphan.Action = PruneActionPrune
+			orphan.Reason = "orphaned for longer than the grace period"
+		}
+		orphans = append(orphans, orphan)
+	}
+
+	// archives referenced again or deleted lose their tombstones
+	if record {
+		pruner.tombstones = tombstones
+		pruner.stateUpdated()
+	}
+	return orphans, nil
+}
+
+// Report returns the orphan archives on storage and what the archive
+// pruner does with them.
+func (pruner *ArchivePruner) Report() (*OrphanReport, error) {
+	orphans, err := pruner.getOrphans(false)
+	if err != nil {
+		return nil, err
+	}
+	return &OrphanReport{
+		DryRun:   pruner.policy.DryRun,
+		Archives: orphans,
+	}, nil
+}
+
+// A user may have deleted pkgs with kubectl or fission cli. That only deletes crd.Package objects from kubernetes
+// and not the archives that are referenced by them, leaving the archives as orphans.
+// getOrphanArchives reaps the orphaned archives, once the retention policy allows it.
+func (pruner *ArchivePruner) getOrphanArchives() {
+	pruner.logger.Debug("getting orphan archives")
+
+	orphans, err := pruner.getOrphans(true)
+	if err != nil {
+		pruner.logger.Error("error getting orphan archives", zap.Error(err))
+		return
+	}
 
 	// send each orphan archive away for deletion
-	for _, archiveID = range orphanedArchives {
-		pruner.insertArchive(archiveID)
+	for _, orphan := range orphans {
+		pruner.logger.Debug("orphan archive",
+			zap.String("archive_id", orphan.ID),
+			zap.String("action", orphan.Action),
+			zap.String("reason", orphan.Reason))
+		if orphan.Action != PruneActionPrune {
+			continue
+		}
+		if pruner.policy.DryRun {
+			pruner.logger.Info("dry run, not pruning archive", zap.String("archive_id", orphan.ID))
+			continue
+		}
+		pruner.insertArchive(orphan.ID)
 	}
 }
 
@@ -138,6 +505,23 @@ func (pruner *ArchivePruner) getOrphanArchives() {
 // Also wakes up at regular intervals to make a list of archive IDs that need to be reaped
 // and sends them over to the channel for deletion
 func (pruner *ArchivePruner) Start() {
+	// tombstones and history must be restored before pruning, else
+	// grace periods restart and retained archives get pruned
+	for {
+		err := pruner.loadState()
+		if err == nil {
+			break
+		}
+		pruner.logger.Error("error loading archive pruner state, retrying", zap.Error(err))
+		time.Sleep(time.Minute)
+	}
+	if pruner.stateStore != nil {
+		go pruner.saveState()
+	}
+	if pruner.policy.KeepLast > 0 {
+		pruner.watchPackages()
+	}
+
 	ticker := time.NewTicker(pruner.pruneInterval * time.Minute)
 	go pruner.pruneArchives()
 	for range ticker.C {
@@ -146,3 +530,12 @@ func (pruner *ArchivePruner) Start() {
 		pruner.getOrphanArchives()
 	}
 }
+
+func contains(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storagesvc
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/crd"
+	"github.com/fission/fission/pkg/generated/clientset/versioned/fake"
+)
+
+func TestRetentionPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storagesvc")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	logger := zap.NewNop()
+	stowClient, err := MakeStowClient(logger, NewLocalStorage(dir))
+	require.NoError(t, err)
+	putFile := func(checksum string) string {
+		id, err := stowClient.putFile(bytes.NewReader([]byte(checksum)), int64(len(checksum)), checksum)
+		require.NoError(t, err)
+		return id
+	}
+	first, second, third := putFile("first"), putFile("second"), putFile("third")
+
+	pkg := &fv1.Package{
+		ObjectMeta: metav1.ObjectMeta{Name: "pkg", Namespace: "default"},
+		Spec: fv1.PackageSpec{
+			Deployment: fv1.Archive{Type: fv1.ArchiveTypeUrl, URL: "http://storagesvc/v1/archive?id=" + first},
+		},
+	}
+	fissionClient := fake.NewSimpleClientset(pkg)
+	pruner := &ArchivePruner{
+		logger:     logger,
+		crdClient:  &crd.FissionClient{Interface: fissionClient},
+		stowClient: stowClient,
+		policy:     RetentionPolicy{GracePeriod: time.Hour, KeepLast: 1},
+		tombstones: make(map[string]time.Time),
+		history:    make(map[string][]string),
+	}
+	actions := func() map[string]string {
+		orphans, err := pruner.getOrphans(true)
+		require.NoError(t, err)
+		actions := make(map[string]string)
+		for _, orphan := range orphans {
+			actions[orphan.ID] = orphan.Action
+		}
+		return actions
+	}
+
+	// orphans wait for the grace period
+	require.Equal(t, map[string]string{second: PruneActionWait, third: PruneActionWait}, actions())
+
+	// the previous archive of the package is kept
+	pkg.Spec.Deployment.URL = "http://storagesvc/v1/archive?id=" + second
+	_, err = fissionClient.CoreV1().Packages("default").Update(context.Background(), pkg, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{first: PruneActionRetain, third: PruneActionWait}, actions())
+
+	// orphans are pruned once tombstoned for the grace period
+	pruner.lock.Lock()
+	pruner.tombstones[third] = time.Now().Add(-2 * time.Hour)
+	pruner.lock.Unlock()
+	require.Equal(t, map[string]string{first: PruneActionRetain, third: PruneActionPrune}, actions())
+
+	// touched orphans get a new grace period
+	pruner.touch(third)
+	require.Equal(t, PruneActionWait, actions()[third])
+
+	// archives of deleted packages aren't kept, and young archives wait
+	require.NoError(t, fissionClient.CoreV1().Packages("default").Delete(context.Background(), "pkg", metav1.DeleteOptions{}))
+	pruner.policy.MinAge = time.Hour
+	require.Equal(t, map[string]string{first: PruneActionWait, second: PruneActionWait, third: PruneActionWait}, actions())
+	report, err := pruner.Report()
+	require.NoError(t, err)
+	require.Len(t, report.Archives, 3)
+}
+
+func TestPruneStatePersisted(t *testing.T) {
+	store := &pruneStateStore{
+		kubeClient: kubefake.NewSimpleClientset(),
+		namespace:  "fission",
+	}
+	logger := zap.NewNop()
+	pruner := &ArchivePruner{
+		logger:       logger,
+		policy:       RetentionPolicy{KeepLast: 1},
+		stateStore:   store,
+		stateChanged: make(chan struct{}, 1),
+		tombstones:   make(map[string]time.Time),
+		history:      make(map[string][]string),
+	}
+	require.NoError(t, pruner.loadState())
+
+	// archives are recorded as packages change
+	pkg := &fv1.Package{
+		ObjectMeta: metav1.ObjectMeta{Name: "pkg", Namespace: "default"},
+		Spec: fv1.PackageSpec{
+			Deployment: fv1.Archive{Type: fv1.ArchiveTypeUrl, URL: "http://storagesvc/v1/archive?id=first"},
+		},
+	}
+	pruner.packageUpdated(pkg)
+	pkg.Spec.Deployment.URL = "http://storagesvc/v1/archive?id=second"
+	pruner.packageUpdated(pkg)
+	tombstone := time.Now().Add(-time.Hour).Round(time.Second)
+	pruner.lock.Lock()
+	pruner.tombstones["third"] = tombstone
+	pruner.lock.Unlock()
+
+	close(pruner.stateChanged)
+	pruner.saveState()
+
+	restarted := &ArchivePruner{logger: logger, stateStore: store}
+	require.NoError(t, restarted.loadState())
+	require.Equal(t, map[string][]string{"default/pkg": {"second", "first"}}, restarted.history)
+	require.True(t, tombstone.Equal(restarted.tombstones["third"]))
+}
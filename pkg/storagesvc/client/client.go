@@ -19,6 +19,8 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -28,6 +30,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.opencensus.io/plugin/ochttp"
@@ -53,42 +56,216 @@ func MakeClient(url string) *Client {
 	}
 }
 
+const (
+	// partSize is the size of the parts of chunked uploads.
+	partSize int64 = 8 * 1024 * 1024
+
+	// maxAttempts is the number of times parts of uploads and downloads
+	// are tried before giving up.
+	maxAttempts = 5
+)
+
 // Upload sends the local file pointed to by filePath to the storage
 // service, along with the metadata.  It returns a file ID that can be
 // used to retrieve the file.
+//
+// The file is uploaded in parts, each retried on failure. If the upload
+// fails anyway, the returned error holds the upload ID for ResumeUpload
+// to upload the remaining parts.
 func (c *Client) Upload(ctx context.Context, filePath string, metadata *map[string]string) (string, error) {
 	fi, err := os.Stat(filePath)
 	if err != nil {
 		return "", err
 	}
-	fileSize := fi.Size()
 
-	buf := &bytes.Buffer{}
-	bodyWriter := multipart.NewWriter(buf)
-	fileWriter, err := bodyWriter.CreateFormFile("uploadfile", filePath)
+	var session storagesvc.UploadSession
+	status, err := c.doJSON(ctx, http.MethodPost, c.url+"/archive/uploads",
+		&storagesvc.InitiateUploadRequest{FileSize: fi.Size()}, &session)
+	if status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+		// storage services without chunked uploads
+		return c.uploadMultipart(ctx, filePath, fi.Size())
+	}
 	if err != nil {
-		return "", err
+		return "", errors.Wrap(err, "error initiating upload")
+	}
+
+	return c.ResumeUpload(ctx, session.ID, filePath)
+}
+
+// ResumeUpload uploads the parts of the file not received yet by the
+// storage service, and completes the upload.
+func (c *Client) ResumeUpload(ctx context.Context, uploadID string, filePath string) (string, error) {
+	uploadURL := fmt.Sprintf("%v/archive/uploads/%v", c.url, url.PathEscape(uploadID))
+
+	var session storagesvc.UploadSession
+	_, err := c.doJSON(ctx, http.MethodGet, uploadURL, nil, &session)
+	if err != nil {
+		return "", errors.Wrapf(err, "error getting upload %v", uploadID)
+	}
+	size := partSize
+	if session.MaxPartSize > 0 && session.MaxPartSize < size {
+		size = session.MaxPartSize
+	}
+	received := make(map[int]storagesvc.UploadPart)
+	for _, part := range session.Parts {
+		received[part.Number] = part
 	}
 
 	f, err := os.Open(filePath)
 	if err != nil {
 		return "", err
 	}
+	defer f.Close()
+
+	var parts []storagesvc.UploadPart
+	for number, offset := 0, int64(0); offset < session.FileSize || number == 0; number, offset = number+1, offset+size {
+		length := size
+		if session.FileSize-offset < length {
+			length = session.FileSize - offset
+		}
+		section := io.NewSectionReader(f, offset, length)
+		hasher := sha256.New()
+		_, err = io.Copy(hasher, section)
+		if err != nil {
+			return "", err
+		}
+		part := storagesvc.UploadPart{
+			Number:   number,
+			Size:     length,
+			Checksum: hex.EncodeToString(hasher.Sum(nil)),
+		}
+		parts = append(parts, part)
+
+		if p, ok := received[number]; ok && p.Checksum == part.Checksum {
+			continue
+		}
+		err = c.uploadPart(ctx, uploadURL, part, section)
+		if err != nil {
+			return "", errors.Wrapf(err, "error uploading part %v of upload %v", number, uploadID)
+		}
+	}
+
+	var ur storagesvc.UploadResponse
+	_, err = c.doJSON(ctx, http.MethodPost, uploadURL+"/complete",
+		&storagesvc.CompleteUploadRequest{Parts: parts}, &ur)
+	if err != nil {
+		return "", errors.Wrapf(err, "error completing upload %v", uploadID)
+	}
+	return ur.ID, nil
+}
+
+// AbortUpload removes an upload and the parts received.
+func (c *Client) AbortUpload(ctx context.Context, uploadID string) error {
+	_, err := c.doJSON(ctx, http.MethodDelete, fmt.Sprintf("%v/archive/uploads/%v", c.url, url.PathEscape(uploadID)), nil, nil)
+	return err
+}
+
+func (c *Client) uploadPart(ctx context.Context, uploadURL string, part storagesvc.UploadPart, section *io.SectionReader) error {
+	var err error
+	for i := 0; i < maxAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(i*i) * 500 * time.Millisecond):
+			}
+		}
+
+		var req *http.Request
+		req, err = http.NewRequest(http.MethodPut, fmt.Sprintf("%v/parts/%v", uploadURL, part.Number),
+			io.NewSectionReader(section, 0, part.Size))
+		if err != nil {
+			return err
+		}
+		req.ContentLength = part.Size
+		req.Header.Set("X-Part-Checksum", part.Checksum)
+
+		var resp *http.Response
+		resp, err = ctxhttp.Do(ctx, c.httpClient, req)
+		if err != nil {
+			continue
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return nil
+		}
+		err = errors.Errorf("HTTP error %v: %v", resp.StatusCode, strings.TrimSpace(string(body)))
+		if resp.StatusCode == http.StatusNotFound {
+			return err
+		}
+	}
+	return err
+}
+
+// doJSON sends a request with a JSON body, and decodes the JSON response
+// into resp if it isn't nil. It returns the status code of the response.
+func (c *Client) doJSON(ctx context.Context, method string, url string, body interface{}, resp interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	r, err := ctxhttp.Do(ctx, c.httpClient, req)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Body.Close()
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return r.StatusCode, err
+	}
+	if r.StatusCode != http.StatusOK && r.StatusCode != http.StatusCreated {
+		return r.StatusCode, errors.Errorf("HTTP error %v: %v", r.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if resp != nil {
+		err = json.Unmarshal(data, resp)
+		if err != nil {
+			return r.StatusCode, err
+		}
+	}
+	return r.StatusCode, nil
+}
 
-	_, err = io.Copy(fileWriter, f)
+// uploadMultipart uploads the file in a single multipart request.
+func (c *Client) uploadMultipart(ctx context.Context, filePath string, fileSize int64) (string, error) {
+	f, err := os.Open(filePath)
 	if err != nil {
 		return "", err
 	}
+	defer f.Close()
 
-	contentType := bodyWriter.FormDataContentType()
-	bodyWriter.Close()
+	// stream the request body instead of buffering the file
+	reader, writer := io.Pipe()
+	bodyWriter := multipart.NewWriter(writer)
+	go func() {
+		fileWriter, err := bodyWriter.CreateFormFile("uploadfile", filePath)
+		if err == nil {
+			_, err = io.Copy(fileWriter, f)
+		}
+		if err == nil {
+			err = bodyWriter.Close()
+		}
+		writer.CloseWithError(err)
+	}()
 
-	req, err := http.NewRequest(http.MethodPost, c.url+"/archive", buf)
+	req, err := http.NewRequest(http.MethodPost, c.url+"/archive", reader)
 	if err != nil {
 		return "", err
 	}
 	req.Header["X-File-Size"] = []string{fmt.Sprintf("%v", fileSize)}
-	req.Header["Content-Type"] = []string{contentType}
+	req.Header["Content-Type"] = []string{bodyWriter.FormDataContentType()}
 
 	resp, err := ctxhttp.Do(ctx, c.httpClient, req)
 	if err != nil {
@@ -113,13 +290,50 @@ func (c *Client) Upload(ctx context.Context, filePath string, metadata *map[stri
 	return ur.ID, nil
 }
 
+// Exists returns the ID of the file with the given sha256 checksum if the
+// storage service has it, files being stored by content.
+func (c *Client) Exists(ctx context.Context, checksum string) (string, bool, error) {
+	var ur storagesvc.UploadResponse
+	status, err := c.doJSON(ctx, http.MethodGet, fmt.Sprintf("%v/archive/checksums/%v", c.url, url.PathEscape(checksum)), nil, &ur)
+	if status == http.StatusNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return ur.ID, true, nil
+}
+
+// ListOrphans lists the archives no package references, and what the
+// archive pruner does with them.
+func (c *Client) ListOrphans(ctx context.Context) (*storagesvc.OrphanReport, error) {
+	var report storagesvc.OrphanReport
+	_, err := c.doJSON(ctx, http.MethodGet, c.url+"/archive/orphans", nil, &report)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Rewrap rewraps the data keys of the encrypted archives with the primary
+// encryption key, returning the number of archives rewrapped.
+func (c *Client) Rewrap(ctx context.Context) (int, error) {
+	var resp storagesvc.RewrapResponse
+	_, err := c.doJSON(ctx, http.MethodPost, c.url+"/archive/rewrap", nil, &resp)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Rewrapped, nil
+}
+
 // GetUrl returns an HTTP URL that can be used to download the file pointed to by ID
 func (c *Client) GetUrl(id string) string {
 	return fmt.Sprintf("%v/archive?id=%v", c.url, url.PathEscape(id))
 }
 
 // Download fetches the file identified by ID to the local file path.
-// filePath must not exist.
+// filePath must not exist. Interrupted transfers are resumed with range
+// requests.
 func (c *Client) Download(ctx context.Context, id string, filePath string) error {
 	// url for id
 	url := c.GetUrl(id)
@@ -137,27 +351,75 @@ func (c *Client) Download(ctx context.Context, id string, filePath string) error
 	}
 	defer f.Close()
 
-	// make request
-	resp, err := ctxhttp.Get(ctx, c.httpClient, url)
+	for i := 0; i < maxAttempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				os.Remove(filePath)
+				return ctx.Err()
+			case <-time.After(time.Duration(i*i) * 500 * time.Millisecond):
+			}
+		}
+
+		var done bool
+		done, err = c.downloadFrom(ctx, url, f)
+		if done {
+			break
+		}
+	}
 	if err != nil {
-		fmt.Println(err)
 		os.Remove(filePath)
 		return err
 	}
+	return nil
+}
+
+// downloadFrom downloads the rest of the file to f, from its current
+// size. It returns whether to stop retrying.
+func (c *Client) downloadFrom(ctx context.Context, url string, f *os.File) (bool, error) {
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return true, err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return true, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%v-", offset))
+	}
+
+	// make request
+	resp, err := ctxhttp.Do(ctx, c.httpClient, req)
+	if err != nil {
+		return false, err
+	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		msg := fmt.Sprintf("HTTP error %v", resp.StatusCode)
-		os.Remove(filePath)
-		return errors.New(msg)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// the whole file, range requests are not supported
+		if offset > 0 {
+			err = f.Truncate(0)
+			if err == nil {
+				_, err = f.Seek(0, io.SeekStart)
+			}
+			if err != nil {
+				return true, err
+			}
+		}
+	case http.StatusPartialContent:
+	default:
+		return resp.StatusCode < 500, errors.Errorf("HTTP error %v", resp.StatusCode)
 	}
 
 	// download and write data
 	_, err = io.Copy(f, resp.Body)
 	if err != nil {
-		return err
+		return false, err
 	}
-
-	return nil
+	return true, nil
 }
 
 func (c *Client) Delete(ctx context.Context, id string) error {
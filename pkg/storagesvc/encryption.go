@@ -0,0 +1,441 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storagesvc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Archives are encrypted with envelope encryption: each archive is
+// encrypted with its own random data key, stored in the archive header
+// wrapped by a master key. Master keys are rotated by adding a key and
+// making it the primary key, archives encrypted with the previous keys
+// being rewrapped without encrypting them again.
+//
+// An encrypted archive is made of:
+//   - the header: encryptionMagic, the length and ID of the master key,
+//     and the length and value of the wrapped data key
+//   - the body: the archive split in chunks of encryptionChunkSize bytes,
+//     each sealed with AES-256-GCM, so that archives are encrypted as
+//     they're streamed and read from any chunk for range requests.
+
+const (
+	encryptionMagic     = "FSNENC01"
+	encryptionChunkSize = 64 * 1024
+
+	// primaryKeyFile names the file holding the ID of the primary key,
+	// in the master key directory.
+	primaryKeyFile = "primary"
+
+	dataKeySize = 32
+	tagSize     = 16
+)
+
+var (
+	keyIDRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,64}$`)
+
+	ErrNoEncryptionKeys = errors.New("archive is encrypted but no encryption keys are configured")
+)
+
+type (
+	// keyring holds the master keys, read from a directory such as a
+	// mounted secret: each file is a base64 encoded 32 bytes key named
+	// by its ID, and the primary file holds the ID of the key that
+	// wraps the data keys of new archives. Keys are read on use, so that
+	// rotated keys are used once the secret is updated.
+	keyring struct {
+		dir string
+	}
+
+	// encryptionHeader is the header of an encrypted archive.
+	encryptionHeader struct {
+		keyID      string
+		wrappedKey []byte
+	}
+)
+
+// makeKeyring returns the keyring of the master keys in dir, or nil if
+// dir is empty and archives aren't encrypted.
+func makeKeyring(dir string) *keyring {
+	if len(dir) == 0 {
+		return nil
+	}
+	return &keyring{dir: dir}
+}
+
+func (k *keyring) key(id string) ([]byte, error) {
+	if !keyIDRegexp.MatchString(id) || id == primaryKeyFile {
+		return nil, errors.Errorf("invalid encryption key ID %q", id)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(k.dir, id))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading encryption key %q", id)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error decoding encryption key %q", id)
+	}
+	if len(key) != dataKeySize {
+		return nil, errors.Errorf("encryption key %q must be %v bytes long", id, dataKeySize)
+	}
+	return key, nil
+}
+
+// primary returns the ID and value of the primary key.
+func (k *keyring) primary() (string, []byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(k.dir, primaryKeyFile))
+	if err != nil {
+		return "", nil, errors.Wrap(err, "error reading primary encryption key ID")
+	}
+	id := strings.TrimSpace(string(data))
+	key, err := k.key(id)
+	if err != nil {
+		return "", nil, err
+	}
+	return id, key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// wrapKey wraps a data key with the primary key, returning the header
+// of the archive encrypted with the data key.
+func (k *keyring) wrapKey(dataKey []byte) (*encryptionHeader, error) {
+	keyID, masterKey, err := k.primary()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptionHeader{
+		keyID:      keyID,
+		wrappedKey: aead.Seal(nonce, nonce, dataKey, []byte(encryptionMagic+keyID)),
+	}, nil
+}
+
+// unwrapKey returns the data key of an archive.
+func (k *keyring) unwrapKey(header *encryptionHeader) ([]byte, error) {
+	masterKey, err := k.key(header.keyID)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(header.wrappedKey) < aead.NonceSize() {
+		return nil, errors.New("invalid wrapped data key")
+	}
+	nonce, wrapped := header.wrappedKey[:aead.NonceSize()], header.wrappedKey[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, wrapped, []byte(encryptionMagic+header.keyID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error unwrapping data key with encryption key %q", header.keyID)
+	}
+	return dataKey, nil
+}
+
+func (h *encryptionHeader) bytes() []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString(encryptionMagic)
+	buf.WriteByte(byte(len(h.keyID)))
+	buf.WriteString(h.keyID)
+	wrappedLen := make([]byte, 2)
+	binary.BigEndian.PutUint16(wrappedLen, uint16(len(h.wrappedKey)))
+	buf.Write(wrappedLen)
+	buf.Write(h.wrappedKey)
+	return buf.Bytes()
+}
+
+// readEncryptionHeader reads the rest of the header of an encrypted
+// archive, after encryptionMagic.
+func readEncryptionHeader(r io.Reader) (*encryptionHeader, error) {
+	var keyIDLen [1]byte
+	_, err := io.ReadFull(r, keyIDLen[:])
+	if err != nil {
+		return nil, err
+	}
+	keyID := make([]byte, keyIDLen[0])
+	_, err = io.ReadFull(r, keyID)
+	if err != nil {
+		return nil, err
+	}
+	var wrappedLen uint16
+	err = binary.Read(r, binary.BigEndian, &wrappedLen)
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey := make([]byte, wrappedLen)
+	_, err = io.ReadFull(r, wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptionHeader{keyID: string(keyID), wrappedKey: wrappedKey}, nil
+}
+
+// encryptedSize returns the size of the body of an encrypted archive.
+func encryptedSize(size int64) int64 {
+	chunks := (size + encryptionChunkSize - 1) / encryptionChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return size + chunks*tagSize
+}
+
+// decryptedSize returns the size of an archive from the size of its
+// encrypted body.
+func decryptedSize(size int64) int64 {
+	chunks := (size + encryptionChunkSize + tagSize - 1) / (encryptionChunkSize + tagSize)
+	return size - chunks*tagSize
+}
+
+// chunkNonce returns the nonce of a chunk. Data keys are used for a single
+// archive, so nonces are the chunk index, with the last chunk flagged so
+// that truncated archives fail to decrypt.
+func chunkNonce(index int64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, uint64(index))
+	if last {
+		nonce[8] = 1
+	}
+	return nonce
+}
+
+// encryptingReader encrypts an archive as it's read.
+type encryptingReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	index   int64
+	pending []byte
+	done    bool
+}
+
+// encrypt returns a reader of the encrypted archive, with its header, and
+// the size of the encrypted archive.
+func (k *keyring) encrypt(r io.Reader, size int64) (io.Reader, int64, error) {
+	dataKey := make([]byte, dataKeySize)
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	header, err := k.wrapKey(dataKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	headerBytes := header.bytes()
+	reader := &encryptingReader{
+		src:  bufio.NewReader(r),
+		aead: aead,
+	}
+	return io.MultiReader(bytes.NewReader(headerBytes), reader), int64(len(headerBytes)) + encryptedSize(size), nil
+}
+
+func (r *encryptingReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		chunk := make([]byte, encryptionChunkSize)
+		n, err := io.ReadFull(r.src, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		last := n < encryptionChunkSize
+		if !last {
+			if _, err := r.src.Peek(1); err == io.EOF {
+				last = true
+			}
+		}
+		r.pending = r.aead.Seal(nil, chunkNonce(r.index, last), chunk[:n], nil)
+		r.index++
+		r.done = last
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// decryptingReader decrypts the body of an encrypted archive as it's read.
+type decryptingReader struct {
+	src     io.ReadCloser
+	buf     *bufio.Reader
+	aead    cipher.AEAD
+	index   int64
+	pending []byte
+	done    bool
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		chunk := make([]byte, encryptionChunkSize+tagSize)
+		n, err := io.ReadFull(r.buf, chunk)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		last := n < len(chunk)
+		if !last {
+			if _, err := r.buf.Peek(1); err == io.EOF {
+				last = true
+			}
+		}
+		plain, err := r.aead.Open(nil, chunkNonce(r.index, last), chunk[:n], nil)
+		if err != nil {
+			return 0, errors.Wrap(err, "error decrypting archive")
+		}
+		r.pending = plain
+		r.index++
+		r.done = last
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *decryptingReader) Close() error {
+	return r.src.Close()
+}
+
+// seekingDecryptingReader decrypts archives read from a seekable source,
+// seeking to the chunk of an offset.
+type seekingDecryptingReader struct {
+	*decryptingReader
+	seeker     io.Seeker
+	bodyOffset int64
+}
+
+// Seek only supports offsets relative to the start of the archive, as
+// used to serve range requests.
+func (r *seekingDecryptingReader) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekStart || offset < 0 {
+		return 0, errors.New("unsupported seek")
+	}
+	index := offset / encryptionChunkSize
+	_, err := r.seeker.Seek(r.bodyOffset+index*(encryptionChunkSize+tagSize), io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+	r.buf.Reset(r.src)
+	r.index = index
+	r.pending = nil
+	r.done = false
+
+	// skip the start of the chunk
+	_, err = io.CopyN(ioutil.Discard, r.decryptingReader, offset-index*encryptionChunkSize)
+	if err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+// decrypt returns a reader of the decrypted archive read from f, whose
+// header was read, and the size of the archive.
+func (k *keyring) decrypt(f io.ReadCloser, header *encryptionHeader, encryptedSize int64) (io.ReadCloser, int64, error) {
+	if k == nil {
+		return nil, 0, ErrNoEncryptionKeys
+	}
+	dataKey, err := k.unwrapKey(header)
+	if err != nil {
+		return nil, 0, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	headerSize := int64(len(header.bytes()))
+	size := decryptedSize(encryptedSize - headerSize)
+	reader := &decryptingReader{
+		src:  f,
+		buf:  bufio.NewReaderSize(f, encryptionChunkSize+tagSize),
+		aead: aead,
+	}
+	if seeker, ok := f.(io.Seeker); ok {
+		return &seekingDecryptingReader{
+			decryptingReader: reader,
+			seeker:           seeker,
+			bodyOffset:       headerSize,
+		}, size, nil
+	}
+	return reader, size, nil
+}
+
+// readHeader reads the header of an archive, returning nil if the archive
+// isn't encrypted, such as the archives stored before encryption was
+// enabled. The returned reader reads the rest of the archive.
+func readHeader(f io.ReadCloser) (*encryptionHeader, io.ReadCloser, error) {
+	magic := make([]byte, len(encryptionMagic))
+	n, err := io.ReadFull(f, magic)
+	if err == nil && string(magic) == encryptionMagic {
+		header, err := readEncryptionHeader(f)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "error reading archive encryption header")
+		}
+		return header, f, nil
+	}
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, nil, err
+	}
+
+	// not encrypted, read it from the start
+	if seeker, ok := f.(io.Seeker); ok {
+		_, err = seeker.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, f, nil
+	}
+	return nil, &readCloser{Reader: io.MultiReader(bytes.NewReader(magic[:n]), f), Closer: f}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storagesvc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestEncryption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storagesvc")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keysDir := filepath.Join(dir, "keys")
+	require.NoError(t, os.MkdirAll(keysDir, 0700))
+	addKey := func(id string) {
+		key := make([]byte, dataKeySize)
+		rand.Read(key)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(keysDir, id), []byte(base64.StdEncoding.EncodeToString(key)), 0600))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(keysDir, primaryKeyFile), []byte(id+"\n"), 0600))
+	}
+
+	stowClient, err := MakeStowClient(zap.NewNop(), NewLocalStorage(dir))
+	require.NoError(t, err)
+	putFile := func(contents []byte, checksum string) string {
+		id, err := stowClient.putFile(bytes.NewReader(contents), int64(len(contents)), checksum)
+		require.NoError(t, err)
+		return id
+	}
+	readFile := func(id string, offset int64) []byte {
+		f, size, err := stowClient.openFile(id)
+		require.NoError(t, err)
+		defer f.Close()
+		if offset > 0 {
+			_, err = f.(io.Seeker).Seek(offset, io.SeekStart)
+			require.NoError(t, err)
+		}
+		data, err := ioutil.ReadAll(f)
+		require.NoError(t, err)
+		require.Equal(t, size-offset, int64(len(data)))
+		return data
+	}
+	readHeaderKeyID := func(id string) string {
+		f, err := os.Open(id)
+		require.NoError(t, err)
+		defer f.Close()
+		header, _, err := readHeader(f)
+		require.NoError(t, err)
+		if header == nil {
+			return ""
+		}
+		return header.keyID
+	}
+
+	// archives stored before encryption is enabled
+	plain := []byte("plain")
+	plainID := putFile(plain, "plain")
+	require.Equal(t, plain, readFile(plainID, 0))
+
+	addKey("first")
+	stowClient.keyring = makeKeyring(keysDir)
+
+	for _, size := range []int{0, 1, encryptionChunkSize, 3*encryptionChunkSize + 7} {
+		contents := make([]byte, size)
+		rand.Read(contents)
+		id := putFile(contents, fmt.Sprintf("size-%v", size))
+		require.Equal(t, "first", readHeaderKeyID(id))
+		require.Equal(t, contents, readFile(id, 0))
+		if size > encryptionChunkSize {
+			require.Equal(t, contents[encryptionChunkSize+3:], readFile(id, encryptionChunkSize+3))
+		}
+	}
+	contents := make([]byte, 2*encryptionChunkSize+1)
+	rand.Read(contents)
+	id := putFile(contents, "contents")
+	require.Equal(t, plain, readFile(plainID, 0))
+
+	// truncated archives fail to decrypt
+	stored, err := ioutil.ReadFile(id)
+	require.NoError(t, err)
+	truncatedID := filepath.Join(filepath.Dir(id), "truncated")
+	require.NoError(t, ioutil.WriteFile(truncatedID, stored[:len(stored)-encryptionChunkSize/2], 0600))
+	f, _, err := stowClient.openFile(truncatedID)
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(f)
+	require.Error(t, err)
+	f.Close()
+	require.NoError(t, os.Remove(truncatedID))
+
+	// archives are decrypted with the previous keys once rotated, and
+	// rewrapped with the primary key
+	addKey("second")
+	require.Equal(t, contents, readFile(id, 0))
+	count, err := stowClient.rewrapFiles()
+	require.NoError(t, err)
+	require.Equal(t, 6, count)
+	require.Equal(t, "second", readHeaderKeyID(id))
+	require.Equal(t, "second", readHeaderKeyID(plainID))
+	rewrapped, err := filepath.Glob(filepath.Join(filepath.Dir(id), "*"+rewrapSuffix))
+	require.NoError(t, err)
+	require.Empty(t, rewrapped)
+	count, err = stowClient.rewrapFiles()
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+
+	require.NoError(t, os.Remove(filepath.Join(keysDir, "first")))
+	require.Equal(t, contents, readFile(id, 0))
+	require.Equal(t, plain, readFile(plainID, 0))
+
+	// encrypted archives can't be read without the keys
+	stowClient.keyring = nil
+	_, _, err = stowClient.openFile(id)
+	require.True(t, errors.Is(err, ErrNoEncryptionKeys))
+	w := httptest.NewRecorder()
+	MakeStorageService(zap.NewNop(), stowClient, 0).downloadHandler(w,
+		httptest.NewRequest(http.MethodGet, "/v1/archive?id="+url.QueryEscape(id), nil))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Contains(t, w.Body.String(), "error decrypting file")
+}
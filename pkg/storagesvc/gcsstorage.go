@@ -0,0 +1,96 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storagesvc
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/graymeta/stow"
+	"github.com/graymeta/stow/google"
+	"github.com/pkg/errors"
+)
+
+type (
+	gcsStorage struct {
+		storageType     StorageType
+		bucketName      string
+		subDir          string
+		projectID       string
+		credentialsFile string
+		// endpoint overrides the GCS endpoint, such as for emulators
+		endpoint string
+	}
+)
+
+// NewGCSStorage returns a new Google Cloud Storage struct. The service
+// account key is read from the file STORAGE_GCS_CREDENTIALS_FILE, usually
+// mounted from a secret, else the application default credentials, such
+// as workload identity, are used.
+func NewGCSStorage() Storage {
+	return gcsStorage{
+		storageType:     StorageTypeGCS,
+		bucketName:      os.Getenv("STORAGE_GCS_BUCKET_NAME"),
+		subDir:          os.Getenv("STORAGE_GCS_SUB_DIR"),
+		projectID:       os.Getenv("STORAGE_GCS_PROJECT_ID"),
+		credentialsFile: os.Getenv("STORAGE_GCS_CREDENTIALS_FILE"),
+		endpoint:        os.Getenv("STORAGE_GCS_ENDPOINT"),
+	}
+}
+
+func (gs gcsStorage) getStorageType() StorageType {
+	return gs.storageType
+}
+
+func (gs gcsStorage) getContainerName() string {
+	return gs.bucketName
+}
+
+func (gs gcsStorage) getFileName(checksum string) string {
+	return path.Join(gs.subDir, contentFileName(checksum))
+}
+
+func (gs gcsStorage) getItemID(containerID string, fileName string) string {
+	return fileName
+}
+
+func (gs gcsStorage) dial() (stow.Location, error) {
+	// empty credentials fall back to the application default credentials
+	var credentials []byte
+	if len(gs.credentialsFile) > 0 {
+		var err error
+		credentials, err = ioutil.ReadFile(gs.credentialsFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading GCS credentials file")
+		}
+	}
+	config := stow.ConfigMap{
+		google.ConfigJSON:      string(credentials),
+		google.ConfigProjectId: gs.projectID,
+	}
+	loc, err := stow.Dial(google.Kind, config)
+	if err != nil {
+		return nil, err
+	}
+	if len(gs.endpoint) > 0 {
+		// uploads go to /upload/storage/v1 on the same host
+		loc.(*google.Location).Service().BasePath = strings.TrimSuffix(gs.endpoint, "/") + "/storage/v1/"
+	}
+	return loc, nil
+}
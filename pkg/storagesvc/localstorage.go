@@ -2,10 +2,10 @@ package storagesvc
 
 import (
 	"os"
+	"path/filepath"
 
 	"github.com/graymeta/stow"
 	_ "github.com/graymeta/stow/local"
-	uuid "github.com/satori/go.uuid"
 )
 
 type localStorage struct {
@@ -32,10 +32,13 @@ func (ls localStorage) getStorageType() StorageType {
 	return ls.storageType
 }
 
-func (ls localStorage) getUploadFileName() string {
+func (ls localStorage) getFileName(checksum string) string {
 	// This is not the item ID (that's returned by Put)
-	// should we just use handler.Filename? what are the constraints here?
-	return uuid.NewV4().String()
+	return contentFileName(checksum)
+}
+
+func (ls localStorage) getItemID(containerID string, fileName string) string {
+	return filepath.Join(containerID, fileName)
 }
 
 func (ls localStorage) getContainerName() string {
@@ -0,0 +1,104 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storagesvc
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	apiv1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sTypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// pruneStateConfigMap is the ConfigMap, in the namespace of the
+	// storage service, the state of the archive pruner is kept in.
+	pruneStateConfigMap = "storagesvc-archive-pruner"
+	pruneStateKey       = "state"
+)
+
+type (
+	// pruneState is the state of the archive pruner surviving restarts.
+	pruneState struct {
+		Tombstones map[string]time.Time `json:"tombstones"`
+		History    map[string][]string  `json:"history"`
+	}
+
+	// pruneStateStore keeps the state of the archive pruner in a ConfigMap.
+	pruneStateStore struct {
+		kubeClient kubernetes.Interface
+		namespace  string
+	}
+)
+
+// load returns the state saved, or an empty state if none is.
+func (store *pruneStateStore) load(ctx context.Context) (*pruneState, error) {
+	state := &pruneState{
+		Tombstones: make(map[string]time.Time),
+		History:    make(map[string][]string),
+	}
+	cm, err := store.kubeClient.CoreV1().ConfigMaps(store.namespace).Get(ctx, pruneStateConfigMap, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "error getting archive pruner state")
+	}
+	if data, ok := cm.Data[pruneStateKey]; ok {
+		err = json.Unmarshal([]byte(data), state)
+		if err != nil {
+			return nil, errors.Wrap(err, "error parsing archive pruner state")
+		}
+	}
+	if state.Tombstones == nil {
+		state.Tombstones = make(map[string]time.Time)
+	}
+	if state.History == nil {
+		state.History = make(map[string][]string)
+	}
+	return state, nil
+}
+
+// save writes the state, creating the ConfigMap if it doesn't exist.
+func (store *pruneStateStore) save(ctx context.Context, state *pruneState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"data": map[string]string{pruneStateKey: string(data)},
+	})
+	if err != nil {
+		return err
+	}
+	configMaps := store.kubeClient.CoreV1().ConfigMaps(store.namespace)
+	_, err = configMaps.Patch(ctx, pruneStateConfigMap, k8sTypes.MergePatchType, patch, metav1.PatchOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pruneStateConfigMap,
+				Namespace: store.namespace,
+			},
+			Data: map[string]string{pruneStateKey: string(data)},
+		}, metav1.CreateOptions{})
+	}
+	return errors.Wrap(err, "error saving archive pruner state")
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/graymeta/stow"
 	"github.com/graymeta/stow/s3"
-	uuid "github.com/satori/go.uuid"
 )
 
 type (
@@ -49,9 +48,12 @@ func (ss s3Storage) getContainerName() string {
 	return ss.bucketName
 }
 
-func (ss s3Storage) getUploadFileName() string {
-	uploadName := uuid.NewV4().String()
-	return path.Join(ss.subDir, uploadName)
+func (ss s3Storage) getFileName(checksum string) string {
+	return path.Join(ss.subDir, contentFileName(checksum))
+}
+
+func (ss s3Storage) getItemID(containerID string, fileName string) string {
+	return fileName
 }
 
 func (ss s3Storage) dial() (stow.Location, error) {
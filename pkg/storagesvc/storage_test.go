@@ -1,9 +1,27 @@
 package storagesvc
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func TestNewS3Storage(t *testing.T) {
@@ -53,3 +71,148 @@ func TestNewLocalStorage(t *testing.T) {
 		t.Errorf("Incorrect storageType field. Got: %s, Want %s", storage.storageType, StorageTypeLocal)
 	}
 }
+
+func TestNewGCSStorage(t *testing.T) {
+	os.Setenv("STORAGE_GCS_BUCKET_NAME", "tmpBucket")
+	os.Setenv("STORAGE_GCS_SUB_DIR", "a/b/c")
+	os.Setenv("STORAGE_GCS_PROJECT_ID", "tmpProject")
+	os.Setenv("STORAGE_GCS_CREDENTIALS_FILE", "/nonexistent/key.json")
+
+	storage := NewGCSStorage().(gcsStorage)
+	if storage.bucketName != "tmpBucket" || storage.subDir != "a/b/c" || storage.projectID != "tmpProject" {
+		t.Errorf("Incorrect gcsStorage fields. Got: %+v", storage)
+	}
+	if storage.getStorageType() != StorageTypeGCS {
+		t.Errorf("Incorrect storageType field. Got: %s, Want %s", storage.getStorageType(), StorageTypeGCS)
+	}
+	if name := storage.getFileName("abc"); name != "a/b/c/sha256-abc" || storage.getItemID("tmpBucket", name) != name {
+		t.Errorf("Incorrect file name. Got: %s", name)
+	}
+
+	// credentials are read from the mounted secret
+	if _, err := storage.dial(); err == nil {
+		t.Errorf("Dialing GCS with a missing credentials file should fail")
+	}
+}
+
+func TestNewAzureStorage(t *testing.T) {
+	os.Setenv("STORAGE_AZURE_CONTAINER_NAME", "tmpContainer")
+	os.Setenv("STORAGE_AZURE_SUB_DIR", "a/b/c")
+	os.Setenv("STORAGE_AZURE_ACCOUNT_NAME", "tmpAccount")
+	os.Setenv("STORAGE_AZURE_ACCOUNT_KEY", "tmpKey")
+
+	storage := NewAzureStorage().(azureStorage)
+	if storage.containerName != "tmpContainer" || storage.subDir != "a/b/c" ||
+		storage.accountName != "tmpAccount" || storage.accountKey != "tmpKey" {
+		t.Errorf("Incorrect azureStorage fields. Got: %+v", storage)
+	}
+	if storage.getStorageType() != StorageTypeAzure {
+		t.Errorf("Incorrect storageType field. Got: %s, Want %s", storage.getStorageType(), StorageTypeAzure)
+	}
+	if name := storage.getFileName("abc"); name != "a/b/c/sha256-abc" || storage.getItemID("tmpContainer", name) != name {
+		t.Errorf("Incorrect file name. Got: %s", name)
+	}
+}
+
+// testStorageBackend stores, finds, reads and removes an archive on a
+// storage backend, in a container created for the test.
+func testStorageBackend(t *testing.T, storage Storage) {
+	loc, err := getStorageLocation(&storageConfig{storage: storage})
+	require.NoError(t, err)
+	_, err = loc.CreateContainer(storage.getContainerName())
+	require.NoError(t, err)
+	defer loc.RemoveContainer(storage.getContainerName())
+
+	client, err := MakeStowClient(zap.NewNop(), storage)
+	require.NoError(t, err)
+
+	contents := []byte("archive contents")
+	sum := sha256.Sum256(contents)
+	checksum := hex.EncodeToString(sum[:])
+	id, err := client.putFile(bytes.NewReader(contents), int64(len(contents)), checksum)
+	require.NoError(t, err)
+	found, err := client.findFile(checksum)
+	require.NoError(t, err)
+	require.Equal(t, id, found)
+
+	// archives already stored aren't written again
+	again, err := client.putFile(bytes.NewReader(contents), int64(len(contents)), checksum)
+	require.NoError(t, err)
+	require.Equal(t, id, again)
+
+	file, size, err := client.openFile(id)
+	require.NoError(t, err)
+	read, err := ioutil.ReadAll(file)
+	file.Close()
+	require.NoError(t, err)
+	require.Equal(t, contents, read)
+	require.Equal(t, int64(len(contents)), size)
+
+	items, err := client.getItems()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	require.NoError(t, client.removeFileByID(id))
+	_, err = client.findFile(checksum)
+	require.Equal(t, ErrNotFound, err)
+}
+
+// TestGCSStorageEmulator runs against fake-gcs-server, whose endpoint is
+// set in STORAGE_GCS_EMULATOR_ENDPOINT.
+func TestGCSStorageEmulator(t *testing.T) {
+	endpoint := os.Getenv("STORAGE_GCS_EMULATOR_ENDPOINT")
+	if len(endpoint) == 0 {
+		t.Skip("STORAGE_GCS_EMULATOR_ENDPOINT not set")
+	}
+
+	// the emulator ignores credentials, but the driver needs a service
+	// account key to get tokens from
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "test", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer tokenServer.Close()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	credentials, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "test",
+		"private_key_id": "test",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"client_email":   "test@test.iam.gserviceaccount.com",
+		"token_uri":      tokenServer.URL,
+	})
+	require.NoError(t, err)
+	dir, err := ioutil.TempDir("", "storagesvc")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	credentialsFile := filepath.Join(dir, "key.json")
+	require.NoError(t, ioutil.WriteFile(credentialsFile, credentials, 0600))
+
+	testStorageBackend(t, gcsStorage{
+		storageType:     StorageTypeGCS,
+		bucketName:      fmt.Sprintf("fission-test-%v", time.Now().UnixNano()),
+		subDir:          "archives",
+		projectID:       "test",
+		credentialsFile: credentialsFile,
+		endpoint:        endpoint,
+	})
+}
+
+// TestAzureStorageEmulator runs against Azurite, listening on the
+// address the Azure SDK connects the emulator account to.
+func TestAzureStorageEmulator(t *testing.T) {
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:10000", time.Second)
+	if err != nil {
+		t.Skip("Azurite not listening on 127.0.0.1:10000")
+	}
+	conn.Close()
+
+	testStorageBackend(t, azureStorage{
+		storageType:   StorageTypeAzure,
+		containerName: fmt.Sprintf("fission-test-%v", time.Now().UnixNano()),
+		subDir:        "archives",
+		accountName:   "devstoreaccount1",
+		accountKey:    "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==",
+	})
+}
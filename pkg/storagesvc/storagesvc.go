@@ -17,11 +17,17 @@ limitations under the License.
 package storagesvc
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -38,19 +44,31 @@ type (
 		dial() (stow.Location, error)
 		// getSubDir() string
 		getContainerName() string
-		getUploadFileName() string
+		// getFileName returns the name of the file with the given
+		// sha256 checksum, archives being stored by content
+		getFileName(checksum string) string
+		// getItemID returns the ID of the item of a file name
+		getItemID(containerID string, fileName string) string
 	}
 
 	// StorageService is a struct to hold all things for storage service
 	StorageService struct {
 		logger        *zap.Logger
 		storageClient *StowClient
+		uploads       *uploadStore
+		pruner        *ArchivePruner
 		port          int
 	}
 
 	UploadResponse struct {
 		ID string `json:"id"`
 	}
+
+	// RewrapResponse is the number of archives rewrapped with the primary
+	// encryption key.
+	RewrapResponse struct {
+		Rewrapped int `json:"rewrapped"`
+	}
 )
 
 // Functions handling storage interface
@@ -62,19 +80,30 @@ func getStorageLocation(config *storageConfig) (stow.Location, error) {
 	return config.storage.dial()
 }
 
-// Handle multipart file uploads.
+// Handle multipart file uploads. The file is streamed to the storage
+// rather than buffered, larger files should use chunked uploads.
 func (ss *StorageService) uploadHandler(w http.ResponseWriter, r *http.Request) {
 	// handle upload
-	err := r.ParseMultipartForm(0)
+	reader, err := r.MultipartReader()
 	if err != nil {
 		http.Error(w, "failed to parse request", http.StatusBadRequest)
-	}
-	file, handler, err := r.FormFile("uploadfile")
-	if err != nil {
-		http.Error(w, "missing upload file", http.StatusBadRequest)
 		return
 	}
+	var file *multipart.Part
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			http.Error(w, "missing upload file", http.StatusBadRequest)
+			return
+		}
+		if part.FormName() == "uploadfile" {
+			file = part
+			break
+		}
+		part.Close()
+	}
 	defer file.Close()
+	filename := file.FileName()
 
 	// stow wants the file size, but that's different from the
 	// content length, the content length being the size of the
@@ -84,7 +113,7 @@ func (ss *StorageService) uploadHandler(w http.ResponseWriter, r *http.Request)
 	fileSizeS, ok := r.Header["X-File-Size"]
 	if !ok {
 		ss.logger.Error("upload is missing the 'X-File-Size' header",
-			zap.String("filename", handler.Filename))
+			zap.String("filename", filename))
 		http.Error(w, "missing X-File-Size header", http.StatusBadRequest)
 		return
 	}
@@ -94,23 +123,51 @@ func (ss *StorageService) uploadHandler(w http.ResponseWriter, r *http.Request)
 		ss.logger.Error("error parsing 'X-File-Size' header",
 			zap.Error(err),
 			zap.Strings("header", fileSizeS),
-			zap.String("filename", handler.Filename))
+			zap.String("filename", filename))
 		http.Error(w, "missing or bad X-File-Size header", http.StatusBadRequest)
 		return
 	}
 
 	// TODO: allow headers to add more metadata (e.g. environment and function metadata)
 	ss.logger.Debug("handling upload",
-		zap.String("filename", handler.Filename))
+		zap.String("filename", filename))
 
-	id, err := ss.storageClient.putFile(file, int64(fileSize))
+	// files are stored by checksum, so the file is spooled to compute it
+	// before writing it to the storage
+	path, size, checksum, err := ss.uploads.spool(file)
+	if err != nil {
+		ss.logger.Error("error receiving uploaded file",
+			zap.Error(err),
+			zap.String("filename", filename))
+		http.Error(w, "Error receiving uploaded file", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(path)
+	if size != int64(fileSize) {
+		http.Error(w, fmt.Sprintf("file size %v doesn't match the X-File-Size header %v", size, fileSize), http.StatusBadRequest)
+		return
+	}
+	spooled, err := os.Open(path)
+	if err != nil {
+		ss.logger.Error("error opening uploaded file",
+			zap.Error(err),
+			zap.String("filename", filename))
+		http.Error(w, "Error saving uploaded file", http.StatusInternalServerError)
+		return
+	}
+	defer spooled.Close()
+
+	id, err := ss.storageClient.putFile(spooled, size, checksum)
 	if err != nil {
 		ss.logger.Error("error saving uploaded file",
 			zap.Error(err),
-			zap.String("filename", handler.Filename))
+			zap.String("filename", filename))
 		http.Error(w, "Error saving uploaded file", http.StatusInternalServerError)
 		return
 	}
+	if ss.pruner != nil {
+		ss.pruner.touch(id)
+	}
 
 	// respond with an ID that can be used to retrieve the file
 	ur := &UploadResponse{
@@ -120,7 +177,7 @@ func (ss *StorageService) uploadHandler(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 		ss.logger.Error("error marshaling uploaded file response",
 			zap.Error(err),
-			zap.String("filename", handler.Filename))
+			zap.String("filename", filename))
 		http.Error(w, "Error marshaling response", http.StatusInternalServerError)
 		return
 	}
@@ -129,7 +186,7 @@ func (ss *StorageService) uploadHandler(w http.ResponseWriter, r *http.Request)
 		ss.logger.Error(
 			"error writing HTTP response",
 			zap.Error(err),
-			zap.String("filename", handler.Filename),
+			zap.String("filename", filename),
 		)
 	}
 
@@ -144,6 +201,63 @@ func (ss *StorageService) getIdFromRequest(r *http.Request) (string, error) {
 	return ids[0], nil
 }
 
+// checksumHandler responds with the ID of the archive with a sha256
+// checksum, for clients to skip uploading archives already stored.
+func (ss *StorageService) checksumHandler(w http.ResponseWriter, r *http.Request) {
+	checksum := strings.ToLower(mux.Vars(r)["checksum"])
+	if _, err := hex.DecodeString(checksum); err != nil || len(checksum) != sha256.Size*2 {
+		http.Error(w, "invalid sha256 checksum", http.StatusBadRequest)
+		return
+	}
+
+	id, err := ss.storageClient.findFile(checksum)
+	if err == ErrNotFound {
+		http.Error(w, "archive not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		ss.logger.Error("error looking up archive", zap.Error(err), zap.String("checksum", checksum))
+		http.Error(w, "Error retrieving item", http.StatusInternalServerError)
+		return
+	}
+	// the client is about to reference the archive again
+	if ss.pruner != nil {
+		ss.pruner.touch(id)
+	}
+	writeJSON(w, ss.logger, &UploadResponse{ID: id})
+}
+
+// orphansHandler lists the archives no package references, and what the
+// archive pruner does with them.
+func (ss *StorageService) orphansHandler(w http.ResponseWriter, r *http.Request) {
+	if ss.pruner == nil {
+		http.Error(w, "archive pruner is disabled", http.StatusNotFound)
+		return
+	}
+	report, err := ss.pruner.Report()
+	if err != nil {
+		ss.logger.Error("error listing orphan archives", zap.Error(err))
+		http.Error(w, "Error listing orphan archives", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, ss.logger, report)
+}
+
+// rewrapHandler rewraps the data keys of the archives with the primary
+// encryption key, encrypting the archives stored unencrypted.
+func (ss *StorageService) rewrapHandler(w http.ResponseWriter, r *http.Request) {
+	if ss.storageClient.keyring == nil {
+		http.Error(w, "archive encryption is disabled", http.StatusNotFound)
+		return
+	}
+	count, err := ss.storageClient.rewrapFiles()
+	if err != nil {
+		ss.logger.Error("error rewrapping archives", zap.Error(err), zap.Int("rewrapped", count))
+		http.Error(w, "Error rewrapping archives", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, ss.logger, RewrapResponse{Rewrapped: count})
+}
+
 func (ss *StorageService) deleteHandler(w http.ResponseWriter, r *http.Request) {
 	// get id from request
 	fileId, err := ss.getIdFromRequest(r)
@@ -152,6 +266,21 @@ func (ss *StorageService) deleteHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// archives are shared by the packages with the same content, so
+	// they're deleted only once no package references them
+	if ss.pruner != nil {
+		refs, err := ss.pruner.getReferenceCounts()
+		if err != nil {
+			ss.logger.Error("error getting archive references", zap.Error(err))
+			http.Error(w, "Error getting archive references", http.StatusInternalServerError)
+			return
+		}
+		if refs[fileId] > 0 {
+			http.Error(w, fmt.Sprintf("archive is referenced by %v packages", refs[fileId]), http.StatusConflict)
+			return
+		}
+	}
+
 	err = ss.storageClient.removeFileByID(fileId)
 	if err != nil {
 		msg := fmt.Sprintf("Error deleting item: %v", err)
@@ -161,6 +290,51 @@ func (ss *StorageService) deleteHandler(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 }
 
+// parseRange parses a single byte range of a Range header for a file of
+// the given size, returning the first and last byte of the range. Ranges
+// other than a single byte range are ignored, and the whole file is sent.
+func parseRange(header string, size int64) (start int64, end int64, ok bool, err error) {
+	if !strings.HasPrefix(header, "bytes=") {
+		return 0, 0, false, nil
+	}
+	spec := strings.TrimPrefix(header, "bytes=")
+	if strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+	s := strings.SplitN(strings.TrimSpace(spec), "-", 2)
+	if len(s) != 2 {
+		return 0, 0, false, errors.Errorf("invalid range %q", header)
+	}
+
+	if len(s[0]) == 0 {
+		// suffix range, the last n bytes of the file
+		n, err := strconv.ParseInt(s[1], 10, 64)
+		if err != nil || n <= 0 || size == 0 {
+			return 0, 0, false, errors.Errorf("invalid range %q", header)
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true, nil
+	}
+
+	start, err = strconv.ParseInt(s[0], 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false, errors.Errorf("invalid range %q", header)
+	}
+	end = size - 1
+	if len(s[1]) > 0 {
+		end, err = strconv.ParseInt(s[1], 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, errors.Errorf("invalid range %q", header)
+		}
+		if end > size-1 {
+			end = size - 1
+		}
+	}
+	return start, end, true, nil
+}
+
 func (ss *StorageService) downloadHandler(w http.ResponseWriter, r *http.Request) {
 	// get id from request
 	fileId, err := ss.getIdFromRequest(r)
@@ -171,7 +345,7 @@ func (ss *StorageService) downloadHandler(w http.ResponseWriter, r *http.Request
 
 	// Get the file (called "item" in stow's jargon), open it,
 	// stream it to response
-	err = ss.storageClient.copyFileToStream(fileId, w)
+	f, size, err := ss.storageClient.openFile(fileId)
 	if err != nil {
 		ss.logger.Error("error getting file from storage client", zap.Error(err), zap.String("file_id", fileId))
 		if err == ErrNotFound {
@@ -180,11 +354,50 @@ func (ss *StorageService) downloadHandler(w http.ResponseWriter, r *http.Request
 			http.Error(w, "Error retrieving item", http.StatusBadRequest)
 		} else if err == ErrOpeningItem {
 			http.Error(w, "Error opening item", http.StatusBadRequest)
-		} else if err == ErrWritingFileIntoResponse {
-			http.Error(w, "Error writing response", http.StatusInternalServerError)
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
+	defer f.Close()
+
+	// Range requests let clients resume interrupted downloads
+	w.Header().Set("Accept-Ranges", "bytes")
+	start, end, partial, err := parseRange(r.Header.Get("Range"), size)
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%v", size))
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	var reader io.Reader = f
+	status := http.StatusOK
+	length := size
+	if partial {
+		if seeker, ok := f.(io.Seeker); ok {
+			_, err = seeker.Seek(start, io.SeekStart)
+		} else {
+			_, err = io.CopyN(ioutil.Discard, f, start)
+		}
+		if err != nil {
+			ss.logger.Error("error seeking file", zap.Error(err), zap.String("file_id", fileId))
+			http.Error(w, "Error opening item", http.StatusInternalServerError)
+			return
+		}
+		length = end - start + 1
+		reader = io.LimitReader(f, length)
+		status = http.StatusPartialContent
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %v-%v/%v", start, end, size))
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+	w.WriteHeader(status)
+
+	_, err = io.Copy(w, reader)
+	if err != nil {
+		ss.logger.Error("error writing file into response", zap.Error(err), zap.String("file_id", fileId))
+		return
+	}
+	ss.logger.Debug("successfully wrote file into httpresponse", zap.String("file", fileId))
 }
 
 func (ss *StorageService) healthHandler(w http.ResponseWriter, r *http.Request) {
@@ -195,21 +408,41 @@ func MakeStorageService(logger *zap.Logger, storageClient *StowClient, port int)
 	return &StorageService{
 		logger:        logger.Named("storage_service"),
 		storageClient: storageClient,
+		uploads:       makeUploadStore(logger, os.Getenv("UPLOAD_STAGING_DIR")),
 		port:          port,
 	}
 }
 
-func (ss *StorageService) Start(port int) {
+// Router returns the handler of the storage service API.
+func (ss *StorageService) Router() http.Handler {
 	r := mux.NewRouter()
 	r.HandleFunc("/v1/archive", ss.uploadHandler).Methods("POST")
 	r.HandleFunc("/v1/archive", ss.downloadHandler).Methods("GET")
 	r.HandleFunc("/v1/archive", ss.deleteHandler).Methods("DELETE")
+	r.HandleFunc("/v1/archive/checksums/{checksum}", ss.checksumHandler).Methods("GET", "HEAD")
+	r.HandleFunc("/v1/archive/orphans", ss.orphansHandler).Methods("GET")
+	r.HandleFunc("/v1/archive/rewrap", ss.rewrapHandler).Methods("POST")
+	r.HandleFunc("/v1/archive/uploads", ss.initiateUploadHandler).Methods("POST")
+	r.HandleFunc("/v1/archive/uploads/{upload}", ss.getUploadHandler).Methods("GET")
+	r.HandleFunc("/v1/archive/uploads/{upload}", ss.abortUploadHandler).Methods("DELETE")
+	r.HandleFunc("/v1/archive/uploads/{upload}/parts/{part}", ss.uploadPartHandler).Methods("PUT")
+	r.HandleFunc("/v1/archive/uploads/{upload}/complete", ss.completeUploadHandler).Methods("POST")
 	r.HandleFunc("/healthz", ss.healthHandler).Methods("GET")
+	return r
+}
+
+func (ss *StorageService) Start(port int) {
+	go func() {
+		for {
+			ss.uploads.prune()
+			time.Sleep(time.Hour)
+		}
+	}()
 
 	address := fmt.Sprintf(":%v", port)
 
 	err := http.ListenAndServe(address, &ochttp.Handler{
-		Handler: r,
+		Handler: ss.Router(),
 	})
 
 	ss.logger.Fatal("done listening", zap.Error(err))
@@ -226,7 +459,6 @@ func Start(logger *zap.Logger, storage Storage, port int) error {
 
 	// create http handlers
 	storageService := MakeStorageService(logger, storageClient, port)
-	go storageService.Start(port)
 
 	// enablePruner prevents storagesvc unit test from needing to talk to kubernetes
 	if enablePruner {
@@ -235,13 +467,16 @@ func Start(logger *zap.Logger, storage Storage, port int) error {
 		if err != nil {
 			pruneInterval = defaultPruneInterval
 		}
-		pruner, err := MakeArchivePruner(logger, storageClient, time.Duration(pruneInterval))
+		pruner, err := MakeArchivePruner(logger, storageClient, time.Duration(pruneInterval), RetentionPolicyFromEnv())
 		if err != nil {
 			return errors.Wrap(err, "Error creating archivePruner")
 		}
+		storageService.pruner = pruner
 		go pruner.Start()
 	}
 
+	go storageService.Start(port)
+
 	logger.Info("storage service started")
 	return nil
 }
@@ -17,11 +17,10 @@ limitations under the License.
 package storagesvc
 
 import (
+	"bytes"
 	"io"
-	"mime/multipart"
 	"os"
 	"strings"
-	"time"
 
 	"github.com/graymeta/stow"
 	"github.com/pkg/errors"
@@ -42,6 +41,7 @@ type (
 		config    *storageConfig
 		location  stow.Location
 		container stow.Container
+		keyring   *keyring
 	}
 )
 
@@ -50,8 +50,16 @@ const (
 	StorageTypeLocal StorageType = "local"
 	// StorageTypeS3 is a constant to hold S3 storage type name literal
 	StorageTypeS3 StorageType = "s3"
+	// StorageTypeGCS is a constant to hold Google Cloud Storage type name literal
+	StorageTypeGCS StorageType = "gcs"
+	// StorageTypeAzure is a constant to hold Azure Blob storage type name literal
+	StorageTypeAzure StorageType = "azure"
 	// PaginationSize is a constant to hold no of pages
 	PaginationSize int = 10
+
+	// rewrapSuffix is the suffix of the names rewrapped files are stored
+	// under before they replace the files
+	rewrapSuffix = ".rewrap"
 )
 
 var (
@@ -64,9 +72,10 @@ var (
 
 // MakeStowClient create a new StowClient for given storage
 func MakeStowClient(logger *zap.Logger, storage Storage) (*StowClient, error) {
-	storageType := getStorageType(storage)
-	if strings.Compare(storageType, "local") == 1 && strings.Compare(storageType, "s3") == 1 {
-		return nil, errors.New("Storage types other than 'local' and 's3' are not implemented")
+	switch storage.getStorageType() {
+	case StorageTypeLocal, StorageTypeS3, StorageTypeGCS, StorageTypeAzure:
+	default:
+		return nil, errors.Errorf("Storage type %q is not implemented", getStorageType(storage))
 	}
 
 	config := &storageConfig{
@@ -74,8 +83,9 @@ func MakeStowClient(logger *zap.Logger, storage Storage) (*StowClient, error) {
 	}
 
 	stowClient := &StowClient{
-		logger: logger.Named("stow_client"),
-		config: config,
+		logger:  logger.Named("stow_client"),
+		config:  config,
+		keyring: makeKeyring(os.Getenv("ENCRYPTION_KEYS_DIR")),
 	}
 
 	loc, err := getStorageLocation(config)
@@ -84,6 +94,18 @@ func MakeStowClient(logger *zap.Logger, storage Storage) (*StowClient, error) {
 	}
 	stowClient.location = loc
 
+	// GCS buckets and Azure containers must exist, so that they're created
+	// with the access policies required, Azure containers created by stow
+	// allowing public read access
+	if t := storage.getStorageType(); t == StorageTypeGCS || t == StorageTypeAzure {
+		con, err := loc.Container(config.storage.getContainerName())
+		if err != nil {
+			return nil, errors.Wrapf(err, "error getting storage container %q", config.storage.getContainerName())
+		}
+		stowClient.container = con
+		return stowClient, nil
+	}
+
 	con, err := loc.CreateContainer(config.storage.getContainerName())
 	if err != nil && (os.IsExist(err) || strings.Contains(err.Error(), "BucketAlreadyOwnedByYou")) {
 		var cons []stow.Container
@@ -109,9 +131,34 @@ func MakeStowClient(logger *zap.Logger, storage Storage) (*StowClient, error) {
 	return stowClient, nil
 }
 
-// putFile writes the file on the storage
-func (client *StowClient) putFile(file multipart.File, fileSize int64) (string, error) {
-	uploadName := client.config.storage.getUploadFileName()
+// contentFileName returns the name of the file with the given sha256
+// checksum.
+func contentFileName(checksum string) string {
+	return "sha256-" + checksum
+}
+
+// putFile writes the file with the given sha256 checksum on the storage.
+// Files are stored by content, so a file already stored isn't written
+// again and its ID is returned. Files are encrypted if encryption keys
+// are configured.
+func (client *StowClient) putFile(file io.Reader, fileSize int64, checksum string) (string, error) {
+	id, err := client.findFile(checksum)
+	if err == nil {
+		client.logger.Debug("file exists on storage", zap.String("file", id))
+		return id, nil
+	}
+
+	uploadName := client.config.storage.getFileName(checksum)
+
+	if client.keyring != nil {
+		file, fileSize, err = client.keyring.encrypt(file, fileSize)
+		if err != nil {
+			client.logger.Error("error encrypting file",
+				zap.Error(err),
+				zap.String("file", uploadName))
+			return "", ErrWritingFile
+		}
+	}
 
 	// save the file to the storage backend
 	item, err := client.container.Put(uploadName, file, fileSize, nil)
@@ -126,30 +173,185 @@ func (client *StowClient) putFile(file multipart.File, fileSize int64) (string,
 	return item.ID(), nil
 }
 
-// copyFileToStream gets the file contents into a stream
-func (client *StowClient) copyFileToStream(fileId string, w io.Writer) error {
+// findFile returns the ID of the file with the given sha256 checksum, or
+// ErrNotFound if it isn't stored.
+func (client *StowClient) findFile(checksum string) (string, error) {
+	storage := client.config.storage
+	id := storage.getItemID(client.container.ID(), storage.getFileName(checksum))
+	_, err := client.container.Item(id)
+	if err != nil {
+		if err == stow.ErrNotFound {
+			return "", ErrNotFound
+		}
+		return "", ErrRetrievingItem
+	}
+	return id, nil
+}
+
+// openFile opens the file for reading, returning its size. Encrypted
+// files are decrypted as they're read.
+func (client *StowClient) openFile(fileId string) (io.ReadCloser, int64, error) {
 	item, err := client.container.Item(fileId)
 	if err != nil {
 		if err == stow.ErrNotFound {
-			return ErrNotFound
+			return nil, 0, ErrNotFound
 		} else {
-			return ErrRetrievingItem
+			return nil, 0, ErrRetrievingItem
 		}
 	}
 
+	size, err := item.Size()
+	if err != nil {
+		return nil, 0, ErrRetrievingItem
+	}
+
 	f, err := item.Open()
 	if err != nil {
-		return ErrOpeningItem
+		return nil, 0, ErrOpeningItem
+	}
+
+	header, f, err := readHeader(f)
+	if err != nil {
+		client.logger.Error("error reading file", zap.Error(err), zap.String("file_id", fileId))
+		return nil, 0, ErrOpeningItem
+	}
+	if header == nil {
+		return f, size, nil
+	}
+
+	reader, size, err := client.keyring.decrypt(f, header, size)
+	if err != nil {
+		f.Close()
+		client.logger.Error("error decrypting file", zap.Error(err), zap.String("file_id", fileId))
+		return nil, 0, errors.Wrap(err, "error decrypting file")
+	}
+	return reader, size, nil
+}
+
+// rewrapFile encrypts the data key of an encrypted file with the primary
+// key, and encrypts files stored unencrypted, returning whether the file
+// was written.
+func (client *StowClient) rewrapFile(item stow.Item) (bool, error) {
+	keyID, _, err := client.keyring.primary()
+	if err != nil {
+		return false, err
+	}
+
+	size, err := item.Size()
+	if err != nil {
+		return false, err
+	}
+	f, err := item.Open()
+	if err != nil {
+		return false, err
 	}
 	defer f.Close()
 
-	_, err = io.Copy(w, f)
+	header, f, err := readHeader(f)
 	if err != nil {
-		return ErrWritingFileIntoResponse
+		return false, err
 	}
 
-	client.logger.Debug("successfully wrote file into httpresponse", zap.String("file", fileId))
-	return nil
+	var reader io.Reader
+	var newSize int64
+	if header == nil {
+		reader, newSize, err = client.keyring.encrypt(f, size)
+		if err != nil {
+			return false, err
+		}
+	} else {
+		if header.keyID == keyID {
+			return false, nil
+		}
+		dataKey, err := client.keyring.unwrapKey(header)
+		if err != nil {
+			return false, err
+		}
+		newHeader, err := client.keyring.wrapKey(dataKey)
+		if err != nil {
+			return false, err
+		}
+		// the body is encrypted with the data key, and left as is
+		newHeaderBytes := newHeader.bytes()
+		reader = io.MultiReader(bytes.NewReader(newHeaderBytes), f)
+		newSize = size - int64(len(header.bytes())) + int64(len(newHeaderBytes))
+	}
+
+	// the rewrapped file is stored under a name of its own first, and only
+	// then swapped with the file, so that the file stays readable while
+	// it's replaced and neither is lost on a crash
+	tmpItem, err := client.container.Put(item.Name()+rewrapSuffix, reader, newSize, nil)
+	if err != nil {
+		return false, errors.Wrap(err, "error writing rewrapped file")
+	}
+	f.Close()
+
+	err = client.swapFile(item, tmpItem, newSize)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// swapFile replaces a file with the file of a temporary item, which is
+// removed. Local files are renamed, atomically; object stores replace
+// objects atomically as they're written, so the temporary item is copied
+// over the file before being removed.
+func (client *StowClient) swapFile(item stow.Item, tmpItem stow.Item, size int64) error {
+	if client.config.storage.getStorageType() == StorageTypeLocal {
+		tmp, err := os.OpenFile(tmpItem.ID(), os.O_RDWR, 0)
+		if err != nil {
+			return err
+		}
+		err = tmp.Sync()
+		tmp.Close()
+		if err != nil {
+			return errors.Wrap(err, "error syncing rewrapped file")
+		}
+		return errors.Wrap(os.Rename(tmpItem.ID(), item.ID()), "error replacing file")
+	}
+
+	tmp, err := tmpItem.Open()
+	if err != nil {
+		return errors.Wrap(err, "error reading rewrapped file")
+	}
+	defer tmp.Close()
+	_, err = client.container.Put(item.Name(), tmp, size, nil)
+	if err != nil {
+		return errors.Wrap(err, "error replacing file")
+	}
+	return errors.Wrap(client.container.RemoveItem(tmpItem.ID()), "error removing rewrapped file")
+}
+
+// rewrapFiles rewraps the data keys of all files with the primary key,
+// encrypting files stored unencrypted, and returns the number of files
+// written.
+func (client *StowClient) rewrapFiles() (int, error) {
+	if client.keyring == nil {
+		return 0, errors.New("no encryption keys are configured")
+	}
+
+	items, err := client.getItems()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, item := range items {
+		// files left behind by an interrupted rewrap are pruned as orphans
+		if strings.HasSuffix(item.Name(), rewrapSuffix) {
+			continue
+		}
+		written, err := client.rewrapFile(item)
+		if err != nil {
+			return count, errors.Wrapf(err, "error rewrapping file %q", item.ID())
+		}
+		if written {
+			client.logger.Info("rewrapped file", zap.String("file", item.ID()))
+			count++
+		}
+	}
+	return count, nil
 }
 
 // removeFileByID deletes the file from storage
@@ -157,49 +359,22 @@ func (client *StowClient) removeFileByID(itemID string) error {
 	return client.container.RemoveItem(itemID)
 }
 
-// filter defines an interface to filter out items from a set of items
-type filter func(stow.Item, interface{}) bool
-
-// This method returns all items in a container, filtering out items based on the filter function passed to it
-func (client *StowClient) getItemIDsWithFilter(filterFunc filter, filterFuncParam interface{}) ([]string, error) {
+// getItems returns all items in the container
+func (client *StowClient) getItems() ([]stow.Item, error) {
 	cursor := stow.CursorStart
 	var items []stow.Item
-	var err error
-
-	archiveIDList := make([]string, 0)
 
 	for {
-		items, cursor, err = client.container.Items(stow.NoPrefix, cursor, PaginationSize)
+		page, next, err := client.container.Items(stow.NoPrefix, cursor, PaginationSize)
 		if err != nil {
 			return nil, errors.Wrap(err, "error getting items from container")
 		}
-
-		for _, item := range items {
-			isItemFilterable := filterFunc(item, filterFuncParam)
-			if isItemFilterable {
-				continue
-			}
-			archiveIDList = append(archiveIDList, item.ID())
-		}
-
-		if stow.IsCursorEnd(cursor) {
+		items = append(items, page...)
+		if stow.IsCursorEnd(next) {
 			break
 		}
+		cursor = next
 	}
 
-	return archiveIDList, nil
-}
-
-// filterItemCreatedAMinuteAgo is one type of filter function that filters out items created less than a minute ago.
-// More filter functions can be written if needed, as long as they are of type filter
-func (client StowClient) filterItemCreatedAMinuteAgo(item stow.Item, currentTime interface{}) bool {
-	itemLastModTime, _ := item.LastMod()
-	if currentTime.(time.Time).Sub(itemLastModTime) < 1*time.Minute {
-
-		client.logger.Debug("item created less than a minute ago",
-			zap.String("item", item.ID()),
-			zap.Time("last_modified_time", itemLastModTime))
-		return true
-	}
-	return false
+	return items, nil
 }
@@ -0,0 +1,490 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storagesvc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	uuid "github.com/satori/go.uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxPartSize is the maximum size of a part of a chunked upload.
+	MaxPartSize int64 = 64 * 1024 * 1024
+
+	// uploadTTL is the time after which chunked uploads not completed
+	// nor aborted are removed.
+	uploadTTL = 24 * time.Hour
+
+	sessionFile = "session.json"
+)
+
+type (
+	// UploadSession is a chunked upload in progress. Parts are uploaded
+	// with their checksums, in any order, and retried independently,
+	// so that interrupted uploads resume where they stopped.
+	UploadSession struct {
+		ID          string       `json:"id"`
+		FileSize    int64        `json:"fileSize"`
+		MaxPartSize int64        `json:"maxPartSize"`
+		Parts       []UploadPart `json:"parts,omitempty"`
+	}
+
+	// UploadPart is a part of a chunked upload, with the hex encoded
+	// sha256 checksum of its content.
+	UploadPart struct {
+		Number   int    `json:"number"`
+		Size     int64  `json:"size"`
+		Checksum string `json:"checksum"`
+	}
+
+	// InitiateUploadRequest starts a chunked upload of a file.
+	InitiateUploadRequest struct {
+		FileSize int64 `json:"fileSize"`
+	}
+
+	// CompleteUploadRequest completes a chunked upload with the parts
+	// making up the file, in order.
+	CompleteUploadRequest struct {
+		Parts []UploadPart `json:"parts"`
+	}
+
+	// uploadStore keeps the parts of chunked uploads on local disk until
+	// they're completed and written to the storage in one piece. Files
+	// uploaded at once are spooled there too, to get their checksum.
+	uploadStore struct {
+		logger *zap.Logger
+		dir    string
+	}
+)
+
+func makeUploadStore(logger *zap.Logger, dir string) *uploadStore {
+	if len(dir) == 0 {
+		dir = filepath.Join(os.TempDir(), "fission-uploads")
+	}
+	return &uploadStore{
+		logger: logger.Named("upload_store"),
+		dir:    dir,
+	}
+}
+
+// sessionDir returns the directory of an upload, checking that the ID
+// can't escape the upload directory.
+func (store *uploadStore) sessionDir(id string) (string, error) {
+	_, err := uuid.FromString(id)
+	if err != nil {
+		return "", errors.Errorf("invalid upload ID %q", id)
+	}
+	return filepath.Join(store.dir, id), nil
+}
+
+func (store *uploadStore) create(fileSize int64) (*UploadSession, error) {
+	session := &UploadSession{
+		ID:          uuid.NewV4().String(),
+		FileSize:    fileSize,
+		MaxPartSize: MaxPartSize,
+	}
+	dir := filepath.Join(store.dir, session.ID)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating upload directory")
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, sessionFile), data, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "error writing upload session")
+	}
+	return session, nil
+}
+
+// get returns an upload with the parts received so far. Part files are
+// named <number>-<checksum>.
+func (store *uploadStore) get(id string) (*UploadSession, error) {
+	dir, err := store.sessionDir(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, sessionFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, errors.Wrap(err, "error reading upload session")
+	}
+	session := &UploadSession{}
+	err = json.Unmarshal(data, session)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing upload session")
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing upload parts")
+	}
+	for _, fi := range files {
+		s := strings.SplitN(fi.Name(), "-", 2)
+		if len(s) != 2 || strings.HasSuffix(fi.Name(), ".tmp") {
+			continue
+		}
+		number, err := strconv.Atoi(s[0])
+		if err != nil {
+			continue
+		}
+		session.Parts = append(session.Parts, UploadPart{
+			Number:   number,
+			Size:     fi.Size(),
+			Checksum: s[1],
+		})
+	}
+	sort.Slice(session.Parts, func(i, j int) bool {
+		return session.Parts[i].Number < session.Parts[j].Number
+	})
+	return session, nil
+}
+
+// putPart writes a part, replacing any previous upload of the part. The
+// part is kept only if its content matches the checksum.
+func (store *uploadStore) putPart(id string, number int, checksum string, r io.Reader) (*UploadPart, error) {
+	dir, err := store.sessionDir(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, sessionFile)); err != nil {
+		return nil, ErrNotFound
+	}
+
+	tmpFile, err := ioutil.TempFile(dir, fmt.Sprintf("%v-*.tmp", number))
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating part file")
+	}
+	defer os.Remove(tmpFile.Name())
+
+	hasher := sha256.New()
+	size, err := io.Copy(tmpFile, io.TeeReader(io.LimitReader(r, MaxPartSize+1), hasher))
+	closeErr := tmpFile.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "error writing part file")
+	}
+	if closeErr != nil {
+		return nil, errors.Wrap(closeErr, "error writing part file")
+	}
+	if size > MaxPartSize {
+		return nil, errors.Errorf("part is larger than the maximum part size of %v bytes", MaxPartSize)
+	}
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if sum != checksum {
+		return nil, errors.Errorf("part checksum %v doesn't match the checksum of its content %v", checksum, sum)
+	}
+
+	old, _ := filepath.Glob(filepath.Join(dir, fmt.Sprintf("%v-*", number)))
+	for _, path := range old {
+		if path != tmpFile.Name() {
+			os.Remove(path) //nolint: errCheck
+		}
+	}
+	err = os.Rename(tmpFile.Name(), filepath.Join(dir, fmt.Sprintf("%v-%v", number, sum)))
+	if err != nil {
+		return nil, errors.Wrap(err, "error writing part file")
+	}
+	return &UploadPart{Number: number, Size: size, Checksum: sum}, nil
+}
+
+// open returns a reader of the file made of the given parts, checking
+// that they were all received and that they make up the whole file.
+func (store *uploadStore) open(id string, parts []UploadPart) (io.ReadCloser, int64, error) {
+	session, err := store.get(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	received := make(map[int]UploadPart)
+	for _, part := range session.Parts {
+		received[part.Number] = part
+	}
+
+	var size int64
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for _, part := range parts {
+		p, ok := received[part.Number]
+		if !ok || p.Checksum != part.Checksum {
+			closeAll()
+			return nil, 0, errors.Errorf("part %v with checksum %v was not uploaded", part.Number, part.Checksum)
+		}
+		f, err := os.Open(filepath.Join(store.dir, id, fmt.Sprintf("%v-%v", p.Number, p.Checksum)))
+		if err != nil {
+			closeAll()
+			return nil, 0, errors.Wrap(err, "error opening part file")
+		}
+		files = append(files, f)
+		size += p.Size
+	}
+	if size != session.FileSize {
+		closeAll()
+		return nil, 0, errors.Errorf("size of the parts %v doesn't match the file size %v", size, session.FileSize)
+	}
+
+	readers := make([]io.Reader, len(files))
+	for i, f := range files {
+		readers[i] = f
+	}
+	return &multiFileReader{Reader: io.MultiReader(readers...), files: files}, size, nil
+}
+
+// checksum returns the sha256 checksum of the file made of the given
+// parts of an upload.
+func (store *uploadStore) checksum(id string, parts []UploadPart) (string, error) {
+	file, _, err := store.open(id, parts)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, file)
+	if err != nil {
+		return "", errors.Wrap(err, "error reading part files")
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// spool writes a file to the upload directory, returning its path, size
+// and sha256 checksum. The caller removes the file.
+func (store *uploadStore) spool(r io.Reader) (string, int64, string, error) {
+	err := os.MkdirAll(store.dir, 0700)
+	if err != nil {
+		return "", 0, "", errors.Wrap(err, "error creating upload directory")
+	}
+	f, err := ioutil.TempFile(store.dir, "spool-*.tmp")
+	if err != nil {
+		return "", 0, "", errors.Wrap(err, "error creating upload file")
+	}
+	hasher := sha256.New()
+	size, err := io.Copy(f, io.TeeReader(r, hasher))
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", 0, "", errors.Wrap(err, "error writing upload file")
+	}
+	return f.Name(), size, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func (store *uploadStore) remove(id string) error {
+	dir, err := store.sessionDir(id)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// prune removes the uploads not updated for uploadTTL, and the spooled
+// files left behind for as long, such as on a crash.
+func (store *uploadStore) prune() {
+	dirs, err := ioutil.ReadDir(store.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			store.logger.Error("error listing uploads", zap.Error(err))
+		}
+		return
+	}
+	for _, fi := range dirs {
+		if time.Since(fi.ModTime()) < uploadTTL {
+			continue
+		}
+		if !fi.IsDir() {
+			if matched, _ := filepath.Match("spool-*.tmp", fi.Name()); !matched {
+				continue
+			}
+			store.logger.Info("removing expired spooled file", zap.String("file", fi.Name()))
+			err = os.Remove(filepath.Join(store.dir, fi.Name()))
+			if err != nil && !os.IsNotExist(err) {
+				store.logger.Error("error removing expired spooled file", zap.Error(err), zap.String("file", fi.Name()))
+			}
+			continue
+		}
+		store.logger.Info("removing expired upload", zap.String("upload_id", fi.Name()))
+		err = os.RemoveAll(filepath.Join(store.dir, fi.Name()))
+		if err != nil {
+			store.logger.Error("error removing expired upload", zap.Error(err), zap.String("upload_id", fi.Name()))
+		}
+	}
+}
+
+// multiFileReader reads the parts of an upload in order.
+type multiFileReader struct {
+	io.Reader
+	files []*os.File
+}
+
+func (r *multiFileReader) Close() error {
+	for _, f := range r.files {
+		f.Close()
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, logger *zap.Logger, obj interface{}) {
+	resp, err := json.Marshal(obj)
+	if err != nil {
+		logger.Error("error marshaling response", zap.Error(err))
+		http.Error(w, "Error marshaling response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err = w.Write(resp)
+	if err != nil {
+		logger.Error("error writing HTTP response", zap.Error(err))
+	}
+}
+
+func uploadErrorStatus(err error) int {
+	if err == ErrNotFound {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+// initiateUploadHandler starts a chunked upload.
+func (ss *StorageService) initiateUploadHandler(w http.ResponseWriter, r *http.Request) {
+	var req InitiateUploadRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.FileSize < 0 {
+		http.Error(w, "failed to parse request", http.StatusBadRequest)
+		return
+	}
+
+	session, err := ss.uploads.create(req.FileSize)
+	if err != nil {
+		ss.logger.Error("error creating upload", zap.Error(err))
+		http.Error(w, "Error creating upload", http.StatusInternalServerError)
+		return
+	}
+	ss.logger.Debug("initiated upload", zap.String("upload_id", session.ID), zap.Int64("file_size", req.FileSize))
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, ss.logger, session)
+}
+
+// getUploadHandler returns an upload with the parts received so far,
+// for clients to resume it.
+func (ss *StorageService) getUploadHandler(w http.ResponseWriter, r *http.Request) {
+	session, err := ss.uploads.get(mux.Vars(r)["upload"])
+	if err != nil {
+		http.Error(w, err.Error(), uploadErrorStatus(err))
+		return
+	}
+	writeJSON(w, ss.logger, session)
+}
+
+// uploadPartHandler receives a part of a chunked upload, whose sha256
+// checksum is given by the X-Part-Checksum header.
+func (ss *StorageService) uploadPartHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["upload"]
+	number, err := strconv.Atoi(mux.Vars(r)["part"])
+	if err != nil || number < 0 {
+		http.Error(w, "invalid part number", http.StatusBadRequest)
+		return
+	}
+	checksum := strings.ToLower(r.Header.Get("X-Part-Checksum"))
+	if len(checksum) == 0 {
+		http.Error(w, "missing X-Part-Checksum header", http.StatusBadRequest)
+		return
+	}
+
+	part, err := ss.uploads.putPart(id, number, checksum, r.Body)
+	if err != nil {
+		ss.logger.Error("error receiving upload part", zap.Error(err), zap.String("upload_id", id), zap.Int("part", number))
+		http.Error(w, err.Error(), uploadErrorStatus(err))
+		return
+	}
+	writeJSON(w, ss.logger, part)
+}
+
+// completeUploadHandler writes the file made of the parts of a chunked
+// upload to the storage, and responds with its ID like uploadHandler.
+func (ss *StorageService) completeUploadHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["upload"]
+	var req CompleteUploadRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "failed to parse request", http.StatusBadRequest)
+		return
+	}
+
+	checksum, err := ss.uploads.checksum(id, req.Parts)
+	if err != nil {
+		ss.logger.Error("error completing upload", zap.Error(err), zap.String("upload_id", id))
+		http.Error(w, err.Error(), uploadErrorStatus(err))
+		return
+	}
+	file, size, err := ss.uploads.open(id, req.Parts)
+	if err != nil {
+		ss.logger.Error("error completing upload", zap.Error(err), zap.String("upload_id", id))
+		http.Error(w, err.Error(), uploadErrorStatus(err))
+		return
+	}
+	defer file.Close()
+
+	fileID, err := ss.storageClient.putFile(file, size, checksum)
+	if err != nil {
+		ss.logger.Error("error saving uploaded file", zap.Error(err), zap.String("upload_id", id))
+		http.Error(w, "Error saving uploaded file", http.StatusInternalServerError)
+		return
+	}
+
+	if ss.pruner != nil {
+		ss.pruner.touch(fileID)
+	}
+
+	err = ss.uploads.remove(id)
+	if err != nil {
+		ss.logger.Error("error removing completed upload", zap.Error(err), zap.String("upload_id", id))
+	}
+	writeJSON(w, ss.logger, &UploadResponse{ID: fileID})
+}
+
+// abortUploadHandler removes a chunked upload and its parts.
+func (ss *StorageService) abortUploadHandler(w http.ResponseWriter, r *http.Request) {
+	err := ss.uploads.remove(mux.Vars(r)["upload"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storagesvc_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/fission/fission/pkg/storagesvc"
+	"github.com/fission/fission/pkg/storagesvc/client"
+)
+
+func TestChunkedUploadRangeDownload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storagesvc")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "storage"), 0700))
+	os.Setenv("UPLOAD_STAGING_DIR", filepath.Join(dir, "uploads"))
+	defer os.Unsetenv("UPLOAD_STAGING_DIR")
+
+	logger := zap.NewNop()
+	stowClient, err := storagesvc.MakeStowClient(logger, storagesvc.NewLocalStorage(filepath.Join(dir, "storage")))
+	require.NoError(t, err)
+	server := httptest.NewServer(storagesvc.MakeStorageService(logger, stowClient, 0).Router())
+	defer server.Close()
+
+	// a file of several parts, the last one partial
+	contents := make([]byte, 20*1024*1024+123)
+	rand.New(rand.NewSource(1)).Read(contents)
+	file := filepath.Join(dir, "archive.zip")
+	require.NoError(t, ioutil.WriteFile(file, contents, 0600))
+
+	ctx := context.Background()
+	ssClient := client.MakeClient(server.URL)
+	id, err := ssClient.Upload(ctx, file, nil)
+	require.NoError(t, err)
+
+	// archives are stored by content
+	checksum := sha256.Sum256(contents)
+	existing, ok, err := ssClient.Exists(ctx, hex.EncodeToString(checksum[:]))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, id, existing)
+	_, ok, err = ssClient.Exists(ctx, strings.Repeat("0", 64))
+	require.NoError(t, err)
+	require.False(t, ok)
+	again, err := ssClient.Upload(ctx, file, nil)
+	require.NoError(t, err)
+	require.Equal(t, id, again)
+
+	downloaded := filepath.Join(dir, "downloaded.zip")
+	require.NoError(t, ssClient.Download(ctx, id, downloaded))
+	data, err := ioutil.ReadFile(downloaded)
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(contents, data))
+
+	for _, test := range []struct {
+		header  string
+		status  int
+		content []byte
+	}{
+		{"bytes=10-19", http.StatusPartialContent, contents[10:20]},
+		{fmt.Sprintf("bytes=%v-", len(contents)-5), http.StatusPartialContent, contents[len(contents)-5:]},
+		{"bytes=-5", http.StatusPartialContent, contents[len(contents)-5:]},
+		{fmt.Sprintf("bytes=%v-", len(contents)), http.StatusRequestedRangeNotSatisfiable, nil},
+	} {
+		req, err := http.NewRequest(http.MethodGet, ssClient.GetUrl(id), nil)
+		require.NoError(t, err)
+		req.Header.Set("Range", test.header)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, test.status, resp.StatusCode, test.header)
+		if test.content != nil {
+			require.Equal(t, test.content, body, test.header)
+		}
+	}
+
+	// parts with a wrong checksum are refused
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/v1/archive/uploads", bytes.NewReader([]byte(`{"fileSize": 4}`)))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	entries, err := ioutil.ReadDir(filepath.Join(dir, "uploads"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	req, err = http.NewRequest(http.MethodPut, fmt.Sprintf("%v/v1/archive/uploads/%v/parts/0", server.URL, entries[0].Name()),
+		bytes.NewReader([]byte("data")))
+	require.NoError(t, err)
+	req.Header.Set("X-Part-Checksum", "0000")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -29,25 +29,3 @@ func getQueryParamValue(urlString string, queryParam string) (string, error) {
 	}
 	return url.Query().Get(queryParam), nil
 }
-
-func getDifferenceOfLists(firstList []string, secondList []string) []string {
-	tempMap := make(map[string]int)
-	differenceList := make([]string, 0)
-
-	for _, item := range firstList {
-		tempMap[item] = 1
-	}
-
-	for _, item := range secondList {
-		_, ok := tempMap[item]
-		if ok {
-			delete(tempMap, item)
-		}
-	}
-
-	for k := range tempMap {
-		differenceList = append(differenceList, k)
-	}
-
-	return differenceList
-}
@@ -17,6 +17,9 @@ limitations under the License.
 package timer
 
 import (
+	// time triggers may use any time zone, the image doesn't ship a zoneinfo database
+	_ "time/tzdata"
+
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 
@@ -35,8 +38,16 @@ func Start(logger *zap.Logger, routerUrl string) error {
 		return errors.Wrap(err, "error waiting for CRDs")
 	}
 
-	poster := publisher.MakeWebhookPublisher(logger, routerUrl)
-	MakeTimerSync(logger, fissionClient, MakeTimer(logger, poster))
+	publisherConfig, err := publisher.WebhookPublisherConfigFromEnv()
+	if err != nil {
+		return errors.Wrap(err, "error reading publisher config")
+	}
+
+	poster, err := publisher.MakeWebhookPublisher(logger, routerUrl, publisherConfig)
+	if err != nil {
+		return errors.Wrap(err, "error creating webhook publisher")
+	}
+	MakeTimerSync(logger, fissionClient, MakeTimer(logger, fissionClient, poster))
 
 	return nil
 }
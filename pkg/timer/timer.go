@@ -17,8 +17,16 @@ limitations under the License.
 package timer
 
 import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
 	"github.com/robfig/cron"
 	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
 
 	fv1 "github.com/fission/fission/pkg/apis/core/v1"
 	"github.com/fission/fission/pkg/crd"
@@ -32,9 +40,19 @@ const (
 	SYNC requestType = iota
 )
 
+const (
+	// cloudEventType is the CloudEvents type of events delivered by time triggers
+	cloudEventType = "io.fission.timer.fired"
+
+	// maxCatchUpRuns is the maximum number of missed runs fired for a trigger
+	// with the All catch-up policy, the latest ones are kept.
+	maxCatchUpRuns = 100
+)
+
 type (
 	Timer struct {
 		logger         *zap.Logger
+		fissionClient  *crd.FissionClient
 		triggers       map[string]*timerTriggerWithCron
 		requestChannel chan *timerRequest
 		publisher      *publisher.Publisher
@@ -49,14 +67,21 @@ type (
 		error
 	}
 	timerTriggerWithCron struct {
+		// lock guards the fields below, which are read by the cron goroutines
+		lock    sync.Mutex
 		trigger fv1.TimeTrigger
 		cron    *cron.Cron
+		stopped bool
+		// cancel functions of the runs in flight, by run number
+		running map[uint64]context.CancelFunc
+		runs    uint64
 	}
 )
 
-func MakeTimer(logger *zap.Logger, publisher publisher.Publisher) *Timer {
+func MakeTimer(logger *zap.Logger, fissionClient *crd.FissionClient, publisher publisher.Publisher) *Timer {
 	timer := &Timer{
 		logger:         logger.Named("timer"),
+		fissionClient:  fissionClient,
 		triggers:       make(map[string]*timerTriggerWithCron),
 		requestChannel: make(chan *timerRequest),
 		publisher:      &publisher,
@@ -93,31 +118,42 @@ func (timer *Timer) syncCron(triggers []fv1.TimeTrigger) error {
 	for _, t := range triggers {
 		triggerMap[crd.CacheKey(&t.ObjectMeta)] = true
 		if item, ok := timer.triggers[crd.CacheKey(&t.ObjectMeta)]; ok {
-			// update cron if the cron spec changed
-			if item.trigger.Spec.Cron != t.Spec.Cron {
+			item.lock.Lock()
+			// update cron if the schedule changed
+			if item.trigger.Spec.Cron != t.Spec.Cron || item.trigger.Spec.Timezone != t.Spec.Timezone {
 				// if there is an cron running, stop it
 				if item.cron != nil {
 					item.cron.Stop()
 				}
-				item.cron = timer.newCron(t)
+				item.cron = timer.newCron(t, item)
 			}
 
 			item.trigger = t
+			item.lock.Unlock()
 		} else {
-			timer.triggers[crd.CacheKey(&t.ObjectMeta)] = &timerTriggerWithCron{
+			item := &timerTriggerWithCron{
 				trigger: t,
-				cron:    timer.newCron(t),
+				running: make(map[uint64]context.CancelFunc),
 			}
+			item.cron = timer.newCron(t, item)
+			timer.triggers[crd.CacheKey(&t.ObjectMeta)] = item
+			timer.catchUp(t, item)
 		}
 	}
 
 	// process removed triggers
 	for k, v := range timer.triggers {
 		if _, found := triggerMap[k]; !found {
+			v.lock.Lock()
 			if v.cron != nil {
 				v.cron.Stop()
 				timer.logger.Info("cron for time trigger stopped", zap.String("trigger", v.trigger.ObjectMeta.Name))
 			}
+			v.stopped = true
+			for _, cancel := range v.running {
+				cancel()
+			}
+			v.lock.Unlock()
 			delete(timer.triggers, k)
 		}
 	}
@@ -125,19 +161,216 @@ func (timer *Timer) syncCron(triggers []fv1.TimeTrigger) error {
 	return nil
 }
 
-func (timer *Timer) newCron(t fv1.TimeTrigger) *cron.Cron {
-	c := cron.New()
-	c.AddFunc(t.Spec.Cron, func() { //nolint: errCheck
-		headers := map[string]string{
-			"X-Fission-Timer-Name": t.ObjectMeta.Name,
-		}
+func (timer *Timer) newCron(t fv1.TimeTrigger, item *timerTriggerWithCron) *cron.Cron {
+	location, err := triggerLocation(t)
+	if err != nil {
+		timer.logger.Error("error loading time zone of time trigger, using local time zone", zap.Error(err),
+			zap.String("trigger", t.ObjectMeta.Name), zap.String("timezone", t.Spec.Timezone))
+	}
 
-		// with the addition of multi-tenancy, the users can create functions in any namespace. however,
-		// the triggers can only be created in the same namespace as the function.
-		// so essentially, function namespace = trigger namespace.
-		(*timer.publisher).Publish("", headers, utils.UrlForFunction(t.Spec.FunctionReference.Name, t.ObjectMeta.Namespace))
+	c := cron.NewWithLocation(location)
+	c.AddFunc(t.Spec.Cron, func() { //nolint: errCheck
+		timer.run(item, time.Now())
 	})
 	c.Start()
 	timer.logger.Info("added new cron for time trigger", zap.String("trigger", t.ObjectMeta.Name))
 	return c
 }
+
+// catchUp fires the runs of a newly added trigger that were missed while
+// the timer was down, according to its starting deadline and catch-up policy.
+func (timer *Timer) catchUp(t fv1.TimeTrigger, item *timerTriggerWithCron) {
+	missed := missedRuns(t, time.Now())
+	if len(missed) == 0 {
+		return
+	}
+	timer.logger.Info("catching up on missed runs of time trigger",
+		zap.String("trigger", t.ObjectMeta.Name), zap.String("namespace", t.ObjectMeta.Namespace),
+		zap.Int("runs", len(missed)), zap.Time("first", missed[0]))
+	// missed runs are invoked oldest first, one at a time, without jitter
+	// and regardless of the concurrency policy, which would otherwise skip
+	// or cancel all but one of them
+	go func() {
+		for _, scheduledAt := range missed {
+			done := timer.invoke(item, scheduledAt, false)
+			if done == nil {
+				return
+			}
+			<-done
+		}
+	}()
+}
+
+// missedRuns returns the schedule times between the last schedule time of
+// the trigger and now that are within its starting deadline, oldest first.
+func missedRuns(t fv1.TimeTrigger, now time.Time) []time.Time {
+	if t.Spec.StartingDeadlineSeconds == nil {
+		return nil
+	}
+
+	sched, err := cron.Parse(t.Spec.Cron)
+	if err != nil {
+		return nil
+	}
+	location, _ := triggerLocation(t)
+
+	// a trigger that never ran may have missed runs since it was created
+	since := t.ObjectMeta.CreationTimestamp.Time
+	if t.Status.LastScheduleTime != nil {
+		since = t.Status.LastScheduleTime.Time
+	}
+	deadline := now.Add(-time.Duration(*t.Spec.StartingDeadlineSeconds) * time.Second)
+	if since.Before(deadline) {
+		since = deadline
+	}
+
+	var missed []time.Time
+	for next := sched.Next(since.In(location)); !next.IsZero() && !next.After(now); next = sched.Next(next) {
+		missed = append(missed, next)
+		if len(missed) > maxCatchUpRuns {
+			missed = missed[1:]
+		}
+	}
+
+	if t.Spec.CatchUpPolicy != fv1.CatchUpAll && len(missed) > 1 {
+		missed = missed[len(missed)-1:]
+	}
+	return missed
+}
+
+// triggerLocation returns the time zone the schedule of the trigger is
+// evaluated in, falling back to the local time zone if it can't be loaded.
+func triggerLocation(t fv1.TimeTrigger) (*time.Location, error) {
+	if len(t.Spec.Timezone) == 0 {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(t.Spec.Timezone)
+	if err != nil {
+		return time.Local, err
+	}
+	return loc, nil
+}
+
+// run invokes the function of the trigger for a run scheduled at the given
+// time, after the jitter of the trigger. It doesn't wait for the jitter, so
+// that the runs fired together don't delay each other.
+func (timer *Timer) run(item *timerTriggerWithCron, scheduledAt time.Time) {
+	item.lock.Lock()
+	jitter := item.trigger.Spec.JitterSeconds
+	item.lock.Unlock()
+
+	if jitter > 0 {
+		delay := time.Duration(rand.Int63n(int64(jitter) * int64(time.Second)))
+		time.AfterFunc(delay, func() {
+			timer.fire(item, scheduledAt)
+		})
+		return
+	}
+	timer.fire(item, scheduledAt)
+}
+
+// fire invokes the function of the trigger for a run scheduled at the given
+// time, applying the concurrency policy of the trigger.
+func (timer *Timer) fire(item *timerTriggerWithCron, scheduledAt time.Time) {
+	timer.invoke(item, scheduledAt, true)
+}
+
+// invoke invokes the function of the trigger for a run scheduled at the
+// given time, applying the concurrency policy of the trigger if asked to.
+// It returns a channel closed once the run finishes, or nil if the run was
+// skipped.
+func (timer *Timer) invoke(item *timerTriggerWithCron, scheduledAt time.Time, applyPolicy bool) <-chan struct{} {
+	item.lock.Lock()
+	t := item.trigger
+	if item.stopped {
+		item.lock.Unlock()
+		return nil
+	}
+	if applyPolicy {
+		switch t.Spec.ConcurrencyPolicy {
+		case fv1.ForbidConcurrent:
+			if len(item.running) > 0 {
+				item.lock.Unlock()
+				timer.logger.Info("skipping run of time trigger, previous run is still running",
+					zap.String("trigger", t.ObjectMeta.Name), zap.String("namespace", t.ObjectMeta.Namespace))
+				return nil
+			}
+		case fv1.ReplaceConcurrent:
+			for _, cancel := range item.running {
+				cancel()
+			}
+		}
+	}
+	finished := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	item.runs++
+	runID := item.runs
+	item.running[runID] = cancel
+	item.lock.Unlock()
+
+	timer.updateStatus(t, func(status *fv1.TimeTriggerStatus) {
+		// catch-up runs may be older than the recorded schedule time
+		if status.LastScheduleTime == nil || status.LastScheduleTime.Time.Before(scheduledAt) {
+			status.LastScheduleTime = &metav1.Time{Time: scheduledAt}
+		}
+	})
+
+	headers := map[string]string{
+		"X-Fission-Timer-Name": t.ObjectMeta.Name,
+	}
+	if len(t.Spec.ContentType) > 0 {
+		headers["Content-Type"] = t.Spec.ContentType
+	}
+
+	// with the addition of multi-tenancy, the users can create functions in any namespace. however,
+	// the triggers can only be created in the same namespace as the function.
+	// so essentially, function namespace = trigger namespace.
+	event := publisher.NewCloudEvent(publisher.TriggerSource("timetriggers", t.ObjectMeta.Namespace, t.ObjectMeta.Name), cloudEventType, "")
+	event.Time = scheduledAt
+	(*timer.publisher).PublishEventTracked(ctx, event, t.Spec.Body, headers,
+		utils.UrlForFunction(t.Spec.FunctionReference.Name, t.ObjectMeta.Namespace), func(err error) {
+			item.lock.Lock()
+			delete(item.running, runID)
+			item.lock.Unlock()
+			cancel()
+			defer close(finished)
+
+			// don't hold up the delivery of other requests to the function
+			finishedAt := metav1.Now()
+			switch {
+			case err == nil:
+				go timer.updateStatus(t, func(status *fv1.TimeTriggerStatus) {
+					status.LastSuccessfulTime = &finishedAt
+				})
+			case errors.Is(err, context.Canceled):
+				// replaced by a newer run or the trigger was removed
+			default:
+				go timer.updateStatus(t, func(status *fv1.TimeTriggerStatus) {
+					status.LastErrorTime = &finishedAt
+					status.LastError = err.Error()
+				})
+			}
+		})
+	return finished
+}
+
+// updateStatus applies the given change to the status of the trigger.
+func (timer *Timer) updateStatus(t fv1.TimeTrigger, update func(status *fv1.TimeTriggerStatus)) {
+	if timer.fissionClient == nil {
+		return
+	}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		client := timer.fissionClient.CoreV1().TimeTriggers(t.ObjectMeta.Namespace)
+		trigger, err := client.Get(context.TODO(), t.ObjectMeta.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		update(&trigger.Status)
+		_, err = client.UpdateStatus(context.TODO(), trigger, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		timer.logger.Error("error updating time trigger status", zap.Error(err),
+			zap.String("trigger", t.ObjectMeta.Name), zap.String("namespace", t.ObjectMeta.Namespace))
+	}
+}
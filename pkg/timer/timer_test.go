@@ -0,0 +1,228 @@
+/*
+Copyright 2021 The Fission Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timer
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	fv1 "github.com/fission/fission/pkg/apis/core/v1"
+	"github.com/fission/fission/pkg/publisher"
+)
+
+// blockingPublisher keeps tracked requests in flight until they are
+// cancelled or released.
+type blockingPublisher struct {
+	lock      sync.Mutex
+	bodies    []string
+	headers   []map[string]string
+	times     []time.Time
+	cancelled int
+	release   chan struct{}
+}
+
+func (p *blockingPublisher) Publish(body string, headers map[string]string, target string) {}
+
+func (p *blockingPublisher) PublishEvent(event publisher.CloudEvent, body string, headers map[string]string, target string) {
+}
+
+func (p *blockingPublisher) PublishEventTracked(ctx context.Context, event publisher.CloudEvent, body string, headers map[string]string, target string, done func(error)) {
+	p.lock.Lock()
+	p.bodies = append(p.bodies, body)
+	p.headers = append(p.headers, headers)
+	p.times = append(p.times, event.Time)
+	p.lock.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.lock.Lock()
+			p.cancelled++
+			p.lock.Unlock()
+			done(ctx.Err())
+		case <-p.release:
+			done(nil)
+		}
+	}()
+}
+
+func (p *blockingPublisher) counts() (int, int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.bodies), p.cancelled
+}
+
+func newTestItem(policy fv1.ConcurrencyPolicy) *timerTriggerWithCron {
+	return &timerTriggerWithCron{
+		trigger: fv1.TimeTrigger{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+			Spec: fv1.TimeTriggerSpec{
+				Cron:              "@every 1h",
+				FunctionReference: fv1.FunctionReference{Type: fv1.FunctionReferenceTypeFunctionName, Name: "bar"},
+				Body:              `{"hello":"world"}`,
+				ContentType:       "application/json",
+				ConcurrencyPolicy: policy,
+			},
+		},
+		running: make(map[uint64]context.CancelFunc),
+	}
+}
+
+func TestTimerConcurrencyPolicy(t *testing.T) {
+	for _, test := range []struct {
+		policy    fv1.ConcurrencyPolicy
+		published int
+		cancelled int
+	}{
+		{policy: fv1.AllowConcurrent, published: 2, cancelled: 0},
+		{policy: fv1.ForbidConcurrent, published: 1, cancelled: 0},
+		{policy: fv1.ReplaceConcurrent, published: 2, cancelled: 1},
+	} {
+		t.Run(string(test.policy), func(t *testing.T) {
+			p := &blockingPublisher{release: make(chan struct{})}
+			defer close(p.release)
+			timer := MakeTimer(zap.NewNop(), nil, p)
+			item := newTestItem(test.policy)
+
+			timer.run(item, time.Now())
+			timer.run(item, time.Now())
+
+			require.Eventually(t, func() bool {
+				published, cancelled := p.counts()
+				return published == test.published && cancelled == test.cancelled
+			}, 5*time.Second, 10*time.Millisecond)
+			require.Equal(t, `{"hello":"world"}`, p.bodies[0])
+			require.Equal(t, "application/json", p.headers[0]["Content-Type"])
+		})
+	}
+}
+
+func TestCatchUpConcurrencyPolicy(t *testing.T) {
+	for _, policy := range []fv1.ConcurrencyPolicy{fv1.ForbidConcurrent, fv1.ReplaceConcurrent} {
+		t.Run(string(policy), func(t *testing.T) {
+			p := &blockingPublisher{release: make(chan struct{})}
+			defer close(p.release)
+			timer := MakeTimer(zap.NewNop(), nil, p)
+			item := newTestItem(policy)
+			deadline := int64(4 * 3600)
+			lastSchedule := metav1.NewTime(time.Now().Truncate(time.Hour).Add(-3 * time.Hour))
+			item.trigger.Spec.Cron = "@hourly"
+			item.trigger.Spec.JitterSeconds = 3600
+			item.trigger.Spec.StartingDeadlineSeconds = &deadline
+			item.trigger.Spec.CatchUpPolicy = fv1.CatchUpAll
+			item.trigger.Status.LastScheduleTime = &lastSchedule
+
+			timer.catchUp(item.trigger, item)
+
+			// each missed run is only invoked once the previous one finished
+			for i := 1; i <= 3; i++ {
+				require.Eventually(t, func() bool {
+					published, _ := p.counts()
+					return published == i
+				}, 5*time.Second, 10*time.Millisecond)
+				require.Never(t, func() bool {
+					published, _ := p.counts()
+					return published > i
+				}, 100*time.Millisecond, 10*time.Millisecond)
+				p.release <- struct{}{}
+			}
+
+			published, cancelled := p.counts()
+			require.Equal(t, 3, published)
+			require.Equal(t, 0, cancelled)
+			for i, scheduledAt := range p.times {
+				require.True(t, lastSchedule.Add(time.Duration(i+1)*time.Hour).Equal(scheduledAt))
+			}
+		})
+	}
+}
+
+func TestMissedRuns(t *testing.T) {
+	now := time.Date(2021, 6, 2, 10, 30, 0, 0, time.UTC)
+	lastSchedule := metav1.NewTime(time.Date(2021, 6, 2, 6, 0, 0, 0, time.UTC))
+	deadline := func(seconds int64) *int64 { return &seconds }
+
+	for _, test := range []struct {
+		name     string
+		spec     fv1.TimeTriggerSpec
+		status   fv1.TimeTriggerStatus
+		expected []time.Time
+	}{
+		{
+			name:   "no starting deadline",
+			spec:   fv1.TimeTriggerSpec{Cron: "@hourly"},
+			status: fv1.TimeTriggerStatus{LastScheduleTime: &lastSchedule},
+		},
+		{
+			name:     "latest run within deadline",
+			spec:     fv1.TimeTriggerSpec{Cron: "@hourly", StartingDeadlineSeconds: deadline(3 * 3600)},
+			status:   fv1.TimeTriggerStatus{LastScheduleTime: &lastSchedule},
+			expected: []time.Time{time.Date(2021, 6, 2, 10, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:   "all runs within deadline",
+			spec:   fv1.TimeTriggerSpec{Cron: "@hourly", StartingDeadlineSeconds: deadline(3 * 3600), CatchUpPolicy: fv1.CatchUpAll},
+			status: fv1.TimeTriggerStatus{LastScheduleTime: &lastSchedule},
+			expected: []time.Time{
+				time.Date(2021, 6, 2, 8, 0, 0, 0, time.UTC),
+				time.Date(2021, 6, 2, 9, 0, 0, 0, time.UTC),
+				time.Date(2021, 6, 2, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "runs missed since creation",
+			spec:   fv1.TimeTriggerSpec{Cron: "0 0 9 * * *", StartingDeadlineSeconds: deadline(24 * 3600), CatchUpPolicy: fv1.CatchUpAll},
+			status: fv1.TimeTriggerStatus{},
+			expected: []time.Time{
+				time.Date(2021, 6, 2, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "deadline passed",
+			spec:   fv1.TimeTriggerSpec{Cron: "0 0 9 * * *", StartingDeadlineSeconds: deadline(60)},
+			status: fv1.TimeTriggerStatus{LastScheduleTime: &lastSchedule},
+		},
+		{
+			name:     "time zone",
+			spec:     fv1.TimeTriggerSpec{Cron: "0 0 12 * * *", Timezone: "Asia/Kolkata", StartingDeadlineSeconds: deadline(24 * 3600)},
+			status:   fv1.TimeTriggerStatus{LastScheduleTime: &lastSchedule},
+			expected: []time.Time{time.Date(2021, 6, 2, 6, 30, 0, 0, time.UTC)},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			trigger := fv1.TimeTrigger{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "foo",
+					CreationTimestamp: metav1.NewTime(time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)),
+				},
+				Spec:   test.spec,
+				Status: test.status,
+			}
+			missed := missedRuns(trigger, now)
+			require.Len(t, missed, len(test.expected))
+			for i := range missed {
+				require.True(t, test.expected[i].Equal(missed[i]), "expected %v, got %v", test.expected[i], missed[i])
+			}
+		})
+	}
+}